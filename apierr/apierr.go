@@ -0,0 +1,126 @@
+// Package apierr описывает ошибки API и отвечает по ним клиенту в одном месте.
+//
+// Обработчик не собирает ответ об ошибке сам, а передаёт ошибку в контекст
+// (c.Error(err); return) — ответ формирует Handler. Ошибка *Error уже знает
+// HTTP-статус и код, доменные ошибки (ledger.ErrInsufficientFunds, ...)
+// сопоставляются со статусом и кодом в known, а всё остальное — внутренняя
+// ошибка: она пишется в журнал с идентификатором запроса, а клиент получает
+// только код и этот идентификатор.
+//
+// Ответ — привычный types.Response или, если клиент принимает
+// application/problem+json, документ RFC 9457 (бывший RFC 7807).
+package apierr
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend_golang/i18n"
+)
+
+// Коды, которые apierr выставляет сам
+const (
+	CodeInternal      = "INTERNAL_ERROR"
+	CodeMissingFields = "MISSING_FIELDS"
+	CodeRouteNotFound = "ROUTE_NOT_FOUND"
+)
+
+// Error ошибка с HTTP-статусом и кодом для клиента
+type Error struct {
+	Status int
+	// Code код ошибки в ответе: USER_NOT_FOUND, MISSING_FIELDS, ...
+	Code string
+	// Key ключ сообщения в каталоге i18n: код или код.вариант
+	Key  string
+	Args i18n.Args
+	// Fields ошибки отдельных полей запроса
+	Fields []FieldError
+	// Data дополнительные данные ответа, например response_code отказа по карте
+	Data map[string]interface{}
+	// Err причина; в ответ не попадает, только в журнал
+	Err error
+}
+
+// FieldError ошибка одного поля запроса
+type FieldError struct {
+	Field string `json:"field"`
+	// Code правило, которое нарушено: REQUIRED, ...; если Message не задано,
+	// сообщение берётся из каталога по ключу FIELD.<Code>
+	Code    string    `json:"code"`
+	Args    i18n.Args `json:"-"`
+	Message string    `json:"message"`
+}
+
+// New ошибка со статусом status и сообщением key; код — key без варианта
+// (NOT_FOUND.kyc → NOT_FOUND)
+func New(status int, key string, args ...i18n.Args) *Error {
+	code, _, _ := strings.Cut(key, ".")
+	e := &Error{Status: status, Code: code, Key: key}
+	for _, a := range args {
+		if e.Args == nil {
+			e.Args = make(i18n.Args, len(a))
+		}
+		for k, v := range a {
+			e.Args[k] = v
+		}
+	}
+	return e
+}
+
+// Wrap как New, но с причиной err: она пишется в журнал для ошибок 5xx
+func Wrap(err error, status int, key string, args ...i18n.Args) *Error {
+	e := New(status, key, args...)
+	e.Err = err
+	return e
+}
+
+// Missing не заполнены обязательные поля
+func Missing(fields ...string) *Error {
+	e := New(http.StatusBadRequest, CodeMissingFields, i18n.Args{"fields": strings.Join(fields, ", ")})
+	for _, field := range fields {
+		e.Fields = append(e.Fields, FieldError{Field: field, Code: "REQUIRED"})
+	}
+	return e
+}
+
+// NoRows превращает sql.ErrNoRows в «не найдено» с сообщением key;
+// остальные ошибки возвращает как есть
+func NoRows(err error, key string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(err, http.StatusNotFound, key)
+	}
+	return err
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Status, e.Code, e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Resolve ошибка для ответа клиенту: *Error из цепочки, известная доменная
+// ошибка или, если не подошло ничего, внутренняя ошибка сервера
+func Resolve(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for _, k := range known {
+		if errors.Is(err, k.target) {
+			e = Wrap(err, k.status, k.key)
+			if k.args != nil {
+				e.Args = k.args(err)
+			}
+			return e
+		}
+	}
+	return Wrap(err, http.StatusInternalServerError, CodeInternal)
+}
@@ -0,0 +1,152 @@
+package apierr
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/i18n"
+	"backend_golang/types"
+)
+
+// CorrelationIDHeader заголовок с идентификатором запроса: клиент может передать
+// свой, иначе он создаётся; возвращается в ответе и пишется в журнал
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// ProblemContentType тип ответа об ошибке по RFC 9457
+const ProblemContentType = "application/problem+json"
+
+const correlationIDKey = "correlation_id"
+
+var validCorrelationID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Handler промежуточный обработчик: выдаёт запросу идентификатор, а после
+// обработчиков отвечает по последней ошибке из c.Errors, если ответа ещё нет
+func Handler(c *gin.Context) {
+	id := c.GetHeader(CorrelationIDHeader)
+	if !validCorrelationID.MatchString(id) {
+		id = newCorrelationID()
+	}
+	c.Set(correlationIDKey, id)
+	c.Header(CorrelationIDHeader, id)
+
+	c.Next()
+
+	if len(c.Errors) == 0 {
+		return
+	}
+	err := c.Errors.Last().Err
+	if c.Writer.Written() {
+		log.Printf("❌ %s %s [%s]: %v (response already sent)", c.Request.Method, c.Request.URL.Path, id, err)
+		return
+	}
+	Respond(c, err)
+}
+
+// Abort передаёт ошибку в Handler и останавливает цепочку обработчиков;
+// для промежуточных обработчиков вроде auth.RequireSession
+func Abort(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// Recovered отвечает на панику в обработчике как на внутреннюю ошибку;
+// для gin.CustomRecovery
+func Recovered(c *gin.Context, recovered interface{}) {
+	Abort(c, fmt.Errorf("panic: %v", recovered))
+}
+
+// NoRoute ответ на запрос к несуществующему адресу
+func NoRoute(c *gin.Context) {
+	c.Error(New(http.StatusNotFound, CodeRouteNotFound))
+}
+
+// CorrelationID идентификатор запроса, выданный Handler
+func CorrelationID(c *gin.Context) string {
+	return c.GetString(correlationIDKey)
+}
+
+// Respond сразу отвечает клиенту по ошибке err. Внутренние ошибки пишутся
+// в журнал с идентификатором запроса, а их текст клиенту не показывается.
+func Respond(c *gin.Context, err error) {
+	e := Resolve(err)
+	id := CorrelationID(c)
+	if e.Status >= http.StatusInternalServerError {
+		log.Printf("❌ %s %s [%s] %s: %v", c.Request.Method, c.Request.URL.Path, id, e.Code, e.Err)
+	}
+
+	args := i18n.Args{"correlation_id": id}
+	for k, v := range e.Args {
+		args[k] = v
+	}
+	message := i18n.T(c, e.Key, args)
+
+	fields := make([]FieldError, len(e.Fields))
+	for i, f := range e.Fields {
+		if f.Message == "" {
+			f.Message = i18n.T(c, "FIELD."+f.Code, i18n.Args{"field": f.Field}, f.Args)
+		}
+		fields[i] = f
+	}
+
+	if !wantsProblem(c) {
+		data := make(map[string]interface{}, len(e.Data)+2)
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		if len(fields) > 0 {
+			data["errors"] = fields
+		}
+		if e.Status >= http.StatusInternalServerError {
+			data["correlation_id"] = id
+		}
+		response := types.Response{Success: false, Message: message, Error: e.Code}
+		if len(data) > 0 {
+			response.Data = data
+		}
+		c.JSON(e.Status, response)
+		return
+	}
+
+	// расширения RFC 9457: код ошибки, идентификатор запроса, ошибки полей
+	// и дополнительные данные ошибки
+	problem := gin.H{
+		"type":           types.ProblemTypeBase + strings.ToLower(strings.ReplaceAll(e.Code, "_", "-")),
+		"title":          http.StatusText(e.Status),
+		"status":         e.Status,
+		"detail":         message,
+		"instance":       c.Request.URL.Path,
+		"code":           e.Code,
+		"correlation_id": id,
+	}
+	if len(fields) > 0 {
+		problem["errors"] = fields
+	}
+	for k, v := range e.Data {
+		if _, ok := problem[k]; !ok {
+			problem[k] = v
+		}
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(e.Status, problem)
+}
+
+// wantsProblem клиент просит ответ в формате RFC 9457
+// или он включён для всех ответов
+func wantsProblem(c *gin.Context) bool {
+	return types.ProblemResponses || strings.Contains(c.GetHeader("Accept"), ProblemContentType)
+}
+
+func newCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package apierr
+
+import (
+	"net/http"
+
+	"backend_golang/fraud"
+	"backend_golang/i18n"
+	"backend_golang/kyc"
+	"backend_golang/ledger"
+	"backend_golang/notify"
+	"backend_golang/sanctions"
+	"backend_golang/types"
+	"backend_golang/webhooks"
+)
+
+// known доменные ошибки и ответ клиенту на них. Ошибки обработчиков
+// объявляются сразу как *Error и сюда не попадают.
+var known = []struct {
+	target error
+	status int
+	key    string
+	// args параметры сообщения, если они нужны
+	args func(err error) i18n.Args
+}{
+	{ledger.ErrInsufficientFunds, http.StatusUnprocessableEntity, "INSUFFICIENT_FUNDS", nil},
+	{ledger.ErrAccountNotFound, http.StatusNotFound, "ACCOUNT_NOT_FOUND", nil},
+	{ledger.ErrInvalidAccount, http.StatusBadRequest, "INVALID_ACCOUNT", nil},
+	{ledger.ErrInvalidAmount, http.StatusBadRequest, "INVALID_AMOUNT", nil},
+	{ledger.ErrNotReversible, http.StatusConflict, "NOT_REVERSIBLE", nil},
+	{ledger.ErrReversalExceeds, http.StatusConflict, "REVERSAL_EXCEEDS_AMOUNT", nil},
+	{ledger.ErrHoldNotActive, http.StatusConflict, "HOLD_NOT_ACTIVE", nil},
+
+	{kyc.ErrLimitExceeded, http.StatusForbidden, "KYC_LIMIT_EXCEEDED", errorText},
+	{kyc.ErrBlobNotFound, http.StatusNotFound, "DOCUMENT_FILE_MISSING", nil},
+	{kyc.ErrInvalidTransition, http.StatusConflict, "INVALID_KYC_STATUS", nil},
+	{kyc.ErrDocumentsLocked, http.StatusConflict, "DOCUMENTS_LOCKED", nil},
+	{kyc.ErrMissingDocuments, http.StatusBadRequest, "MISSING_DOCUMENTS", nil},
+	{kyc.ErrUnknownDocumentType, http.StatusBadRequest, "INVALID_DOCUMENT_TYPE", nil},
+	{kyc.ErrUnsupportedFile, http.StatusUnsupportedMediaType, "UNSUPPORTED_FILE_TYPE", nil},
+	{kyc.ErrFileTooLarge, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", kycMaxFileSize},
+	{kyc.ErrInvalidLevel, http.StatusBadRequest, "INVALID_LEVEL", nil},
+
+	{sanctions.ErrReviewNotPending, http.StatusConflict, "REVIEW_NOT_PENDING", nil},
+	{fraud.ErrReviewNotPending, http.StatusConflict, "REVIEW_NOT_PENDING", nil},
+
+	{notify.ErrUnknownEvent, http.StatusBadRequest, "INVALID_EVENT", nil},
+	{notify.ErrUnknownChannel, http.StatusBadRequest, "INVALID_CHANNEL", nil},
+	{notify.ErrInvalidLocale, http.StatusBadRequest, "INVALID_LOCALE", nil},
+	{notify.ErrInvalidEmail, http.StatusBadRequest, "INVALID_EMAIL", nil},
+	{notify.ErrInvalidTimezone, http.StatusBadRequest, "INVALID_TIMEZONE", nil},
+	{notify.ErrInvalidQuietHours, http.StatusBadRequest, "INVALID_QUIET_HOURS", nil},
+	{notify.ErrInvalidPlatform, http.StatusBadRequest, "INVALID_PLATFORM", nil},
+
+	{webhooks.ErrInvalidURL, http.StatusBadRequest, "INVALID_URL", nil},
+	{webhooks.ErrPrivateAddress, http.StatusBadRequest, "INVALID_URL.private_address", nil},
+	{webhooks.ErrInvalidEventTypes, http.StatusBadRequest, "INVALID_EVENT_TYPES", nil},
+	{webhooks.ErrDeliveryPending, http.StatusConflict, "DELIVERY_PENDING", nil},
+}
+
+// errorText текст ошибки в сообщении: подробности лимита и т.п.
+func errorText(err error) i18n.Args {
+	return i18n.Args{"error": err.Error()}
+}
+
+func kycMaxFileSize(error) i18n.Args {
+	return i18n.Args{"max_mb": types.KYCMaxFileSize >> 20}
+}
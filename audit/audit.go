@@ -0,0 +1,204 @@
+// Package audit ведёт журнал событий безопасности и денежных операций.
+// Журнал только дописывается: каждая запись содержит хеш предыдущей,
+// поэтому изменение или удаление записи задним числом ломает цепочку
+// и обнаруживается проверкой Verify.
+package audit
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// События журнала
+const (
+	EventRegistered          = "auth.registered"
+	EventLoginSucceeded      = "auth.login_succeeded"
+	EventLoginFailed         = "auth.login_failed"
+	EventPasswordChanged     = "auth.password_changed"
+	EventProfileUpdated      = "user.profile_updated"
+	EventUserDeleted         = "user.deleted"
+	EventTransactionPosted   = "ledger.transaction_posted"
+	EventAdminAction         = "admin.action"
+	EventFraudDecision       = "fraud.decision"
+	EventFraudReviewed       = "fraud.review_decided"
+	EventSanctionsHit        = "sanctions.hit"
+	EventSanctionsReviewed   = "sanctions.review_decided"
+	EventKYCDocumentUploaded = "kyc.document_uploaded"
+	EventKYCDocumentViewed   = "kyc.document_viewed"
+	EventKYCSubmitted        = "kyc.submitted"
+	EventKYCReviewed         = "kyc.reviewed"
+)
+
+// Результаты событий
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// GenesisHash предыдущий хеш для первой записи журнала
+var GenesisHash = strings.Repeat("0", 64)
+
+// Entry запись журнала
+type Entry struct {
+	ID        int64                  `json:"id"`
+	CreatedAt time.Time              `json:"-"`
+	Created   string                 `json:"created_at"`
+	Event     string                 `json:"event"`
+	ActorID   int64                  `json:"actor_id,omitempty"`
+	Subject   string                 `json:"subject,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	PrevHash  string                 `json:"prev_hash"`
+	Hash      string                 `json:"hash"`
+
+	details string
+}
+
+// hash считает SHA-256 записи вместе с хешем предыдущей
+func (e Entry) hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%s|%d|%s|%s|%s|%s|%s",
+		e.ID, e.CreatedAt.UTC().Format(time.RFC3339Nano), e.Event, e.ActorID,
+		e.Subject, e.IP, e.Outcome, e.details, e.PrevHash,
+	)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Record дописывает запись в журнал внутри транзакции tx.
+// Голова цепочки блокируется до конца tx, поэтому записи
+// выстраиваются в одну цепочку без пропусков.
+func Record(tx *sql.Tx, e Entry) error {
+	if e.Outcome == "" {
+		e.Outcome = OutcomeSuccess
+	}
+
+	details := "{}"
+	if len(e.Details) > 0 {
+		body, err := json.Marshal(e.Details)
+		if err != nil {
+			return err
+		}
+		details = string(body)
+	}
+	e.details = details
+
+	var lastID int64
+	err := tx.QueryRow("SELECT last_id, last_hash FROM audit_head WHERE id = 1 FOR UPDATE").Scan(&lastID, &e.PrevHash)
+	if err != nil {
+		return err
+	}
+
+	e.ID = lastID + 1
+	e.CreatedAt = time.Now().Truncate(time.Microsecond)
+	e.Hash = e.hash()
+
+	var actor interface{}
+	if e.ActorID != 0 {
+		actor = e.ActorID
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO audit_log (id, created_at, event, actor_id, subject, ip, outcome, details, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.CreatedAt, e.Event, actor, e.Subject, e.IP, e.Outcome, details, e.PrevHash, e.Hash,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE audit_head SET last_id = ?, last_hash = ? WHERE id = 1", e.ID, e.Hash)
+	return err
+}
+
+// Log дописывает запись в журнал в отдельной транзакции
+func Log(e Entry) error {
+	return database.WithTx(func(tx *sql.Tx) error {
+		return Record(tx, e)
+	})
+}
+
+// LogRequest пишет событие HTTP-запроса с IP клиента.
+// Ошибка записи в журнал не прерывает запрос и только логируется.
+func LogRequest(c *gin.Context, actorID int64, event, subject, outcome string, details map[string]interface{}) {
+	err := Log(Entry{
+		Event:   event,
+		ActorID: actorID,
+		Subject: subject,
+		IP:      c.ClientIP(),
+		Outcome: outcome,
+		Details: details,
+	})
+	if err != nil {
+		log.Printf("❌ Audit %s failed: %v", event, err)
+	}
+}
+
+// UserSubject возвращает субъект события для пользователя
+func UserSubject(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// PostHook пишет в журнал каждую проведённую транзакцию в той же транзакции БД
+func PostHook(tx *sql.Tx, txID int64, t ledger.Transaction) error {
+	if t.Currency == "" {
+		t.Currency = types.DefaultCurrency
+	}
+
+	details := map[string]interface{}{
+		"kind":         t.Kind,
+		"from_account": t.FromAccount,
+		"to_account":   t.ToAccount,
+		"amount":       ledger.Round(t.Amount),
+		"currency":     t.Currency,
+	}
+	if t.ReferenceID != 0 {
+		details["reference_id"] = t.ReferenceID
+	}
+	if t.Force {
+		details["force"] = true
+	}
+
+	return Record(tx, Entry{
+		Event:   EventTransactionPosted,
+		ActorID: t.CreatedBy,
+		Subject: fmt.Sprintf("transaction:%d", txID),
+		Details: details,
+	})
+}
+
+// Middleware пишет в журнал каждый запрос группы как событие event.
+// actor возвращает ID пользователя, выполнившего запрос.
+func Middleware(event string, actor func(*gin.Context) int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		outcome := OutcomeSuccess
+		if c.Writer.Status() >= 400 {
+			outcome = OutcomeFailure
+		}
+
+		details := map[string]interface{}{
+			"method": c.Request.Method,
+			"route":  c.FullPath(),
+			"path":   c.Request.URL.Path,
+			"status": c.Writer.Status(),
+		}
+		if c.Request.URL.RawQuery != "" {
+			details["query"] = c.Request.URL.RawQuery
+		}
+
+		LogRequest(c, actor(c), event, "", outcome, details)
+	}
+}
@@ -0,0 +1,195 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// verifyBatch сколько записей читать за один запрос при проверке
+const verifyBatch = 1000
+
+// Problem нарушение цепочки
+type Problem struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// VerifyReport результат проверки журнала
+type VerifyReport struct {
+	CheckedAt string    `json:"checked_at"`
+	Entries   int       `json:"entries"`
+	LastID    int64     `json:"last_id"`
+	LastHash  string    `json:"last_hash"`
+	OK        bool      `json:"ok"`
+	Problems  []Problem `json:"problems"`
+}
+
+// Verify проходит журнал от первой записи до последней, пересчитывает
+// хеши и проверяет, что номера идут подряд, каждая запись ссылается на
+// хеш предыдущей, а голова цепочки указывает на последнюю запись
+func Verify() (VerifyReport, error) {
+	report := VerifyReport{
+		CheckedAt: time.Now().Format(types.TimeLayout),
+		LastHash:  GenesisHash,
+		Problems:  make([]Problem, 0),
+	}
+
+	for {
+		entries, err := load(database.DB, "WHERE id > ? ORDER BY id LIMIT ?", report.LastID, verifyBatch)
+		if err != nil {
+			return report, err
+		}
+
+		for _, e := range entries {
+			report.check(e)
+		}
+
+		if len(entries) < verifyBatch {
+			break
+		}
+	}
+
+	var headID int64
+	var headHash string
+	err := database.DB.QueryRow("SELECT last_id, last_hash FROM audit_head WHERE id = 1").Scan(&headID, &headHash)
+	if err != nil {
+		return report, err
+	}
+	report.checkHead(headID, headHash)
+
+	report.OK = len(report.Problems) == 0
+	return report, nil
+}
+
+// check проверяет очередную запись журнала по предыдущей
+func (r *VerifyReport) check(e Entry) {
+	if e.ID != r.LastID+1 {
+		r.Problems = append(r.Problems, Problem{e.ID,
+			fmt.Sprintf("записи %d–%d отсутствуют", r.LastID+1, e.ID-1)})
+	}
+	if e.PrevHash != r.LastHash {
+		r.Problems = append(r.Problems, Problem{e.ID, "prev_hash не совпадает с хешем предыдущей записи"})
+	}
+	if e.hash() != e.Hash {
+		r.Problems = append(r.Problems, Problem{e.ID, "хеш не совпадает с содержимым записи"})
+	}
+	r.Entries++
+	r.LastID, r.LastHash = e.ID, e.Hash
+}
+
+// checkHead сверяет голову цепочки с последней проверенной записью
+func (r *VerifyReport) checkHead(headID int64, headHash string) {
+	if headID != r.LastID || headHash != r.LastHash {
+		r.Problems = append(r.Problems, Problem{headID,
+			fmt.Sprintf("голова цепочки указывает на запись %d, последняя запись в журнале — %d", headID, r.LastID)})
+	}
+}
+
+// Filter условия выборки из журнала
+type Filter struct {
+	Event    string
+	ActorID  int64
+	Subject  string
+	Outcome  string
+	From     time.Time
+	To       time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// Find возвращает записи по фильтру от новых к старым
+func Find(q ledger.Querier, f Filter) ([]Entry, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if f.Event != "" {
+		// событие можно задать префиксом: auth.* выберет все события входа
+		if prefix, ok := strings.CutSuffix(f.Event, "*"); ok {
+			add("event LIKE ?", prefix+"%")
+		} else {
+			add("event = ?", f.Event)
+		}
+	}
+	if f.ActorID != 0 {
+		add("actor_id = ?", f.ActorID)
+	}
+	if f.Subject != "" {
+		add("subject = ?", f.Subject)
+	}
+	if f.Outcome != "" {
+		add("outcome = ?", f.Outcome)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To)
+	}
+	if f.BeforeID > 0 {
+		add("id < ?", f.BeforeID)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	return load(q, where+" ORDER BY id DESC LIMIT ?", append(args, f.Limit)...)
+}
+
+func load(q ledger.Querier, clause string, args ...interface{}) ([]Entry, error) {
+	rows, err := q.Query(
+		`SELECT id, created_at, event, actor_id, subject, ip, outcome, details, prev_hash, hash
+		FROM audit_log `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var e Entry
+		var actor sql.NullInt64
+		err := rows.Scan(&e.ID, &e.CreatedAt, &e.Event, &actor, &e.Subject, &e.IP, &e.Outcome,
+			&e.details, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		e.ActorID = actor.Int64
+		e.Created = e.CreatedAt.Format(types.TimeLayout)
+		if err := json.Unmarshal([]byte(e.details), &e.Details); err != nil {
+			return nil, fmt.Errorf("audit entry %d: %w", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Summary короткое текстовое описание отчёта для консоли
+func (r VerifyReport) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Checked %d audit entries at %s, last id %d\n", r.Entries, r.CheckedAt, r.LastID)
+	if r.OK {
+		b.WriteString("✅ Audit chain is intact\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "❌ %d problems\n", len(r.Problems))
+	for _, p := range r.Problems {
+		fmt.Fprintf(&b, "  entry %d: %s\n", p.ID, p.Reason)
+	}
+	return b.String()
+}
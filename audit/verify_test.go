@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+// chain строит цепочку из n записей с верными хешами
+func chain(n int) []Entry {
+	entries := make([]Entry, 0, n)
+	prev := GenesisHash
+	for i := 1; i <= n; i++ {
+		e := Entry{
+			ID:        int64(i),
+			CreatedAt: time.Date(2025, 3, 1, 12, 0, i, 0, time.UTC),
+			Event:     EventLoginSucceeded,
+			ActorID:   42,
+			Subject:   "user:42",
+			IP:        "10.0.0.1",
+			Outcome:   OutcomeSuccess,
+			PrevHash:  prev,
+			details:   `{"device":"web"}`,
+		}
+		e.Hash = e.hash()
+		prev = e.Hash
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestVerifyChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]Entry) []Entry
+		head   int64 // ID записи, на которую указывает голова; 0 — последняя
+		bad    []int64
+	}{
+		{"intact", func(e []Entry) []Entry { return e }, 0, nil},
+		{"edited details", func(e []Entry) []Entry {
+			e[2].details = `{"device":"evil"}`
+			return e
+		}, 0, []int64{3}},
+		{"edited and rehashed", func(e []Entry) []Entry {
+			e[2].ActorID = 1
+			e[2].Hash = e[2].hash()
+			return e
+		}, 0, []int64{4}},
+		{"deleted entry", func(e []Entry) []Entry {
+			return append(e[:2], e[3:]...)
+		}, 0, []int64{4, 4}},
+		{"deleted tail", func(e []Entry) []Entry {
+			return e[:3]
+		}, 5, []int64{5}},
+		{"empty journal", func(e []Entry) []Entry { return nil }, 0, nil},
+	}
+	for _, tt := range tests {
+		entries := tt.tamper(chain(5))
+
+		report := VerifyReport{LastHash: GenesisHash}
+		for _, e := range entries {
+			report.check(e)
+		}
+
+		headID, headHash := report.LastID, report.LastHash
+		if tt.head != 0 {
+			full := chain(5)
+			headID, headHash = tt.head, full[tt.head-1].Hash
+		}
+		report.checkHead(headID, headHash)
+
+		if len(report.Problems) != len(tt.bad) {
+			t.Errorf("%s: problems %v, want entries %v", tt.name, report.Problems, tt.bad)
+			continue
+		}
+		for i, p := range report.Problems {
+			if p.ID != tt.bad[i] {
+				t.Errorf("%s: problems %v, want entries %v", tt.name, report.Problems, tt.bad)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/integrity"
+	"backend_golang/validation"
+)
+
+// commands служебные команды, которые запускаются вместо сервера:
+//
+//	simple_bank verify-ledger [-from YYYY-MM-DD]
+//	simple_bank snapshot
+//	simple_bank verify-audit
+//	simple_bank check-i18n [-dir .]
+//	simple_bank normalize-phones [-dry-run]
+var commands = map[string]func(args []string) int{
+	"verify-ledger":    verifyLedger,
+	"snapshot":         snapshot,
+	"verify-audit":     verifyAudit,
+	"check-i18n":       checkI18n,
+	"normalize-phones": normalizePhones,
+}
+
+// runCommand выполняет команду из аргументов и возвращает код выхода
+func runCommand(args []string) int {
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		fmt.Fprintln(os.Stderr, "usage: simple_bank [verify-ledger [-from YYYY-MM-DD] | snapshot | verify-audit | check-i18n [-dir .] | normalize-phones [-dry-run]]")
+		return 2
+	}
+	return command(args[1:])
+}
+
+// verifyLedger печатает отчёт о сверке книг; код выхода 1, если есть расхождения
+func verifyLedger(args []string) int {
+	flags := flag.NewFlagSet("verify-ledger", flag.ContinueOnError)
+	rawFrom := flags.String("from", "", "check snapshots starting from this date (YYYY-MM-DD)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var from time.Time
+	if *rawFrom != "" {
+		var err error
+		if from, err = time.ParseInLocation("2006-01-02", *rawFrom, time.Local); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid -from:", err)
+			return 2
+		}
+	}
+
+	if err := integrity.BackfillOpeningBalances(); err != nil {
+		fmt.Fprintln(os.Stderr, "backfill failed:", err)
+		return 2
+	}
+
+	report, err := integrity.Verify(from)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "verify failed:", err)
+		return 2
+	}
+
+	fmt.Print(report.Summary())
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// snapshot снимает остатки за все закрытые дни без снимка
+func snapshot(args []string) int {
+	if err := integrity.BackfillOpeningBalances(); err != nil {
+		fmt.Fprintln(os.Stderr, "backfill failed:", err)
+		return 2
+	}
+	if err := integrity.SnapshotJob(); err != nil {
+		fmt.Fprintln(os.Stderr, "snapshot failed:", err)
+		return 2
+	}
+	fmt.Println("✅ Snapshots are up to date")
+	return 0
+}
+
+// verifyAudit проверяет цепочку хешей журнала аудита; код выхода 1, если она нарушена
+func verifyAudit(args []string) int {
+	report, err := audit.Verify()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "verify failed:", err)
+		return 2
+	}
+
+	fmt.Print(report.Summary())
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// normalizePhones приводит телефоны, сохранённые до проверки формата, к E.164:
+// у пользователей и в счетах на оплату. Номер, который не разбирается или
+// совпадёт с чужим, остаётся как есть и печатается; тогда код выхода 1.
+func normalizePhones(args []string) int {
+	flags := flag.NewFlagSet("normalize-phones", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print changes without saving them")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	type user struct {
+		id    int64
+		phone string
+	}
+	rows, err := database.DB.Query("SELECT id, phone_number FROM users ORDER BY id")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "normalize failed:", err)
+		return 2
+	}
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.phone); err != nil {
+			rows.Close()
+			fmt.Fprintln(os.Stderr, "normalize failed:", err)
+			return 2
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "normalize failed:", err)
+		return 2
+	}
+
+	// Номер достаётся тому, у кого он уже записан в E.164, иначе первому по id
+	owners := make(map[string]int64)
+	for _, exact := range []bool{true, false} {
+		for _, u := range users {
+			phone, ok := validation.NormalizePhone(u.phone)
+			if _, taken := owners[phone]; ok && !taken && (phone == u.phone) == exact {
+				owners[phone] = u.id
+			}
+		}
+	}
+
+	changed, problems := 0, 0
+	for _, u := range users {
+		phone, ok := validation.NormalizePhone(u.phone)
+		switch {
+		case !ok:
+			fmt.Printf("❌ User %d: %q is not a phone number\n", u.id, u.phone)
+			problems++
+			continue
+		case owners[phone] != u.id:
+			fmt.Printf("❌ User %d: %q is the same number as user %d has\n", u.id, u.phone, owners[phone])
+			problems++
+			continue
+		case phone == u.phone:
+			continue
+		}
+
+		fmt.Printf("📞 User %d: %q → %s\n", u.id, u.phone, phone)
+		changed++
+		if *dryRun {
+			continue
+		}
+		if _, err := database.DB.Exec("UPDATE users SET phone_number = ? WHERE id = ?", phone, u.id); err != nil {
+			fmt.Fprintln(os.Stderr, "normalize failed:", err)
+			return 2
+		}
+	}
+
+	// В счетах на оплату номер только сравнивается с номером плательщика,
+	// поэтому совпадения не мешают
+	rows, err = database.DB.Query("SELECT DISTINCT payer_phone FROM payment_requests WHERE payer_phone IS NOT NULL")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "normalize failed:", err)
+		return 2
+	}
+	var payers []string
+	for rows.Next() {
+		var phone string
+		if err := rows.Scan(&phone); err != nil {
+			rows.Close()
+			fmt.Fprintln(os.Stderr, "normalize failed:", err)
+			return 2
+		}
+		payers = append(payers, phone)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "normalize failed:", err)
+		return 2
+	}
+
+	for _, raw := range payers {
+		phone, ok := validation.NormalizePhone(raw)
+		if !ok || phone == raw {
+			continue
+		}
+		fmt.Printf("📞 Payment requests: %q → %s\n", raw, phone)
+		changed++
+		if *dryRun {
+			continue
+		}
+		if _, err := database.DB.Exec("UPDATE payment_requests SET payer_phone = ? WHERE payer_phone = ?", phone, raw); err != nil {
+			fmt.Fprintln(os.Stderr, "normalize failed:", err)
+			return 2
+		}
+	}
+
+	verb := "normalized"
+	if *dryRun {
+		verb = "to normalize"
+	}
+	fmt.Printf("✅ %d phone numbers %s, %d left as is\n", changed, verb, problems)
+	if problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Коды и ключи сообщений в исходниках: ключ после HTTP-статуса
+// в apierr.New, apierr.Wrap и таблице известных ошибок, ключи i18n.T
+// и коды отказов карточной сети
+var (
+	errorKeyPattern    = regexp.MustCompile(`http\.Status\w+, "([A-Za-z0-9_.]+)"`)
+	messageKeyPattern  = regexp.MustCompile(`i18n\.T\(c, "([A-Za-z0-9_.]+)"`)
+	declineCodePattern = regexp.MustCompile(`decline\{"\d+", "([A-Z0-9_]+)"\}`)
+)
+
+// checkI18n проверяет каталог сообщений: переводы на все языки и сообщения
+// для всех кодов ошибок из исходников в -dir; код выхода 1, если чего-то нет
+func checkI18n(args []string) int {
+	flags := flag.NewFlagSet("check-i18n", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "source directory to scan for error codes")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ok := true
+	if err := i18n.Check(); err != nil {
+		fmt.Println(err)
+		ok = false
+	}
+
+	// код покрыт, если есть сообщение для него самого или для его варианта;
+	// "FIELD." в i18n.T(c, "FIELD."+code) — если есть хоть один вариант
+	covered := make(map[string]bool)
+	for _, key := range i18n.Keys() {
+		covered[key] = true
+		if code, _, ok := strings.Cut(key, "."); ok {
+			covered[code] = true
+			covered[code+"."] = true
+		}
+	}
+
+	missing := make(map[string][]string)
+	err := filepath.WalkDir(*dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == ".git" || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, pattern := range []*regexp.Regexp{errorKeyPattern, messageKeyPattern, declineCodePattern} {
+			for _, match := range pattern.FindAllSubmatch(source, -1) {
+				key := string(match[1])
+				if !covered[key] {
+					missing[key] = append(missing[key], path)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "scan failed:", err)
+		return 2
+	}
+
+	if len(missing) > 0 {
+		keys := make([]string, 0, len(missing))
+		for key := range missing {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Printf("❌ %d code(s) without messages:\n", len(keys))
+		for _, key := range keys {
+			fmt.Printf("  %s (%s)\n", key, strings.Join(unique(missing[key]), ", "))
+		}
+		ok = false
+	}
+
+	if !ok {
+		return 1
+	}
+	fmt.Printf("✅ %d messages in %s, every code is translated\n", len(i18n.Keys()), strings.Join(i18n.Languages, ", "))
+	return 0
+}
+
+// unique убирает идущие подряд повторы
+func unique(values []string) []string {
+	var result []string
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -3,6 +3,7 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -26,6 +27,9 @@ var Address = "localhost"
 var Port = "3306"
 var DBName = "simple_bank"
 
+// init только готовит пул: sql.Open не подключается к серверу.
+// Подключение и миграции выполняет Connect, поэтому пакеты,
+// импортирующие database, тестируются без MySQL.
 func init() {
 	var err error
 
@@ -38,12 +42,20 @@ func init() {
 
 	DB.SetMaxOpenConns(25)
 	DB.SetMaxIdleConns(5)
+}
 
-	if err = DB.Ping(); err != nil {
-		log.Fatal("Error connecting to database:", err)
+// Connect проверяет подключение к базе и применяет миграции
+func Connect() error {
+	if err := DB.Ping(); err != nil {
+		return fmt.Errorf("connect: %w", err)
 	}
 
 	log.Println("✅ Database connection established")
+
+	if err := Migrate(); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
 }
 
 func Close() {
@@ -52,3 +64,18 @@ func Close() {
 		log.Println("📴 Database connection closed")
 	}
 }
+
+// WithTx выполняет fn в транзакции и фиксирует её, если fn не вернула ошибку
+func WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
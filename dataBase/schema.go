@@ -0,0 +1,492 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Schema содержит таблицы, которые создаются при старте приложения.
+// Таблица users создаётся вручную и здесь не описывается.
+var Schema = []string{
+	`CREATE TABLE IF NOT EXISTS user_roles (
+		user_id BIGINT NOT NULL,
+		role VARCHAR(32) NOT NULL,
+		PRIMARY KEY (user_id, role)
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		kind VARCHAR(32) NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'posted',
+		from_account VARCHAR(64) NOT NULL,
+		to_account VARCHAR(64) NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		currency CHAR(3) NOT NULL DEFAULT 'RUB',
+		memo VARCHAR(255) NOT NULL DEFAULT '',
+		reference_id BIGINT NULL,
+		reversed_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+		reason_code VARCHAR(32) NOT NULL DEFAULT '',
+		created_by BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_transactions_reference (reference_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS ledger_entries (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		transaction_id BIGINT NOT NULL,
+		account VARCHAR(64) NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_ledger_entries_transaction (transaction_id),
+		INDEX idx_ledger_entries_account (account, created_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS reversal_requests (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		transaction_id BIGINT NOT NULL,
+		requested_by BIGINT NOT NULL,
+		recipient_id BIGINT NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		reason VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		reversal_tx_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		resolved_at DATETIME NULL,
+		INDEX idx_reversal_requests_transaction (transaction_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS payment_requests (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		requester_id BIGINT NOT NULL,
+		payer_phone VARCHAR(32) NULL,
+		token VARCHAR(64) NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		currency CHAR(3) NOT NULL DEFAULT 'RUB',
+		memo VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		expires_at DATETIME NOT NULL,
+		paid_by BIGINT NULL,
+		paid_tx_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_payment_requests_token (token),
+		INDEX idx_payment_requests_requester (requester_id),
+		INDEX idx_payment_requests_payer (payer_phone)
+	)`,
+	`CREATE TABLE IF NOT EXISTS holds (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		source VARCHAR(32) NOT NULL,
+		reference_id BIGINT NOT NULL DEFAULT 0,
+		status VARCHAR(16) NOT NULL DEFAULT 'active',
+		expires_at DATETIME NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_holds_user_status (user_id, status)
+	)`,
+	`CREATE TABLE IF NOT EXISTS cards (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		pan_hash CHAR(64) NOT NULL,
+		last4 CHAR(4) NOT NULL,
+		expiry_month TINYINT NOT NULL,
+		expiry_year SMALLINT NOT NULL,
+		cvv_hash VARCHAR(255) NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'active',
+		per_transaction_limit DECIMAL(15,2) NOT NULL DEFAULT 0,
+		daily_limit DECIMAL(15,2) NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_cards_pan_hash (pan_hash),
+		INDEX idx_cards_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_authorizations (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		card_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		captured_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+		refunded_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+		merchant VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL,
+		hold_id BIGINT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_card_authorizations_card (card_id, created_at)
+	)`,
+	`ALTER TABLE holds ADD COLUMN description VARCHAR(255) NOT NULL DEFAULT ''`,
+	`CREATE INDEX idx_holds_status_expires ON holds (status, expires_at)`,
+	`CREATE TABLE IF NOT EXISTS savings_accounts (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		name VARCHAR(100) NOT NULL DEFAULT '',
+		product VARCHAR(32) NOT NULL DEFAULT 'standard',
+		balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+		status VARCHAR(16) NOT NULL DEFAULT 'open',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_savings_accounts_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS savings_rates (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		product VARCHAR(32) NOT NULL,
+		annual_rate DECIMAL(7,4) NOT NULL,
+		effective_from DATE NOT NULL,
+		created_by BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_savings_rates_product_date (product, effective_from)
+	)`,
+	`CREATE TABLE IF NOT EXISTS savings_accruals (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		account_id BIGINT NOT NULL,
+		accrual_date DATE NOT NULL,
+		eod_balance DECIMAL(15,2) NOT NULL,
+		annual_rate DECIMAL(7,4) NOT NULL,
+		day_count VARCHAR(16) NOT NULL,
+		amount DECIMAL(18,6) NOT NULL,
+		capitalised_tx_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_savings_accruals_account_date (account_id, accrual_date)
+	)`,
+	`CREATE TABLE IF NOT EXISTS loans (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		principal DECIMAL(15,2) NOT NULL,
+		annual_rate DECIMAL(7,4) NOT NULL,
+		term_months INT NOT NULL,
+		schedule_type VARCHAR(16) NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'applied',
+		balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+		decided_by BIGINT NULL,
+		disbursed_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_loans_user (user_id),
+		INDEX idx_loans_status (status)
+	)`,
+	`ALTER TABLE loans ADD COLUMN interest_paid_to DATE NULL`,
+	`CREATE TABLE IF NOT EXISTS loan_installments (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		loan_id BIGINT NOT NULL,
+		number INT NOT NULL,
+		due_date DATE NOT NULL,
+		principal DECIMAL(15,2) NOT NULL,
+		interest DECIMAL(15,2) NOT NULL,
+		late_fee DECIMAL(15,2) NOT NULL DEFAULT 0,
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		paid_tx_id BIGINT NULL,
+		paid_at DATETIME NULL,
+		UNIQUE KEY uq_loan_installments_number (loan_id, number),
+		INDEX idx_loan_installments_due (status, due_date)
+	)`,
+	`CREATE TABLE IF NOT EXISTS overdrafts (
+		user_id BIGINT PRIMARY KEY,
+		limit_amount DECIMAL(15,2) NOT NULL,
+		annual_rate DECIMAL(7,4) NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'within',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS overdraft_interest (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		accrual_date DATE NOT NULL,
+		eod_balance DECIMAL(15,2) NOT NULL,
+		annual_rate DECIMAL(7,4) NOT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		transaction_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_overdraft_interest_user_date (user_id, accrual_date)
+	)`,
+	`CREATE TABLE IF NOT EXISTS notifications (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		event VARCHAR(64) NOT NULL,
+		params TEXT NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		last_error VARCHAR(255) NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		sent_at DATETIME NULL,
+		INDEX idx_notifications_status (status, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS goals (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		target_amount DECIMAL(15,2) NOT NULL,
+		target_date DATE NULL,
+		balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+		status VARCHAR(16) NOT NULL DEFAULT 'open',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_goals_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS goal_rules (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		goal_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		rule_type VARCHAR(32) NOT NULL,
+		value DECIMAL(15,2) NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_goal_rules_user (user_id, active)
+	)`,
+	`CREATE TABLE IF NOT EXISTS account_holders (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		account_user_id BIGINT NOT NULL,
+		holder_user_id BIGINT NOT NULL,
+		permission VARCHAR(16) NOT NULL,
+		transfer_limit DECIMAL(15,2) NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'invited',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_account_holders (account_user_id, holder_user_id),
+		INDEX idx_account_holders_holder (holder_user_id, status)
+	)`,
+	`ALTER TABLE users ADD COLUMN profile_type VARCHAR(16) NOT NULL DEFAULT 'standard'`,
+	`ALTER TABLE users ADD COLUMN guardian_id BIGINT NULL`,
+	`ALTER TABLE users ADD COLUMN daily_spend_limit DECIMAL(15,2) NULL`,
+	`CREATE TABLE IF NOT EXISTS payout_batches (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		created_by BIGINT NOT NULL,
+		file_name VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(32) NOT NULL,
+		total_rows INT NOT NULL DEFAULT 0,
+		invalid_rows INT NOT NULL DEFAULT 0,
+		total_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+		approved_by BIGINT NULL,
+		approved_at DATETIME NULL,
+		completed_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_payout_batches_user (user_id),
+		INDEX idx_payout_batches_status (status)
+	)`,
+	`CREATE TABLE IF NOT EXISTS payout_rows (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		batch_id BIGINT NOT NULL,
+		line_number INT NOT NULL,
+		recipient VARCHAR(64) NOT NULL,
+		recipient_user_id BIGINT NULL,
+		amount DECIMAL(15,2) NOT NULL,
+		memo VARCHAR(255) NOT NULL DEFAULT '',
+		status VARCHAR(16) NOT NULL,
+		error VARCHAR(255) NOT NULL DEFAULT '',
+		transaction_id BIGINT NULL,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_payout_rows_line (batch_id, line_number),
+		INDEX idx_payout_rows_status (batch_id, status)
+	)`,
+	`ALTER TABLE payout_batches ADD COLUMN message_id VARCHAR(35) NULL`,
+	`CREATE UNIQUE INDEX uq_payout_batches_message ON payout_batches (user_id, message_id)`,
+	`ALTER TABLE payout_rows ADD COLUMN end_to_end_id VARCHAR(35) NOT NULL DEFAULT ''`,
+	`CREATE INDEX idx_payout_rows_transaction ON payout_rows (transaction_id)`,
+	`CREATE TABLE IF NOT EXISTS balance_snapshots (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		snapshot_date DATE NOT NULL,
+		account VARCHAR(64) NOT NULL,
+		opening_balance DECIMAL(15,2) NOT NULL,
+		movement DECIMAL(15,2) NOT NULL,
+		closing_balance DECIMAL(15,2) NOT NULL,
+		entry_count INT NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_balance_snapshots_day (snapshot_date, account),
+		INDEX idx_balance_snapshots_account (account, snapshot_date)
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGINT PRIMARY KEY,
+		created_at DATETIME(6) NOT NULL,
+		event VARCHAR(64) NOT NULL,
+		actor_id BIGINT NULL,
+		subject VARCHAR(64) NOT NULL DEFAULT '',
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		outcome VARCHAR(16) NOT NULL,
+		details TEXT NOT NULL,
+		prev_hash CHAR(64) NOT NULL,
+		hash CHAR(64) NOT NULL,
+		INDEX idx_audit_log_event (event, id),
+		INDEX idx_audit_log_actor (actor_id, id),
+		INDEX idx_audit_log_subject (subject, id),
+		INDEX idx_audit_log_created (created_at)
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_head (
+		id TINYINT PRIMARY KEY,
+		last_id BIGINT NOT NULL,
+		last_hash CHAR(64) NOT NULL
+	)`,
+	`INSERT IGNORE INTO audit_head (id, last_id, last_hash) VALUES (1, 0, REPEAT('0', 64))`,
+	`CREATE TABLE IF NOT EXISTS user_devices (
+		user_id BIGINT NOT NULL,
+		device_id VARCHAR(128) NOT NULL,
+		first_seen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_seen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, device_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS fraud_reviews (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		event_type VARCHAR(16) NOT NULL,
+		user_id BIGINT NOT NULL,
+		actor_id BIGINT NOT NULL,
+		device_id VARCHAR(128) NOT NULL DEFAULT '',
+		ip VARCHAR(64) NOT NULL DEFAULT '',
+		score INT NOT NULL,
+		matches TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		hold_id BIGINT NULL,
+		status VARCHAR(16) NOT NULL,
+		decided_by BIGINT NULL,
+		note VARCHAR(255) NOT NULL DEFAULT '',
+		transaction_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		decided_at DATETIME NULL,
+		INDEX idx_fraud_reviews_status (status, id),
+		INDEX idx_fraud_reviews_user (user_id)
+	)`,
+	`ALTER TABLE users ADD COLUMN compliance_status VARCHAR(16) NOT NULL DEFAULT 'clear'`,
+	`CREATE TABLE IF NOT EXISTS compliance_reviews (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		subject_type VARCHAR(16) NOT NULL,
+		user_id BIGINT NOT NULL,
+		actor_id BIGINT NOT NULL,
+		screened_name VARCHAR(255) NOT NULL,
+		matches TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		hold_id BIGINT NULL,
+		status VARCHAR(16) NOT NULL,
+		decided_by BIGINT NULL,
+		note VARCHAR(255) NOT NULL DEFAULT '',
+		transaction_id BIGINT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		decided_at DATETIME NULL,
+		INDEX idx_compliance_reviews_status (status, id),
+		INDEX idx_compliance_reviews_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS sanctions_clearances (
+		user_id BIGINT NOT NULL,
+		list VARCHAR(32) NOT NULL,
+		entry_id VARCHAR(64) NOT NULL,
+		review_id BIGINT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, list, entry_id)
+	)`,
+	`ALTER TABLE users ADD COLUMN kyc_status VARCHAR(16) NOT NULL DEFAULT 'none'`,
+	`ALTER TABLE users ADD COLUMN kyc_level TINYINT NOT NULL DEFAULT 0`,
+	`ALTER TABLE users ADD COLUMN kyc_note VARCHAR(255) NOT NULL DEFAULT ''`,
+	`ALTER TABLE users ADD COLUMN kyc_updated_at DATETIME NULL`,
+	`CREATE TABLE IF NOT EXISTS kyc_documents (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		document_type VARCHAR(32) NOT NULL,
+		file_name VARCHAR(255) NOT NULL DEFAULT '',
+		content_type VARCHAR(64) NOT NULL,
+		size BIGINT NOT NULL,
+		sha256 CHAR(64) NOT NULL,
+		blob_key VARCHAR(128) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_kyc_documents_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS outbox_events (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		event_id CHAR(36) NOT NULL,
+		event_type VARCHAR(64) NOT NULL,
+		aggregate_type VARCHAR(32) NOT NULL,
+		aggregate_id VARCHAR(64) NOT NULL,
+		payload TEXT NOT NULL,
+		occurred_at DATETIME(6) NOT NULL,
+		published_at DATETIME(6) NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		last_error VARCHAR(255) NOT NULL DEFAULT '',
+		next_attempt_at DATETIME NULL,
+		UNIQUE KEY uq_outbox_event (event_id),
+		INDEX idx_outbox_pending (published_at, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		url VARCHAR(2048) NOT NULL,
+		secret VARCHAR(128) NOT NULL,
+		event_types TEXT NOT NULL,
+		description VARCHAR(255) NOT NULL DEFAULT '',
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+		INDEX idx_webhook_endpoints_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		endpoint_id BIGINT NOT NULL,
+		event_id CHAR(36) NOT NULL,
+		event_type VARCHAR(64) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(16) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_status_code INT NULL,
+		last_error VARCHAR(255) NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		delivered_at DATETIME NULL,
+		UNIQUE KEY uq_webhook_delivery (endpoint_id, event_id),
+		INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+		INDEX idx_webhook_deliveries_endpoint (endpoint_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS webhook_attempts (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		delivery_id BIGINT NOT NULL,
+		attempt INT NOT NULL,
+		status_code INT NULL,
+		error VARCHAR(255) NOT NULL DEFAULT '',
+		response_body VARCHAR(1024) NOT NULL DEFAULT '',
+		duration_ms INT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_webhook_attempts_delivery (delivery_id)
+	)`,
+	`ALTER TABLE notifications ADD COLUMN channel VARCHAR(16) NOT NULL DEFAULT ''`,
+	`ALTER TABLE notifications ADD COLUMN next_attempt_at DATETIME NULL`,
+	`CREATE TABLE IF NOT EXISTS notification_settings (
+		user_id BIGINT PRIMARY KEY,
+		locale VARCHAR(8) NOT NULL DEFAULT '',
+		email VARCHAR(255) NOT NULL DEFAULT '',
+		timezone VARCHAR(64) NOT NULL DEFAULT '',
+		quiet_from VARCHAR(5) NOT NULL DEFAULT '',
+		quiet_to VARCHAR(5) NOT NULL DEFAULT '',
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id BIGINT NOT NULL,
+		event VARCHAR(64) NOT NULL,
+		channel VARCHAR(16) NOT NULL,
+		enabled BOOLEAN NOT NULL,
+		PRIMARY KEY (user_id, event, channel)
+	)`,
+	`CREATE TABLE IF NOT EXISTS push_tokens (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		token VARCHAR(255) NOT NULL,
+		platform VARCHAR(16) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE KEY uq_push_token (token),
+		INDEX idx_push_tokens_user (user_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS notification_inbox (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		event VARCHAR(64) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		body TEXT NOT NULL,
+		params TEXT NOT NULL,
+		read_at DATETIME NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		INDEX idx_notification_inbox_user (user_id, id)
+	)`,
+}
+
+// Migrate создаёт недостающие таблицы из Schema.
+// Повторное добавление колонки или индекса ошибкой не считается,
+// поэтому ALTER TABLE в Schema можно выполнять при каждом старте.
+func Migrate() error {
+	for _, stmt := range Schema {
+		if _, err := DB.Exec(stmt); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && (mysqlErr.Number == 1060 || mysqlErr.Number == 1061) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
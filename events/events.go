@@ -0,0 +1,234 @@
+// Package events публикует доменные события для других сервисов.
+// Событие пишется в таблицу outbox_events в той же транзакции, что и само
+// изменение, поэтому при откате его никто не увидит, а при фиксации оно
+// точно будет опубликовано. Ретранслятор (RelayJob) отправляет события
+// через Publisher не реже одного раза; события одного агрегата уходят
+// в порядке записи. Получатели отбрасывают повторы по ID события.
+package events
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Типы событий
+const (
+	UserRegistered    = "UserRegistered"
+	LoggedIn          = "LoggedIn"
+	PasswordChanged   = "PasswordChanged"
+	ProfileUpdated    = "ProfileUpdated"
+	UserDeleted       = "UserDeleted"
+	TransferPosted    = "TransferPosted"
+	TransactionPosted = "TransactionPosted"
+)
+
+// Types все типы событий, на которые можно подписаться
+var Types = []string{
+	UserRegistered, LoggedIn, PasswordChanged, ProfileUpdated, UserDeleted,
+	TransferPosted, TransactionPosted,
+}
+
+// Типы агрегатов
+const (
+	AggregateUser    = "user"
+	AggregateAccount = "account"
+)
+
+// Event доменное событие
+type Event struct {
+	ID            string                 `json:"id"`
+	Sequence      int64                  `json:"sequence"`
+	Type          string                 `json:"type"`
+	AggregateType string                 `json:"aggregate_type"`
+	AggregateID   string                 `json:"aggregate_id"`
+	OccurredAt    time.Time              `json:"occurred_at"`
+	Payload       map[string]interface{} `json:"payload"`
+}
+
+// Key ключ агрегата; события с одним ключом публикуются по порядку
+func (e Event) Key() string {
+	return e.AggregateType + ":" + e.AggregateID
+}
+
+// Users клиенты, которых касается событие: сам пользователь для событий
+// пользователя и владельцы клиентских счетов обеих сторон проводки
+func (e Event) Users() []int64 {
+	var ids []int64
+	add := func(id int64) {
+		for _, existing := range ids {
+			if existing == id {
+				return
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	switch e.AggregateType {
+	case AggregateUser:
+		if id, err := strconv.ParseInt(e.AggregateID, 10, 64); err == nil {
+			add(id)
+		}
+	case AggregateAccount:
+		for _, field := range []string{"from_account", "to_account"} {
+			account, _ := e.Payload[field].(string)
+			if kind, id, err := ledger.ParseAccount(account); err == nil && kind == "user" {
+				add(id)
+			}
+		}
+	}
+	return ids
+}
+
+// ForUser событие агрегата «пользователь»
+func ForUser(eventType string, userID int64, payload map[string]interface{}) Event {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	payload["user_id"] = userID
+	return Event{
+		Type:          eventType,
+		AggregateType: AggregateUser,
+		AggregateID:   strconv.FormatInt(userID, 10),
+		Payload:       payload,
+	}
+}
+
+// Record пишет событие в outbox в транзакции изменения. Вызывать его нужно
+// после того, как изменение заблокировало строку агрегата: тогда события
+// одного агрегата получают номера в порядке фиксации транзакций.
+func Record(q ledger.Querier, e Event) error {
+	if e.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+
+	body, err := json.Marshal(e.Payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(
+		`INSERT INTO outbox_events (event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		e.ID, e.Type, e.AggregateType, e.AggregateID, string(body), e.OccurredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range recordHooks {
+		if err := hook(q, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const selectEvent = `SELECT id, event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at
+	FROM outbox_events`
+
+func scanEvent(row interface{ Scan(...interface{}) error }) (Event, error) {
+	var e Event
+	var payload string
+	err := row.Scan(&e.Sequence, &e.ID, &e.Type, &e.AggregateType, &e.AggregateID, &payload, &e.OccurredAt)
+	if err != nil {
+		return e, err
+	}
+	return e, json.Unmarshal([]byte(payload), &e.Payload)
+}
+
+// Since возвращает до limit событий с номером больше afterID по порядку,
+// опубликованные и нет
+func Since(q ledger.Querier, afterID int64, limit int) ([]Event, error) {
+	rows, err := q.Query(selectEvent+" WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]Event, 0)
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// RecordHook вызывается в транзакции изменения после записи события
+type RecordHook func(q ledger.Querier, e Event) error
+
+var recordHooks []RecordHook
+
+// OnRecord регистрирует hook, вызываемый после записи каждого события.
+// Ошибка hook откатывает всю транзакцию.
+func OnRecord(hook RecordHook) {
+	recordHooks = append(recordHooks, hook)
+}
+
+// PostHook пишет событие о каждой проведённой транзакции. Агрегат — счёт
+// клиента: счёт списания, а если списание с банковского счёта, то зачисления.
+// Такие счета блокируются при проводке, поэтому порядок событий сохраняется.
+func PostHook(tx *sql.Tx, txID int64, t ledger.Transaction) error {
+	if t.Currency == "" {
+		t.Currency = types.DefaultCurrency
+	}
+
+	eventType := TransactionPosted
+	if t.Kind == ledger.KindTransfer {
+		eventType = TransferPosted
+	}
+
+	account := t.FromAccount
+	if kind, _, err := ledger.ParseAccount(account); err == nil && kind == "bank" {
+		account = t.ToAccount
+	}
+
+	payload := map[string]interface{}{
+		"transaction_id": txID,
+		"kind":           t.Kind,
+		"from_account":   t.FromAccount,
+		"to_account":     t.ToAccount,
+		"amount":         ledger.Round(t.Amount),
+		"currency":       t.Currency,
+	}
+	if t.ReferenceID != 0 {
+		payload["reference_id"] = t.ReferenceID
+	}
+	if t.CreatedBy != 0 {
+		payload["created_by"] = t.CreatedBy
+	}
+
+	return Record(tx, Event{
+		Type:          eventType,
+		AggregateType: AggregateAccount,
+		AggregateID:   account,
+		Payload:       payload,
+	})
+}
+
+// newID случайный UUID версии 4
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KafkaRESTPublisher публикует события в Kafka через REST Proxy (API v2).
+// Ключ записи — агрегат события, поэтому события одного агрегата попадают
+// в одну партицию и читаются в порядке публикации.
+type KafkaRESTPublisher struct {
+	url    string
+	topic  string
+	client *http.Client
+}
+
+func NewKafkaRESTPublisher(baseURL, topic string) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		url:    strings.TrimRight(baseURL, "/"),
+		topic:  topic,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{
+			{"key": e.Key(), "value": e},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/topics/"+url.PathEscape(p.topic), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("kafka rest: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	// Прокси отвечает 200, даже если запись не принята брокером
+	var result struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("kafka rest: %w", err)
+	}
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("kafka rest: error %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
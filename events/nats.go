@@ -0,0 +1,129 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NATSPublisher публикует события в NATS по текстовому протоколу клиента,
+// без внешних зависимостей. Тема — prefix.<тип события>. ID события уходит
+// в заголовке Nats-Msg-Id, по которому JetStream отбрасывает повторы.
+// Публикация считается выполненной, когда сервер ответил на PING после неё.
+type NATSPublisher struct {
+	addr   string
+	prefix string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	headers bool
+}
+
+func NewNATSPublisher(addr, prefix string) *NATSPublisher {
+	return &NATSPublisher{addr: addr, prefix: prefix}
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(ctx, p.prefix+"."+e.Type, e.ID, body); err != nil {
+		// после ошибки состояние потока неизвестно — соединение открывается заново
+		p.conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	setDeadline(ctx, conn)
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("nats: unexpected greeting %q", strings.TrimSpace(line))
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+
+	connect := fmt.Sprintf(`CONNECT {"verbose":false,"pedantic":false,"name":"simple_bank","headers":%t}`+"\r\n", info.Headers)
+	if _, err := conn.Write([]byte(connect)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	p.conn, p.reader, p.headers = conn, reader, info.Headers
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject, id string, body []byte) error {
+	setDeadline(ctx, p.conn)
+
+	var msg string
+	if p.headers {
+		header := "NATS/1.0\r\nNats-Msg-Id: " + id + "\r\n\r\n"
+		msg = fmt.Sprintf("HPUB %s %d %d\r\n%s%s\r\n", subject, len(header), len(header)+len(body), header, body)
+	} else {
+		msg = fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(body), body)
+	}
+	if _, err := p.conn.Write([]byte(msg + "PING\r\n")); err != nil {
+		return err
+	}
+
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := p.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", line)
+		}
+		// +OK и INFO о смене кластера пропускаются
+	}
+}
+
+// setDeadline ограничивает обмен с сервером сроком ctx, а без него — 10 секундами
+func setDeadline(ctx context.Context, conn net.Conn) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+}
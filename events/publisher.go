@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"backend_golang/types"
+)
+
+// Publisher отправляет событие во внешнюю шину. Ошибка означает, что
+// событие не доставлено и будет отправлено повторно.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// LogPublisher пишет события в лог; используется, пока шина не настроена
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, e Event) error {
+	log.Printf("📣 Event %s #%d %s %v", e.Type, e.Sequence, e.Key(), e.Payload)
+	return nil
+}
+
+// DefaultPublisher шина, через которую RelayJob публикует события
+var DefaultPublisher Publisher = LogPublisher{}
+
+// NewPublisher создаёт шину по имени: log, memory, nats или kafka
+func NewPublisher(name string) (Publisher, error) {
+	switch name {
+	case "log", "":
+		return LogPublisher{}, nil
+	case "memory":
+		return NewMemoryPublisher(), nil
+	case "nats":
+		return NewNATSPublisher(types.OutboxNATSAddr, types.OutboxTopic), nil
+	case "kafka":
+		return NewKafkaRESTPublisher(types.OutboxKafkaRESTURL, types.OutboxTopic), nil
+	}
+	return nil, fmt.Errorf("events: unknown publisher %q", name)
+}
+
+// MemoryPublisher шина внутри процесса: события получают подписчики
+// Subscribe. Подходит для подписчиков в том же сервисе и для проверок.
+type MemoryPublisher struct {
+	mu          sync.RWMutex
+	subscribers []func(Event) error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Subscribe добавляет подписчика на все события. Ошибка подписчика
+// возвращает событие в outbox, и его получат все подписчики ещё раз.
+func (p *MemoryPublisher) Subscribe(fn func(Event) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, fn)
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, e Event) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, fn := range p.subscribers {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
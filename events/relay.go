@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/types"
+)
+
+// relayBatch сколько событий ретранслятор берёт за один запуск
+const relayBatch = 500
+
+// maxBackoff предельная пауза между попытками отправить событие
+const maxBackoff = 10 * time.Minute
+
+// RelayJob публикует неотправленные события из outbox через DefaultPublisher.
+// События идут по порядку записи; если событие агрегата не отправилось,
+// следующие события этого агрегата ждут его повторной отправки, поэтому
+// порядок внутри агрегата не нарушается. Ретранслятор работает в одном
+// экземпляре на все серверы: пока блокировка MySQL занята, остальные
+// пропускают запуск.
+func RelayJob() error {
+	ctx := context.Background()
+	conn, err := database.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK('outbox_relay', 0)").Scan(&locked); err != nil {
+		return err
+	}
+	if locked.Int64 != 1 {
+		return nil
+	}
+	defer conn.ExecContext(ctx, "SELECT RELEASE_LOCK('outbox_relay')")
+
+	pending, err := loadPending()
+	if err != nil {
+		return err
+	}
+
+	// blocked агрегаты, у которых есть неотправленное событие раньше текущего
+	blocked := make(map[string]bool)
+	for _, p := range pending {
+		key := p.event.Key()
+		if blocked[key] {
+			continue
+		}
+		if p.nextAttempt.Valid && p.nextAttempt.Time.After(time.Now()) {
+			blocked[key] = true
+			continue
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		publishErr := DefaultPublisher.Publish(publishCtx, p.event)
+		cancel()
+
+		if publishErr != nil {
+			blocked[key] = true
+			log.Printf("❌ Event %s #%d publish failed (attempt %d): %v", p.event.Type, p.event.Sequence, p.attempts+1, publishErr)
+			_, err = database.DB.Exec(
+				"UPDATE outbox_events SET attempts = attempts + 1, last_error = LEFT(?, 255), next_attempt_at = ? WHERE id = ?",
+				publishErr.Error(), time.Now().Add(backoff(p.attempts+1)), p.event.Sequence,
+			)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = database.DB.Exec(
+			"UPDATE outbox_events SET attempts = attempts + 1, published_at = ? WHERE id = ?",
+			time.Now(), p.event.Sequence,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// опубликованные события хранятся ещё OutboxRetention для разбора инцидентов
+	_, err = database.DB.Exec(
+		"DELETE FROM outbox_events WHERE published_at < ? LIMIT 1000",
+		time.Now().Add(-types.OutboxRetention),
+	)
+	return err
+}
+
+type pendingEvent struct {
+	event       Event
+	attempts    int
+	nextAttempt sql.NullTime
+}
+
+func loadPending() ([]pendingEvent, error) {
+	rows, err := database.DB.Query(
+		`SELECT id, event_id, event_type, aggregate_type, aggregate_id, payload, occurred_at, attempts, next_attempt_at
+		FROM outbox_events WHERE published_at IS NULL ORDER BY id LIMIT ?`,
+		relayBatch,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingEvent
+	for rows.Next() {
+		var p pendingEvent
+		var payload string
+		err := rows.Scan(&p.event.Sequence, &p.event.ID, &p.event.Type, &p.event.AggregateType,
+			&p.event.AggregateID, &payload, &p.event.OccurredAt, &p.attempts, &p.nextAttempt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(payload), &p.event.Payload); err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// backoff пауза перед попыткой attempt: 2, 4, 8 ... секунд, не больше maxBackoff
+func backoff(attempt int) time.Duration {
+	if attempt > 10 {
+		return maxBackoff
+	}
+	delay := time.Duration(1<<attempt) * time.Second
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
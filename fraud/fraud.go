@@ -0,0 +1,254 @@
+// Package fraud оценивает переводы и входы по настраиваемым правилам.
+// Каждое сработавшее правило добавляет баллы, по сумме баллов выбирается
+// действие: пропустить, запросить подтверждение, отправить на проверку
+// аналитику или заблокировать. Правила читаются из JSON-файла и
+// перечитываются без перезапуска, как только файл изменится.
+package fraud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"backend_golang/audit"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Типы оцениваемых событий
+const (
+	EventTransfer = "transfer"
+	EventLogin    = "login"
+	// EventPayout строка пакета выплат в очереди аналитика. Оценивается
+	// правилами переводов, но сама не проводится: её загружают заново.
+	EventPayout = "payout"
+)
+
+// Действия по результату оценки
+const (
+	ActionAllow  = "allow"
+	ActionStepUp = "step_up"
+	ActionReview = "review"
+	ActionBlock  = "block"
+)
+
+// DeviceHeader заголовок с идентификатором устройства клиента
+const DeviceHeader = "X-Device-ID"
+
+// maxScore предел суммы баллов
+const maxScore = 100
+
+// Rule правило из файла
+type Rule struct {
+	Name    string             `json:"name"`
+	Type    string             `json:"type"`
+	Events  []string           `json:"events,omitempty"`
+	Score   int                `json:"score"`
+	Enabled *bool              `json:"enabled,omitempty"`
+	Params  map[string]float64 `json:"params"`
+}
+
+// Config набор правил и пороги действий
+type Config struct {
+	Thresholds struct {
+		StepUp int `json:"step_up"`
+		Review int `json:"review"`
+		Block  int `json:"block"`
+	} `json:"thresholds"`
+	Rules []Rule `json:"rules"`
+
+	LoadedAt string `json:"loaded_at,omitempty"`
+}
+
+// Event оцениваемое событие. UserID — владелец счёта, ActorID — тот,
+// кто действует (совладелец или опекун может действовать за владельца).
+type Event struct {
+	Type      string
+	UserID    int64
+	ActorID   int64
+	Amount    float64
+	Recipient string
+	DeviceID  string
+	IP        string
+}
+
+// Match сработавшее правило
+type Match struct {
+	Rule   string `json:"rule"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// Decision результат оценки
+type Decision struct {
+	Score   int     `json:"score"`
+	Action  string  `json:"action"`
+	Matches []Match `json:"matches"`
+}
+
+var (
+	mu       sync.RWMutex
+	current  = &Config{}
+	modified time.Time
+)
+
+// Current возвращает действующие правила
+func Current() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Load читает и проверяет правила из файла path и делает их действующими.
+// Если файл с ошибкой, остаются прежние правила.
+func Load(path string) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	config := &Config{}
+	if err := json.Unmarshal(body, config); err != nil {
+		return fmt.Errorf("fraud rules %s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return fmt.Errorf("fraud rules %s: %w", path, err)
+	}
+	config.LoadedAt = time.Now().Format(types.TimeLayout)
+
+	mu.Lock()
+	current = config
+	mu.Unlock()
+	return nil
+}
+
+func (c *Config) validate() error {
+	t := c.Thresholds
+	if t.StepUp <= 0 || t.StepUp > t.Review || t.Review > t.Block {
+		return errors.New("thresholds must be positive and step_up <= review <= block")
+	}
+
+	names := make(map[string]bool)
+	for i, r := range c.Rules {
+		kind, ok := ruleTypes[r.Type]
+		if !ok {
+			return fmt.Errorf("rule %d: unknown type %q", i+1, r.Type)
+		}
+		if r.Name == "" || names[r.Name] {
+			return fmt.Errorf("rule %d: name must be unique and not empty", i+1)
+		}
+		names[r.Name] = true
+
+		for _, param := range kind.params {
+			if r.Params[param] <= 0 {
+				return fmt.Errorf("rule %s: param %s must be positive", r.Name, param)
+			}
+		}
+		for _, event := range r.Events {
+			if event != EventTransfer && event != EventLogin {
+				return fmt.Errorf("rule %s: unknown event %q", r.Name, event)
+			}
+		}
+		if len(r.Events) == 0 {
+			c.Rules[i].Events = kind.events
+		}
+	}
+	return nil
+}
+
+// ReloadJob перечитывает файл правил, если он изменился с прошлой загрузки.
+// Пока файла нет, правила не действуют и все события пропускаются.
+func ReloadJob() error {
+	info, err := os.Stat(types.FraudRulesFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().After(modified) {
+		return nil
+	}
+
+	// Время изменения запоминается и при ошибке: повторять ту же ошибку
+	// при каждой проверке незачем, следующая правка файла перечитается снова
+	modified = info.ModTime()
+	if err := Load(types.FraudRulesFile); err != nil {
+		return err
+	}
+	log.Printf("🛡️ Fraud rules loaded from %s", types.FraudRulesFile)
+	return nil
+}
+
+// Evaluate прогоняет событие через действующие правила.
+// Решения строже allow пишутся в журнал аудита.
+func Evaluate(q ledger.Querier, e Event) (Decision, error) {
+	config := Current()
+	decision := Decision{Action: ActionAllow, Matches: make([]Match, 0)}
+
+	for _, r := range config.Rules {
+		if (r.Enabled != nil && !*r.Enabled) || !contains(r.Events, e.Type) {
+			continue
+		}
+
+		matched, reason, err := ruleTypes[r.Type].check(q, e, r.Params)
+		if err != nil {
+			return decision, fmt.Errorf("fraud rule %s: %w", r.Name, err)
+		}
+		if matched {
+			decision.Score += r.Score
+			decision.Matches = append(decision.Matches, Match{r.Name, r.Score, reason})
+		}
+	}
+
+	if decision.Score > maxScore {
+		decision.Score = maxScore
+	}
+
+	t := config.Thresholds
+	switch {
+	case len(config.Rules) == 0:
+	case decision.Score >= t.Block:
+		decision.Action = ActionBlock
+	case decision.Score >= t.Review:
+		decision.Action = ActionReview
+	case decision.Score >= t.StepUp:
+		decision.Action = ActionStepUp
+	}
+
+	if decision.Action != ActionAllow {
+		err := audit.Log(audit.Entry{
+			Event:   audit.EventFraudDecision,
+			ActorID: e.ActorID,
+			Subject: audit.UserSubject(e.UserID),
+			IP:      e.IP,
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]interface{}{
+				"type":      e.Type,
+				"action":    decision.Action,
+				"score":     decision.Score,
+				"matches":   decision.Matches,
+				"amount":    e.Amount,
+				"recipient": e.Recipient,
+				"device_id": e.DeviceID,
+			},
+		})
+		if err != nil {
+			log.Printf("❌ Audit %s failed: %v", audit.EventFraudDecision, err)
+		}
+	}
+	return decision, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package fraud
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы проверки
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// HoldSource источник холда на сумму перевода, ждущего проверки
+const HoldSource = "fraud_review"
+
+var ErrReviewNotPending = errors.New("fraud: review is not pending")
+
+// Review событие в очереди аналитика
+type Review struct {
+	ID            int64                  `json:"id"`
+	EventType     string                 `json:"event_type"`
+	UserID        int64                  `json:"user_id"`
+	ActorID       int64                  `json:"actor_id"`
+	DeviceID      string                 `json:"device_id,omitempty"`
+	IP            string                 `json:"ip,omitempty"`
+	Score         int                    `json:"score"`
+	Matches       []Match                `json:"matches"`
+	Payload       map[string]interface{} `json:"payload,omitempty"`
+	HoldID        int64                  `json:"hold_id,omitempty"`
+	Status        string                 `json:"status"`
+	DecidedBy     int64                  `json:"decided_by,omitempty"`
+	Note          string                 `json:"note,omitempty"`
+	TransactionID int64                  `json:"transaction_id,omitempty"`
+	CreatedAt     string                 `json:"created_at"`
+	DecidedAt     string                 `json:"decided_at,omitempty"`
+}
+
+// PendingTransfer перевод, который будет проведён после одобрения
+type PendingTransfer struct {
+	FromAccount string  `json:"from_account"`
+	ToAccount   string  `json:"to_account"`
+	Amount      float64 `json:"amount"`
+	Memo        string  `json:"memo,omitempty"`
+	// PaymentRequestID запрос денег, который оплачивается переводом
+	PaymentRequestID int64 `json:"payment_request_id,omitempty"`
+}
+
+// QueueTransfer блокирует сумму перевода холдом и ставит перевод в очередь аналитика
+func QueueTransfer(tx *sql.Tx, e Event, d Decision, t PendingTransfer) (int64, error) {
+	holdID, err := ledger.PlaceHold(tx, ledger.Hold{
+		UserID:      e.UserID,
+		Amount:      t.Amount,
+		Source:      HoldSource,
+		Description: "Перевод на проверке",
+		ExpiresAt:   time.Now().Add(types.FraudReviewHold),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reviewID, err := queue(tx, e, d, t, holdID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE holds SET reference_id = ? WHERE id = ?", reviewID, holdID)
+	return reviewID, err
+}
+
+// QueueLogin ставит вход в очередь аналитика; одобрение делает устройство знакомым
+func QueueLogin(q ledger.Querier, e Event, d Decision) (int64, error) {
+	return queue(q, e, d, nil, 0)
+}
+
+// QueuePayout ставит строку пакета выплат в очередь аналитика для сведения:
+// выплата не проведена, одобрение её не проводит
+func QueuePayout(q ledger.Querier, e Event, d Decision, payload map[string]interface{}) (int64, error) {
+	e.Type = EventPayout
+	return queue(q, e, d, payload, 0)
+}
+
+func queue(q ledger.Querier, e Event, d Decision, payload interface{}, holdID int64) (int64, error) {
+	matches, err := json.Marshal(d.Matches)
+	if err != nil {
+		return 0, err
+	}
+
+	body := []byte("{}")
+	if payload != nil {
+		if body, err = json.Marshal(payload); err != nil {
+			return 0, err
+		}
+	}
+
+	var hold interface{}
+	if holdID != 0 {
+		hold = holdID
+	}
+
+	result, err := q.Exec(
+		`INSERT INTO fraud_reviews
+		(event_type, user_id, actor_id, device_id, ip, score, matches, payload, hold_id, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Type, e.UserID, e.ActorID, e.DeviceID, e.IP, d.Score, string(matches), string(body), hold, ReviewPending,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// KnownDevice показывает, входил ли пользователь с этого устройства.
+// Пустой идентификатор считается новым устройством.
+func KnownDevice(q ledger.Querier, userID int64, deviceID string) (bool, error) {
+	if deviceID == "" {
+		return false, nil
+	}
+
+	var known bool
+	err := q.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM user_devices WHERE user_id = ? AND device_id = ?)",
+		userID, deviceID,
+	).Scan(&known)
+	return known, err
+}
+
+// RememberDevice запоминает устройство пользователя после успешного входа или перевода
+func RememberDevice(q ledger.Querier, userID int64, deviceID string) error {
+	if deviceID == "" {
+		return nil
+	}
+
+	_, err := q.Exec(
+		`INSERT INTO user_devices (user_id, device_id) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE last_seen = NOW()`,
+		userID, deviceID,
+	)
+	return err
+}
+
+const selectReview = `SELECT id, event_type, user_id, actor_id, device_id, ip, score, matches, payload,
+	hold_id, status, decided_by, note, transaction_id, created_at, decided_at FROM fraud_reviews`
+
+// GetReview возвращает событие из очереди; forUpdate блокирует строку до конца транзакции
+func GetReview(q ledger.Querier, id int64, forUpdate bool) (Review, error) {
+	query := selectReview + " WHERE id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := q.Query(query, id)
+	if err != nil {
+		return Review{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Review{}, err
+		}
+		return Review{}, sql.ErrNoRows
+	}
+	return scanReview(rows)
+}
+
+// ListReviews возвращает очередь от старых к новым; пустой status означает все статусы
+func ListReviews(q ledger.Querier, status string) ([]Review, error) {
+	query := selectReview
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id LIMIT 200"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]Review, 0)
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+func scanReview(rows *sql.Rows) (Review, error) {
+	var r Review
+	var matches, payload string
+	var holdID, decidedBy, transactionID sql.NullInt64
+	var createdAt time.Time
+	var decidedAt sql.NullTime
+
+	err := rows.Scan(&r.ID, &r.EventType, &r.UserID, &r.ActorID, &r.DeviceID, &r.IP, &r.Score,
+		&matches, &payload, &holdID, &r.Status, &decidedBy, &r.Note, &transactionID, &createdAt, &decidedAt)
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal([]byte(matches), &r.Matches); err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal([]byte(payload), &r.Payload); err != nil {
+		return r, err
+	}
+
+	r.HoldID = holdID.Int64
+	r.DecidedBy = decidedBy.Int64
+	r.TransactionID = transactionID.Int64
+	r.CreatedAt = createdAt.Format(types.TimeLayout)
+	if decidedAt.Valid {
+		r.DecidedAt = decidedAt.Time.Format(types.TimeLayout)
+	}
+	return r, nil
+}
+
+// PendingTransferOf разбирает перевод из события очереди
+func PendingTransferOf(r Review) (PendingTransfer, error) {
+	var t PendingTransfer
+	body, err := json.Marshal(r.Payload)
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(body, &t)
+	return t, err
+}
+
+// Decide закрывает событие очереди решением аналитика
+func Decide(tx *sql.Tx, reviewID int64, status string, analystID int64, note string, transactionID int64) error {
+	var transaction interface{}
+	if transactionID != 0 {
+		transaction = transactionID
+	}
+
+	result, err := tx.Exec(
+		`UPDATE fraud_reviews SET status = ?, decided_by = ?, note = ?, transaction_id = ?, decided_at = NOW()
+		WHERE id = ? AND status = ?`,
+		status, analystID, note, transaction, reviewID, ReviewPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrReviewNotPending
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package fraud
+
+import (
+	"fmt"
+	"time"
+
+	"backend_golang/audit"
+	"backend_golang/ledger"
+)
+
+// ruleType реализация типа правила: check решает, сработало ли правило
+type ruleType struct {
+	// events события, к которым правило применяется, если в файле не указано иное
+	events []string
+	// params обязательные положительные параметры
+	params []string
+	check  func(q ledger.Querier, e Event, params map[string]float64) (bool, string, error)
+}
+
+var ruleTypes = map[string]ruleType{
+	// velocity: слишком много переводов за короткое время
+	"velocity": {
+		events: []string{EventTransfer},
+		params: []string{"window_minutes", "max_count"},
+		check:  velocity,
+	},
+	// unusual_amount: сумма во много раз больше средней по истории клиента
+	"unusual_amount": {
+		events: []string{EventTransfer},
+		params: []string{"lookback_days", "min_history", "multiplier"},
+		check:  unusualAmount,
+	},
+	// new_device_large_transfer: крупный перевод с незнакомого устройства
+	"new_device_large_transfer": {
+		events: []string{EventTransfer},
+		params: []string{"amount"},
+		check:  newDeviceLargeTransfer,
+	},
+	// new_payees: много новых получателей за короткое время
+	"new_payees": {
+		events: []string{EventTransfer},
+		params: []string{"window_hours", "max_new"},
+		check:  newPayees,
+	},
+	// new_device: событие с незнакомого устройства
+	"new_device": {
+		events: []string{EventLogin},
+		check:  newDevice,
+	},
+	// failed_logins: перед успешным входом было много неудачных попыток
+	"failed_logins": {
+		events: []string{EventLogin},
+		params: []string{"window_minutes", "max_count"},
+		check:  failedLogins,
+	},
+}
+
+func velocity(q ledger.Querier, e Event, params map[string]float64) (bool, string, error) {
+	window := time.Duration(params["window_minutes"]) * time.Minute
+
+	var count int
+	err := q.QueryRow(
+		"SELECT COUNT(*) FROM transactions WHERE from_account = ? AND kind = ? AND created_at >= ?",
+		ledger.UserAccount(e.UserID), ledger.KindTransfer, time.Now().Add(-window),
+	).Scan(&count)
+	if err != nil {
+		return false, "", err
+	}
+
+	// текущий перевод тоже считается
+	if float64(count+1) <= params["max_count"] {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%d переводов за %v", count+1, window), nil
+}
+
+func unusualAmount(q ledger.Querier, e Event, params map[string]float64) (bool, string, error) {
+	var count int
+	var average float64
+	err := q.QueryRow(
+		`SELECT COUNT(*), COALESCE(AVG(amount), 0) FROM transactions
+		WHERE from_account = ? AND kind = ? AND created_at >= ?`,
+		ledger.UserAccount(e.UserID), ledger.KindTransfer,
+		time.Now().AddDate(0, 0, -int(params["lookback_days"])),
+	).Scan(&count, &average)
+	if err != nil {
+		return false, "", err
+	}
+
+	if float64(count) < params["min_history"] || e.Amount <= average*params["multiplier"] {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("сумма %.2f больше средней %.2f в %.0f раз", e.Amount, average, params["multiplier"]), nil
+}
+
+func newDeviceLargeTransfer(q ledger.Querier, e Event, params map[string]float64) (bool, string, error) {
+	if e.Amount < params["amount"] {
+		return false, "", nil
+	}
+
+	known, err := KnownDevice(q, e.ActorID, e.DeviceID)
+	if err != nil || known {
+		return false, "", err
+	}
+	return true, fmt.Sprintf("перевод от %.2f с нового устройства", params["amount"]), nil
+}
+
+func newPayees(q ledger.Querier, e Event, params map[string]float64) (bool, string, error) {
+	window := time.Duration(params["window_hours"]) * time.Hour
+	since := time.Now().Add(-window)
+	account := ledger.UserAccount(e.UserID)
+
+	// получатели, которым первый перевод сделан внутри окна
+	var count int
+	err := q.QueryRow(
+		`SELECT COUNT(DISTINCT t.to_account) FROM transactions t
+		WHERE t.from_account = ? AND t.kind = ? AND t.created_at >= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM transactions p
+			WHERE p.from_account = t.from_account AND p.to_account = t.to_account
+			AND p.kind = t.kind AND p.created_at < ?
+		)`,
+		account, ledger.KindTransfer, since, since,
+	).Scan(&count)
+	if err != nil {
+		return false, "", err
+	}
+
+	var paid bool
+	err = q.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM transactions WHERE from_account = ? AND to_account = ? AND kind = ?)",
+		account, e.Recipient, ledger.KindTransfer,
+	).Scan(&paid)
+	if err != nil {
+		return false, "", err
+	}
+	if paid {
+		return false, "", nil
+	}
+
+	if float64(count+1) <= params["max_new"] {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%d новых получателей за %v", count+1, window), nil
+}
+
+func newDevice(q ledger.Querier, e Event, _ map[string]float64) (bool, string, error) {
+	known, err := KnownDevice(q, e.ActorID, e.DeviceID)
+	if err != nil || known {
+		return false, "", err
+	}
+	return true, "новое устройство", nil
+}
+
+func failedLogins(q ledger.Querier, e Event, params map[string]float64) (bool, string, error) {
+	window := time.Duration(params["window_minutes"]) * time.Minute
+
+	var count int
+	err := q.QueryRow(
+		"SELECT COUNT(*) FROM audit_log WHERE event = ? AND subject = ? AND created_at >= ?",
+		audit.EventLoginFailed, audit.UserSubject(e.UserID), time.Now().Add(-window),
+	).Scan(&count)
+	if err != nil {
+		return false, "", err
+	}
+
+	if float64(count) < params["max_count"] {
+		return false, "", nil
+	}
+	return true, fmt.Sprintf("%d неудачных попыток входа за %v", count, window), nil
+}
@@ -0,0 +1,190 @@
+package accounts
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+type dependent struct {
+	ID              int64   `json:"id"`
+	Name            string  `json:"name"`
+	Surname         string  `json:"surname"`
+	PhoneNumber     string  `json:"phone_number"`
+	DailySpendLimit float64 `json:"daily_spend_limit"`
+	ledger.Balance
+}
+
+const selectDependent = "SELECT id, name, surname, phone_number, daily_spend_limit, balance, " +
+	ledger.PendingSQL + " FROM users"
+
+func scanDependent(row interface{ Scan(...interface{}) error }) (dependent, error) {
+	var d dependent
+	var limit sql.NullFloat64
+	var balance, pending float64
+	err := row.Scan(&d.ID, &d.Name, &d.Surname, &d.PhoneNumber, &limit, &balance, &pending)
+	if err != nil {
+		return d, err
+	}
+	d.DailySpendLimit = limit.Float64
+	d.Balance = ledger.NewBalance(balance, pending)
+	return d, nil
+}
+
+// CreateDependent создаёт детский профиль, опекуном которого становится
+// текущий пользователь. Ребёнок входит в приложение по своему телефону и паролю.
+func CreateDependent(c *gin.Context) {
+	var req struct {
+		Name            string  `json:"name" form:"name" binding:"required,person_name"`
+		Surname         string  `json:"surname" form:"surname" binding:"required,person_name"`
+		PhoneNumber     string  `json:"phone_number" form:"phone_number" binding:"required,phone"`
+		Password        string  `json:"password" form:"password" binding:"required,password"`
+		DailySpendLimit float64 `json:"daily_spend_limit" form:"daily_spend_limit"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if req.DailySpendLimit < 0 {
+		respondError(c, errInvalidLimit)
+		return
+	}
+
+	guardianID := auth.CurrentUserID(c)
+	isDependent, err := auth.IsDependent(database.DB, guardianID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if isDependent {
+		respondError(c, errDependent)
+		return
+	}
+
+	var exists bool
+	err = database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE phone_number = ?)", req.PhoneNumber).Scan(&exists)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if exists {
+		c.Error(apierr.New(http.StatusConflict, "USER_EXISTS"))
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), types.BcryptSalt)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "HASH_ERROR"))
+		return
+	}
+
+	var limit interface{}
+	if req.DailySpendLimit > 0 {
+		limit = ledger.Round(req.DailySpendLimit)
+	}
+
+	result, err := database.DB.Exec(
+		`INSERT INTO users (name, surname, phone_number, balance, password_hash, profile_type, guardian_id, daily_spend_limit)
+		VALUES (?, ?, ?, 0, ?, ?, ?, ?)`,
+		req.Name, req.Surname, req.PhoneNumber, string(passwordHash), auth.ProfileDependent, guardianID, limit,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	d, err := scanDependent(database.DB.QueryRow(selectDependent+" WHERE id = ?", id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Детский профиль создан",
+		Data:    d,
+	})
+}
+
+// ListDependents возвращает детские профили опекуна с остатками
+func ListDependents(c *gin.Context) {
+	rows, err := database.DB.Query(selectDependent+" WHERE guardian_id = ? ORDER BY id", auth.CurrentUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	dependents := make([]dependent, 0)
+	for rows.Next() {
+		d, err := scanDependent(rows)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		dependents = append(dependents, d)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Детские профили",
+		Data:    dependents,
+	})
+}
+
+// UpdateDependentLimit меняет дневной лимит трат детского профиля; 0 снимает лимит
+func UpdateDependentLimit(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	var req struct {
+		DailySpendLimit float64 `json:"daily_spend_limit" form:"daily_spend_limit"`
+	}
+	if err := c.ShouldBind(&req); err != nil || req.DailySpendLimit < 0 {
+		respondError(c, errInvalidLimit)
+		return
+	}
+
+	var limit interface{}
+	if req.DailySpendLimit > 0 {
+		limit = ledger.Round(req.DailySpendLimit)
+	}
+
+	guardianID := auth.CurrentUserID(c)
+	_, err = database.DB.Exec(
+		"UPDATE users SET daily_spend_limit = ? WHERE id = ? AND guardian_id = ?",
+		limit, id, guardianID,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	d, err := scanDependent(database.DB.QueryRow(selectDependent+" WHERE id = ? AND guardian_id = ?", id, guardianID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Лимит обновлён",
+		Data:    d,
+	})
+}
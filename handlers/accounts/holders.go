@@ -0,0 +1,367 @@
+// Package accounts отвечает за совместные счета: приглашения совладельцев,
+// их права и детские профили под контролем опекуна.
+package accounts
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+// Статусы приглашения совладельца
+const (
+	StatusInvited  = "invited"
+	StatusActive   = "active"
+	StatusDeclined = "declined"
+	StatusRevoked  = "revoked"
+)
+
+var (
+	errNotInvited     = apierr.New(http.StatusConflict, "INVITATION_NOT_PENDING")
+	errSelfInvite     = apierr.New(http.StatusBadRequest, "SELF_INVITE")
+	errDependent      = apierr.New(http.StatusForbidden, "DEPENDENT_PROFILE.holders")
+	errAlreadyHolder  = apierr.New(http.StatusConflict, "ALREADY_HOLDER")
+	errInvalidLimit   = apierr.New(http.StatusBadRequest, "INVALID_LIMIT.transfer")
+	errInvalidRequest = apierr.New(http.StatusBadRequest, "INVALID_PERMISSION")
+)
+
+type holder struct {
+	ID            int64   `json:"id"`
+	AccountUserID int64   `json:"account_user_id"`
+	AccountName   string  `json:"account_name"`
+	HolderUserID  int64   `json:"holder_user_id"`
+	HolderName    string  `json:"holder_name"`
+	Permission    string  `json:"permission"`
+	TransferLimit float64 `json:"transfer_limit,omitempty"`
+	Status        string  `json:"status"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// selectHolder называет и владельца счёта, и совладельца: в shared_with_me
+// клиенту нужен владелец, а совладелец там он сам
+const selectHolder = `SELECT h.id, h.account_user_id, CONCAT(o.name, ' ', o.surname),
+	h.holder_user_id, CONCAT(u.name, ' ', u.surname),
+	h.permission, h.transfer_limit, h.status, h.created_at
+	FROM account_holders h
+	JOIN users o ON o.id = h.account_user_id
+	JOIN users u ON u.id = h.holder_user_id`
+
+func scanHolder(row interface{ Scan(...interface{}) error }) (holder, error) {
+	var h holder
+	var limit sql.NullFloat64
+	var createdAt time.Time
+	err := row.Scan(&h.ID, &h.AccountUserID, &h.AccountName, &h.HolderUserID, &h.HolderName,
+		&h.Permission, &limit, &h.Status, &createdAt)
+	if err != nil {
+		return h, err
+	}
+	h.TransferLimit = limit.Float64
+	h.CreatedAt = createdAt.Format(types.TimeLayout)
+	return h, nil
+}
+
+// Invite приглашает другого пользователя совладельцем своего счёта.
+// Доступ появится только после того, как приглашённый примет приглашение.
+func Invite(c *gin.Context) {
+	var req struct {
+		UserID        int64   `json:"user_id" form:"user_id"`
+		PhoneNumber   string  `json:"phone_number" form:"phone_number" binding:"omitempty,phone"`
+		Permission    string  `json:"permission" form:"permission"`
+		TransferLimit float64 `json:"transfer_limit" form:"transfer_limit"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := validatePermission(req.Permission, req.TransferLimit); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ownerID := auth.CurrentUserID(c)
+	holderID := req.UserID
+	if holderID == 0 {
+		err := database.DB.QueryRow("SELECT id FROM users WHERE phone_number = ?", req.PhoneNumber).Scan(&holderID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	var id int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		if holderID == ownerID {
+			return errSelfInvite
+		}
+
+		for _, userID := range []int64{ownerID, holderID} {
+			dependent, err := auth.IsDependent(tx, userID)
+			if err != nil {
+				return err
+			}
+			if dependent {
+				return errDependent
+			}
+		}
+
+		var status string
+		err := tx.QueryRow(
+			"SELECT status FROM account_holders WHERE account_user_id = ? AND holder_user_id = ? FOR UPDATE",
+			ownerID, holderID,
+		).Scan(&status)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if status == StatusInvited || status == StatusActive {
+			return errAlreadyHolder
+		}
+
+		// Отклонённое или отозванное приглашение можно отправить заново
+		_, err = tx.Exec(
+			`INSERT INTO account_holders (account_user_id, holder_user_id, permission, transfer_limit, status)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), permission = VALUES(permission),
+				transfer_limit = VALUES(transfer_limit), status = VALUES(status)`,
+			ownerID, holderID, req.Permission, limitValue(req.Permission, req.TransferLimit), StatusInvited,
+		)
+		if err != nil {
+			return err
+		}
+		return tx.QueryRow("SELECT LAST_INSERT_ID()").Scan(&id)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h, err := scanHolder(database.DB.QueryRow(selectHolder+" WHERE h.id = ?", id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Приглашение отправлено",
+		Data:    h,
+	})
+}
+
+// List возвращает совладельцев своего счёта и счета, к которым у пользователя есть доступ
+func List(c *gin.Context) {
+	userID := auth.CurrentUserID(c)
+
+	holders, err := listHolders(selectHolder+" WHERE h.account_user_id = ? ORDER BY h.id", userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	shared, err := listHolders(selectHolder+" WHERE h.holder_user_id = ? AND h.status IN (?, ?) ORDER BY h.id",
+		userID, StatusInvited, StatusActive)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Совместный доступ",
+		Data: map[string]interface{}{
+			"holders":        holders,
+			"shared_with_me": shared,
+		},
+	})
+}
+
+func listHolders(query string, args ...interface{}) ([]holder, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := make([]holder, 0)
+	for rows.Next() {
+		h, err := scanHolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		holders = append(holders, h)
+	}
+	return holders, rows.Err()
+}
+
+// Accept принимает приглашение
+func Accept(c *gin.Context) {
+	answer(c, StatusActive)
+}
+
+// Decline отклоняет приглашение
+func Decline(c *gin.Context) {
+	answer(c, StatusDeclined)
+}
+
+func answer(c *gin.Context, status string) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE account_holders SET status = ? WHERE id = ? AND holder_user_id = ? AND status = ?",
+		status, id, auth.CurrentUserID(c), StatusInvited,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(c, errNotInvited)
+		return
+	}
+
+	h, err := scanHolder(database.DB.QueryRow(selectHolder+" WHERE h.id = ?", id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Ответ на приглашение сохранён",
+		Data:    h,
+	})
+}
+
+// Update меняет права совладельца; доступно только владельцу счёта
+func Update(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Permission    string  `json:"permission" form:"permission"`
+		TransferLimit float64 `json:"transfer_limit" form:"transfer_limit"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if err := validatePermission(req.Permission, req.TransferLimit); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result, err := database.DB.Exec(
+		`UPDATE account_holders SET permission = ?, transfer_limit = ?
+		WHERE id = ? AND account_user_id = ? AND status IN (?, ?)`,
+		req.Permission, limitValue(req.Permission, req.TransferLimit), id, auth.CurrentUserID(c),
+		StatusInvited, StatusActive,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		// Повторное сохранение тех же прав тоже даёт 0 строк, поэтому проверяем наличие
+		if _, err := ownedHolder(id, auth.CurrentUserID(c)); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	h, err := scanHolder(database.DB.QueryRow(selectHolder+" WHERE h.id = ?", id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Права обновлены",
+		Data:    h,
+	})
+}
+
+// Revoke отзывает доступ: владелец убирает совладельца
+// или совладелец сам отказывается от доступа
+func Revoke(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	result, err := database.DB.Exec(
+		`UPDATE account_holders SET status = ?
+		WHERE id = ? AND (account_user_id = ? OR holder_user_id = ?) AND status IN (?, ?)`,
+		StatusRevoked, id, userID, userID, StatusInvited, StatusActive,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(c, sql.ErrNoRows)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Доступ отозван",
+	})
+}
+
+func ownedHolder(id, ownerID int64) (holder, error) {
+	return scanHolder(database.DB.QueryRow(
+		selectHolder+" WHERE h.id = ? AND h.account_user_id = ? AND h.status IN (?, ?)",
+		id, ownerID, StatusInvited, StatusActive,
+	))
+}
+
+func validatePermission(permission string, limit float64) error {
+	if !auth.ValidPermission(permission) {
+		return errInvalidRequest
+	}
+	if permission == auth.PermissionTransfer && ledger.Cents(limit) <= 0 {
+		return errInvalidLimit
+	}
+	return nil
+}
+
+// limitValue лимит хранится только для права transfer
+func limitValue(permission string, limit float64) interface{} {
+	if permission != auth.PermissionTransfer {
+		return nil
+	}
+	return ledger.Round(limit)
+}
+
+func paramID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — счёт или приглашение не найдены
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "NOT_FOUND"))
+}
@@ -0,0 +1,90 @@
+// Package audit выдаёт аудиторам записи журнала и результат проверки цепочки.
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/types"
+)
+
+const dateLayout = "2006-01-02"
+
+// List возвращает записи журнала от новых к старым. Фильтры: event
+// (точное имя или префикс с *, например auth.*), actor_id, subject, outcome,
+// from и to (YYYY-MM-DD, включительно). before_id задаёт страницу, limit — её размер (до 500).
+func List(c *gin.Context) {
+	f := audit.Filter{
+		Event:   c.Query("event"),
+		Subject: c.Query("subject"),
+		Outcome: c.Query("outcome"),
+	}
+
+	var err error
+	if f.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "50")); err != nil || f.Limit <= 0 || f.Limit > 500 {
+		f.Limit = 50
+	}
+	f.BeforeID, _ = strconv.ParseInt(c.Query("before_id"), 10, 64)
+
+	if raw := c.Query("actor_id"); raw != "" {
+		if f.ActorID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "actor_id"}))
+			return
+		}
+	}
+
+	for _, p := range []struct {
+		name   string
+		target *time.Time
+		days   int
+	}{{"from", &f.From, 0}, {"to", &f.To, 1}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		date, err := time.ParseInLocation(dateLayout, raw, time.Local)
+		if err != nil {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_DATE", i18n.Args{"param": p.name}))
+			return
+		}
+		*p.target = date.AddDate(0, 0, p.days)
+	}
+
+	entries, err := audit.Find(database.DB, f)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Журнал аудита",
+		Data:    entries,
+	})
+}
+
+// Verify проверяет целостность цепочки хешей журнала
+func Verify(c *gin.Context) {
+	report, err := audit.Verify()
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	message := "Цепочка журнала не нарушена"
+	if !report.OK {
+		message = "Цепочка журнала нарушена"
+	}
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    report,
+	})
+}
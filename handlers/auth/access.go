@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/kyc"
+	"backend_golang/ledger"
+)
+
+// Права совладельца или доверенного лица на счёт, по возрастанию
+const (
+	PermissionView     = "view"
+	PermissionTransfer = "transfer"
+	PermissionFull     = "full"
+)
+
+// Типы профиля пользователя
+const (
+	ProfileStandard  = "standard"
+	ProfileDependent = "dependent"
+)
+
+// AccountHeader заголовок, которым клиент выбирает чужой счёт,
+// к которому у него есть доступ
+const AccountHeader = "X-Account-ID"
+
+const accountIDKey = "account_user_id"
+
+var permissionRank = map[string]int{
+	PermissionView:     1,
+	PermissionTransfer: 2,
+	PermissionFull:     3,
+}
+
+// ValidPermission проверяет название права
+func ValidPermission(permission string) bool {
+	return permissionRank[permission] > 0
+}
+
+// ErrSpendingLimit превышен лимит трат доверенного лица или детского профиля
+var ErrSpendingLimit = apierr.New(http.StatusForbidden, "SPENDING_LIMIT_EXCEEDED")
+
+// Access описывает доступ пользователя к счёту
+type Access struct {
+	Permission    string
+	TransferLimit float64
+}
+
+// AccessTo возвращает доступ actorID к счёту ownerID. Владелец и опекун
+// детского профиля имеют полный доступ, совладельцы — выданный при приглашении.
+// Пустое Permission означает отсутствие доступа.
+func AccessTo(q ledger.Querier, actorID, ownerID int64) (Access, error) {
+	if actorID == ownerID {
+		return Access{Permission: PermissionFull}, nil
+	}
+
+	var guardianID sql.NullInt64
+	err := q.QueryRow("SELECT guardian_id FROM users WHERE id = ?", ownerID).Scan(&guardianID)
+	if err == sql.ErrNoRows {
+		return Access{}, nil
+	}
+	if err != nil {
+		return Access{}, err
+	}
+	if guardianID.Valid && guardianID.Int64 == actorID {
+		return Access{Permission: PermissionFull}, nil
+	}
+
+	var a Access
+	var limit sql.NullFloat64
+	err = q.QueryRow(
+		`SELECT permission, transfer_limit FROM account_holders
+		WHERE account_user_id = ? AND holder_user_id = ? AND status = 'active'`,
+		ownerID, actorID,
+	).Scan(&a.Permission, &limit)
+	if err == sql.ErrNoRows {
+		return Access{}, nil
+	}
+	a.TransferLimit = limit.Float64
+	return a, err
+}
+
+// Allows проверяет, что доступ не ниже permission
+func (a Access) Allows(permission string) bool {
+	return a.Permission != "" && permissionRank[a.Permission] >= permissionRank[permission]
+}
+
+// RequireAccountAccess определяет счёт запроса по заголовку X-Account-ID
+// (по умолчанию — собственный счёт) и проверяет право permission на него.
+// Используется после RequireSession.
+func RequireAccountAccess(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := CurrentUserID(c)
+		accountID := userID
+
+		if raw := c.GetHeader(AccountHeader); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				apierr.Abort(c, apierr.New(http.StatusBadRequest, "INVALID_ACCOUNT_ID", i18n.Args{"header": AccountHeader}))
+				return
+			}
+			accountID = id
+		}
+
+		access, err := AccessTo(database.DB, userID, accountID)
+		if err != nil {
+			apierr.Abort(c, apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+			return
+		}
+
+		// Счёт без доступа неотличим от несуществующего
+		if access.Permission == "" {
+			apierr.Abort(c, apierr.New(http.StatusNotFound, "ACCOUNT_NOT_FOUND"))
+			return
+		}
+		if !access.Allows(permission) {
+			apierr.Abort(c, apierr.New(http.StatusForbidden, "FORBIDDEN.account"))
+			return
+		}
+
+		c.Set(accountIDKey, accountID)
+		c.Next()
+	}
+}
+
+// OwnerOnly отклоняет запрос к чужому счёту по X-Account-ID: разделы
+// с собственными продуктами пользователя (карты, вклады, копилки, кредиты)
+// совладельцам не открываются. Используется после RequireSession.
+func OwnerOnly(c *gin.Context) {
+	if raw := c.GetHeader(AccountHeader); raw != "" && raw != strconv.FormatInt(CurrentUserID(c), 10) {
+		apierr.Abort(c, apierr.New(http.StatusForbidden, "FORBIDDEN.owner_only"))
+		return
+	}
+	c.Next()
+}
+
+// CurrentAccountID возвращает ID владельца счёта, выбранного RequireAccountAccess.
+// Без RequireAccountAccess это счёт текущего пользователя.
+func CurrentAccountID(c *gin.Context) int64 {
+	if id, ok := c.Get(accountIDKey); ok {
+		return id.(int64)
+	}
+	return CurrentUserID(c)
+}
+
+// IsDependent проверяет, что пользователь — детский профиль
+func IsDependent(q ledger.Querier, userID int64) (bool, error) {
+	var profile string
+	err := q.QueryRow("SELECT profile_type FROM users WHERE id = ?", userID).Scan(&profile)
+	return profile == ProfileDependent, err
+}
+
+// CheckSpending проверяет лимиты трат при списании amount со счёта ownerID
+// по инициативе actorID: дневной лимит доверенного лица с правом transfer,
+// лимиты уровня идентификации владельца и дневной лимит детского профиля.
+// Вызывается внутри транзакции списания.
+func CheckSpending(tx *sql.Tx, actorID, ownerID int64, amount float64) error {
+	if actorID != ownerID {
+		access, err := AccessTo(tx, actorID, ownerID)
+		if err != nil {
+			return err
+		}
+		if !access.Allows(PermissionTransfer) {
+			return ErrSpendingLimit
+		}
+
+		if access.Permission == PermissionTransfer {
+			var spent float64
+			err := tx.QueryRow(
+				`SELECT COALESCE(SUM(amount), 0) FROM transactions
+				WHERE from_account = ? AND created_by = ? AND kind = ? AND created_at >= CURDATE()`,
+				ledger.UserAccount(ownerID), actorID, ledger.KindTransfer,
+			).Scan(&spent)
+			if err != nil {
+				return err
+			}
+			if ledger.Cents(spent)+ledger.Cents(amount) > ledger.Cents(access.TransferLimit) {
+				return ErrSpendingLimit
+			}
+		}
+	}
+
+	if err := kyc.CheckLimits(tx, ownerID, amount); err != nil {
+		return err
+	}
+
+	var limit sql.NullFloat64
+	var profile string
+	err := tx.QueryRow("SELECT profile_type, daily_spend_limit FROM users WHERE id = ?", ownerID).Scan(&profile, &limit)
+	if err != nil {
+		return err
+	}
+	if profile != ProfileDependent || !limit.Valid {
+		return nil
+	}
+
+	// Тратами детского профиля считаются переводы, карточные списания
+	// и ещё не списанные карточные холды за сегодня
+	var spent float64
+	err = tx.QueryRow(
+		`SELECT COALESCE(SUM(-e.amount), 0) FROM ledger_entries e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE e.account = ? AND e.amount < 0 AND t.kind IN (?, ?) AND e.created_at >= CURDATE()`,
+		ledger.UserAccount(ownerID), ledger.KindTransfer, ledger.KindCardCapture,
+	).Scan(&spent)
+	if err != nil {
+		return err
+	}
+
+	var held float64
+	err = tx.QueryRow(
+		`SELECT COALESCE(SUM(amount), 0) FROM holds
+		WHERE user_id = ? AND source = 'card' AND status = ? AND created_at >= CURDATE()`,
+		ownerID, ledger.HoldActive,
+	).Scan(&held)
+	if err != nil {
+		return err
+	}
+
+	if ledger.Cents(spent)+ledger.Cents(held)+ledger.Cents(amount) > ledger.Cents(limit.Float64) {
+		return ErrSpendingLimit
+	}
+	return nil
+}
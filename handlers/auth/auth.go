@@ -4,112 +4,143 @@ package auth
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 
+	"backend_golang/apierr"
+	"backend_golang/audit"
 	"backend_golang/database"
+	"backend_golang/events"
+	"backend_golang/fraud"
+	"backend_golang/ledger"
 	"backend_golang/methods"
+	"backend_golang/sanctions"
 	"backend_golang/types"
+	"backend_golang/validation"
 )
 
 func Register(c *gin.Context) {
-	name := c.PostForm("name")
-	surname := c.PostForm("surname")
-	phoneNumber := c.PostForm("phone_number")
-	password := c.PostForm("password")
-	balanceStr := c.PostForm("balance")
-
-	if name == "" || surname == "" || phoneNumber == "" || password == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Имя, фамилия, телефон и пароль обязательны",
-			Error:   "MISSING_FIELDS",
-		})
+	var req struct {
+		Name        string  `json:"name" form:"name" binding:"required,person_name"`
+		Surname     string  `json:"surname" form:"surname" binding:"required,person_name"`
+		PhoneNumber string  `json:"phone_number" form:"phone_number" binding:"required,phone"`
+		Password    string  `json:"password" form:"password" binding:"required,password"`
+		Balance     float64 `json:"balance" form:"balance" binding:"gte=0"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
 	var existingID int64
 	err := database.DB.QueryRow(
 		"SELECT id FROM users WHERE phone_number = ?",
-		phoneNumber,
+		req.PhoneNumber,
 	).Scan(&existingID)
 
 	if err == nil {
-		c.JSON(http.StatusConflict, types.Response{
-			Success: false,
-			Message: "Пользователь с таким телефоном уже существует",
-			Error:   "USER_EXISTS",
-		})
+		c.Error(apierr.New(http.StatusConflict, "USER_EXISTS"))
 		return
 	} else if err != sql.ErrNoRows {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке пользователя: " + err.Error(),
-			Error:   "DATABASE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		return
 	}
 
-	var balance float64
-	if balanceStr != "" {
-		if bal, err := strconv.ParseFloat(balanceStr, 64); err == nil && bal >= 0 {
-			balance = bal
-		} else {
-			c.JSON(http.StatusBadRequest, types.Response{
-				Success: false,
-				Message: "Неверный формат баланса",
-				Error:   "INVALID_BALANCE",
-			})
-			return
-		}
-	}
-
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), types.BcryptSalt)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), types.BcryptSalt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при хешировании пароля: " + err.Error(),
-			Error:   "HASH_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "HASH_ERROR"))
 		return
 	}
 
 	session := methods.GenerateSecureSession(types.DefaultSession)
 
-	query := `
-        INSERT INTO users 
-        (name, surname, phone_number, balance, password_hash, session) 
-        VALUES (?, ?, ?, ?, ?, ?)
-    `
-
-	result, err := database.DB.Exec(
-		query,
-		name,
-		surname,
-		phoneNumber,
-		balance,
-		string(passwordHash),
-		session,
-	)
+	// Начальный остаток зачисляется проводкой, чтобы остаток сходился с ledger
+	var userID, reviewID int64
+	var screening sanctions.Screening
+	err = database.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			`INSERT INTO users
+			(name, surname, phone_number, balance, password_hash, session)
+			VALUES (?, ?, ?, 0, ?, ?)`,
+			req.Name,
+			req.Surname,
+			req.PhoneNumber,
+			string(passwordHash),
+			session,
+		)
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при регистрации пользователя: " + err.Error(),
-			Error:   "REGISTRATION_ERROR",
+		userID, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		// При совпадении с санкционным списком клиент создаётся,
+		// но его сессии не действуют до решения комплаенса
+		screening, err = sanctions.ScreenUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		complianceStatus := sanctions.StatusClear
+		if screening.Hit() {
+			if reviewID, err = sanctions.QueueRegistration(tx, screening); err != nil {
+				return err
+			}
+			complianceStatus = sanctions.StatusPending
+		}
+
+		err = events.Record(tx, events.ForUser(events.UserRegistered, userID, map[string]interface{}{
+			"name":              req.Name,
+			"surname":           req.Surname,
+			"phone_number":      req.PhoneNumber,
+			"compliance_status": complianceStatus,
+		}))
+		if err != nil {
+			return err
+		}
+
+		if ledger.Cents(req.Balance) == 0 {
+			return nil
+		}
+
+		_, err = ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindOpeningBalance,
+			FromAccount: ledger.BankOpeningBalance,
+			ToAccount:   ledger.UserAccount(userID),
+			Amount:      req.Balance,
+			Memo:        "Начальный остаток",
 		})
+		return err
+	})
+
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "REGISTRATION_ERROR"))
 		return
 	}
 
-	userID, err := result.LastInsertId()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при получении ID пользователя: " + err.Error(),
-			Error:   "ID_ERROR",
+	audit.LogRequest(c, userID, audit.EventRegistered, audit.UserSubject(userID), audit.OutcomeSuccess, nil)
+
+	if screening.Hit() {
+		audit.LogRequest(c, userID, audit.EventSanctionsHit, audit.UserSubject(userID), audit.OutcomeFailure, map[string]interface{}{
+			"subject_type": sanctions.SubjectRegistration,
+			"review_id":    reviewID,
+			"matches":      screening.Matches,
+		})
+
+		c.JSON(http.StatusAccepted, types.Response{
+			Success: true,
+			Message: "Пользователь зарегистрирован, но до проверки службой комплаенса вход недоступен",
+			Data: map[string]interface{}{
+				"user_id":           userID,
+				"compliance_status": sanctions.StatusPending,
+				"review_id":         reviewID,
+			},
 		})
 		return
 	}
@@ -118,160 +149,191 @@ func Register(c *gin.Context) {
 		Success: true,
 		Message: "Пользователь успешно зарегистрирован",
 		Data: map[string]interface{}{
-			"user_id": userID,
-			"session": session,
-			"balance": balance,
+			"user_id":           userID,
+			"session":           session,
+			"ledger_balance":    req.Balance,
+			"available_balance": req.Balance,
+			"pending":           0,
 		},
 	})
 }
 
+var ErrWrongPassword = errors.New("auth: wrong password")
+
+// CheckPassword сверяет пароль пользователя, например для подтверждения операции
+func CheckPassword(userID int64, password string) error {
+	var passwordHash string
+	err := database.DB.QueryRow("SELECT password_hash FROM users WHERE id = ?", userID).Scan(&passwordHash)
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrWrongPassword
+	}
+	return err
+}
+
 func Login(c *gin.Context) {
-	phoneNumber := c.PostForm("phone_number")
-	password := c.PostForm("password")
-
-	if phoneNumber == "" || password == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Телефон и пароль обязательны",
-			Error:   "MISSING_FIELDS",
-		})
+	// Политика паролей при входе не проверяется: старые пароли должны работать
+	var req struct {
+		PhoneNumber string `json:"phone_number" form:"phone_number" binding:"required,phone"`
+		Password    string `json:"password" form:"password" binding:"required"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
+	var userID int64
 	var passwordHash string
 	err := database.DB.QueryRow(
-		"SELECT password_hash FROM users WHERE phone_number = ?",
-		phoneNumber,
-	).Scan(&passwordHash)
+		"SELECT id, password_hash FROM users WHERE phone_number = ?",
+		req.PhoneNumber,
+	).Scan(&userID, &passwordHash)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, types.Response{
-				Success: false,
-				Message: "Пользователь не найден",
-				Error:   "USER_NOT_FOUND",
+			audit.LogRequest(c, 0, audit.EventLoginFailed, "", audit.OutcomeFailure, map[string]interface{}{
+				"phone_number": req.PhoneNumber,
+				"reason":       "USER_NOT_FOUND",
 			})
+			c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
 		} else {
-			c.JSON(http.StatusInternalServerError, types.Response{
-				Success: false,
-				Message: "Ошибка базы данных: " + err.Error(),
-				Error:   "DATABASE_ERROR",
-			})
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		}
 		return
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password))
 	if err != nil {
 		if err == bcrypt.ErrMismatchedHashAndPassword {
-			c.JSON(http.StatusBadRequest, types.Response{
-				Success: false,
-				Message: "Неправильный пароль",
-				Error:   "INVALID_PASSWORD",
+			audit.LogRequest(c, 0, audit.EventLoginFailed, audit.UserSubject(userID), audit.OutcomeFailure, map[string]interface{}{
+				"reason": "INVALID_PASSWORD",
 			})
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_PASSWORD"))
 		} else {
-			c.JSON(http.StatusInternalServerError, types.Response{
-				Success: false,
-				Message: "Ошибка проверки пароля: " + err.Error(),
-				Error:   "PASSWORD_CHECK_ERROR",
-			})
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "PASSWORD_CHECK_ERROR"))
 		}
 		return
 	}
 
-	var userID int64
-	var balance float64
+	event := fraud.Event{
+		Type:     fraud.EventLogin,
+		UserID:   userID,
+		ActorID:  userID,
+		DeviceID: c.GetHeader(fraud.DeviceHeader),
+		IP:       c.ClientIP(),
+	}
+	decision, err := fraud.Evaluate(database.DB, event)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	// Пароль уже проверен, поэтому step_up при входе не требует ничего сверх него
+	switch decision.Action {
+	case fraud.ActionBlock:
+		c.Error(apierr.New(http.StatusForbidden, "LOGIN_BLOCKED"))
+		return
+	case fraud.ActionReview:
+		if _, err := fraud.QueueLogin(database.DB, event, decision); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+			return
+		}
+		c.Error(apierr.New(http.StatusForbidden, "LOGIN_UNDER_REVIEW"))
+		return
+	}
+
+	if err := fraud.RememberDevice(database.DB, userID, event.DeviceID); err != nil {
+		log.Printf("❌ Remember device for user %d failed: %v", userID, err)
+	}
+
 	var name, surname string
-	var sessionNullable sql.NullString 
 
 	err = database.DB.QueryRow(
-		"SELECT id, name, surname, balance, session FROM users WHERE phone_number = ?",
-		phoneNumber,
-	).Scan(&userID, &name, &surname, &balance, &sessionNullable)
+		"SELECT name, surname FROM users WHERE id = ?",
+		userID,
+	).Scan(&name, &surname)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при получении данных пользователя: " + err.Error(),
-			Error:   "USER_DATA_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "USER_DATA_ERROR"))
+		return
+	}
+
+	balances, err := ledger.Balances(database.DB, userID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "USER_DATA_ERROR"))
 		return
 	}
 
+	// Сессия создаётся и событие входа пишется в одной транзакции;
+	// блокировка строки не даёт двум одновременным входам создать разные сессии
 	var session string
-	if sessionNullable.Valid {
-		session = sessionNullable.String
-	} else {
-		session = methods.GenerateSecureSession(types.DefaultSession)
-		_, err = database.DB.Exec(
-			"UPDATE users SET session = ? WHERE id = ?",
-			session, userID,
-		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, types.Response{
-				Success: false,
-				Message: "Ошибка при создании сессии: " + err.Error(),
-				Error:   "SESSION_CREATE_ERROR",
-			})
-			return
+	err = database.WithTx(func(tx *sql.Tx) error {
+		var current sql.NullString
+		if err := tx.QueryRow("SELECT session FROM users WHERE id = ? FOR UPDATE", userID).Scan(&current); err != nil {
+			return err
 		}
+
+		session = current.String
+		if !current.Valid {
+			session = methods.GenerateSecureSession(types.DefaultSession)
+			if _, err := tx.Exec("UPDATE users SET session = ? WHERE id = ?", session, userID); err != nil {
+				return err
+			}
+		}
+
+		return events.Record(tx, events.ForUser(events.LoggedIn, userID, map[string]interface{}{
+			"ip":        c.ClientIP(),
+			"device_id": event.DeviceID,
+		}))
+	})
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SESSION_CREATE_ERROR"))
+		return
 	}
 
+	audit.LogRequest(c, userID, audit.EventLoginSucceeded, audit.UserSubject(userID), audit.OutcomeSuccess, nil)
+
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Успешный вход в систему",
 		Data: map[string]interface{}{
-			"user_id":      userID,
-			"name":         name,
-			"surname":      surname,
-			"phone_number": phoneNumber,
-			"balance":      balance,
-			"session":      session,
+			"user_id":           userID,
+			"name":              name,
+			"surname":           surname,
+			"phone_number":      req.PhoneNumber,
+			"ledger_balance":    balances.LedgerBalance,
+			"available_balance": balances.AvailableBalance,
+			"pending":           balances.Pending,
+			"session":           session,
 		},
 	})
 }
 
 func Logout(c *gin.Context) {
-	session := c.Query("session")
-	if session == "" {
-		session = c.GetHeader("Session")
-	}
-
+	session := SessionFromRequest(c)
 	if session == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Сессия обязательна",
-			Error:   "MISSING_FIELDS",
-		})
+		c.Error(apierr.New(http.StatusUnauthorized, "MISSING_SESSION"))
 		return
 	}
 
 	result, err := database.DB.Exec("UPDATE users SET session = NULL WHERE session = ?", session)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Не удалось удалить сессию",
-			Error:   "SESSION_DELETE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SESSION_DELETE_ERROR"))
 		return
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке результата",
-			Error:   "RESULT_CHECK_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "RESULT_CHECK_ERROR"))
 		return
 	}
 
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь с такой сессией не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND.session"))
 		return
 	}
 
@@ -282,22 +344,9 @@ func Logout(c *gin.Context) {
 }
 
 func RefreshSession(c *gin.Context) {
-	session := c.PostForm("session")
+	session := SessionFromRequest(c)
 	if session == "" {
-		var req struct {
-			Session string `json:"session"`
-		}
-		if err := c.ShouldBindJSON(&req); err == nil {
-			session = req.Session
-		}
-	}
-
-	if session == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Сессия обязательна",
-			Error:   "MISSING_FIELDS",
-		})
+		c.Error(apierr.New(http.StatusUnauthorized, "MISSING_SESSION"))
 		return
 	}
 
@@ -305,30 +354,18 @@ func RefreshSession(c *gin.Context) {
 
 	result, err := database.DB.Exec("UPDATE users SET session = ? WHERE session = ?", newSession, session)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Не удалось изменить сессию",
-			Error:   "SESSION_UPDATE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SESSION_UPDATE_ERROR"))
 		return
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке результата",
-			Error:   "RESULT_CHECK_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "RESULT_CHECK_ERROR"))
 		return
 	}
 
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь с такой сессией не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND.session"))
 		return
 	}
 
@@ -341,149 +378,104 @@ func RefreshSession(c *gin.Context) {
 	})
 }
 
+// RefreshPassword меняет пароль текущего пользователя. Используется после
+// RequireSession; действующий пароль подтверждает, что сессию не перехватили.
 func RefreshPassword(c *gin.Context) {
-	userID := c.PostForm("id")
-	password := c.PostForm("new_password")
-
-	if userID == "" || password == "" {
-		var req struct {
-			ID       string `json:"id"`
-			Password string `json:"new_password"`
-		}
-		if err := c.ShouldBindJSON(&req); err == nil {
-			userID = req.ID
-			password = req.Password
-		}
+	var req struct {
+		CurrentPassword string `json:"current_password" form:"current_password" binding:"required"`
+		Password        string `json:"new_password" form:"new_password" binding:"required,password"`
 	}
-
-	if userID == "" || password == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Пароль и айди обязательны",
-			Error:   "MISSING_FIELDS",
-		})
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
 		return
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), types.BcryptSalt)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при хешировании пароля",
-			Error:   "HASH_ERROR",
-		})
+	userID := CurrentUserID(c)
+	if err := CheckPassword(userID, req.CurrentPassword); err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			audit.LogRequest(c, userID, audit.EventPasswordChanged, audit.UserSubject(userID), audit.OutcomeFailure, map[string]interface{}{
+				"reason": "INVALID_PASSWORD",
+			})
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_PASSWORD"))
+		} else {
+			c.Error(apierr.NoRows(err, "USER_NOT_FOUND"))
+		}
 		return
 	}
 
-	result, err := database.DB.Exec("UPDATE users SET password_hash = ? WHERE id = ?", string(passwordHash), userID)
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), types.BcryptSalt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Не удалось сменить пароль",
-			Error:   "PASSWORD_UPDATE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "HASH_ERROR"))
 		return
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	var rowsAffected int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", string(passwordHash), userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+		return events.Record(tx, events.ForUser(events.PasswordChanged, userID, nil))
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке результата",
-			Error:   "RESULT_CHECK_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "PASSWORD_UPDATE_ERROR"))
 		return
 	}
 
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь с таким айди не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
 		return
 	}
 
+	audit.LogRequest(c, userID, audit.EventPasswordChanged, audit.UserSubject(userID), audit.OutcomeSuccess, nil)
+
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Пароль успешно изменен",
 	})
 }
 
+// GetBySession возвращает пользователя текущей сессии с остатками, как /users/:id
 func GetBySession(c *gin.Context) {
-	session := c.Query("session")
-
+	session := SessionFromRequest(c)
 	if session == "" {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Сессия обязательна",
-			Error:   "MISSING_FIELDS",
-		})
-		return
-	}
-
-	rows, err := database.DB.Query("SELECT * FROM users WHERE session = ?", session)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка базы данных: " + err.Error(),
-			Error:   "DATABASE_ERROR",
-		})
-		return
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь с такой сессией не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusUnauthorized, "MISSING_SESSION"))
 		return
 	}
 
-	columns, err := rows.Columns()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка получения колонок: " + err.Error(),
-			Error:   "COLUMNS_ERROR",
-		})
+	var user types.UserResponse
+	err := database.DB.QueryRow(
+		"SELECT id, name, surname, phone_number, balance, "+ledger.PendingSQL+" FROM users WHERE session = ?",
+		session,
+	).Scan(
+		&user.ID,
+		&user.Name,
+		&user.Surname,
+		&user.PhoneNumber,
+		&user.LedgerBalance,
+		&user.Pending,
+	)
+	if err == sql.ErrNoRows {
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND.session"))
 		return
 	}
-
-	values := make([]interface{}, len(columns))
-	valuePtrs := make([]interface{}, len(columns))
-	for i := range values {
-		valuePtrs[i] = &values[i]
-	}
-
-	err = rows.Scan(valuePtrs...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка чтения данных: " + err.Error(),
-			Error:   "SCAN_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		return
 	}
 
-	userData := make(map[string]interface{})
-	for i, col := range columns {
-		var v interface{}
-		val := values[i]
-		b, ok := val.([]byte)
-		if ok {
-			v = string(b)
-		} else {
-			v = val
-		}
-		userData[col] = v
-	}
+	balance := ledger.NewBalance(user.LedgerBalance, user.Pending)
+	user.LedgerBalance = balance.LedgerBalance
+	user.AvailableBalance = balance.AvailableBalance
+	user.Pending = balance.Pending
 
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Пользователь найден по сессии",
-		Data:    userData,
+		Data:    user,
 	})
 }
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/sanctions"
+)
+
+// Роли пользователей
+const (
+	RoleAdmin       = "admin"
+	RoleAuditor     = "auditor"
+	RoleAnalyst     = "analyst"
+	RoleCompliance  = "compliance"
+	RoleKYCReviewer = "kyc_reviewer"
+)
+
+const (
+	userIDKey  = "user_id"
+	sessionKey = "session"
+)
+
+const (
+	// SessionScheme схема заголовка Authorization: Bearer <сессия>
+	SessionScheme = "Bearer"
+	// WebSocketProtocol подпротокол, которым браузер передаёт сессию при
+	// подключении к WebSocket: задать заголовки он не может, а в адресе
+	// сессия попала бы в логи. new WebSocket(url, ["bearer", session])
+	WebSocketProtocol = "bearer"
+)
+
+// SessionFromRequest возвращает сессию из заголовка Authorization, а при
+// открытии WebSocket — и из Sec-WebSocket-Protocol. В адресе запроса сессия
+// не принимается.
+func SessionFromRequest(c *gin.Context) string {
+	scheme, session, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, SessionScheme) {
+		return strings.TrimSpace(session)
+	}
+
+	if !isWebSocketUpgrade(c.Request) {
+		return ""
+	}
+	protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+	for i := 0; i+1 < len(protocols); i++ {
+		if strings.TrimSpace(protocols[i]) == WebSocketProtocol {
+			return strings.TrimSpace(protocols[i+1])
+		}
+	}
+	return ""
+}
+
+// isWebSocketUpgrade проверяет, что запрос открывает WebSocket:
+// Upgrade: websocket и upgrade среди значений Connection
+func isWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireSession пропускает запрос только с действующей сессией
+// и кладёт ID пользователя в контекст
+func RequireSession(c *gin.Context) {
+	session := SessionFromRequest(c)
+	if session == "" {
+		apierr.Abort(c, apierr.New(http.StatusUnauthorized, "MISSING_SESSION"))
+		return
+	}
+
+	// язык из настроек клиента важнее Accept-Language
+	var userID int64
+	var complianceStatus string
+	var language sql.NullString
+	err := database.DB.QueryRow(
+		`SELECT u.id, u.compliance_status, s.locale FROM users u
+		 LEFT JOIN notification_settings s ON s.user_id = u.id
+		 WHERE u.session = ?`, session,
+	).Scan(&userID, &complianceStatus, &language)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			apierr.Abort(c, apierr.New(http.StatusUnauthorized, "INVALID_SESSION"))
+		} else {
+			apierr.Abort(c, apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		}
+		return
+	}
+	if language.String != "" {
+		i18n.SetLanguage(c, language.String)
+	}
+
+	// Клиент с совпадением по санкционным спискам ждёт решения комплаенса
+	switch complianceStatus {
+	case sanctions.StatusPending:
+		apierr.Abort(c, apierr.New(http.StatusForbidden, "COMPLIANCE_REVIEW"))
+		return
+	case sanctions.StatusBlocked:
+		apierr.Abort(c, apierr.New(http.StatusForbidden, "COMPLIANCE_BLOCKED"))
+		return
+	}
+
+	c.Set(userIDKey, userID)
+	c.Set(sessionKey, session)
+	c.Next()
+}
+
+// CurrentUserID возвращает ID пользователя, установленный RequireSession
+func CurrentUserID(c *gin.Context) int64 {
+	return c.GetInt64(userIDKey)
+}
+
+// CurrentSession возвращает сессию, по которой RequireSession пропустил запрос
+func CurrentSession(c *gin.Context) string {
+	return c.GetString(sessionKey)
+}
+
+// SessionActive проверяет, что сессия пользователя ещё действует;
+// нужна долгим соединениям, которые проверили сессию только при открытии
+func SessionActive(userID int64, session string) (bool, error) {
+	var exists bool
+	err := database.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM users WHERE id = ? AND session = ?)",
+		userID, session,
+	).Scan(&exists)
+	return exists, err
+}
+
+// HasRole проверяет, есть ли у пользователя роль
+func HasRole(userID int64, role string) (bool, error) {
+	var exists bool
+	err := database.DB.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM user_roles WHERE user_id = ? AND role = ?)",
+		userID, role,
+	).Scan(&exists)
+	return exists, err
+}
+
+// RequireRole пропускает только пользователей с одной из ролей.
+// Используется после RequireSession.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := CurrentUserID(c)
+
+		for _, role := range roles {
+			ok, err := HasRole(userID, role)
+			if err != nil {
+				apierr.Abort(c, apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+				return
+			}
+			if ok {
+				c.Next()
+				return
+			}
+		}
+
+		apierr.Abort(c, apierr.New(http.StatusForbidden, "FORBIDDEN"))
+	}
+}
@@ -0,0 +1,313 @@
+// Package cards выпускает виртуальные карты и симулирует
+// сообщения карточной сети.
+package cards
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/methods"
+	"backend_golang/types"
+)
+
+// Статусы карты
+const (
+	StatusActive = "active"
+	StatusFrozen = "frozen"
+)
+
+type card struct {
+	ID                  int64   `json:"id"`
+	UserID              int64   `json:"user_id"`
+	MaskedPAN           string  `json:"masked_pan"`
+	Expiry              string  `json:"expiry"`
+	Status              string  `json:"status"`
+	PerTransactionLimit float64 `json:"per_transaction_limit"`
+	DailyLimit          float64 `json:"daily_limit"`
+	CreatedAt           string  `json:"created_at"`
+
+	expiryMonth int
+	expiryYear  int
+	cvvHash     string
+}
+
+const selectCard = `SELECT id, user_id, last4, expiry_month, expiry_year, cvv_hash, status,
+	per_transaction_limit, daily_limit, created_at FROM cards`
+
+func scanCard(row interface{ Scan(...interface{}) error }) (card, error) {
+	var k card
+	var last4 string
+	var createdAt time.Time
+
+	err := row.Scan(&k.ID, &k.UserID, &last4, &k.expiryMonth, &k.expiryYear, &k.cvvHash, &k.Status,
+		&k.PerTransactionLimit, &k.DailyLimit, &createdAt)
+	if err != nil {
+		return k, err
+	}
+
+	k.MaskedPAN = "**** **** **** " + last4
+	k.Expiry = fmt.Sprintf("%02d/%02d", k.expiryMonth, k.expiryYear%100)
+	k.CreatedAt = createdAt.Format(types.TimeLayout)
+	return k, nil
+}
+
+// expired проверяет, истёк ли срок действия карты (карта действует до конца месяца)
+func (k card) expired(now time.Time) bool {
+	end := time.Date(k.expiryYear, time.Month(k.expiryMonth)+1, 1, 0, 0, 0, 0, now.Location())
+	return !now.Before(end)
+}
+
+// panKey секрет HMAC номеров карт; загружается в Configure
+var panKey []byte
+
+// Configure читает ключ карточной сети из CARD_NETWORK_KEY и загружает ключ
+// HMAC номеров карт из types.CardPANKeyFile (32 байта в hex). Если файла
+// с ключом HMAC нет, ключ создаётся.
+func Configure() error {
+	if key := os.Getenv("CARD_NETWORK_KEY"); key != "" {
+		types.CardNetworkKey = key
+	}
+	if types.CardNetworkKey == "" {
+		return errors.New("cards: CARD_NETWORK_KEY is not set")
+	}
+
+	key, err := loadPANKey(types.CardPANKeyFile)
+	if err != nil {
+		return err
+	}
+	panKey = key
+	return nil
+}
+
+func loadPANKey(keyFile string) ([]byte, error) {
+	body, err := os.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("🔑 Card PAN key created in %s", keyFile)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("card PAN key %s: expected 32 bytes in hex", keyFile)
+	}
+	return key, nil
+}
+
+// HashPAN возвращает HMAC-SHA256 номера карты, по которому карта ищется
+// в базе. Без ключа номер из 16 цифр с известным BIN перебирается за минуты.
+func HashPAN(pan string) string {
+	if panKey == nil {
+		panic("cards: PAN key is not loaded, call cards.Configure")
+	}
+	mac := hmac.New(sha256.New, panKey)
+	mac.Write([]byte(pan))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// legacyHashPAN хеш без ключа, которым хранились номера раньше
+func legacyHashPAN(pan string) string {
+	sum := sha256.Sum256([]byte(pan))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue выпускает виртуальную карту. Номер и CVV показываются только один раз:
+// в базе хранятся лишь их хеши.
+func Issue(c *gin.Context) {
+	var req struct {
+		PerTransactionLimit float64 `json:"per_transaction_limit" form:"per_transaction_limit"`
+		DailyLimit          float64 `json:"daily_limit" form:"daily_limit"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.PerTransactionLimit < 0 || req.DailyLimit < 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_LIMIT"))
+		return
+	}
+
+	pan := methods.GeneratePAN(types.CardBIN, types.CardPANLength)
+	cvv := methods.GenerateDigits(3)
+	expiry := time.Now().AddDate(types.CardValidityYears, 0, 0)
+
+	cvvHash, err := bcrypt.GenerateFromPassword([]byte(cvv), types.BcryptSalt)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "HASH_ERROR"))
+		return
+	}
+
+	result, err := database.DB.Exec(
+		`INSERT INTO cards
+		(user_id, pan_hash, last4, expiry_month, expiry_year, cvv_hash, status, per_transaction_limit, daily_limit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		auth.CurrentUserID(c), HashPAN(pan), pan[len(pan)-4:], int(expiry.Month()), expiry.Year(),
+		string(cvvHash), StatusActive, req.PerTransactionLimit, req.DailyLimit,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "CARD_ISSUE_ERROR"))
+		return
+	}
+
+	cardID, err := result.LastInsertId()
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "ID_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Карта выпущена. Сохраните номер и CVV: повторно они не показываются",
+		Data: map[string]interface{}{
+			"card_id": cardID,
+			"pan":     pan,
+			"expiry":  fmt.Sprintf("%02d/%02d", int(expiry.Month()), expiry.Year()%100),
+			"cvv":     cvv,
+		},
+	})
+}
+
+func List(c *gin.Context) {
+	rows, err := database.DB.Query(selectCard+" WHERE user_id = ? ORDER BY id", auth.CurrentUserID(c))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	cards := make([]card, 0)
+	for rows.Next() {
+		k, err := scanCard(rows)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		cards = append(cards, k)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: fmt.Sprintf("Найдено карт: %d", len(cards)),
+		Data:    cards,
+	})
+}
+
+func Freeze(c *gin.Context) {
+	setStatus(c, StatusFrozen, "Карта заморожена")
+}
+
+func Unfreeze(c *gin.Context) {
+	setStatus(c, StatusActive, "Карта разморожена")
+}
+
+func setStatus(c *gin.Context, status, message string) {
+	k, ok := ownCard(c)
+	if !ok {
+		return
+	}
+
+	_, err := database.DB.Exec("UPDATE cards SET status = ? WHERE id = ?", status, k.ID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "CARD_UPDATE_ERROR"))
+		return
+	}
+
+	k.Status = status
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    k,
+	})
+}
+
+// UpdateLimits меняет лимиты карты. Ноль означает отсутствие лимита.
+func UpdateLimits(c *gin.Context) {
+	k, ok := ownCard(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		PerTransactionLimit *float64 `json:"per_transaction_limit" form:"per_transaction_limit"`
+		DailyLimit          *float64 `json:"daily_limit" form:"daily_limit"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.PerTransactionLimit != nil {
+		k.PerTransactionLimit = *req.PerTransactionLimit
+	}
+	if req.DailyLimit != nil {
+		k.DailyLimit = *req.DailyLimit
+	}
+	if k.PerTransactionLimit < 0 || k.DailyLimit < 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_LIMIT"))
+		return
+	}
+
+	_, err := database.DB.Exec(
+		"UPDATE cards SET per_transaction_limit = ?, daily_limit = ? WHERE id = ?",
+		k.PerTransactionLimit, k.DailyLimit, k.ID,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "CARD_UPDATE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Лимиты карты обновлены",
+		Data:    k,
+	})
+}
+
+// ownCard загружает карту из параметра :id и проверяет, что она принадлежит пользователю
+func ownCard(c *gin.Context) (card, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return card{}, false
+	}
+
+	k, err := scanCard(database.DB.QueryRow(selectCard+" WHERE id = ? AND user_id = ?", id, auth.CurrentUserID(c)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.Error(apierr.New(http.StatusNotFound, "CARD_NOT_FOUND"))
+		} else {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		}
+		return card{}, false
+	}
+
+	return k, true
+}
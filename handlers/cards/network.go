@@ -0,0 +1,420 @@
+package cards
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/transfers"
+	"backend_golang/i18n"
+	"backend_golang/kyc"
+	"backend_golang/ledger"
+	"backend_golang/methods"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+// Статусы авторизаций
+const (
+	AuthAuthorized = "authorized"
+	AuthCaptured   = "captured"
+	AuthReversed   = "reversed"
+	AuthRefunded   = "refunded"
+	AuthExpired    = "expired"
+)
+
+// decline отказ в авторизации с кодом ответа в стиле ISO 8583;
+// текст для клиента берётся из каталога i18n по errorCode
+type decline struct {
+	responseCode string
+	errorCode    string
+}
+
+func (d decline) Error() string {
+	return d.responseCode + " " + d.errorCode
+}
+
+var (
+	declineInvalidCard  = decline{"14", "INVALID_CARD"}
+	declineExpired      = decline{"54", "CARD_EXPIRED"}
+	declineFrozen       = decline{"62", "CARD_FROZEN"}
+	declineBadCVV       = decline{"82", "INVALID_CVV"}
+	declineLimit        = decline{"61", "CARD_LIMIT_EXCEEDED"}
+	declineInsufficient = decline{"51", "INSUFFICIENT_FUNDS"}
+	declineSpending     = decline{"61", "SPENDING_LIMIT_EXCEEDED"}
+	declineKYCLimit     = decline{"61", "KYC_LIMIT_EXCEEDED"}
+)
+
+var (
+	errAuthNotFound = apierr.New(http.StatusNotFound, "AUTHORIZATION_NOT_FOUND")
+	errAuthState    = apierr.New(http.StatusConflict, "INVALID_AUTHORIZATION_STATE")
+	errAuthAmount   = apierr.New(http.StatusBadRequest, "INVALID_AMOUNT.exceeds_authorization")
+)
+
+type authorization struct {
+	ID             int64   `json:"authorization_id"`
+	CardID         int64   `json:"card_id"`
+	UserID         int64   `json:"-"`
+	Amount         float64 `json:"amount"`
+	CapturedAmount float64 `json:"captured_amount"`
+	RefundedAmount float64 `json:"refunded_amount"`
+	Merchant       string  `json:"merchant,omitempty"`
+	Status         string  `json:"status"`
+	HoldID         int64   `json:"-"`
+	TransactionID  int64   `json:"transaction_id,omitempty"`
+}
+
+// RequireNetworkKey пропускает только запросы симулятора карточной сети
+func RequireNetworkKey(c *gin.Context) {
+	key := c.GetHeader("X-Network-Key")
+	if subtle.ConstantTimeCompare([]byte(key), []byte(types.CardNetworkKey)) != 1 {
+		apierr.Abort(c, apierr.New(http.StatusUnauthorized, "INVALID_NETWORK_KEY"))
+		return
+	}
+	c.Next()
+}
+
+// Authorize обрабатывает запрос авторизации: проверяет карту, лимиты
+// и ставит холд на сумму покупки. Без карты (card_present = false)
+// срок действия и CVV обязательны.
+func Authorize(c *gin.Context) {
+	var req struct {
+		PAN         string  `json:"pan" form:"pan" binding:"required"`
+		Expiry      string  `json:"expiry" form:"expiry" binding:"required_if=CardPresent false"`
+		CVV         string  `json:"cvv" form:"cvv" binding:"required_if=CardPresent false"`
+		CardPresent bool    `json:"card_present" form:"card_present"`
+		Amount      float64 `json:"amount" form:"amount" binding:"required,gt=0"`
+		Merchant    string  `json:"merchant" form:"merchant"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if ledger.Cents(req.Amount) <= 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_AMOUNT.positive"))
+		return
+	}
+
+	var approved authorization
+	err := database.WithTx(func(tx *sql.Tx) error {
+		if !validPAN(req.PAN) {
+			return declineInvalidCard
+		}
+
+		k, err := cardByPAN(tx, req.PAN)
+		if err == sql.ErrNoRows {
+			return declineInvalidCard
+		}
+		if err != nil {
+			return err
+		}
+
+		if k.expired(time.Now()) || (req.Expiry != "" && req.Expiry != k.Expiry) {
+			return declineExpired
+		}
+		if k.Status != StatusActive {
+			return declineFrozen
+		}
+		if req.CVV != "" && bcrypt.CompareHashAndPassword([]byte(k.cvvHash), []byte(req.CVV)) != nil {
+			return declineBadCVV
+		}
+		if k.PerTransactionLimit > 0 && ledger.Cents(req.Amount) > ledger.Cents(k.PerTransactionLimit) {
+			return declineLimit
+		}
+
+		if k.DailyLimit > 0 {
+			var spent float64
+			err := tx.QueryRow(
+				`SELECT COALESCE(SUM(CASE WHEN status = ? THEN amount ELSE captured_amount - refunded_amount END), 0)
+				FROM card_authorizations
+				WHERE card_id = ? AND status IN (?, ?, ?) AND created_at >= CURDATE()`,
+				AuthAuthorized, k.ID, AuthAuthorized, AuthCaptured, AuthRefunded,
+			).Scan(&spent)
+			if err != nil {
+				return err
+			}
+			if ledger.Cents(spent)+ledger.Cents(req.Amount) > ledger.Cents(k.DailyLimit) {
+				return declineLimit
+			}
+		}
+
+		err = auth.CheckSpending(tx, k.UserID, k.UserID, req.Amount)
+		if err == auth.ErrSpendingLimit {
+			return declineSpending
+		}
+		if errors.Is(err, kyc.ErrLimitExceeded) {
+			return declineKYCLimit
+		}
+		if err != nil {
+			return err
+		}
+
+		holdID, err := ledger.PlaceHold(tx, ledger.Hold{
+			UserID:      k.UserID,
+			Amount:      req.Amount,
+			Source:      "card",
+			ReferenceID: k.ID,
+			Description: req.Merchant,
+			ExpiresAt:   time.Now().Add(types.CardHoldTTL),
+		})
+		if err == ledger.ErrInsufficientFunds {
+			return declineInsufficient
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO card_authorizations (card_id, user_id, amount, merchant, status, hold_id)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			k.ID, k.UserID, ledger.Round(req.Amount), req.Merchant, AuthAuthorized, holdID,
+		)
+		if err != nil {
+			return err
+		}
+
+		approved = authorization{
+			CardID:   k.ID,
+			UserID:   k.UserID,
+			Amount:   ledger.Round(req.Amount),
+			Merchant: req.Merchant,
+			Status:   AuthAuthorized,
+			HoldID:   holdID,
+		}
+		approved.ID, err = result.LastInsertId()
+		return err
+	})
+
+	if err != nil {
+		respondNetworkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Авторизация одобрена",
+		Data: map[string]interface{}{
+			"response_code":    "00",
+			"authorization_id": approved.ID,
+			"amount":           approved.Amount,
+		},
+	})
+}
+
+// cardByPAN блокирует карту по номеру. Карта, выпущенная до перехода
+// на HMAC, находится по старому хешу и сразу получает новый.
+func cardByPAN(tx *sql.Tx, pan string) (card, error) {
+	k, err := scanCard(tx.QueryRow(selectCard+" WHERE pan_hash = ? FOR UPDATE", HashPAN(pan)))
+	if err != sql.ErrNoRows {
+		return k, err
+	}
+
+	k, err = scanCard(tx.QueryRow(selectCard+" WHERE pan_hash = ? FOR UPDATE", legacyHashPAN(pan)))
+	if err != nil {
+		return k, err
+	}
+	_, err = tx.Exec("UPDATE cards SET pan_hash = ? WHERE id = ?", HashPAN(pan), k.ID)
+	return k, err
+}
+
+func validPAN(pan string) bool {
+	return len(pan) == types.CardPANLength && strings.HasPrefix(pan, types.CardBIN) && methods.LuhnValid(pan)
+}
+
+// Capture списывает деньги по авторизации. Холд снимается, остаток
+// незахваченной суммы освобождается.
+func Capture(c *gin.Context) {
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	withAuthorization(c, "Списание проведено", func(tx *sql.Tx, a *authorization) error {
+		if a.Status != AuthAuthorized {
+			return errAuthState
+		}
+
+		amount := req.Amount
+		if amount == 0 {
+			amount = a.Amount
+		}
+		if ledger.Cents(amount) > ledger.Cents(a.Amount) {
+			return errAuthAmount
+		}
+
+		if err := ledger.ReleaseHold(tx, a.HoldID, ledger.HoldCaptured); err != nil {
+			return err
+		}
+
+		// Списание по одобренной авторизации проводится всегда: деньги уже
+		// были зарезервированы холдом, а сеть ждёт расчёта с мерчантом
+		txID, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindCardCapture,
+			FromAccount: ledger.UserAccount(a.UserID),
+			ToAccount:   ledger.BankCardSettlement,
+			Amount:      amount,
+			Memo:        cardMemo(a),
+			Force:       true,
+		})
+		if err != nil {
+			return err
+		}
+
+		a.CapturedAmount = ledger.Round(amount)
+		a.Status = AuthCaptured
+		a.TransactionID = txID
+		_, err = tx.Exec(
+			"UPDATE card_authorizations SET captured_amount = ?, status = ? WHERE id = ?",
+			a.CapturedAmount, a.Status, a.ID,
+		)
+		return err
+	})
+}
+
+// Reversal отменяет авторизацию до списания и снимает холд
+func Reversal(c *gin.Context) {
+	withAuthorization(c, "Авторизация отменена", func(tx *sql.Tx, a *authorization) error {
+		if a.Status != AuthAuthorized {
+			return errAuthState
+		}
+
+		if err := ledger.ReleaseHold(tx, a.HoldID, ledger.HoldReleased); err != nil {
+			return err
+		}
+
+		a.Status = AuthReversed
+		_, err := tx.Exec("UPDATE card_authorizations SET status = ? WHERE id = ?", a.Status, a.ID)
+		return err
+	})
+}
+
+// Refund возвращает деньги по уже списанной авторизации, полностью или частично
+func Refund(c *gin.Context) {
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	withAuthorization(c, "Возврат по карте проведён", func(tx *sql.Tx, a *authorization) error {
+		if a.Status != AuthCaptured && a.Status != AuthRefunded {
+			return errAuthState
+		}
+
+		remaining := ledger.Cents(a.CapturedAmount) - ledger.Cents(a.RefundedAmount)
+		amount := req.Amount
+		if amount == 0 {
+			amount = float64(remaining) / 100
+		}
+		if ledger.Cents(amount) <= 0 || ledger.Cents(amount) > remaining {
+			return errAuthAmount
+		}
+
+		txID, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindCardRefund,
+			FromAccount: ledger.BankCardSettlement,
+			ToAccount:   ledger.UserAccount(a.UserID),
+			Amount:      amount,
+			Memo:        cardMemo(a),
+		})
+		if err != nil {
+			return err
+		}
+
+		a.RefundedAmount = ledger.Round(a.RefundedAmount + amount)
+		a.Status = AuthRefunded
+		a.TransactionID = txID
+		_, err = tx.Exec(
+			"UPDATE card_authorizations SET refunded_amount = ?, status = ? WHERE id = ?",
+			a.RefundedAmount, a.Status, a.ID,
+		)
+		return err
+	})
+}
+
+func cardMemo(a *authorization) string {
+	memo := fmt.Sprintf("Оплата картой, авторизация #%d", a.ID)
+	if a.Merchant != "" {
+		memo += ": " + a.Merchant
+	}
+	return memo
+}
+
+// withAuthorization блокирует авторизацию из параметра :id и выполняет fn в транзакции
+func withAuthorization(c *gin.Context, message string, fn func(tx *sql.Tx, a *authorization) error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	var a authorization
+	err = database.WithTx(func(tx *sql.Tx) error {
+		err := tx.QueryRow(
+			`SELECT id, card_id, user_id, amount, captured_amount, refunded_amount, merchant, status, hold_id
+			FROM card_authorizations WHERE id = ? FOR UPDATE`,
+			id,
+		).Scan(&a.ID, &a.CardID, &a.UserID, &a.Amount, &a.CapturedAmount, &a.RefundedAmount,
+			&a.Merchant, &a.Status, &a.HoldID)
+		if err == sql.ErrNoRows {
+			return errAuthNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return fn(tx, &a)
+	})
+
+	if err != nil {
+		respondNetworkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    a,
+	})
+}
+
+func respondNetworkError(c *gin.Context, err error) {
+	var d decline
+	switch {
+	case errors.As(err, &d):
+		e := apierr.New(http.StatusPaymentRequired, d.errorCode)
+		e.Data = map[string]interface{}{"response_code": d.responseCode}
+		c.Error(e)
+	case err == ledger.ErrHoldNotActive:
+		c.Error(errAuthState)
+	default:
+		transfers.RespondLedgerError(c, err)
+	}
+}
+
+// ExpireAuthorizations помечает истёкшими авторизации, чей холд истёк
+// до списания
+func ExpireAuthorizations() error {
+	_, err := database.DB.Exec(
+		`UPDATE card_authorizations a JOIN holds h ON h.id = a.hold_id
+		SET a.status = ? WHERE a.status = ? AND h.status = ?`,
+		AuthExpired, AuthAuthorized, ledger.HoldExpired,
+	)
+	return err
+}
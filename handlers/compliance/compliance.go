@@ -0,0 +1,220 @@
+// Package compliance даёт офицерам комплаенса очередь совпадений
+// с санкционными списками и ручную проверку имён.
+package compliance
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/payments"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/sanctions"
+	"backend_golang/types"
+)
+
+// ListReviews возвращает очередь комплаенса; по умолчанию только ожидающие решения
+func ListReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", sanctions.ReviewPending)
+	if status == "all" {
+		status = ""
+	}
+
+	reviews, err := sanctions.ListReviews(database.DB, status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Очередь комплаенса",
+		Data:    reviews,
+	})
+}
+
+// GetReview возвращает событие очереди вместе с найденными записями списков
+func GetReview(c *gin.Context) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	review, err := sanctions.GetReview(database.DB, id, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Событие найдено",
+		Data:    review,
+	})
+}
+
+// Clear снимает совпадение как ложное: задержанный перевод проводится,
+// клиент на регистрации получает доступ
+func Clear(c *gin.Context) {
+	decide(c, sanctions.ReviewCleared)
+}
+
+// Confirm подтверждает совпадение: перевод отменяется, клиент блокируется
+func Confirm(c *gin.Context) {
+	decide(c, sanctions.ReviewConfirmed)
+}
+
+func decide(c *gin.Context, status string) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" form:"note"`
+	}
+	c.ShouldBind(&req)
+
+	officerID := auth.CurrentUserID(c)
+	err := database.WithTx(func(tx *sql.Tx) error {
+		review, err := sanctions.GetReview(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if review.Status != sanctions.ReviewPending {
+			return sanctions.ErrReviewNotPending
+		}
+
+		if review.HoldID != 0 {
+			// холд мог истечь, пока событие ждало решения
+			err := ledger.ReleaseHold(tx, review.HoldID, ledger.HoldReleased)
+			if err != nil && !errors.Is(err, ledger.ErrHoldNotActive) {
+				return err
+			}
+		}
+
+		var txID int64
+		if status == sanctions.ReviewCleared && review.SubjectType == sanctions.SubjectTransfer {
+			t, err := sanctions.PendingTransferOf(review)
+			if err != nil {
+				return err
+			}
+			txID, err = ledger.Transfer(tx, ledger.Transaction{
+				Kind:        ledger.KindTransfer,
+				FromAccount: t.FromAccount,
+				ToAccount:   t.ToAccount,
+				Amount:      t.Amount,
+				Memo:        t.Memo,
+				CreatedBy:   review.ActorID,
+			})
+			if err != nil {
+				return err
+			}
+			if t.PaymentRequestID != 0 {
+				if err := payments.Settle(tx, t.PaymentRequestID, txID); err != nil {
+					return err
+				}
+			}
+		} else if review.SubjectType == sanctions.SubjectTransfer {
+			// отклонённую оплату запроса денег можно повторить
+			t, err := sanctions.PendingTransferOf(review)
+			if err != nil {
+				return err
+			}
+			if t.PaymentRequestID != 0 {
+				if err := payments.Reopen(tx, t.PaymentRequestID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := sanctions.Decide(tx, review, status, officerID, req.Note, txID); err != nil {
+			return err
+		}
+
+		return audit.Record(tx, audit.Entry{
+			Event:   audit.EventSanctionsReviewed,
+			ActorID: officerID,
+			Subject: fmt.Sprintf("compliance_review:%d", id),
+			IP:      c.ClientIP(),
+			Details: map[string]interface{}{
+				"status":         status,
+				"subject_type":   review.SubjectType,
+				"user_id":        review.UserID,
+				"note":           req.Note,
+				"transaction_id": txID,
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	review, err := sanctions.GetReview(database.DB, id, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	message := "Совпадение снято"
+	if status == sanctions.ReviewConfirmed {
+		message = "Совпадение подтверждено, клиент заблокирован"
+	}
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    review,
+	})
+}
+
+// Lists возвращает сведения о загруженных санкционных списках
+func Lists(c *gin.Context) {
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Санкционные списки",
+		Data:    sanctions.Current(),
+	})
+}
+
+// Screen проверяет произвольное имя по спискам без постановки в очередь; фамилия — последним словом
+func Screen(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		c.Error(apierr.Missing("name"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Результат проверки",
+		Data: map[string]interface{}{
+			"name":      name,
+			"threshold": types.SanctionsMatchThreshold,
+			"matches":   sanctions.Screen(name),
+		},
+	})
+}
+
+func reviewID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — проверка не найдена
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "REVIEW_NOT_FOUND"))
+}
@@ -0,0 +1,209 @@
+// Package fraud даёт аналитикам очередь событий, задержанных антифродом.
+package fraud
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/fraud"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/payments"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// ListReviews возвращает очередь проверки; по умолчанию только ожидающие решения
+func ListReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", fraud.ReviewPending)
+	if status == "all" {
+		status = ""
+	}
+
+	reviews, err := fraud.ListReviews(database.DB, status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Очередь проверки",
+		Data:    reviews,
+	})
+}
+
+// GetReview возвращает событие очереди вместе со сработавшими правилами
+func GetReview(c *gin.Context) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	review, err := fraud.GetReview(database.DB, id, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Событие найдено",
+		Data:    review,
+	})
+}
+
+// Approve одобряет событие: задержанный перевод проводится, а устройство
+// задержанного входа становится знакомым
+func Approve(c *gin.Context) {
+	decide(c, fraud.ReviewApproved)
+}
+
+// Reject отклоняет событие: холд задержанного перевода снимается
+func Reject(c *gin.Context) {
+	decide(c, fraud.ReviewRejected)
+}
+
+func decide(c *gin.Context, status string) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Note string `json:"note" form:"note"`
+	}
+	c.ShouldBind(&req)
+
+	analystID := auth.CurrentUserID(c)
+	err := database.WithTx(func(tx *sql.Tx) error {
+		review, err := fraud.GetReview(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if review.Status != fraud.ReviewPending {
+			return fraud.ErrReviewNotPending
+		}
+
+		if review.HoldID != 0 {
+			// холд мог истечь, пока событие ждало решения
+			err := ledger.ReleaseHold(tx, review.HoldID, ledger.HoldReleased)
+			if err != nil && !errors.Is(err, ledger.ErrHoldNotActive) {
+				return err
+			}
+		}
+
+		var txID int64
+		if status == fraud.ReviewApproved {
+			switch review.EventType {
+			case fraud.EventTransfer:
+				t, err := fraud.PendingTransferOf(review)
+				if err != nil {
+					return err
+				}
+				txID, err = ledger.Transfer(tx, ledger.Transaction{
+					Kind:        ledger.KindTransfer,
+					FromAccount: t.FromAccount,
+					ToAccount:   t.ToAccount,
+					Amount:      t.Amount,
+					Memo:        t.Memo,
+					CreatedBy:   review.ActorID,
+				})
+				if err != nil {
+					return err
+				}
+				if t.PaymentRequestID != 0 {
+					if err := payments.Settle(tx, t.PaymentRequestID, txID); err != nil {
+						return err
+					}
+				}
+			case fraud.EventLogin:
+				if err := fraud.RememberDevice(tx, review.UserID, review.DeviceID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if status != fraud.ReviewApproved && review.EventType == fraud.EventTransfer {
+			// отклонённую оплату запроса денег можно повторить
+			t, err := fraud.PendingTransferOf(review)
+			if err != nil {
+				return err
+			}
+			if t.PaymentRequestID != 0 {
+				if err := payments.Reopen(tx, t.PaymentRequestID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := fraud.Decide(tx, id, status, analystID, req.Note, txID); err != nil {
+			return err
+		}
+
+		return audit.Record(tx, audit.Entry{
+			Event:   audit.EventFraudReviewed,
+			ActorID: analystID,
+			Subject: fmt.Sprintf("fraud_review:%d", id),
+			IP:      c.ClientIP(),
+			Details: map[string]interface{}{
+				"status":         status,
+				"event_type":     review.EventType,
+				"user_id":        review.UserID,
+				"note":           req.Note,
+				"transaction_id": txID,
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	review, err := fraud.GetReview(database.DB, id, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	message := "Событие одобрено"
+	if status == fraud.ReviewRejected {
+		message = "Событие отклонено"
+	}
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    review,
+	})
+}
+
+// Rules возвращает действующие правила антифрода
+func Rules(c *gin.Context) {
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Правила антифрода",
+		Data:    fraud.Current(),
+	})
+}
+
+func reviewID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — проверка не найдена
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "REVIEW_NOT_FOUND"))
+}
@@ -0,0 +1,320 @@
+// Package goals отвечает за копилки: отложенные под цель деньги
+// с целевой суммой и датой, ручными и автоматическими пополнениями.
+package goals
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы копилки
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+const dateLayout = "2006-01-02"
+
+var errGoalClosed = apierr.New(http.StatusConflict, "GOAL_CLOSED")
+
+type goal struct {
+	ID           int64   `json:"id"`
+	UserID       int64   `json:"user_id"`
+	Name         string  `json:"name"`
+	TargetAmount float64 `json:"target_amount"`
+	TargetDate   string  `json:"target_date,omitempty"`
+	Balance      float64 `json:"balance"`
+	Status       string  `json:"status"`
+	CreatedAt    string  `json:"created_at"`
+
+	Progress  float64 `json:"progress_percent"`
+	Remaining float64 `json:"remaining"`
+	DaysLeft  *int    `json:"days_left,omitempty"`
+}
+
+const selectGoal = "SELECT id, user_id, name, target_amount, target_date, balance, status, created_at FROM goals"
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (goal, error) {
+	var g goal
+	var targetDate sql.NullTime
+	var createdAt time.Time
+	err := row.Scan(&g.ID, &g.UserID, &g.Name, &g.TargetAmount, &targetDate, &g.Balance, &g.Status, &createdAt)
+	if err != nil {
+		return g, err
+	}
+	g.CreatedAt = createdAt.Format(types.TimeLayout)
+
+	// Прогресс не больше 100%, даже если в копилке больше цели
+	if g.TargetAmount > 0 {
+		g.Progress = math.Min(100, math.Round(g.Balance/g.TargetAmount*10000)/100)
+	}
+	g.Remaining = math.Max(0, ledger.Round(g.TargetAmount-g.Balance))
+
+	if targetDate.Valid {
+		g.TargetDate = targetDate.Time.Format(dateLayout)
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		days := int(math.Round(targetDate.Time.Sub(today).Hours() / 24))
+		g.DaysLeft = &days
+	}
+	return g, nil
+}
+
+func Create(c *gin.Context) {
+	var req struct {
+		Name         string  `json:"name" form:"name"`
+		TargetAmount float64 `json:"target_amount" form:"target_amount"`
+		TargetDate   string  `json:"target_date" form:"target_date"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.Name == "" || ledger.Cents(req.TargetAmount) <= 0 {
+		c.Error(apierr.Missing("name", "target_amount"))
+		return
+	}
+
+	var targetDate interface{}
+	if req.TargetDate != "" {
+		date, err := time.ParseInLocation(dateLayout, req.TargetDate, time.Local)
+		if err != nil || !date.After(time.Now()) {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_TARGET_DATE"))
+			return
+		}
+		targetDate = req.TargetDate
+	}
+
+	result, err := database.DB.Exec(
+		"INSERT INTO goals (user_id, name, target_amount, target_date, status) VALUES (?, ?, ?, ?, ?)",
+		auth.CurrentUserID(c), req.Name, ledger.Round(req.TargetAmount), targetDate, StatusOpen,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "GOAL_CREATE_ERROR"))
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	g, err := scanGoal(database.DB.QueryRow(selectGoal+" WHERE id = ?", id))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Копилка создана",
+		Data:    g,
+	})
+}
+
+func List(c *gin.Context) {
+	query := selectGoal + " WHERE user_id = ?"
+	args := []interface{}{auth.CurrentUserID(c)}
+	if status := c.Query("status"); status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	rows, err := database.DB.Query(query+" ORDER BY id DESC", args...)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	goals := make([]goal, 0)
+	var saved float64
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		saved += g.Balance
+		goals = append(goals, g)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Копилки",
+		Data: map[string]interface{}{
+			"total_saved": ledger.Round(saved),
+			"goals":       goals,
+		},
+	})
+}
+
+func GetByID(c *gin.Context) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Копилка",
+		Data:    g,
+	})
+}
+
+// Contribute переводит деньги с основного счёта в копилку
+func Contribute(c *gin.Context) {
+	move(c, true)
+}
+
+// Withdraw возвращает деньги из копилки на основной счёт
+func Withdraw(c *gin.Context) {
+	move(c, false)
+}
+
+func move(c *gin.Context, contribute bool) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	t := ledger.Transaction{
+		Kind:        ledger.KindGoalContribution,
+		FromAccount: ledger.UserAccount(g.UserID),
+		ToAccount:   ledger.GoalAccount(g.ID),
+		Amount:      req.Amount,
+		Memo:        "Копилка «" + g.Name + "»",
+		CreatedBy:   g.UserID,
+	}
+	if !contribute {
+		t.Kind = ledger.KindGoalWithdrawal
+		t.FromAccount, t.ToAccount = t.ToAccount, t.FromAccount
+	}
+
+	var txID int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var status string
+		if err := tx.QueryRow("SELECT status FROM goals WHERE id = ? FOR UPDATE", g.ID).Scan(&status); err != nil {
+			return err
+		}
+		if status != StatusOpen {
+			return errGoalClosed
+		}
+
+		var err error
+		txID, err = ledger.Transfer(tx, t)
+		return err
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	g, err = scanGoal(database.DB.QueryRow(selectGoal+" WHERE id = ?", g.ID))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Операция по копилке проведена",
+		Data: map[string]interface{}{
+			"transaction_id": txID,
+			"goal":           g,
+		},
+	})
+}
+
+// Close закрывает копилку, возвращает остаток на основной счёт
+// и отключает её правила
+func Close(c *gin.Context) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	var txID int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var status string
+		var balance float64
+		err := tx.QueryRow("SELECT status, balance FROM goals WHERE id = ? FOR UPDATE", g.ID).Scan(&status, &balance)
+		if err != nil {
+			return err
+		}
+		if status != StatusOpen {
+			return errGoalClosed
+		}
+
+		if ledger.Cents(balance) > 0 {
+			txID, err = ledger.Transfer(tx, ledger.Transaction{
+				Kind:        ledger.KindGoalWithdrawal,
+				FromAccount: ledger.GoalAccount(g.ID),
+				ToAccount:   ledger.UserAccount(g.UserID),
+				Amount:      balance,
+				Memo:        "Закрытие копилки «" + g.Name + "»",
+				CreatedBy:   g.UserID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE goals SET status = ? WHERE id = ?", StatusClosed, g.ID); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE goal_rules SET active = FALSE WHERE goal_id = ?", g.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Копилка закрыта",
+		Data: map[string]interface{}{
+			"goal_id":        g.ID,
+			"transaction_id": txID,
+		},
+	})
+}
+
+// ownGoal загружает копилку из параметра :id и проверяет владельца
+func ownGoal(c *gin.Context) (goal, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return goal{}, false
+	}
+
+	g, err := scanGoal(database.DB.QueryRow(selectGoal+" WHERE id = ? AND user_id = ?", id, auth.CurrentUserID(c)))
+	if err != nil {
+		respondError(c, err)
+		return goal{}, false
+	}
+	return g, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — копилка не найдена
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "GOAL_NOT_FOUND"))
+}
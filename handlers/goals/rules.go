@@ -0,0 +1,260 @@
+package goals
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Виды правил автопополнения
+const (
+	// RuleRoundUp откладывает сдачу от карточного платежа до ближайшего кратного value
+	RuleRoundUp = "round_up"
+	// RulePercentIncoming откладывает value процентов от входящего перевода
+	RulePercentIncoming = "percent_incoming"
+)
+
+var errInvalidRule = errors.New("invalid goal rule")
+
+type rule struct {
+	ID        int64   `json:"id"`
+	GoalID    int64   `json:"goal_id"`
+	Type      string  `json:"type"`
+	Value     float64 `json:"value"`
+	Active    bool    `json:"active"`
+	CreatedAt string  `json:"created_at"`
+}
+
+func AddRule(c *gin.Context) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Type  string  `json:"type" form:"type"`
+		Value float64 `json:"value" form:"value"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.Type == RuleRoundUp && req.Value == 0 {
+		req.Value = types.GoalRoundUpStep
+	}
+
+	switch {
+	case req.Type == RuleRoundUp && ledger.Cents(req.Value) > 0:
+	case req.Type == RulePercentIncoming && req.Value > 0 && req.Value <= 100:
+	default:
+		respondError(c, errInvalidRule)
+		return
+	}
+
+	if g.Status != StatusOpen {
+		respondError(c, errGoalClosed)
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"INSERT INTO goal_rules (goal_id, user_id, rule_type, value) VALUES (?, ?, ?, ?)",
+		g.ID, g.UserID, req.Type, ledger.Round(req.Value),
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "RULE_CREATE_ERROR"))
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Правило добавлено",
+		Data: rule{
+			ID:        id,
+			GoalID:    g.ID,
+			Type:      req.Type,
+			Value:     ledger.Round(req.Value),
+			Active:    true,
+			CreatedAt: time.Now().Format(types.TimeLayout),
+		},
+	})
+}
+
+func ListRules(c *gin.Context) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	rows, err := database.DB.Query(
+		"SELECT id, goal_id, rule_type, value, active, created_at FROM goal_rules WHERE goal_id = ? ORDER BY id",
+		g.ID,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	rules := make([]rule, 0)
+	for rows.Next() {
+		var r rule
+		var createdAt time.Time
+		if err := rows.Scan(&r.ID, &r.GoalID, &r.Type, &r.Value, &r.Active, &createdAt); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		r.CreatedAt = createdAt.Format(types.TimeLayout)
+		rules = append(rules, r)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Правила копилки",
+		Data:    rules,
+	})
+}
+
+// DeleteRule отключает правило; история пополнений по нему сохраняется
+func DeleteRule(c *gin.Context) {
+	g, ok := ownGoal(c)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(c.Param("rule_id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "rule_id"}))
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE goal_rules SET active = FALSE WHERE id = ? AND goal_id = ? AND active = TRUE",
+		ruleID, g.ID,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.Error(apierr.New(http.StatusNotFound, "RULE_NOT_FOUND"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Правило отключено",
+	})
+}
+
+// RuleHook применяет правила копилок к проведённой транзакции:
+// сдачу от карточного платежа и процент от входящего перевода.
+// Регистрируется через ledger.OnPost.
+func RuleHook(tx *sql.Tx, txID int64, t ledger.Transaction) error {
+	var userID int64
+	var ruleType string
+
+	switch t.Kind {
+	case ledger.KindCardCapture:
+		kind, id, err := ledger.ParseAccount(t.FromAccount)
+		if err != nil || kind != "user" {
+			return nil
+		}
+		userID, ruleType = id, RuleRoundUp
+	case ledger.KindTransfer:
+		kind, id, err := ledger.ParseAccount(t.ToAccount)
+		if err != nil || kind != "user" || t.FromAccount == t.ToAccount {
+			return nil
+		}
+		userID, ruleType = id, RulePercentIncoming
+	default:
+		return nil
+	}
+
+	rows, err := tx.Query(
+		`SELECT r.goal_id, r.value FROM goal_rules r JOIN goals g ON g.id = r.goal_id
+		WHERE r.user_id = ? AND r.rule_type = ? AND r.active = TRUE AND g.status = ?
+		ORDER BY r.id`,
+		userID, ruleType, StatusOpen,
+	)
+	if err != nil {
+		return err
+	}
+
+	type sweep struct {
+		goalID int64
+		amount int64
+	}
+
+	var sweeps []sweep
+	for rows.Next() {
+		var goalID int64
+		var value float64
+		if err := rows.Scan(&goalID, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		sweeps = append(sweeps, sweep{goalID, ruleAmount(ruleType, value, t.Amount)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range sweeps {
+		if s.amount <= 0 {
+			continue
+		}
+
+		// Автопополнение не должно уводить счёт в минус или в овердрафт:
+		// если денег не хватает, правило просто пропускается
+		balance, err := ledger.Balances(tx, userID)
+		if err != nil {
+			return err
+		}
+		if ledger.Cents(balance.AvailableBalance) < s.amount {
+			continue
+		}
+
+		_, err = ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindGoalContribution,
+			FromAccount: ledger.UserAccount(userID),
+			ToAccount:   ledger.GoalAccount(s.goalID),
+			Amount:      float64(s.amount) / 100,
+			Memo:        fmt.Sprintf("Автопополнение копилки по операции #%d", txID),
+			ReferenceID: txID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleAmount возвращает сумму пополнения в копейках
+func ruleAmount(ruleType string, value, amount float64) int64 {
+	switch ruleType {
+	case RuleRoundUp:
+		step := ledger.Cents(value)
+		if step <= 0 {
+			return 0
+		}
+		return (step - ledger.Cents(amount)%step) % step
+	case RulePercentIncoming:
+		return ledger.Cents(amount * value / 100)
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+package goals
+
+import "testing"
+
+func TestRuleAmount(t *testing.T) {
+	tests := []struct {
+		ruleType string
+		value    float64
+		amount   float64
+		want     int64
+	}{
+		{RuleRoundUp, 10, 123.45, 655},
+		{RuleRoundUp, 10, 120, 0},
+		{RuleRoundUp, 100, 0.01, 9999},
+		{RuleRoundUp, 0.5, 10.3, 20},
+		{RuleRoundUp, 0, 123.45, 0},
+		{RuleRoundUp, -10, 123.45, 0},
+		{RulePercentIncoming, 10, 50000, 500000},
+		{RulePercentIncoming, 3, 333.33, 1000},
+		{RulePercentIncoming, 0, 50000, 0},
+		{"unknown", 10, 123.45, 0},
+	}
+	for _, tt := range tests {
+		if got := ruleAmount(tt.ruleType, tt.value, tt.amount); got != tt.want {
+			t.Errorf("ruleAmount(%s, %v, %v) = %d, want %d", tt.ruleType, tt.value, tt.amount, got, tt.want)
+		}
+	}
+}
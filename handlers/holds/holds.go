@@ -0,0 +1,142 @@
+// Package holds показывает остатки и холды по счёту
+// и позволяет администратору блокировать средства вручную.
+package holds
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/cards"
+	"backend_golang/handlers/transfers"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// GetBalance возвращает проведённый и доступный остатки и сумму холдов
+func GetBalance(c *gin.Context) {
+	balance, err := ledger.Balances(database.DB, auth.CurrentAccountID(c))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Баланс счёта",
+		Data:    balance,
+	})
+}
+
+func List(c *gin.Context) {
+	holds, err := ledger.ListHolds(database.DB, auth.CurrentAccountID(c), c.Query("status"))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Холды по счёту",
+		Data:    holds,
+	})
+}
+
+// Place ставит ручной холд, например под ожидающий вывод средств
+func Place(c *gin.Context) {
+	var req struct {
+		UserID         int64   `json:"user_id" form:"user_id"`
+		Amount         float64 `json:"amount" form:"amount"`
+		Description    string  `json:"description" form:"description"`
+		ExpiresInHours int     `json:"expires_in_hours" form:"expires_in_hours"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.UserID == 0 || req.ExpiresInHours <= 0 {
+		c.Error(apierr.Missing("user_id", "expires_in_hours"))
+		return
+	}
+
+	var holdID int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var err error
+		holdID, err = ledger.PlaceHold(tx, ledger.Hold{
+			UserID:      req.UserID,
+			Amount:      req.Amount,
+			Source:      "manual",
+			ReferenceID: auth.CurrentUserID(c),
+			Description: req.Description,
+			ExpiresAt:   time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour),
+		})
+		return err
+	})
+
+	if err != nil {
+		transfers.RespondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Холд установлен",
+		Data: map[string]interface{}{
+			"hold_id": holdID,
+		},
+	})
+}
+
+// Release снимает ручной холд
+func Release(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	err = database.WithTx(func(tx *sql.Tx) error {
+		var source string
+		err := tx.QueryRow("SELECT source FROM holds WHERE id = ? FOR UPDATE", id).Scan(&source)
+		if err != nil {
+			return err
+		}
+		if source != "manual" {
+			return ledger.ErrHoldNotActive
+		}
+		return ledger.ReleaseHold(tx, id, ledger.HoldReleased)
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.Error(apierr.New(http.StatusNotFound, "HOLD_NOT_FOUND"))
+		} else if err == ledger.ErrHoldNotActive {
+			c.Error(apierr.New(http.StatusConflict, "HOLD_NOT_ACTIVE"))
+		} else {
+			transfers.RespondLedgerError(c, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Холд снят",
+	})
+}
+
+// ExpireJob снимает просроченные холды и закрывает связанные с ними
+// карточные авторизации
+func ExpireJob() error {
+	if _, err := ledger.ExpireHolds(database.DB); err != nil {
+		return err
+	}
+	return cards.ExpireAuthorizations()
+}
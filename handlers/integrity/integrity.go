@@ -0,0 +1,71 @@
+// Package integrity выдаёт администраторам снимки остатков и отчёт о сверке книг.
+package integrity
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/i18n"
+	"backend_golang/integrity"
+	"backend_golang/types"
+)
+
+const dateLayout = "2006-01-02"
+
+// Verify пересчитывает книги и возвращает найденные расхождения.
+// from=YYYY-MM-DD ограничивает проверку снимков датами не раньше from.
+func Verify(c *gin.Context) {
+	var from time.Time
+	if raw := c.Query("from"); raw != "" {
+		var err error
+		if from, err = time.ParseInLocation(dateLayout, raw, time.Local); err != nil {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_DATE", i18n.Args{"param": "from"}))
+			return
+		}
+	}
+
+	report, err := integrity.Verify(from)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	message := "Книги сходятся"
+	if !report.OK {
+		message = "Найдены расхождения"
+	}
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+		Data:    report,
+	})
+}
+
+// Snapshots возвращает остатки на конец дня date (по умолчанию — вчера);
+// account сужает выборку до одного счёта
+func Snapshots(c *gin.Context) {
+	date := time.Now().AddDate(0, 0, -1)
+	if raw := c.Query("date"); raw != "" {
+		var err error
+		if date, err = time.ParseInLocation(dateLayout, raw, time.Local); err != nil {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_DATE", i18n.Args{"param": "date"}))
+			return
+		}
+	}
+
+	snapshots, err := integrity.Snapshots(database.DB, date, c.Query("account"))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Остатки на конец дня",
+		Data:    snapshots,
+	})
+}
@@ -0,0 +1,308 @@
+// Package kyc даёт клиентам загрузку документов для идентификации,
+// а сотрудникам — очередь заявок и решения по ним.
+package kyc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/kyc"
+	"backend_golang/types"
+)
+
+// Get возвращает статус идентификации клиента, его документы и лимиты уровней
+func Get(c *gin.Context) {
+	profile, err := kyc.Get(database.DB, auth.CurrentUserID(c), false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Статус идентификации",
+		Data: map[string]interface{}{
+			"profile": profile,
+			"tiers":   types.KYCTiers,
+		},
+	})
+}
+
+// UploadDocument принимает файл документа (multipart: document_type, file)
+func UploadDocument(c *gin.Context) {
+	// запас на поля и заголовки multipart сверх размера самого файла
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, types.KYCMaxFileSize+64<<10)
+
+	docType := c.PostForm("document_type")
+	header, err := c.FormFile("file")
+	if err != nil || docType == "" {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, kyc.ErrFileTooLarge)
+			return
+		}
+		c.Error(apierr.Missing("document_type", "file"))
+		return
+	}
+	if header.Size > types.KYCMaxFileSize {
+		respondError(c, kyc.ErrFileTooLarge)
+		return
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, types.KYCMaxFileSize+1))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	document, err := kyc.AddDocument(userID, docType, header.Filename, data)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	audit.LogRequest(c, userID, audit.EventKYCDocumentUploaded, audit.UserSubject(userID), audit.OutcomeSuccess, map[string]interface{}{
+		"document_id":   document.ID,
+		"document_type": document.Type,
+		"sha256":        document.SHA256,
+	})
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Документ загружен",
+		Data:    document,
+	})
+}
+
+// DeleteDocument удаляет документ, пока заявка не отправлена
+func DeleteDocument(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := kyc.DeleteDocument(auth.CurrentUserID(c), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Документ удалён",
+	})
+}
+
+// Submit отправляет загруженные документы на проверку
+func Submit(c *gin.Context) {
+	userID := auth.CurrentUserID(c)
+	err := database.WithTx(func(tx *sql.Tx) error {
+		previous, err := kyc.Submit(tx, userID)
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Event:   audit.EventKYCSubmitted,
+			ActorID: userID,
+			Subject: audit.UserSubject(userID),
+			IP:      c.ClientIP(),
+			Details: map[string]interface{}{
+				"from":      previous.Status,
+				"documents": len(previous.Documents),
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	profile, err := kyc.Get(database.DB, userID, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Документы отправлены на проверку",
+		Data:    profile,
+	})
+}
+
+// ListSubmissions возвращает заявки со статусом status (по умолчанию submitted)
+func ListSubmissions(c *gin.Context) {
+	profiles, err := kyc.List(database.DB, c.DefaultQuery("status", kyc.StatusSubmitted))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Заявки на идентификацию",
+		Data:    profiles,
+	})
+}
+
+// GetSubmission возвращает заявку клиента со списком документов
+func GetSubmission(c *gin.Context) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	profile, err := kyc.Get(database.DB, userID, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Заявка найдена",
+		Data:    profile,
+	})
+}
+
+// DownloadDocument отдаёт расшифрованный файл документа.
+// Каждый просмотр пишется в журнал аудита.
+func DownloadDocument(c *gin.Context) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	document, err := kyc.GetDocument(database.DB, userID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	data, err := kyc.Blobs.Get(document.BlobKey)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	audit.LogRequest(c, auth.CurrentUserID(c), audit.EventKYCDocumentViewed, audit.UserSubject(userID), audit.OutcomeSuccess, map[string]interface{}{
+		"document_id":   document.ID,
+		"document_type": document.Type,
+	})
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=kyc_%d_%d", userID, document.ID))
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, document.ContentType, data)
+}
+
+// Approve одобряет заявку и назначает уровень идентификации (level, по умолчанию 1)
+func Approve(c *gin.Context) {
+	review(c, kyc.StatusApproved)
+}
+
+// Reject отклоняет заявку; причина в note обязательна
+func Reject(c *gin.Context) {
+	review(c, kyc.StatusRejected)
+}
+
+// RequestInfo просит клиента дозагрузить документы; что именно нужно — в note
+func RequestInfo(c *gin.Context) {
+	review(c, kyc.StatusNeedsMoreInfo)
+}
+
+func review(c *gin.Context, status string) {
+	userID, ok := paramID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Level int    `json:"level" form:"level"`
+		Note  string `json:"note" form:"note"`
+	}
+	c.ShouldBind(&req)
+
+	if req.Note == "" && status != kyc.StatusApproved {
+		c.Error(apierr.Missing("note"))
+		return
+	}
+	if status == kyc.StatusApproved && req.Level == 0 {
+		req.Level = 1
+	}
+
+	reviewerID := auth.CurrentUserID(c)
+	err := database.WithTx(func(tx *sql.Tx) error {
+		previous, err := kyc.Review(tx, userID, status, req.Level, req.Note)
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Event:   audit.EventKYCReviewed,
+			ActorID: reviewerID,
+			Subject: audit.UserSubject(userID),
+			IP:      c.ClientIP(),
+			Details: map[string]interface{}{
+				"from":  previous.Status,
+				"to":    status,
+				"level": req.Level,
+				"note":  req.Note,
+			},
+		})
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	profile, err := kyc.Get(database.DB, userID, false)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	messages := map[string]string{
+		kyc.StatusApproved:      "Заявка одобрена",
+		kyc.StatusRejected:      "Заявка отклонена",
+		kyc.StatusNeedsMoreInfo: "Клиенту отправлен запрос документов",
+	}
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: messages[status],
+		Data:    profile,
+	})
+}
+
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": name}))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — заявка или документ не найдены
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "NOT_FOUND.kyc"))
+}
@@ -0,0 +1,563 @@
+// Package loans отвечает за потребительские кредиты: заявки,
+// графики платежей, выдачу и погашение.
+package loans
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы кредита
+const (
+	StatusApplied  = "applied"
+	StatusRejected = "rejected"
+	StatusActive   = "active"
+	StatusOverdue  = "overdue"
+	StatusClosed   = "closed"
+)
+
+// Статусы платежа по графику
+const (
+	InstallmentPending   = "pending"
+	InstallmentOverdue   = "overdue"
+	InstallmentPaid      = "paid"
+	InstallmentCancelled = "cancelled"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	errLoanState   = apierr.New(http.StatusConflict, "INVALID_LOAN_STATE")
+	errLoanOverdue = apierr.New(http.StatusConflict, "LOAN_OVERDUE")
+	// errPrepayInterest сумма не покрывает даже набежавшие проценты
+	errPrepayInterest = apierr.New(http.StatusBadRequest, "INVALID_AMOUNT.below_interest")
+)
+
+type loan struct {
+	ID           int64   `json:"id"`
+	UserID       int64   `json:"user_id"`
+	Principal    float64 `json:"principal"`
+	AnnualRate   float64 `json:"annual_rate"`
+	TermMonths   int     `json:"term_months"`
+	ScheduleType string  `json:"schedule_type"`
+	Status       string  `json:"status"`
+	Outstanding  float64 `json:"outstanding_principal"`
+	DisbursedAt  string  `json:"disbursed_at,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+
+	disbursedAt time.Time
+	// interestPaidTo до какой даты проценты погашены досрочным погашением
+	interestPaidTo time.Time
+}
+
+const selectLoan = `SELECT id, user_id, principal, annual_rate, term_months, schedule_type, status,
+	balance, disbursed_at, interest_paid_to, created_at FROM loans`
+
+func scanLoan(row interface{ Scan(...interface{}) error }) (loan, error) {
+	var l loan
+	var balance float64
+	var disbursedAt, interestPaidTo sql.NullTime
+	var createdAt time.Time
+
+	err := row.Scan(&l.ID, &l.UserID, &l.Principal, &l.AnnualRate, &l.TermMonths, &l.ScheduleType,
+		&l.Status, &balance, &disbursedAt, &interestPaidTo, &createdAt)
+	if err != nil {
+		return l, err
+	}
+
+	l.Outstanding = ledger.Round(-balance)
+	if disbursedAt.Valid {
+		l.disbursedAt = disbursedAt.Time
+		l.DisbursedAt = disbursedAt.Time.Format(types.TimeLayout)
+	}
+	l.interestPaidTo = interestPaidTo.Time
+	l.CreatedAt = createdAt.Format(types.TimeLayout)
+	return l, nil
+}
+
+// Calculator считает график платежей без создания заявки
+func Calculator(c *gin.Context) {
+	amount, _ := strconv.ParseFloat(c.Query("amount"), 64)
+	months, _ := strconv.Atoi(c.Query("term_months"))
+	scheduleType := c.DefaultQuery("type", ScheduleAnnuity)
+
+	rate := types.LoanAnnualRate
+	if raw := c.Query("annual_rate"); raw != "" {
+		rate, _ = strconv.ParseFloat(raw, 64)
+	}
+
+	schedule, err := BuildSchedule(amount, rate, months, scheduleType, time.Now(), 1)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_SCHEDULE"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "График платежей",
+		Data:    summarize(schedule),
+	})
+}
+
+// summarize добавляет к графику итоги по платежам
+func summarize(schedule []Installment) map[string]interface{} {
+	var total, interest int64
+	for _, i := range schedule {
+		total += ledger.Cents(i.Total)
+		interest += ledger.Cents(i.Interest)
+	}
+	return map[string]interface{}{
+		"total_payment":  float64(total) / 100,
+		"total_interest": float64(interest) / 100,
+		"installments":   schedule,
+	}
+}
+
+// Apply создаёт заявку на кредит. Деньги выдаются после одобрения администратором.
+func Apply(c *gin.Context) {
+	var req struct {
+		Amount       float64 `json:"amount" form:"amount"`
+		TermMonths   int     `json:"term_months" form:"term_months"`
+		ScheduleType string  `json:"schedule_type" form:"schedule_type"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.ScheduleType == "" {
+		req.ScheduleType = ScheduleAnnuity
+	}
+
+	if req.Amount < types.LoanMinAmount || req.Amount > types.LoanMaxAmount ||
+		req.TermMonths < 1 || req.TermMonths > types.LoanMaxTermMonths ||
+		(req.ScheduleType != ScheduleAnnuity && req.ScheduleType != ScheduleDifferentiated) {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_LOAN_TERMS"))
+		return
+	}
+
+	dependent, err := auth.IsDependent(database.DB, auth.CurrentUserID(c))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	if dependent {
+		c.Error(apierr.New(http.StatusForbidden, "DEPENDENT_PROFILE.loans"))
+		return
+	}
+
+	result, err := database.DB.Exec(
+		`INSERT INTO loans (user_id, principal, annual_rate, term_months, schedule_type, status)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		auth.CurrentUserID(c), ledger.Round(req.Amount), types.LoanAnnualRate, req.TermMonths,
+		req.ScheduleType, StatusApplied,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "LOAN_CREATE_ERROR"))
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "ID_ERROR"))
+		return
+	}
+
+	l, err := scanLoan(database.DB.QueryRow(selectLoan+" WHERE id = ?", id))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Заявка на кредит принята",
+		Data:    l,
+	})
+}
+
+func List(c *gin.Context) {
+	query := selectLoan + " WHERE user_id = ?"
+	args := []interface{}{auth.CurrentUserID(c)}
+	if c.GetBool(adminListKey) {
+		query = selectLoan + " WHERE status = ?"
+		args = []interface{}{c.DefaultQuery("status", StatusApplied)}
+	}
+
+	rows, err := database.DB.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	loans := make([]loan, 0)
+	for rows.Next() {
+		l, err := scanLoan(rows)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		loans = append(loans, l)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Кредиты",
+		Data:    loans,
+	})
+}
+
+const adminListKey = "loans_admin_list"
+
+// AdminList показывает кредиты всех клиентов в статусе status (по умолчанию заявки)
+func AdminList(c *gin.Context) {
+	c.Set(adminListKey, true)
+	List(c)
+}
+
+func GetByID(c *gin.Context) {
+	l, ok := ownLoan(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Кредит найден",
+		Data:    l,
+	})
+}
+
+// Schedule возвращает полный график платежей. До выдачи показывается
+// расчётный график от текущей даты.
+func Schedule(c *gin.Context) {
+	l, ok := ownLoan(c)
+	if !ok {
+		return
+	}
+
+	if l.Status == StatusApplied || l.Status == StatusRejected {
+		schedule, err := BuildSchedule(l.Principal, l.AnnualRate, l.TermMonths, l.ScheduleType, time.Now(), 1)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCHEDULE_ERROR"))
+			return
+		}
+
+		data := summarize(schedule)
+		data["loan"] = l
+		data["projected"] = true
+		c.JSON(http.StatusOK, types.Response{
+			Success: true,
+			Message: "Расчётный график платежей",
+			Data:    data,
+		})
+		return
+	}
+
+	schedule, err := loadSchedule(database.DB, l.ID, false)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	data := summarize(schedule)
+	data["loan"] = l
+	data["projected"] = false
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "График платежей",
+		Data:    data,
+	})
+}
+
+// loadSchedule читает график кредита; отменённые при досрочном
+// погашении платежи пропускаются
+func loadSchedule(q ledger.Querier, loanID int64, forUpdate bool) ([]Installment, error) {
+	query := `SELECT number, due_date, principal, interest, late_fee, status FROM loan_installments
+		WHERE loan_id = ? AND status <> ? ORDER BY number`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := q.Query(query, loanID, InstallmentCancelled)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedule := make([]Installment, 0)
+	var principalLeft int64
+	for rows.Next() {
+		var i Installment
+		var dueDate time.Time
+		if err := rows.Scan(&i.Number, &dueDate, &i.Principal, &i.Interest, &i.LateFee, &i.Status); err != nil {
+			return nil, err
+		}
+		i.DueDate = dueDate.Format(dateLayout)
+		i.Total = float64(ledger.Cents(i.Principal)+ledger.Cents(i.Interest)+ledger.Cents(i.LateFee)) / 100
+		principalLeft += ledger.Cents(i.Principal)
+		schedule = append(schedule, i)
+	}
+
+	for n := range schedule {
+		principalLeft -= ledger.Cents(schedule[n].Principal)
+		schedule[n].Remaining = float64(principalLeft) / 100
+	}
+	return schedule, rows.Err()
+}
+
+// Approve одобряет заявку: деньги зачисляются заёмщику и создаётся график
+func Approve(c *gin.Context) {
+	decide(c, true)
+}
+
+func Reject(c *gin.Context) {
+	decide(c, false)
+}
+
+func decide(c *gin.Context, approve bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	adminID := auth.CurrentUserID(c)
+	err = database.WithTx(func(tx *sql.Tx) error {
+		l, err := scanLoan(tx.QueryRow(selectLoan+" WHERE id = ? FOR UPDATE", id))
+		if err != nil {
+			return err
+		}
+		if l.Status != StatusApplied {
+			return errLoanState
+		}
+
+		if !approve {
+			_, err = tx.Exec("UPDATE loans SET status = ?, decided_by = ? WHERE id = ?", StatusRejected, adminID, l.ID)
+			return err
+		}
+
+		now := time.Now()
+		schedule, err := BuildSchedule(l.Principal, l.AnnualRate, l.TermMonths, l.ScheduleType, now, 1)
+		if err != nil {
+			return err
+		}
+
+		_, err = ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindLoanDisbursement,
+			FromAccount: ledger.LoanAccount(l.ID),
+			ToAccount:   ledger.UserAccount(l.UserID),
+			Amount:      l.Principal,
+			Memo:        "Выдача кредита #" + strconv.FormatInt(l.ID, 10),
+			CreatedBy:   adminID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := insertSchedule(tx, l.ID, schedule); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE loans SET status = ?, decided_by = ?, disbursed_at = ? WHERE id = ?",
+			StatusActive, adminID, now, l.ID,
+		)
+		return err
+	})
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	l, err := scanLoan(database.DB.QueryRow(selectLoan+" WHERE id = ?", id))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Решение по заявке принято",
+		Data:    l,
+	})
+}
+
+func insertSchedule(tx *sql.Tx, loanID int64, schedule []Installment) error {
+	for _, i := range schedule {
+		_, err := tx.Exec(
+			`INSERT INTO loan_installments (loan_id, number, due_date, principal, interest, status)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			loanID, i.Number, i.DueDate, i.Principal, i.Interest, InstallmentPending,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prepay досрочно погашает часть основного долга или весь кредит.
+// Сначала из суммы гасятся проценты, набежавшие с последнего платежа
+// по графику или прошлого досрочного погашения, остаток уменьшает
+// основной долг. При частичном погашении
+// оставшиеся платежи пересчитываются на прежний срок с уменьшенной суммой.
+func Prepay(c *gin.Context) {
+	l, ok := ownLoan(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	err := database.WithTx(func(tx *sql.Tx) error {
+		l, err := scanLoan(tx.QueryRow(selectLoan+" WHERE id = ? FOR UPDATE", l.ID))
+		if err != nil {
+			return err
+		}
+		if l.Status != StatusActive {
+			if l.Status == StatusOverdue {
+				return errLoanOverdue
+			}
+			return errLoanState
+		}
+
+		schedule, err := loadSchedule(tx, l.ID, true)
+		if err != nil {
+			return err
+		}
+
+		paid, left := 0, 0
+		for _, i := range schedule {
+			if i.Status == InstallmentPaid {
+				paid++
+			} else {
+				left++
+			}
+		}
+
+		now := time.Now()
+		lastDue := dueDate(l.disbursedAt, paid)
+		accrued, err := accruedInterest(l.Outstanding, l.AnnualRate, interestFrom(lastDue, l.interestPaidTo), now)
+		if err != nil {
+			return err
+		}
+
+		payoff := ledger.Cents(l.Outstanding) + ledger.Cents(accrued)
+		amount := ledger.Cents(req.Amount)
+		if amount == 0 || amount > payoff {
+			amount = payoff
+		}
+		if amount <= ledger.Cents(accrued) {
+			return errPrepayInterest
+		}
+		principal := amount - ledger.Cents(accrued)
+
+		postings := []ledger.Posting{
+			{Account: ledger.UserAccount(l.UserID), Amount: -float64(amount) / 100},
+			{Account: ledger.LoanAccount(l.ID), Amount: float64(principal) / 100},
+		}
+		if ledger.Cents(accrued) > 0 {
+			postings = append(postings, ledger.Posting{Account: ledger.BankInterestIncome, Amount: accrued})
+		}
+		_, err = ledger.Post(tx, ledger.Transaction{
+			Kind:        ledger.KindLoanPrepayment,
+			FromAccount: ledger.UserAccount(l.UserID),
+			ToAccount:   ledger.LoanAccount(l.ID),
+			Amount:      float64(amount) / 100,
+			Memo:        "Досрочное погашение кредита #" + strconv.FormatInt(l.ID, 10),
+			CreatedBy:   l.UserID,
+		}, postings)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE loans SET interest_paid_to = ? WHERE id = ?", now.Format(dateLayout), l.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE loan_installments SET status = ? WHERE loan_id = ? AND status = ?",
+			InstallmentCancelled, l.ID, InstallmentPending,
+		)
+		if err != nil {
+			return err
+		}
+
+		remaining := ledger.Cents(l.Outstanding) - principal
+		if remaining <= 0 || left == 0 {
+			_, err = tx.Exec("UPDATE loans SET status = ? WHERE id = ?", StatusClosed, l.ID)
+			return err
+		}
+
+		// Отменённые номера заняты уникальным ключом, поэтому новый
+		// график продолжает нумерацию после последнего существующего номера
+		var maxNumber int
+		if err := tx.QueryRow("SELECT MAX(number) FROM loan_installments WHERE loan_id = ?", l.ID).Scan(&maxNumber); err != nil {
+			return err
+		}
+
+		newSchedule, err := BuildSchedule(float64(remaining)/100, l.AnnualRate, left, l.ScheduleType,
+			dueDate(l.disbursedAt, paid), maxNumber+1)
+		if err != nil {
+			return err
+		}
+		// Даты считаются от выдачи, а не от последнего платежа: иначе после
+		// платежа 28 февраля все следующие сдвинулись бы на 28-е
+		for k := range newSchedule {
+			newSchedule[k].DueDate = dueDate(l.disbursedAt, paid+k+1).Format(dateLayout)
+		}
+		if len(newSchedule) > 0 {
+			trimFirstInterest(&newSchedule[0], lastDue, interestFrom(lastDue, now), dueDate(l.disbursedAt, paid+1))
+		}
+		return insertSchedule(tx, l.ID, newSchedule)
+	})
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	Schedule(c)
+}
+
+// ownLoan загружает кредит из параметра :id и проверяет владельца
+func ownLoan(c *gin.Context) (loan, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return loan{}, false
+	}
+
+	l, err := scanLoan(database.DB.QueryRow(selectLoan+" WHERE id = ? AND user_id = ?", id, auth.CurrentUserID(c)))
+	if err != nil {
+		respondError(c, err)
+		return loan{}, false
+	}
+	return l, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — кредит не найден
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "LOAN_NOT_FOUND"))
+}
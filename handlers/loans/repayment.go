@@ -0,0 +1,164 @@
+package loans
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// RepaymentJob списывает наступившие платежи со счетов заёмщиков.
+// Если денег не хватает дольше льготного периода, платёж становится
+// просроченным, к нему добавляется штраф, а кредит переходит в статус overdue.
+func RepaymentJob() error {
+	rows, err := database.DB.Query(
+		`SELECT id FROM loan_installments WHERE status IN (?, ?) AND due_date <= CURDATE()
+		ORDER BY loan_id, number`,
+		InstallmentPending, InstallmentOverdue,
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Ошибка по одному платежу не должна останавливать списания по остальным
+	for _, id := range ids {
+		if err := collect(id); err != nil {
+			log.Printf("❌ Loan installment %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// collect пытается погасить один платёж
+func collect(installmentID int64) error {
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var loanID, userID int64
+		var number int
+		var principal, interest, lateFee float64
+		var status string
+
+		err := tx.QueryRow(
+			`SELECT i.loan_id, l.user_id, i.number, i.principal, i.interest, i.late_fee, i.status
+			FROM loan_installments i JOIN loans l ON l.id = i.loan_id
+			WHERE i.id = ? FOR UPDATE`,
+			installmentID,
+		).Scan(&loanID, &userID, &number, &principal, &interest, &lateFee, &status)
+		if err != nil {
+			return err
+		}
+		if status != InstallmentPending && status != InstallmentOverdue {
+			return nil
+		}
+
+		total := float64(ledger.Cents(principal)+ledger.Cents(interest)+ledger.Cents(lateFee)) / 100
+		postings := []ledger.Posting{
+			{Account: ledger.UserAccount(userID), Amount: -total},
+			{Account: ledger.LoanAccount(loanID), Amount: principal},
+		}
+		if ledger.Cents(interest) > 0 {
+			postings = append(postings, ledger.Posting{Account: ledger.BankInterestIncome, Amount: interest})
+		}
+		if ledger.Cents(lateFee) > 0 {
+			postings = append(postings, ledger.Posting{Account: ledger.BankFeeIncome, Amount: lateFee})
+		}
+
+		txID, err := ledger.Post(tx, ledger.Transaction{
+			Kind:        ledger.KindLoanRepayment,
+			FromAccount: ledger.UserAccount(userID),
+			ToAccount:   ledger.LoanAccount(loanID),
+			Amount:      total,
+			Memo:        fmt.Sprintf("Платёж %d по кредиту #%d", number, loanID),
+		}, postings)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE loan_installments SET status = ?, paid_tx_id = ?, paid_at = NOW() WHERE id = ?",
+			InstallmentPaid, txID, installmentID,
+		)
+		if err != nil {
+			return err
+		}
+		return refreshLoanStatus(tx, loanID)
+	})
+
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		return markOverdue(installmentID)
+	}
+	return err
+}
+
+// markOverdue переводит платёж в просрочку по окончании льготного периода
+// и начисляет штраф один раз
+func markOverdue(installmentID int64) error {
+	return database.WithTx(func(tx *sql.Tx) error {
+		var loanID int64
+		var dueDate time.Time
+		var status string
+
+		err := tx.QueryRow(
+			"SELECT loan_id, due_date, status FROM loan_installments WHERE id = ? FOR UPDATE",
+			installmentID,
+		).Scan(&loanID, &dueDate, &status)
+		if err != nil {
+			return err
+		}
+
+		if status != InstallmentPending || time.Now().Before(dueDate.AddDate(0, 0, types.LoanGraceDays+1)) {
+			return nil
+		}
+
+		_, err = tx.Exec(
+			"UPDATE loan_installments SET status = ?, late_fee = ? WHERE id = ?",
+			InstallmentOverdue, ledger.Round(types.LoanLateFee), installmentID,
+		)
+		if err != nil {
+			return err
+		}
+		return refreshLoanStatus(tx, loanID)
+	})
+}
+
+// refreshLoanStatus выставляет статус кредита по состоянию графика
+func refreshLoanStatus(tx *sql.Tx, loanID int64) error {
+	var overdue, open int
+	err := tx.QueryRow(
+		`SELECT COALESCE(SUM(status = ?), 0), COALESCE(SUM(status IN (?, ?)), 0)
+		FROM loan_installments WHERE loan_id = ?`,
+		InstallmentOverdue, InstallmentPending, InstallmentOverdue, loanID,
+	).Scan(&overdue, &open)
+	if err != nil {
+		return err
+	}
+
+	status := StatusActive
+	switch {
+	case open == 0:
+		status = StatusClosed
+	case overdue > 0:
+		status = StatusOverdue
+	}
+
+	_, err = tx.Exec("UPDATE loans SET status = ? WHERE id = ?", status, loanID)
+	return err
+}
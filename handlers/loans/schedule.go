@@ -0,0 +1,153 @@
+package loans
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Виды графика платежей
+const (
+	ScheduleAnnuity        = "annuity"
+	ScheduleDifferentiated = "differentiated"
+)
+
+var errInvalidSchedule = errors.New("invalid schedule parameters")
+
+// Installment один платёж по графику
+type Installment struct {
+	Number    int     `json:"number"`
+	DueDate   string  `json:"due_date"`
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	LateFee   float64 `json:"late_fee,omitempty"`
+	Total     float64 `json:"total"`
+	Remaining float64 `json:"remaining_principal"`
+	Status    string  `json:"status,omitempty"`
+}
+
+// BuildSchedule строит график из months ежемесячных платежей по сумме principal
+// и годовой ставке annualRate (в процентах). Проценты за месяц считаются как
+// остаток * ставка / 12. Первый платёж через месяц после start (см. dueDate),
+// номера начинаются с firstNumber. Последний платёж закрывает остаток копейка в копейку.
+func BuildSchedule(principal, annualRate float64, months int, scheduleType string, start time.Time, firstNumber int) ([]Installment, error) {
+	if ledger.Cents(principal) <= 0 || months <= 0 || annualRate < 0 {
+		return nil, errInvalidSchedule
+	}
+	if scheduleType != ScheduleAnnuity && scheduleType != ScheduleDifferentiated {
+		return nil, errInvalidSchedule
+	}
+
+	rate := annualRate / 100 / 12
+	remaining := ledger.Cents(principal)
+
+	var payment int64
+	if scheduleType == ScheduleAnnuity {
+		if rate == 0 {
+			payment = ledger.Cents(principal / float64(months))
+		} else {
+			payment = ledger.Cents(principal * rate / (1 - math.Pow(1+rate, -float64(months))))
+		}
+	}
+	principalPart := ledger.Cents(principal / float64(months))
+
+	schedule := make([]Installment, 0, months)
+	for i := 1; i <= months; i++ {
+		interest := int64(math.Round(float64(remaining) * rate))
+
+		var part int64
+		switch {
+		case i == months:
+			part = remaining
+		case scheduleType == ScheduleAnnuity:
+			part = payment - interest
+		default:
+			part = principalPart
+		}
+		if part > remaining {
+			part = remaining
+		}
+		remaining -= part
+
+		schedule = append(schedule, Installment{
+			Number:    firstNumber + i - 1,
+			DueDate:   dueDate(start, i).Format(dateLayout),
+			Principal: float64(part) / 100,
+			Interest:  float64(interest) / 100,
+			Total:     float64(part+interest) / 100,
+			Remaining: float64(remaining) / 100,
+		})
+	}
+
+	return schedule, nil
+}
+
+// dueDate дата n-го ежемесячного платежа от start. Если в месяце нет такого
+// числа, платёж переносится на последний день месяца: кредит от 31 января
+// гасится 28 февраля, 31 марта, 30 апреля. Каждая дата считается от start,
+// поэтому короткий месяц не сдвигает следующие. AddDate здесь не подходит:
+// 31 января + 1 месяц у него 3 марта.
+func dueDate(start time.Time, n int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	last := first.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// accruedInterest проценты на остаток principal по годовой ставке annualRate
+// за полные дни с from по to по конвенции types.DayCountConvention
+func accruedInterest(principal, annualRate float64, from, to time.Time) (float64, error) {
+	days := daysBetween(from, to)
+	if days <= 0 {
+		return 0, nil
+	}
+	daysInYear, err := ledger.DaysInYear(types.DayCountConvention, to)
+	if err != nil {
+		return 0, err
+	}
+	return ledger.Round(principal * annualRate / 100 * float64(days) / daysInYear), nil
+}
+
+// daysBetween число календарных дней от from до to без учёта времени суток
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// interestFrom дата, с которой набегают проценты: последний платёж по графику
+// или более позднее досрочное погашение, уже погасившее проценты до paidTo
+func interestFrom(lastDue, paidTo time.Time) time.Time {
+	if daysBetween(lastDue, paidTo) > 0 {
+		return paidTo
+	}
+	return lastDue
+}
+
+// trimFirstInterest оставляет в первом платеже графика, пересчитанного после
+// досрочного погашения, проценты только за дни с from по next: проценты
+// за период с lastDue по from уже погашены
+func trimFirstInterest(first *Installment, lastDue, from, next time.Time) {
+	period := daysBetween(lastDue, next)
+	if period <= 0 {
+		return
+	}
+	share := float64(daysBetween(from, next)) / float64(period)
+	if share < 0 {
+		share = 0
+	}
+	if share > 1 {
+		share = 1
+	}
+	interest := int64(math.Round(float64(ledger.Cents(first.Interest)) * share))
+	first.Interest = float64(interest) / 100
+	first.Total = float64(ledger.Cents(first.Principal)+interest) / 100
+}
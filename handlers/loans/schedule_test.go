@@ -0,0 +1,185 @@
+package loans
+
+import (
+	"testing"
+	"time"
+
+	"backend_golang/ledger"
+)
+
+func TestDueDate(t *testing.T) {
+	tests := []struct {
+		start string
+		n     int
+		want  string
+	}{
+		{"2025-01-15", 1, "2025-02-15"},
+		{"2025-01-31", 1, "2025-02-28"},
+		{"2025-01-31", 2, "2025-03-31"},
+		{"2025-01-31", 3, "2025-04-30"},
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2025-08-31", 6, "2026-02-28"},
+		{"2025-11-30", 3, "2026-02-28"},
+		{"2025-12-31", 12, "2026-12-31"},
+	}
+	for _, tt := range tests {
+		start, _ := time.Parse(dateLayout, tt.start)
+		if got := dueDate(start, tt.n).Format(dateLayout); got != tt.want {
+			t.Errorf("dueDate(%s, %d) = %s, want %s", tt.start, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAccruedInterest(t *testing.T) {
+	tests := []struct {
+		principal, rate float64
+		from, to        string
+		want            float64
+	}{
+		{100000, 19.9, "2025-03-10", "2025-03-20", 545.21},
+		{100000, 19.9, "2025-03-10", "2025-03-10", 0},
+		{100000, 19.9, "2025-03-10", "2025-03-01", 0},
+		{50000, 12, "2025-01-31", "2025-02-28", 460.27},
+		{50000, 0, "2025-01-31", "2025-02-28", 0},
+	}
+	for _, tt := range tests {
+		from, _ := time.Parse(dateLayout, tt.from)
+		to, _ := time.Parse(dateLayout, tt.to)
+		got, err := accruedInterest(tt.principal, tt.rate, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("accruedInterest(%v, %v, %s, %s) = %v, want %v", tt.principal, tt.rate, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestBuildSchedule(t *testing.T) {
+	start, _ := time.Parse(dateLayout, "2025-01-31")
+	tests := []struct {
+		name         string
+		principal    float64
+		rate         float64
+		months       int
+		scheduleType string
+		payment      float64 // платёж по аннуитету или часть долга по дифференцированному
+		lastTotal    float64
+	}{
+		{"annuity", 100000, 19.9, 12, ScheduleAnnuity, 9258.67, 9258.61},
+		{"annuity without interest", 1000, 0, 3, ScheduleAnnuity, 333.33, 333.34},
+		{"differentiated", 120000, 12, 12, ScheduleDifferentiated, 10000, 10100},
+		{"single month", 5000, 24, 1, ScheduleAnnuity, 5100, 5100},
+	}
+	for _, tt := range tests {
+		schedule, err := BuildSchedule(tt.principal, tt.rate, tt.months, tt.scheduleType, start, 3)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(schedule) != tt.months {
+			t.Fatalf("%s: %d installments, want %d", tt.name, len(schedule), tt.months)
+		}
+
+		var principal int64
+		for i, inst := range schedule {
+			principal += ledger.Cents(inst.Principal)
+			if inst.Number != 3+i {
+				t.Errorf("%s: installment %d has number %d", tt.name, i, inst.Number)
+			}
+			if want := dueDate(start, i+1).Format(dateLayout); inst.DueDate != want {
+				t.Errorf("%s: installment %d due %s, want %s", tt.name, i, inst.DueDate, want)
+			}
+			if ledger.Cents(inst.Total) != ledger.Cents(inst.Principal)+ledger.Cents(inst.Interest) {
+				t.Errorf("%s: installment %d total %v != %v + %v", tt.name, i, inst.Total, inst.Principal, inst.Interest)
+			}
+			if i == len(schedule)-1 {
+				break
+			}
+			got := inst.Total
+			if tt.scheduleType == ScheduleDifferentiated {
+				got = inst.Principal
+			}
+			if ledger.Cents(got) != ledger.Cents(tt.payment) {
+				t.Errorf("%s: installment %d = %v, want %v", tt.name, i, got, tt.payment)
+			}
+		}
+
+		last := schedule[len(schedule)-1]
+		if principal != ledger.Cents(tt.principal) || last.Remaining != 0 {
+			t.Errorf("%s: principal repaid %d, remaining %v", tt.name, principal, last.Remaining)
+		}
+		if ledger.Cents(last.Total) != ledger.Cents(tt.lastTotal) {
+			t.Errorf("%s: last installment %v, want %v", tt.name, last.Total, tt.lastTotal)
+		}
+	}
+}
+
+func TestBuildScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		principal, rate float64
+		months          int
+		scheduleType    string
+	}{
+		{0, 10, 12, ScheduleAnnuity},
+		{0.004, 10, 12, ScheduleAnnuity},
+		{1000, -1, 12, ScheduleAnnuity},
+		{1000, 10, 0, ScheduleAnnuity},
+		{1000, 10, 12, "balloon"},
+	}
+	for _, tt := range tests {
+		if _, err := BuildSchedule(tt.principal, tt.rate, tt.months, tt.scheduleType, time.Now(), 1); err == nil {
+			t.Errorf("BuildSchedule(%v, %v, %d, %q): want error", tt.principal, tt.rate, tt.months, tt.scheduleType)
+		}
+	}
+}
+
+// TestTwoPrepaymentsInOnePeriod проходит два досрочных погашения между
+// платежами по графику: второе начисляет проценты только с даты первого
+func TestTwoPrepaymentsInOnePeriod(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	disbursed := date("2025-01-15")
+	lastDue, next := dueDate(disbursed, 0), dueDate(disbursed, 1)
+	outstanding := 100000.0
+	var paidTo time.Time
+
+	prepayments := []struct {
+		on          string
+		amount      float64
+		from        string
+		accrued     float64
+		firstShare  float64 // доля процентов первого платежа, которая остаётся
+		outstanding float64
+	}{
+		{"2025-01-25", 20000, "2025-01-15", 545.21, 21.0 / 31, 80545.21},
+		{"2025-02-05", 10000, "2025-01-25", 483.05, 10.0 / 31, 71028.26},
+	}
+	for _, p := range prepayments {
+		now := date(p.on)
+		from := interestFrom(lastDue, paidTo)
+		if got := from.Format(dateLayout); got != p.from {
+			t.Fatalf("%s: interest from %s, want %s", p.on, got, p.from)
+		}
+		accrued, err := accruedInterest(outstanding, 19.9, from, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accrued != p.accrued {
+			t.Errorf("%s: accrued %v, want %v", p.on, accrued, p.accrued)
+		}
+
+		outstanding = ledger.Round(outstanding - (p.amount - accrued))
+		paidTo = now
+		if outstanding != p.outstanding {
+			t.Errorf("%s: outstanding %v, want %v", p.on, outstanding, p.outstanding)
+		}
+
+		first := Installment{Principal: 1000, Interest: 310, Total: 1310}
+		trimFirstInterest(&first, lastDue, interestFrom(lastDue, paidTo), next)
+		if want := ledger.Round(310 * p.firstShare); first.Interest != want || first.Total != ledger.Round(1000+want) {
+			t.Errorf("%s: first installment interest %v, total %v, want %v", p.on, first.Interest, first.Total, want)
+		}
+	}
+}
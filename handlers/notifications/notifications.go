@@ -0,0 +1,246 @@
+// Package notifications отдаёт клиенту входящие уведомления приложения
+// и настройки уведомлений: язык, e-mail, тихие часы, каналы по событиям
+// и push-токены устройств.
+package notifications
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/notify"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+// Inbox возвращает входящие, новые первыми, и число непрочитанных.
+// ?unread=true оставляет только непрочитанные, before_id задаёт страницу,
+// limit — её размер (до 100).
+func Inbox(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	beforeID, _ := strconv.ParseInt(c.Query("before_id"), 10, 64)
+	userID := auth.CurrentUserID(c)
+
+	items, err := notify.Inbox(database.DB, userID, c.Query("unread") == "true", beforeID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	unread, err := notify.UnreadCount(database.DB, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Входящие уведомления",
+		Data: map[string]interface{}{
+			"items":        items,
+			"unread_count": unread,
+		},
+	})
+}
+
+func MarkRead(c *gin.Context) {
+	markRead(c, true, "Уведомление прочитано")
+}
+
+func MarkUnread(c *gin.Context) {
+	markRead(c, false, "Уведомление отмечено непрочитанным")
+}
+
+func markRead(c *gin.Context, read bool, message string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	if err := notify.MarkRead(database.DB, auth.CurrentUserID(c), id, read); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: message,
+	})
+}
+
+func MarkAllRead(c *gin.Context) {
+	count, err := notify.MarkAllRead(database.DB, auth.CurrentUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Все уведомления прочитаны",
+		Data:    map[string]int64{"marked": count},
+	})
+}
+
+// Settings возвращает настройки уведомлений и каналы по всем событиям
+func Settings(c *gin.Context) {
+	userID := auth.CurrentUserID(c)
+
+	settings, err := notify.GetSettings(database.DB, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	prefs, err := notify.Preferences(database.DB, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Настройки уведомлений",
+		Data: map[string]interface{}{
+			"settings":    settings,
+			"preferences": prefs,
+			"channels":    notify.AllChannels,
+			"locales":     notify.Locales,
+		},
+	})
+}
+
+// UpdateSettings меняет locale, email, timezone, quiet_from и quiet_to;
+// пустые quiet_from и quiet_to выключают тихие часы
+func UpdateSettings(c *gin.Context) {
+	var req struct {
+		Locale    *string `json:"locale" form:"locale"`
+		Email     *string `json:"email" form:"email"`
+		Timezone  *string `json:"timezone" form:"timezone"`
+		QuietFrom *string `json:"quiet_from" form:"quiet_from"`
+		QuietTo   *string `json:"quiet_to" form:"quiet_to"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	settings, err := notify.UpdateSettings(database.DB, auth.CurrentUserID(c),
+		req.Locale, req.Email, req.Timezone, req.QuietFrom, req.QuietTo)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Настройки уведомлений сохранены",
+		Data:    settings,
+	})
+}
+
+// UpdatePreferences включает и выключает каналы для событий:
+// {"preferences": [{"event": "...", "channel": "sms", "enabled": false}]}
+func UpdatePreferences(c *gin.Context) {
+	var req struct {
+		Preferences []struct {
+			Event   string `json:"event"`
+			Channel string `json:"channel"`
+			Enabled bool   `json:"enabled"`
+		} `json:"preferences"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+	if len(req.Preferences) == 0 {
+		c.Error(apierr.Missing("preferences"))
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	err := database.WithTx(func(tx *sql.Tx) error {
+		for _, p := range req.Preferences {
+			if err := notify.SetPreference(tx, userID, p.Event, p.Channel, p.Enabled); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	prefs, err := notify.Preferences(database.DB, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Настройки уведомлений сохранены",
+		Data:    prefs,
+	})
+}
+
+// RegisterPushToken привязывает устройство (token, platform: ios, android, web)
+func RegisterPushToken(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" form:"token"`
+		Platform string `json:"platform" form:"platform"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+	if req.Token == "" || req.Platform == "" || len(req.Token) > 255 {
+		c.Error(apierr.Missing("token", "platform"))
+		return
+	}
+
+	if err := notify.RegisterPushToken(database.DB, auth.CurrentUserID(c), req.Token, req.Platform); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Устройство зарегистрировано",
+	})
+}
+
+// DeletePushToken отвязывает устройство (token)
+func DeletePushToken(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" form:"token"`
+	}
+	if err := c.ShouldBind(&req); err != nil || req.Token == "" {
+		c.Error(apierr.Missing("token"))
+		return
+	}
+
+	if err := notify.DeletePushToken(database.DB, auth.CurrentUserID(c), req.Token); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Устройство отвязано",
+	})
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — уведомление не найдено
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "NOT_FOUND.notification"))
+}
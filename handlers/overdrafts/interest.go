@@ -0,0 +1,135 @@
+package overdrafts
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// dayStart возвращает полночь даты t в локальной зоне
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// InterestJob списывает проценты за каждый закрытый день, в конце которого
+// остаток был отрицательным. Каждый день обрабатывается один раз:
+// запись в overdraft_interest и проводка делаются в одной транзакции.
+func InterestJob() error {
+	rows, err := database.DB.Query(
+		`SELECT o.user_id, o.created_at, MAX(i.accrual_date)
+		FROM overdrafts o LEFT JOIN overdraft_interest i ON i.user_id = o.user_id
+		GROUP BY o.user_id, o.created_at`,
+	)
+	if err != nil {
+		return err
+	}
+
+	type pendingUser struct {
+		userID int64
+		from   time.Time
+	}
+
+	var users []pendingUser
+	for rows.Next() {
+		var u pendingUser
+		var createdAt time.Time
+		var lastAccrual sql.NullTime
+		if err := rows.Scan(&u.userID, &createdAt, &lastAccrual); err != nil {
+			rows.Close()
+			return err
+		}
+
+		u.from = dayStart(createdAt)
+		if lastAccrual.Valid {
+			u.from = dayStart(lastAccrual.Time).AddDate(0, 0, 1)
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	today := dayStart(time.Now())
+	for _, u := range users {
+		for day := u.from; day.Before(today); day = day.AddDate(0, 0, 1) {
+			if err := chargeDay(u.userID, day); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chargeDay начисляет проценты на отрицательный остаток конца дня day.
+// Остаток считается от users.balance, как в выписке: у пользователей,
+// созданных до ведения книги, сумма проводок меньше остатка.
+func chargeDay(userID int64, day time.Time) error {
+	eod, err := ledger.BalanceBefore(database.DB, ledger.UserAccount(userID), day.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	days, err := ledger.DaysInYear(types.DayCountConvention, day)
+	if err != nil {
+		return err
+	}
+
+	return database.WithTx(func(tx *sql.Tx) error {
+		// Порядок блокировок как в ledger: сначала пользователь, затем овердрафт
+		var id int64
+		if err := tx.QueryRow("SELECT id FROM users WHERE id = ? FOR UPDATE", userID).Scan(&id); err != nil {
+			return err
+		}
+
+		var rate float64
+		err := tx.QueryRow("SELECT annual_rate FROM overdrafts WHERE user_id = ? FOR UPDATE", userID).Scan(&rate)
+		if err != nil {
+			return err
+		}
+
+		amount := 0.0
+		if eod < 0 {
+			amount = ledger.Round(-eod * rate / 100 / days)
+		}
+
+		res, err := tx.Exec(
+			`INSERT IGNORE INTO overdraft_interest (user_id, accrual_date, eod_balance, annual_rate, amount)
+			VALUES (?, ?, ?, ?, ?)`,
+			userID, day.Format(dateLayout), ledger.Round(eod), rate, amount,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		if ledger.Cents(amount) <= 0 {
+			return nil
+		}
+
+		// Проценты списываются даже сверх лимита: тогда овердрафт станет breached
+		txID, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindOverdraftInterest,
+			FromAccount: ledger.UserAccount(userID),
+			ToAccount:   ledger.BankInterestIncome,
+			Amount:      amount,
+			Memo:        fmt.Sprintf("Проценты по овердрафту за %s", day.Format(dateLayout)),
+			Force:       true,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE overdraft_interest SET transaction_id = ? WHERE user_id = ? AND accrual_date = ?",
+			txID, userID, day.Format(dateLayout),
+		)
+		return err
+	})
+}
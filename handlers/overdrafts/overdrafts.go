@@ -0,0 +1,268 @@
+// Package overdrafts управляет разрешённым овердрафтом: лимитом,
+// статусом и ежедневными процентами на отрицательный остаток.
+package overdrafts
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/notify"
+	"backend_golang/types"
+)
+
+// Статусы овердрафта
+const (
+	StatusWithin   = "within"
+	StatusIn       = "in_overdraft"
+	StatusBreached = "breached"
+)
+
+// События уведомлений
+const (
+	EventEntered  = "overdraft_entered"
+	EventLeft     = "overdraft_left"
+	EventBreached = "overdraft_breached"
+)
+
+const dateLayout = "2006-01-02"
+
+type overdraft struct {
+	UserID     int64   `json:"user_id"`
+	Limit      float64 `json:"limit"`
+	AnnualRate float64 `json:"annual_rate"`
+	Status     string  `json:"status"`
+	Balance    float64 `json:"balance"`
+	Used       float64 `json:"used"`
+	UpdatedAt  string  `json:"updated_at"`
+}
+
+// statusFor определяет статус овердрафта по остатку и лимиту
+func statusFor(balance, limit float64) string {
+	switch {
+	case ledger.Cents(balance) >= 0:
+		return StatusWithin
+	case ledger.Cents(balance)+ledger.Cents(limit) >= 0:
+		return StatusIn
+	default:
+		return StatusBreached
+	}
+}
+
+// eventFor возвращает событие для перехода между статусами
+func eventFor(from, to string) string {
+	switch {
+	case to == StatusBreached:
+		return EventBreached
+	case to == StatusWithin:
+		return EventLeft
+	case from == StatusWithin:
+		return EventEntered
+	}
+	return ""
+}
+
+// setStatus сохраняет новый статус и ставит уведомление о переходе
+func setStatus(tx *sql.Tx, userID int64, from, to string, balance, limit float64) error {
+	if from == to {
+		return nil
+	}
+
+	if _, err := tx.Exec("UPDATE overdrafts SET status = ? WHERE user_id = ?", to, userID); err != nil {
+		return err
+	}
+
+	event := eventFor(from, to)
+	if event == "" {
+		return nil
+	}
+	return notify.Queue(tx, userID, event, map[string]interface{}{
+		"balance": ledger.Round(balance),
+		"limit":   ledger.Round(limit),
+	})
+}
+
+// BalanceHook пересчитывает статус овердрафта при изменении остатка пользователя.
+// Регистрируется через ledger.OnBalanceChange.
+func BalanceHook(tx *sql.Tx, account string, before, after float64) error {
+	kind, userID, err := ledger.ParseAccount(account)
+	if err != nil || kind != "user" {
+		return err
+	}
+
+	var limit float64
+	var status string
+	err = tx.QueryRow(
+		"SELECT limit_amount, status FROM overdrafts WHERE user_id = ? FOR UPDATE", userID,
+	).Scan(&limit, &status)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return setStatus(tx, userID, status, statusFor(after, limit), after, limit)
+}
+
+func load(q ledger.Querier, userID int64) (overdraft, error) {
+	var o overdraft
+	var updatedAt time.Time
+	err := q.QueryRow(
+		`SELECT o.user_id, o.limit_amount, o.annual_rate, o.status, o.updated_at, u.balance
+		FROM overdrafts o JOIN users u ON u.id = o.user_id WHERE o.user_id = ?`,
+		userID,
+	).Scan(&o.UserID, &o.Limit, &o.AnnualRate, &o.Status, &updatedAt, &o.Balance)
+	if err != nil {
+		return o, err
+	}
+
+	o.UpdatedAt = updatedAt.Format(types.TimeLayout)
+	if o.Balance < 0 {
+		o.Used = ledger.Round(-o.Balance)
+	}
+	return o, nil
+}
+
+// Get возвращает овердрафт текущего пользователя
+func Get(c *gin.Context) {
+	o, err := load(database.DB, auth.CurrentAccountID(c))
+	if err == sql.ErrNoRows {
+		c.Error(apierr.New(http.StatusNotFound, "OVERDRAFT_NOT_FOUND"))
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Овердрафт",
+		Data:    o,
+	})
+}
+
+// Interest возвращает начисленные проценты по овердрафту текущего пользователя
+func Interest(c *gin.Context) {
+	rows, err := database.DB.Query(
+		`SELECT accrual_date, eod_balance, annual_rate, amount, transaction_id
+		FROM overdraft_interest WHERE user_id = ? ORDER BY accrual_date DESC LIMIT 366`,
+		auth.CurrentAccountID(c),
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	type charge struct {
+		Date          string  `json:"date"`
+		EODBalance    float64 `json:"eod_balance"`
+		AnnualRate    float64 `json:"annual_rate"`
+		Amount        float64 `json:"amount"`
+		TransactionID int64   `json:"transaction_id,omitempty"`
+	}
+
+	charges := make([]charge, 0)
+	for rows.Next() {
+		var ch charge
+		var date time.Time
+		var txID sql.NullInt64
+		if err := rows.Scan(&date, &ch.EODBalance, &ch.AnnualRate, &ch.Amount, &txID); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		ch.Date = date.Format(dateLayout)
+		ch.TransactionID = txID.Int64
+		charges = append(charges, ch)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Проценты по овердрафту",
+		Data:    charges,
+	})
+}
+
+// Set подключает овердрафт или меняет его лимит и ставку.
+// Нулевой лимит фактически отключает овердрафт для новых списаний.
+func Set(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "user_id"}))
+		return
+	}
+
+	var req struct {
+		Limit      float64  `json:"limit" form:"limit"`
+		AnnualRate *float64 `json:"annual_rate" form:"annual_rate"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	rate := types.OverdraftAnnualRate
+	if req.AnnualRate != nil {
+		rate = *req.AnnualRate
+	}
+
+	if req.Limit < 0 || req.Limit > types.OverdraftMaxLimit || rate < 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_OVERDRAFT", i18n.Args{"max": strconv.FormatFloat(types.OverdraftMaxLimit, 'f', 2, 64)}))
+		return
+	}
+
+	var result overdraft
+	err = database.WithTx(func(tx *sql.Tx) error {
+		var balance float64
+		err := tx.QueryRow("SELECT balance FROM users WHERE id = ? FOR UPDATE", userID).Scan(&balance)
+		if err != nil {
+			return err
+		}
+
+		status := StatusWithin
+		err = tx.QueryRow("SELECT status FROM overdrafts WHERE user_id = ? FOR UPDATE", userID).Scan(&status)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO overdrafts (user_id, limit_amount, annual_rate, status) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE limit_amount = VALUES(limit_amount), annual_rate = VALUES(annual_rate)`,
+			userID, ledger.Round(req.Limit), rate, status,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := setStatus(tx, userID, status, statusFor(balance, req.Limit), balance, req.Limit); err != nil {
+			return err
+		}
+
+		result, err = load(tx, userID)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
+		return
+	}
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "OVERDRAFT_UPDATE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Овердрафт обновлён",
+		Data:    result,
+	})
+}
@@ -0,0 +1,495 @@
+// Package payments отвечает за запросы денег
+// и ссылки на оплату.
+package payments
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/fraud"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/transfers"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/methods"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+// Статусы запроса денег
+const (
+	StatusPending   = "pending"
+	StatusPaid      = "paid"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+	StatusExpired   = "expired"
+	// StatusReview оплата на проверке комплаенса или антифрода, сумма заблокирована
+	StatusReview = "review"
+)
+
+var (
+	errNotPending = apierr.New(http.StatusConflict, "PAYMENT_REQUEST_NOT_PENDING")
+	errExpired    = apierr.New(http.StatusGone, "PAYMENT_REQUEST_EXPIRED")
+	errOwnRequest = apierr.New(http.StatusBadRequest, "SELF_TRANSFER.payment_request")
+)
+
+type paymentRequest struct {
+	ID          int64   `json:"id"`
+	RequesterID int64   `json:"requester_id"`
+	PayerPhone  string  `json:"payer_phone,omitempty"`
+	Token       string  `json:"token,omitempty"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Memo        string  `json:"memo,omitempty"`
+	Status      string  `json:"status"`
+	ExpiresAt   string  `json:"expires_at"`
+	PaidBy      int64   `json:"paid_by,omitempty"`
+	PaidTxID    int64   `json:"paid_tx_id,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+	Link        string  `json:"link,omitempty"`
+	QRPayload   string  `json:"qr_payload,omitempty"`
+}
+
+const selectRequest = `SELECT id, requester_id, payer_phone, token, amount, currency, memo, status,
+	expires_at, paid_by, paid_tx_id, created_at FROM payment_requests`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRequest(row scanner) (paymentRequest, error) {
+	var r paymentRequest
+	var payerPhone sql.NullString
+	var paidBy, paidTxID sql.NullInt64
+	var expiresAt, createdAt time.Time
+
+	err := row.Scan(&r.ID, &r.RequesterID, &payerPhone, &r.Token, &r.Amount, &r.Currency, &r.Memo,
+		&r.Status, &expiresAt, &paidBy, &paidTxID, &createdAt)
+	if err != nil {
+		return r, err
+	}
+
+	r.PayerPhone = payerPhone.String
+	r.PaidBy = paidBy.Int64
+	r.PaidTxID = paidTxID.Int64
+	r.ExpiresAt = expiresAt.Format(types.TimeLayout)
+	r.CreatedAt = createdAt.Format(types.TimeLayout)
+	if r.Status == StatusPending && time.Now().After(expiresAt) {
+		r.Status = StatusExpired
+	}
+	return r, nil
+}
+
+// withLink добавляет ссылку и содержимое QR-кода для открытого запроса
+func withLink(r paymentRequest) paymentRequest {
+	r.Link = types.PublicBaseURL + "/payment-requests/link/" + r.Token
+
+	query := url.Values{}
+	query.Set("token", r.Token)
+	query.Set("amount", strconv.FormatFloat(r.Amount, 'f', 2, 64))
+	query.Set("currency", r.Currency)
+	if r.Memo != "" {
+		query.Set("memo", r.Memo)
+	}
+	r.QRPayload = "simplebank://pay?" + query.Encode()
+	return r
+}
+
+func Create(c *gin.Context) {
+	var req struct {
+		PhoneNumber    string  `json:"phone_number" form:"phone_number" binding:"omitempty,phone"`
+		Amount         float64 `json:"amount" form:"amount"`
+		Currency       string  `json:"currency" form:"currency"`
+		Memo           string  `json:"memo" form:"memo"`
+		ExpiresInHours int     `json:"expires_in_hours" form:"expires_in_hours"`
+	}
+
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if ledger.Cents(req.Amount) <= 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_AMOUNT.positive"))
+		return
+	}
+
+	if req.Currency == "" {
+		req.Currency = types.DefaultCurrency
+	}
+	if req.Currency != types.DefaultCurrency {
+		c.Error(apierr.New(http.StatusBadRequest, "UNSUPPORTED_CURRENCY"))
+		return
+	}
+
+	ttl := types.PaymentRequestTTL
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	var payerPhone interface{}
+	if req.PhoneNumber != "" {
+		payerPhone = req.PhoneNumber
+	}
+
+	token := methods.GenerateToken(16)
+	result, err := database.DB.Exec(
+		`INSERT INTO payment_requests (requester_id, payer_phone, token, amount, currency, memo, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		auth.CurrentUserID(c), payerPhone, token, ledger.Round(req.Amount), req.Currency, req.Memo,
+		time.Now().Add(ttl),
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "PAYMENT_REQUEST_CREATE_ERROR"))
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "ID_ERROR"))
+		return
+	}
+
+	r, err := scanRequest(database.DB.QueryRow(selectRequest+" WHERE id = ?", id))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Запрос денег создан",
+		Data:    withLink(r),
+	})
+}
+
+// List возвращает запросы денег пользователя.
+// direction=incoming — адресованные ему, direction=outgoing — созданные им.
+func List(c *gin.Context) {
+	userID := auth.CurrentUserID(c)
+
+	var phone string
+	err := database.DB.QueryRow("SELECT phone_number FROM users WHERE id = ?", userID).Scan(&phone)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	query := selectRequest
+	var args []interface{}
+	switch c.Query("direction") {
+	case "incoming":
+		query += " WHERE payer_phone = ?"
+		args = append(args, phone)
+	case "outgoing":
+		query += " WHERE requester_id = ?"
+		args = append(args, userID)
+	default:
+		query += " WHERE (payer_phone = ? OR requester_id = ?)"
+		args = append(args, phone, userID)
+	}
+	// Просроченный запрос хранится как pending, пока его не тронут,
+	// поэтому фильтр сверяет и срок, как scanRequest
+	switch status := c.Query("status"); status {
+	case "":
+	case StatusPending:
+		query += " AND status = ? AND expires_at > NOW()"
+		args = append(args, StatusPending)
+	case StatusExpired:
+		query += " AND (status = ? OR (status = ? AND expires_at <= NOW()))"
+		args = append(args, StatusExpired, StatusPending)
+	default:
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	requests := make([]paymentRequest, 0)
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		if r.RequesterID == userID {
+			r = withLink(r)
+		} else {
+			r.Token = ""
+		}
+		requests = append(requests, r)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запросы денег",
+		Data:    requests,
+	})
+}
+
+// GetByToken показывает запрос по ссылке перед оплатой
+func GetByToken(c *gin.Context) {
+	r, err := scanRequest(database.DB.QueryRow(selectRequest+" WHERE token = ?", c.Param("token")))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запрос денег найден",
+		Data:    withLink(r),
+	})
+}
+
+func Pay(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+	pay(c, "id = ?", id)
+}
+
+func PayByToken(c *gin.Context) {
+	pay(c, "token = ?", c.Param("token"))
+}
+
+// pay оплачивает запрос. Оплата проходит те же проверки, что и перевод:
+// санкционные списки и антифрод, и может уйти в их очередь. Строка запроса
+// блокируется до конца транзакции, поэтому параллельные попытки оплаты
+// проходят по очереди и деньги списываются только один раз.
+func pay(c *gin.Context, where string, arg interface{}) {
+	var req struct {
+		// Password подтверждает оплату, если антифрод запросил step-up
+		Password string `json:"password" form:"password"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	r, err := scanRequest(database.DB.QueryRow(selectRequest+" WHERE "+where, arg))
+	if err == nil {
+		err = payable(database.DB, r, userID)
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	memo := "Оплата запроса #" + strconv.FormatInt(r.ID, 10)
+	if r.Memo != "" {
+		memo += ": " + r.Memo
+	}
+	t := transfers.Transfer{
+		UserID:           userID,
+		AccountID:        userID,
+		RecipientID:      r.RequesterID,
+		Amount:           r.Amount,
+		Memo:             memo,
+		PaymentRequestID: r.ID,
+		Password:         req.Password,
+	}
+	check, err := transfers.Checks(c, t)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var paid paymentRequest
+	var reviewID int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		r, err := scanRequest(tx.QueryRow(selectRequest+" WHERE id = ? FOR UPDATE", r.ID))
+		if err != nil {
+			return err
+		}
+		if err := payable(tx, r, userID); err != nil {
+			return err
+		}
+
+		if err := auth.CheckSpending(tx, userID, userID, r.Amount); err != nil {
+			return err
+		}
+
+		if reviewID, err = check.Queue(tx, t); err != nil {
+			return err
+		}
+		if reviewID != 0 {
+			_, err = tx.Exec(
+				"UPDATE payment_requests SET status = ?, paid_by = ? WHERE id = ? AND status = ?",
+				StatusReview, userID, r.ID, StatusPending,
+			)
+			return err
+		}
+
+		txID, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindTransfer,
+			FromAccount: ledger.UserAccount(userID),
+			ToAccount:   ledger.UserAccount(r.RequesterID),
+			Amount:      r.Amount,
+			Currency:    r.Currency,
+			Memo:        memo,
+			CreatedBy:   userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE payment_requests SET status = ?, paid_by = ?, paid_tx_id = ? WHERE id = ? AND status = ?",
+			StatusPaid, userID, txID, r.ID, StatusPending,
+		)
+		if err != nil {
+			return err
+		}
+
+		r.Status = StatusPaid
+		r.PaidBy = userID
+		r.PaidTxID = txID
+		r.Token = ""
+		paid = r
+		return nil
+	})
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if reviewID != 0 {
+		check.RespondQueued(c, t, reviewID)
+		return
+	}
+
+	if err := fraud.RememberDevice(database.DB, userID, check.Event.DeviceID); err != nil {
+		log.Printf("❌ Remember device for user %d failed: %v", userID, err)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запрос денег оплачен",
+		Data:    paid,
+	})
+}
+
+// payable проверяет, что userID может оплатить запрос r
+func payable(q ledger.Querier, r paymentRequest, userID int64) error {
+	if r.RequesterID == userID {
+		return errOwnRequest
+	}
+	if r.PayerPhone != "" {
+		var phone string
+		if err := q.QueryRow("SELECT phone_number FROM users WHERE id = ?", userID).Scan(&phone); err != nil {
+			return err
+		}
+		if phone != r.PayerPhone {
+			return sql.ErrNoRows
+		}
+	}
+	if r.Status == StatusExpired {
+		return errExpired
+	}
+	if r.Status != StatusPending {
+		return errNotPending
+	}
+	return nil
+}
+
+// Settle отмечает оплаченным запрос, оплата которого прошла проверку.
+// Вызывается в транзакции проведения перевода из очереди.
+func Settle(tx *sql.Tx, id, txID int64) error {
+	_, err := tx.Exec(
+		"UPDATE payment_requests SET status = ?, paid_tx_id = ? WHERE id = ? AND status = ?",
+		StatusPaid, txID, id, StatusReview,
+	)
+	return err
+}
+
+// Reopen возвращает к оплате запрос, оплату которого отклонили на проверке
+func Reopen(tx *sql.Tx, id int64) error {
+	_, err := tx.Exec(
+		"UPDATE payment_requests SET status = ?, paid_by = NULL WHERE id = ? AND status = ?",
+		StatusPending, id, StatusReview,
+	)
+	return err
+}
+
+// Decline отклоняет адресованный пользователю запрос
+func Decline(c *gin.Context) {
+	resolve(c, StatusDeclined)
+}
+
+// Cancel отменяет запрос его автором
+func Cancel(c *gin.Context) {
+	resolve(c, StatusCancelled)
+}
+
+func resolve(c *gin.Context, status string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+
+	err = database.WithTx(func(tx *sql.Tx) error {
+		r, err := scanRequest(tx.QueryRow(selectRequest+" WHERE id = ? FOR UPDATE", id))
+		if err != nil {
+			return err
+		}
+
+		if status == StatusCancelled && r.RequesterID != userID {
+			return sql.ErrNoRows
+		}
+		if status == StatusDeclined {
+			var phone string
+			if err := tx.QueryRow("SELECT phone_number FROM users WHERE id = ?", userID).Scan(&phone); err != nil {
+				return err
+			}
+			if r.PayerPhone == "" || r.PayerPhone != phone {
+				return sql.ErrNoRows
+			}
+		}
+		if r.Status != StatusPending {
+			return errNotPending
+		}
+
+		_, err = tx.Exec("UPDATE payment_requests SET status = ? WHERE id = ?", status, r.ID)
+		return err
+	})
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запрос денег обновлён",
+		Data: map[string]interface{}{
+			"id":     id,
+			"status": status,
+		},
+	})
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — запрос не найден
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "PAYMENT_REQUEST_NOT_FOUND"))
+}
@@ -0,0 +1,279 @@
+package payouts
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Pain001NamespacePrefix общий префикс версий pain.001
+const Pain001NamespacePrefix = "urn:iso:std:iso:20022:tech:xsd:pain.001.001."
+
+var (
+	isoNumber = regexp.MustCompile(`^[0-9]{1,15}$`)
+	isoAmount = regexp.MustCompile(`^[0-9]{1,18}(\.[0-9]{1,5})?$`)
+)
+
+type painAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+// id возвращает идентификатор счёта; IBAN у нас не ведётся
+func (a *painAccount) id() string {
+	if a == nil {
+		return ""
+	}
+	if a.Other != "" {
+		return strings.TrimSpace(a.Other)
+	}
+	return strings.TrimSpace(a.IBAN)
+}
+
+type painPresence struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+type painAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type painTransaction struct {
+	EndToEndID string      `xml:"PmtId>EndToEndId"`
+	Amount     *painAmount `xml:"Amt>InstdAmt"`
+	Creditor   *struct {
+		Name string `xml:"Nm"`
+	} `xml:"Cdtr"`
+	CreditorAccount *painAccount `xml:"CdtrAcct"`
+	Remittance      string       `xml:"RmtInf>Ustrd"`
+}
+
+type painPaymentInfo struct {
+	ID            string `xml:"PmtInfId"`
+	Method        string `xml:"PmtMtd"`
+	NumberOfTxs   string `xml:"NbOfTxs"`
+	ControlSum    string `xml:"CtrlSum"`
+	ExecutionDate struct {
+		Value string `xml:",chardata"`
+		Date  string `xml:"Dt"`
+	} `xml:"ReqdExctnDt"`
+	Debtor        *painPresence     `xml:"Dbtr"`
+	DebtorAccount *painAccount      `xml:"DbtrAcct"`
+	DebtorAgent   *painPresence     `xml:"DbtrAgt"`
+	Transactions  []painTransaction `xml:"CdtTrfTxInf"`
+}
+
+type pain001Document struct {
+	XMLName    xml.Name `xml:"Document"`
+	Initiation *struct {
+		Header struct {
+			MessageID      string        `xml:"MsgId"`
+			Created        string        `xml:"CreDtTm"`
+			NumberOfTxs    string        `xml:"NbOfTxs"`
+			ControlSum     string        `xml:"CtrlSum"`
+			InitiatingPart *painPresence `xml:"InitgPty"`
+		} `xml:"GrpHdr"`
+		Payments []painPaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrCdtTrfInitn"`
+}
+
+// ImportPain001 принимает pain.001 (Customer Credit Transfer Initiation) и создаёт
+// из его поручений пакет выплат. Дальше пакет проходит тот же путь, что и CSV:
+// подтверждение, фоновое исполнение и отчёт. Счёт плательщика (DbtrAcct/Id/Othr/Id)
+// должен совпадать с выбранным счётом, счёт получателя — user:ID или телефон.
+func ImportPain001(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, types.PayoutMaxFileSize)
+	content, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_FILE", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	var doc pain001Document
+	if err := xml.Unmarshal(content, &doc); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_XML", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	accountID := auth.CurrentAccountID(c)
+	if errs := validatePain001(doc, accountID); len(errs) > 0 {
+		e := apierr.New(http.StatusBadRequest, "INVALID_PAIN001")
+		e.Fields = errs
+		c.Error(e)
+		return
+	}
+
+	var rows []row
+	seen := make(map[string]bool)
+	for _, p := range doc.Initiation.Payments {
+		for _, t := range p.Transactions {
+			record := []string{t.CreditorAccount.id(), strings.TrimSpace(t.Amount.Value), strings.TrimSpace(t.Remittance)}
+			r := validate(len(rows)+1, record, accountID)
+			r.EndToEndID = strings.TrimSpace(t.EndToEndID)
+
+			switch {
+			case r.Status == RowInvalid:
+			case t.Amount.Currency != types.DefaultCurrency:
+				r.Status, r.Error = RowInvalid, "UNSUPPORTED_CURRENCY"
+			case r.EndToEndID != "NOTPROVIDED" && seen[r.EndToEndID]:
+				r.Status, r.Error = RowInvalid, "DUPLICATE_END_TO_END_ID"
+			}
+			seen[r.EndToEndID] = true
+			rows = append(rows, r)
+		}
+	}
+
+	if len(rows) > types.PayoutMaxRows {
+		respondError(c, errTooManyRows)
+		return
+	}
+
+	fileName := c.DefaultQuery("file_name", "pain.001.xml")
+	createBatch(c, fileName, strings.TrimSpace(doc.Initiation.Header.MessageID), rows)
+}
+
+// validatePain001 проверяет обязательные элементы, длины и контрольные суммы
+// по схеме pain.001.001.03 (совместимо с более новыми версиями)
+func validatePain001(doc pain001Document, accountID int64) []apierr.FieldError {
+	var errs []apierr.FieldError
+	add := func(path, format string, args ...interface{}) {
+		errs = append(errs, apierr.FieldError{Field: path, Code: "SCHEMA", Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !strings.HasPrefix(doc.XMLName.Space, Pain001NamespacePrefix) {
+		add("Document", "ожидается пространство имён %s*", Pain001NamespacePrefix)
+	}
+	if doc.Initiation == nil {
+		add("Document/CstmrCdtTrfInitn", "обязательный элемент отсутствует")
+		return errs
+	}
+
+	h := doc.Initiation.Header
+	const hdr = "Document/CstmrCdtTrfInitn/GrpHdr"
+	checkText(add, hdr+"/MsgId", h.MessageID, 35)
+	if !validDateTime(h.Created) {
+		add(hdr+"/CreDtTm", "ожидается ISODateTime")
+	}
+	if h.InitiatingPart == nil {
+		add(hdr+"/InitgPty", "обязательный элемент отсутствует")
+	}
+
+	var count int
+	var total int64
+	for i, p := range doc.Initiation.Payments {
+		path := fmt.Sprintf("Document/CstmrCdtTrfInitn/PmtInf[%d]", i+1)
+		checkText(add, path+"/PmtInfId", p.ID, 35)
+		if p.Method != "TRF" {
+			add(path+"/PmtMtd", "поддерживается только TRF")
+		}
+		if !validDate(p.ExecutionDate.Value) && !validDate(p.ExecutionDate.Date) {
+			add(path+"/ReqdExctnDt", "ожидается ISODate")
+		}
+		if p.Debtor == nil {
+			add(path+"/Dbtr", "обязательный элемент отсутствует")
+		}
+		if p.DebtorAgent == nil {
+			add(path+"/DbtrAgt", "обязательный элемент отсутствует")
+		}
+		if p.DebtorAccount.id() != ledger.UserAccount(accountID) {
+			add(path+"/DbtrAcct/Id/Othr/Id", "ожидается счёт %s", ledger.UserAccount(accountID))
+		}
+		if len(p.Transactions) == 0 {
+			add(path+"/CdtTrfTxInf", "нужно хотя бы одно поручение")
+		}
+
+		var paymentTotal int64
+		for j, t := range p.Transactions {
+			txPath := fmt.Sprintf("%s/CdtTrfTxInf[%d]", path, j+1)
+			checkText(add, txPath+"/PmtId/EndToEndId", t.EndToEndID, 35)
+			if t.Amount == nil || !isoAmount.MatchString(strings.TrimSpace(t.Amount.Value)) || t.Amount.Currency == "" {
+				add(txPath+"/Amt/InstdAmt", "ожидается сумма с атрибутом Ccy")
+			} else {
+				paymentTotal += parseCents(t.Amount.Value)
+			}
+			if t.CreditorAccount.id() == "" {
+				add(txPath+"/CdtrAcct", "обязательный элемент отсутствует")
+			}
+			if len(t.Remittance) > 140 {
+				add(txPath+"/RmtInf/Ustrd", "длина больше 140 символов")
+			}
+		}
+
+		checkTotals(add, path, p.NumberOfTxs, p.ControlSum, len(p.Transactions), paymentTotal, false)
+		count += len(p.Transactions)
+		total += paymentTotal
+	}
+	if len(doc.Initiation.Payments) == 0 {
+		add("Document/CstmrCdtTrfInitn/PmtInf", "нужен хотя бы один блок PmtInf")
+	}
+
+	checkTotals(add, hdr, h.NumberOfTxs, h.ControlSum, count, total, true)
+	return errs
+}
+
+// checkTotals сверяет NbOfTxs и CtrlSum с фактическими значениями
+func checkTotals(add func(string, string, ...interface{}), path, number, controlSum string, count int, total int64, required bool) {
+	number = strings.TrimSpace(number)
+	switch {
+	case number == "" && required:
+		add(path+"/NbOfTxs", "обязательный элемент отсутствует")
+	case number != "" && !isoNumber.MatchString(number):
+		add(path+"/NbOfTxs", "ожидается число")
+	case number != "" && number != fmt.Sprint(count):
+		add(path+"/NbOfTxs", "указано %s, в сообщении %d поручений", number, count)
+	}
+
+	controlSum = strings.TrimSpace(controlSum)
+	switch {
+	case controlSum == "":
+	case !isoAmount.MatchString(controlSum):
+		add(path+"/CtrlSum", "ожидается десятичное число")
+	case parseCents(controlSum) != total:
+		add(path+"/CtrlSum", "не совпадает с суммой поручений")
+	}
+}
+
+func checkText(add func(string, string, ...interface{}), path, value string, max int) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		add(path, "обязательный элемент отсутствует")
+	case len(value) > max:
+		add(path, "длина больше %d символов", max)
+	}
+}
+
+func parseCents(value string) int64 {
+	var amount float64
+	fmt.Sscan(strings.TrimSpace(value), &amount)
+	return ledger.Cents(amount)
+}
+
+func validDate(value string) bool {
+	_, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	return err == nil
+}
+
+// validDateTime принимает ISODateTime с зоной или без неё
+func validDateTime(value string) bool {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,583 @@
+// Package payouts отвечает за массовые выплаты из CSV-файла:
+// проверку строк, подтверждение, асинхронное исполнение и отчёт.
+package payouts
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+// Статусы пакета выплат
+const (
+	BatchValidated           = "validated"
+	BatchInvalid             = "invalid"
+	BatchApproved            = "approved"
+	BatchProcessing          = "processing"
+	BatchCompleted           = "completed"
+	BatchCompletedWithErrors = "completed_with_errors"
+	BatchCancelled           = "cancelled"
+)
+
+// Статусы строки пакета
+const (
+	RowValid   = "valid"
+	RowInvalid = "invalid"
+	RowPending = "pending"
+	RowPaid    = "paid"
+	RowFailed  = "failed"
+)
+
+var (
+	errBatchState  = apierr.New(http.StatusConflict, "INVALID_BATCH_STATE")
+	errEmptyFile   = apierr.New(http.StatusBadRequest, "EMPTY_FILE")
+	errTooManyRows = apierr.New(http.StatusBadRequest, "TOO_MANY_ROWS", i18n.Args{"max": types.PayoutMaxRows})
+
+	errDuplicateMessage = apierr.New(http.StatusConflict, "DUPLICATE_MESSAGE")
+)
+
+type batch struct {
+	ID          int64   `json:"id"`
+	UserID      int64   `json:"user_id"`
+	CreatedBy   int64   `json:"created_by"`
+	FileName    string  `json:"file_name"`
+	MessageID   string  `json:"message_id,omitempty"`
+	Status      string  `json:"status"`
+	TotalRows   int     `json:"total_rows"`
+	InvalidRows int     `json:"invalid_rows"`
+	TotalAmount float64 `json:"total_amount"`
+	ApprovedBy  int64   `json:"approved_by,omitempty"`
+	ApprovedAt  string  `json:"approved_at,omitempty"`
+	CompletedAt string  `json:"completed_at,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+
+	Counts map[string]int `json:"row_counts,omitempty"`
+}
+
+type row struct {
+	Line          int     `json:"line"`
+	Recipient     string  `json:"recipient"`
+	RecipientID   int64   `json:"recipient_user_id,omitempty"`
+	Amount        float64 `json:"amount"`
+	Memo          string  `json:"memo,omitempty"`
+	EndToEndID    string  `json:"end_to_end_id,omitempty"`
+	Status        string  `json:"status"`
+	Error         string  `json:"error,omitempty"`
+	TransactionID int64   `json:"transaction_id,omitempty"`
+}
+
+const selectBatch = `SELECT id, user_id, created_by, file_name, COALESCE(message_id, ''), status, total_rows, invalid_rows, total_amount,
+	approved_by, approved_at, completed_at, created_at FROM payout_batches`
+
+func scanBatch(r interface{ Scan(...interface{}) error }) (batch, error) {
+	var b batch
+	var approvedBy sql.NullInt64
+	var approvedAt, completedAt sql.NullTime
+	var createdAt time.Time
+	err := r.Scan(&b.ID, &b.UserID, &b.CreatedBy, &b.FileName, &b.MessageID, &b.Status, &b.TotalRows, &b.InvalidRows,
+		&b.TotalAmount, &approvedBy, &approvedAt, &completedAt, &createdAt)
+	if err != nil {
+		return b, err
+	}
+	b.ApprovedBy = approvedBy.Int64
+	if approvedAt.Valid {
+		b.ApprovedAt = approvedAt.Time.Format(types.TimeLayout)
+	}
+	if completedAt.Valid {
+		b.CompletedAt = completedAt.Time.Format(types.TimeLayout)
+	}
+	b.CreatedAt = createdAt.Format(types.TimeLayout)
+	return b, nil
+}
+
+// Upload принимает CSV со строками "получатель,сумма,назначение" и проверяет
+// каждую строку. Получатель — телефон или счёт вида user:ID.
+// Пакет с ошибками нельзя подтвердить: нужно исправить файл и загрузить заново.
+func Upload(c *gin.Context) {
+	content, fileName, err := readFile(c)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_FILE", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	rows, err := parse(content, auth.CurrentAccountID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	createBatch(c, fileName, "", rows)
+}
+
+// createBatch сохраняет проверенные строки пакетом и отвечает клиенту
+// списком ошибок. messageID — идентификатор сообщения клиента для защиты
+// от повторной загрузки того же файла.
+func createBatch(c *gin.Context, fileName, messageID string, rows []row) {
+	accountID := auth.CurrentAccountID(c)
+
+	var id int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		if messageID != "" {
+			var exists bool
+			err := tx.QueryRow(
+				"SELECT EXISTS(SELECT 1 FROM payout_batches WHERE user_id = ? AND message_id = ?)",
+				accountID, messageID,
+			).Scan(&exists)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return errDuplicateMessage
+			}
+		}
+
+		status := BatchValidated
+		invalid := 0
+		var total int64
+		for _, r := range rows {
+			if r.Status == RowInvalid {
+				invalid++
+				continue
+			}
+			total += ledger.Cents(r.Amount)
+		}
+		if invalid > 0 {
+			status = BatchInvalid
+		}
+
+		var message interface{}
+		if messageID != "" {
+			message = messageID
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO payout_batches (user_id, created_by, file_name, message_id, status, total_rows, invalid_rows, total_amount)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			accountID, auth.CurrentUserID(c), fileName, message, status, len(rows), invalid, float64(total)/100,
+		)
+		if err != nil {
+			return err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		for _, r := range rows {
+			var recipientID interface{}
+			if r.RecipientID != 0 {
+				recipientID = r.RecipientID
+			}
+			_, err := tx.Exec(
+				`INSERT INTO payout_rows (batch_id, line_number, recipient, recipient_user_id, amount, memo, end_to_end_id, status, error)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, r.Line, r.Recipient, recipientID, ledger.Round(r.Amount), r.Memo, r.EndToEndID, r.Status, r.Error,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	b, err := scanBatch(database.DB.QueryRow(selectBatch+" WHERE id = ?", id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	invalid := make([]row, 0)
+	for _, r := range rows {
+		if r.Status == RowInvalid {
+			invalid = append(invalid, r)
+		}
+	}
+
+	message := "Файл проверен, пакет ожидает подтверждения"
+	if b.Status == BatchInvalid {
+		message = "В файле есть ошибки, пакет не может быть исполнен"
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: message,
+		Data: map[string]interface{}{
+			"batch":  b,
+			"errors": invalid,
+		},
+	})
+}
+
+// readFile читает файл из multipart-поля file или тело запроса целиком
+func readFile(c *gin.Context) ([]byte, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, types.PayoutMaxFileSize)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		f, err := header.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+
+		content, err := io.ReadAll(f)
+		return content, header.Filename, err
+	}
+
+	content, err := io.ReadAll(c.Request.Body)
+	return content, c.Query("file_name"), err
+}
+
+// parse разбирает CSV и проверяет строки. Первая строка пропускается,
+// если это заголовок. Номера строк соответствуют строкам файла.
+func parse(content []byte, accountID int64) ([]row, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, row{Line: parseErr.Line, Status: RowInvalid, Error: "MALFORMED_CSV"})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(rows) == 0 && isHeader(record) {
+			continue
+		}
+		if len(rows) >= types.PayoutMaxRows {
+			return nil, errTooManyRows
+		}
+
+		rows = append(rows, validate(line, record, accountID))
+	}
+
+	if len(rows) == 0 {
+		return nil, errEmptyFile
+	}
+	return rows, nil
+}
+
+func isHeader(record []string) bool {
+	if len(record) < 2 {
+		return false
+	}
+	_, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+	return err != nil && strings.EqualFold(strings.TrimSpace(record[1]), "amount")
+}
+
+// validate проверяет одну строку файла
+func validate(line int, record []string, accountID int64) row {
+	r := row{Line: line, Status: RowValid}
+	if len(record) < 2 || len(record) > 3 {
+		r.Status, r.Error = RowInvalid, "INVALID_COLUMNS"
+		return r
+	}
+
+	r.Recipient = strings.TrimSpace(record[0])
+	if len(record) == 3 {
+		r.Memo = strings.TrimSpace(record[2])
+	}
+
+	amount, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+	switch {
+	case err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || ledger.Cents(amount) <= 0:
+		r.Status, r.Error = RowInvalid, "INVALID_AMOUNT"
+		return r
+	case math.Abs(amount*100-math.Round(amount*100)) > 1e-6:
+		r.Status, r.Error = RowInvalid, "TOO_MANY_DECIMALS"
+		return r
+	}
+	r.Amount = ledger.Round(amount)
+
+	if len(r.Memo) > 255 {
+		r.Status, r.Error = RowInvalid, "MEMO_TOO_LONG"
+		return r
+	}
+
+	r.RecipientID, err = resolveRecipient(r.Recipient)
+	switch {
+	case err == sql.ErrNoRows:
+		r.Status, r.Error = RowInvalid, "RECIPIENT_NOT_FOUND"
+	case err != nil:
+		r.Status, r.Error = RowInvalid, "RECIPIENT_LOOKUP_FAILED"
+	case r.RecipientID == accountID:
+		r.Status, r.Error = RowInvalid, "SELF_PAYOUT"
+	}
+	return r
+}
+
+// resolveRecipient находит получателя по счёту user:ID или телефону
+func resolveRecipient(recipient string) (int64, error) {
+	var id int64
+	if kind, accountID, err := ledger.ParseAccount(recipient); err == nil && kind == "user" {
+		err := database.DB.QueryRow("SELECT id FROM users WHERE id = ?", accountID).Scan(&id)
+		return id, err
+	}
+	phone, ok := validation.NormalizePhone(recipient)
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+
+	err := database.DB.QueryRow("SELECT id FROM users WHERE phone_number = ?", phone).Scan(&id)
+	return id, err
+}
+
+func List(c *gin.Context) {
+	rows, err := database.DB.Query(selectBatch+" WHERE user_id = ? ORDER BY id DESC", auth.CurrentAccountID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer rows.Close()
+
+	batches := make([]batch, 0)
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		batches = append(batches, b)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Пакеты выплат",
+		Data:    batches,
+	})
+}
+
+// GetByID возвращает пакет со строками; параметр status фильтрует строки
+func GetByID(c *gin.Context) {
+	b, ok := ownBatch(c)
+	if !ok {
+		return
+	}
+
+	rows, err := loadRows(b.ID, c.Query("status"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	b.Counts, err = countRows(database.DB, b.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Пакет выплат",
+		Data: map[string]interface{}{
+			"batch": b,
+			"rows":  rows,
+		},
+	})
+}
+
+func loadRows(batchID int64, status string) ([]row, error) {
+	query := `SELECT line_number, recipient, recipient_user_id, amount, memo, end_to_end_id, status, error, transaction_id
+		FROM payout_rows WHERE batch_id = ?`
+	args := []interface{}{batchID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	rows, err := database.DB.Query(query+" ORDER BY line_number", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]row, 0)
+	for rows.Next() {
+		var r row
+		var recipientID, txID sql.NullInt64
+		err := rows.Scan(&r.Line, &r.Recipient, &recipientID, &r.Amount, &r.Memo, &r.EndToEndID, &r.Status, &r.Error, &txID)
+		if err != nil {
+			return nil, err
+		}
+		r.RecipientID = recipientID.Int64
+		r.TransactionID = txID.Int64
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func countRows(q ledger.Querier, batchID int64) (map[string]int, error) {
+	rows, err := q.Query("SELECT status, COUNT(*) FROM payout_rows WHERE batch_id = ? GROUP BY status", batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// Approve подтверждает проверенный пакет; исполнение идёт в фоне
+func Approve(c *gin.Context) {
+	b, ok := ownBatch(c)
+	if !ok {
+		return
+	}
+
+	err := database.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(
+			`UPDATE payout_batches SET status = ?, approved_by = ?, approved_at = NOW()
+			WHERE id = ? AND status = ?`,
+			BatchApproved, auth.CurrentUserID(c), b.ID, BatchValidated,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return errBatchState
+		}
+
+		_, err = tx.Exec("UPDATE payout_rows SET status = ? WHERE batch_id = ? AND status = ?", RowPending, b.ID, RowValid)
+		return err
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	b, err = scanBatch(database.DB.QueryRow(selectBatch+" WHERE id = ?", b.ID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, types.Response{
+		Success: true,
+		Message: "Пакет подтверждён и поставлен в очередь на исполнение",
+		Data:    b,
+	})
+}
+
+// Cancel отменяет пакет, который ещё не подтверждён
+func Cancel(c *gin.Context) {
+	b, ok := ownBatch(c)
+	if !ok {
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE payout_batches SET status = ? WHERE id = ? AND status IN (?, ?)",
+		BatchCancelled, b.ID, BatchValidated, BatchInvalid,
+	)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		respondError(c, errBatchState)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Пакет отменён",
+	})
+}
+
+// Report отдаёт CSV-отчёт по строкам пакета
+func Report(c *gin.Context) {
+	b, ok := ownBatch(c)
+	if !ok {
+		return
+	}
+
+	rows, err := loadRows(b.ID, "")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"line", "recipient", "amount", "memo", "end_to_end_id", "status", "error", "transaction_id"})
+	for _, r := range rows {
+		txID := ""
+		if r.TransactionID != 0 {
+			txID = strconv.FormatInt(r.TransactionID, 10)
+		}
+		w.Write([]string{
+			strconv.Itoa(r.Line), r.Recipient, strconv.FormatFloat(r.Amount, 'f', 2, 64),
+			r.Memo, r.EndToEndID, r.Status, r.Error, txID,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=payout-"+strconv.FormatInt(b.ID, 10)+"-report.csv")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ownBatch загружает пакет из параметра :id выбранного счёта
+func ownBatch(c *gin.Context) (batch, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return batch{}, false
+	}
+
+	b, err := scanBatch(database.DB.QueryRow(selectBatch+" WHERE id = ? AND user_id = ?", id, auth.CurrentAccountID(c)))
+	if err != nil {
+		respondError(c, err)
+		return batch{}, false
+	}
+	return b, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — пакет не найден
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "PAYOUT_BATCH_NOT_FOUND"))
+}
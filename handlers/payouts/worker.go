@@ -0,0 +1,252 @@
+package payouts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"backend_golang/database"
+	"backend_golang/fraud"
+	"backend_golang/handlers/auth"
+	"backend_golang/kyc"
+	"backend_golang/ledger"
+	"backend_golang/sanctions"
+)
+
+// errRecipientNotFound получатель удалён после подтверждения пакета
+var errRecipientNotFound = errors.New("payouts: recipient not found")
+
+// rowErrors ошибки, после которых строка помечается неуспешной.
+// Остальные ошибки (например, потеря соединения с БД) оставляют строку
+// в pending, и следующий запуск задачи повторит её.
+var rowErrors = map[error]string{
+	ledger.ErrInsufficientFunds: "INSUFFICIENT_FUNDS",
+	ledger.ErrAccountNotFound:   "ACCOUNT_NOT_FOUND",
+	ledger.ErrInvalidAmount:     "INVALID_AMOUNT",
+	auth.ErrSpendingLimit:       "SPENDING_LIMIT_EXCEEDED",
+	kyc.ErrLimitExceeded:        "KYC_LIMIT_EXCEEDED",
+	errRecipientNotFound:        "RECIPIENT_NOT_FOUND",
+}
+
+// Job исполняет подтверждённые пакеты. Каждая строка проводится в своей
+// транзакции вместе со сменой статуса, поэтому после сбоя исполнение
+// продолжается с первой непроведённой строки без повторных выплат.
+// Сбой одного пакета не задерживает следующие: он повторится при следующем запуске.
+func Job() error {
+	rows, err := database.DB.Query(
+		"SELECT id FROM payout_batches WHERE status IN (?, ?) ORDER BY id",
+		BatchApproved, BatchProcessing,
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := process(id); err != nil {
+			log.Printf("❌ Payout batch %d failed: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func process(batchID int64) error {
+	_, err := database.DB.Exec(
+		"UPDATE payout_batches SET status = ? WHERE id = ? AND status = ?",
+		BatchProcessing, batchID, BatchApproved,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := database.DB.Query(
+		"SELECT id FROM payout_rows WHERE batch_id = ? AND status = ? ORDER BY line_number",
+		batchID, RowPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := payRow(id); err != nil {
+			return err
+		}
+	}
+
+	return database.WithTx(func(tx *sql.Tx) error {
+		counts, err := countRows(tx, batchID)
+		if err != nil {
+			return err
+		}
+		if counts[RowPending] > 0 {
+			return nil
+		}
+
+		status := BatchCompleted
+		if counts[RowFailed] > 0 {
+			status = BatchCompletedWithErrors
+		}
+		_, err = tx.Exec(
+			"UPDATE payout_batches SET status = ?, completed_at = NOW() WHERE id = ? AND status = ?",
+			status, batchID, BatchProcessing,
+		)
+		return err
+	})
+}
+
+// payRow проводит одну выплату; ошибка проведения фиксируется в строке
+func payRow(rowID int64) error {
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var batchID, userID, createdBy, recipientID int64
+		var line int
+		var amount float64
+		var memo, status string
+
+		err := tx.QueryRow(
+			`SELECT r.batch_id, b.user_id, b.created_by, r.recipient_user_id, r.line_number, r.amount, r.memo, r.status
+			FROM payout_rows r JOIN payout_batches b ON b.id = r.batch_id
+			WHERE r.id = ? FOR UPDATE`,
+			rowID,
+		).Scan(&batchID, &userID, &createdBy, &recipientID, &line, &amount, &memo, &status)
+		if err != nil {
+			return err
+		}
+		if status != RowPending {
+			return nil
+		}
+
+		// Строку с совпадением по санкционным спискам не проводим, а передаём
+		// комплаенсу; после снятия совпадения выплату можно загрузить снова
+		screening, err := sanctions.ScreenUser(tx, recipientID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errRecipientNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if screening.Status == sanctions.StatusBlocked || screening.Hit() {
+			code := "RECIPIENT_BLOCKED"
+			if screening.Status != sanctions.StatusBlocked {
+				code = "SANCTIONS_HIT"
+				_, err := sanctions.Queue(tx, sanctions.SubjectPayout, createdBy, screening, map[string]interface{}{
+					"batch_id":    batchID,
+					"row_id":      rowID,
+					"line_number": line,
+					"amount":      amount,
+				}, 0)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec(
+				"UPDATE payout_rows SET status = ?, error = ? WHERE id = ?",
+				RowFailed, code, rowID,
+			)
+			return err
+		}
+
+		// Антифрод оценивает строку как перевод. Заблокированную или подозрительную
+		// выплату не проводим, подозрительную передаём аналитику. Пароль в пакете
+		// не спросить, поэтому step_up пропускается: пакет уже подтверждён.
+		event := fraud.Event{
+			Type:      fraud.EventTransfer,
+			UserID:    userID,
+			ActorID:   createdBy,
+			Amount:    amount,
+			Recipient: ledger.UserAccount(recipientID),
+		}
+		decision, err := fraud.Evaluate(tx, event)
+		if err != nil {
+			return err
+		}
+		if decision.Action == fraud.ActionBlock || decision.Action == fraud.ActionReview {
+			code := "FRAUD_BLOCKED"
+			if decision.Action == fraud.ActionReview {
+				code = "FRAUD_REVIEW"
+				_, err := fraud.QueuePayout(tx, event, decision, map[string]interface{}{
+					"batch_id":    batchID,
+					"row_id":      rowID,
+					"line_number": line,
+					"amount":      amount,
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.Exec(
+				"UPDATE payout_rows SET status = ?, error = ? WHERE id = ?",
+				RowFailed, code, rowID,
+			)
+			return err
+		}
+
+		if err := auth.CheckSpending(tx, createdBy, userID, amount); err != nil {
+			return err
+		}
+
+		if memo == "" {
+			memo = fmt.Sprintf("Выплата по пакету #%d, строка %d", batchID, line)
+		}
+
+		// reference_id у транзакций указывает на другую транзакцию (сторно),
+		// поэтому пакет с выплатой связывает payout_rows.transaction_id
+		txID, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindTransfer,
+			FromAccount: ledger.UserAccount(userID),
+			ToAccount:   ledger.UserAccount(recipientID),
+			Amount:      amount,
+			Memo:        memo,
+			CreatedBy:   createdBy,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"UPDATE payout_rows SET status = ?, transaction_id = ? WHERE id = ?",
+			RowPaid, txID, rowID,
+		)
+		return err
+	})
+
+	for rowErr, code := range rowErrors {
+		if errors.Is(err, rowErr) {
+			_, err = database.DB.Exec(
+				"UPDATE payout_rows SET status = ?, error = ? WHERE id = ? AND status = ?",
+				RowFailed, code, rowID, RowPending,
+			)
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,216 @@
+package savings
+
+import (
+	"database/sql"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+const dateLayout = "2006-01-02"
+
+// dayStart возвращает полночь даты t в локальной зоне
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// rateOn возвращает годовую ставку продукта, действующую на дату date
+func rateOn(q ledger.Querier, product string, date time.Time) (float64, error) {
+	var rate float64
+	err := q.QueryRow(
+		`SELECT annual_rate FROM savings_rates WHERE product = ? AND effective_from <= ?
+		ORDER BY effective_from DESC LIMIT 1`,
+		product, date.Format(dateLayout),
+	).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return rate, err
+}
+
+// InterestJob начисляет проценты за закрытые дни и затем капитализирует
+// закрытые месяцы. Порядок важен: месяц капитализируется только после
+// начисления за его последний день.
+func InterestJob() error {
+	if err := AccrueJob(); err != nil {
+		return err
+	}
+	return CapitaliseJob()
+}
+
+// AccrueJob начисляет проценты за каждый закрытый день, за который
+// начисления ещё нет. Уже записанные начисления не пересчитываются,
+// поэтому смена ставки не меняет начисленное ранее.
+func AccrueJob() error {
+	rows, err := database.DB.Query(
+		`SELECT a.id, a.product, a.created_at, MAX(s.accrual_date)
+		FROM savings_accounts a LEFT JOIN savings_accruals s ON s.account_id = a.id
+		WHERE a.status = ? GROUP BY a.id, a.product, a.created_at`,
+		StatusOpen,
+	)
+	if err != nil {
+		return err
+	}
+
+	type pendingAccount struct {
+		id      int64
+		product string
+		from    time.Time
+	}
+
+	var accounts []pendingAccount
+	for rows.Next() {
+		var a pendingAccount
+		var createdAt time.Time
+		var lastAccrual sql.NullTime
+		if err := rows.Scan(&a.id, &a.product, &createdAt, &lastAccrual); err != nil {
+			rows.Close()
+			return err
+		}
+
+		a.from = dayStart(createdAt)
+		if lastAccrual.Valid {
+			a.from = dayStart(lastAccrual.Time).AddDate(0, 0, 1)
+		}
+		accounts = append(accounts, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	today := dayStart(time.Now())
+	for _, a := range accounts {
+		for day := a.from; day.Before(today); day = day.AddDate(0, 0, 1) {
+			if err := accrueDay(a.id, a.product, day); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// accrueDay записывает начисление по остатку на конец дня day
+func accrueDay(accountID int64, product string, day time.Time) error {
+	eod, err := ledger.BalanceAt(database.DB, ledger.SavingsAccount(accountID), day.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	rate, err := rateOn(database.DB, product, day)
+	if err != nil {
+		return err
+	}
+
+	dayCount := types.DayCountConvention
+	amount, err := dailyInterest(eod, rate, dayCount, day)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(
+		`INSERT IGNORE INTO savings_accruals (account_id, accrual_date, eod_balance, annual_rate, day_count, amount)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		accountID, day.Format(dateLayout), ledger.Round(eod), rate, dayCount, amount,
+	)
+	return err
+}
+
+// dailyInterest проценты за день day на остаток eod по годовой ставке rate.
+// Сумма не округляется: до копеек округляется только итог месяца.
+func dailyInterest(eod, rate float64, dayCount string, day time.Time) (float64, error) {
+	days, err := ledger.DaysInYear(dayCount, day)
+	if err != nil {
+		return 0, err
+	}
+	if eod <= 0 {
+		return 0, nil
+	}
+	return eod * rate / 100 / days, nil
+}
+
+// CapitaliseJob переносит начисленные за прошедшие месяцы проценты
+// на сберегательный счёт одной проводкой на месяц
+func CapitaliseJob() error {
+	monthStart := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.Local)
+
+	rows, err := database.DB.Query(
+		`SELECT DISTINCT account_id, DATE_FORMAT(accrual_date, '%Y-%m') FROM savings_accruals
+		WHERE capitalised_tx_id IS NULL AND accrual_date < ?`,
+		monthStart.Format(dateLayout),
+	)
+	if err != nil {
+		return err
+	}
+
+	type pendingMonth struct {
+		accountID int64
+		month     string
+	}
+
+	var months []pendingMonth
+	for rows.Next() {
+		var m pendingMonth
+		if err := rows.Scan(&m.accountID, &m.month); err != nil {
+			rows.Close()
+			return err
+		}
+		months = append(months, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range months {
+		if err := capitalise(m.accountID, m.month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func capitalise(accountID int64, month string) error {
+	from, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return err
+	}
+	to := from.AddDate(0, 1, 0)
+
+	return database.WithTx(func(tx *sql.Tx) error {
+		var total float64
+		err := tx.QueryRow(
+			`SELECT COALESCE(SUM(amount), 0) FROM savings_accruals
+			WHERE account_id = ? AND accrual_date >= ? AND accrual_date < ? AND capitalised_tx_id IS NULL
+			FOR UPDATE`,
+			accountID, from.Format(dateLayout), to.Format(dateLayout),
+		).Scan(&total)
+		if err != nil {
+			return err
+		}
+
+		// Если за месяц набежало меньше копейки, начисления просто закрываются
+		var txID int64
+		if ledger.Cents(total) > 0 {
+			txID, err = ledger.Transfer(tx, ledger.Transaction{
+				Kind:        ledger.KindInterest,
+				FromAccount: ledger.BankInterestExpense,
+				ToAccount:   ledger.SavingsAccount(accountID),
+				Amount:      ledger.Round(total),
+				Memo:        "Капитализация процентов за " + month,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(
+			`UPDATE savings_accruals SET capitalised_tx_id = ?
+			WHERE account_id = ? AND accrual_date >= ? AND accrual_date < ? AND capitalised_tx_id IS NULL`,
+			txID, accountID, from.Format(dateLayout), to.Format(dateLayout),
+		)
+		return err
+	})
+}
@@ -0,0 +1,38 @@
+package savings
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDailyInterest(t *testing.T) {
+	tests := []struct {
+		eod, rate float64
+		dayCount  string
+		day       string
+		want      float64
+	}{
+		{100000, 7.3, "ACT/365", "2025-03-01", 20},
+		{100000, 7.2, "ACT/360", "2025-03-01", 20},
+		{100000, 7.32, "ACT/ACT", "2024-03-01", 20},
+		{100000, 7.3, "ACT/ACT", "2025-03-01", 20},
+		{0, 7.3, "ACT/365", "2025-03-01", 0},
+		{-500, 7.3, "ACT/365", "2025-03-01", 0},
+		{100000, 0, "ACT/365", "2025-03-01", 0},
+	}
+	for _, tt := range tests {
+		day, _ := time.Parse(dateLayout, tt.day)
+		got, err := dailyInterest(tt.eod, tt.rate, tt.dayCount, day)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("dailyInterest(%v, %v, %s, %s) = %v, want %v", tt.eod, tt.rate, tt.dayCount, tt.day, got, tt.want)
+		}
+	}
+
+	if _, err := dailyInterest(100000, 7.3, "30/360", time.Now()); err == nil {
+		t.Error("dailyInterest with 30/360: want error")
+	}
+}
@@ -0,0 +1,347 @@
+// Package savings отвечает за сберегательные счета,
+// начисление и капитализацию процентов.
+package savings
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/transfers"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы сберегательного счёта
+const (
+	StatusOpen = "open"
+)
+
+type account struct {
+	ID            int64   `json:"id"`
+	UserID        int64   `json:"user_id"`
+	Name          string  `json:"name"`
+	Product       string  `json:"product"`
+	Balance       float64 `json:"balance"`
+	AccruedUnpaid float64 `json:"accrued_interest"`
+	Status        string  `json:"status"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+const selectAccount = `SELECT a.id, a.user_id, a.name, a.product, a.balance, a.status, a.created_at,
+	(SELECT COALESCE(SUM(s.amount), 0) FROM savings_accruals s
+		WHERE s.account_id = a.id AND s.capitalised_tx_id IS NULL)
+	FROM savings_accounts a`
+
+func scanAccount(row interface{ Scan(...interface{}) error }) (account, error) {
+	var a account
+	var createdAt time.Time
+	err := row.Scan(&a.ID, &a.UserID, &a.Name, &a.Product, &a.Balance, &a.Status, &createdAt, &a.AccruedUnpaid)
+	if err != nil {
+		return a, err
+	}
+	a.AccruedUnpaid = ledger.Round(a.AccruedUnpaid)
+	a.CreatedAt = createdAt.Format(types.TimeLayout)
+	return a, nil
+}
+
+func Open(c *gin.Context) {
+	var req struct {
+		Name string `json:"name" form:"name"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"INSERT INTO savings_accounts (user_id, name, product, status) VALUES (?, ?, ?, ?)",
+		auth.CurrentUserID(c), req.Name, types.DefaultSavingsProduct, StatusOpen,
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SAVINGS_CREATE_ERROR"))
+		return
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "ID_ERROR"))
+		return
+	}
+
+	a, err := scanAccount(database.DB.QueryRow(selectAccount+" WHERE a.id = ?", id))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Сберегательный счёт открыт",
+		Data:    a,
+	})
+}
+
+func List(c *gin.Context) {
+	rows, err := database.DB.Query(selectAccount+" WHERE a.user_id = ? ORDER BY a.id", auth.CurrentUserID(c))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	accounts := make([]account, 0)
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		accounts = append(accounts, a)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Сберегательные счета",
+		Data:    accounts,
+	})
+}
+
+// Deposit переводит деньги с основного счёта на сберегательный
+func Deposit(c *gin.Context) {
+	move(c, true)
+}
+
+// Withdraw возвращает деньги со сберегательного счёта на основной
+func Withdraw(c *gin.Context) {
+	move(c, false)
+}
+
+func move(c *gin.Context, deposit bool) {
+	a, ok := ownAccount(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	t := ledger.Transaction{
+		Kind:        ledger.KindSavingsDeposit,
+		FromAccount: ledger.UserAccount(a.UserID),
+		ToAccount:   ledger.SavingsAccount(a.ID),
+		Amount:      req.Amount,
+		CreatedBy:   a.UserID,
+	}
+	if !deposit {
+		t.Kind = ledger.KindSavingsWithdrawal
+		t.FromAccount, t.ToAccount = t.ToAccount, t.FromAccount
+	}
+
+	var txID int64
+	err := database.WithTx(func(tx *sql.Tx) error {
+		var err error
+		txID, err = ledger.Transfer(tx, t)
+		return err
+	})
+	if err != nil {
+		transfers.RespondLedgerError(c, err)
+		return
+	}
+
+	a, err = scanAccount(database.DB.QueryRow(selectAccount+" WHERE a.id = ?", a.ID))
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Операция по сберегательному счёту проведена",
+		Data: map[string]interface{}{
+			"transaction_id": txID,
+			"account":        a,
+		},
+	})
+}
+
+// Accruals возвращает отчёт о начислениях по счёту за период from..to (YYYY-MM-DD)
+func Accruals(c *gin.Context) {
+	a, ok := ownAccount(c)
+	if !ok {
+		return
+	}
+
+	query := `SELECT accrual_date, eod_balance, annual_rate, day_count, amount, capitalised_tx_id
+		FROM savings_accruals WHERE account_id = ?`
+	args := []interface{}{a.ID}
+	if from := c.Query("from"); from != "" {
+		query += " AND accrual_date >= ?"
+		args = append(args, from)
+	}
+	if to := c.Query("to"); to != "" {
+		query += " AND accrual_date <= ?"
+		args = append(args, to)
+	}
+	query += " ORDER BY accrual_date"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	type accrual struct {
+		Date            string  `json:"date"`
+		EODBalance      float64 `json:"eod_balance"`
+		AnnualRate      float64 `json:"annual_rate"`
+		DayCount        string  `json:"day_count"`
+		Amount          float64 `json:"amount"`
+		Capitalised     bool    `json:"capitalised"`
+		CapitalisedTxID int64   `json:"capitalised_tx_id,omitempty"`
+	}
+
+	accruals := make([]accrual, 0)
+	var total, capitalised float64
+	for rows.Next() {
+		var r accrual
+		var date time.Time
+		var txID sql.NullInt64
+		if err := rows.Scan(&date, &r.EODBalance, &r.AnnualRate, &r.DayCount, &r.Amount, &txID); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		r.Date = date.Format(dateLayout)
+		r.Capitalised = txID.Valid
+		r.CapitalisedTxID = txID.Int64
+		total += r.Amount
+		if r.Capitalised {
+			capitalised += r.Amount
+		}
+		accruals = append(accruals, r)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Отчёт о начислениях",
+		Data: map[string]interface{}{
+			"account":       a,
+			"day_count":     types.DayCountConvention,
+			"total_accrued": ledger.Round(total),
+			"capitalised":   ledger.Round(capitalised),
+			"pending":       ledger.Round(total - capitalised),
+			"accruals":      accruals,
+		},
+	})
+}
+
+func ListRates(c *gin.Context) {
+	rows, err := database.DB.Query(
+		"SELECT product, annual_rate, effective_from FROM savings_rates ORDER BY product, effective_from",
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	rates := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		var product string
+		var rate float64
+		var effectiveFrom time.Time
+		if err := rows.Scan(&product, &rate, &effectiveFrom); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		rates = append(rates, map[string]interface{}{
+			"product":        product,
+			"annual_rate":    rate,
+			"effective_from": effectiveFrom.Format(dateLayout),
+		})
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Таблица ставок",
+		Data:    rates,
+	})
+}
+
+// AddRate добавляет ставку в таблицу. Ставка может вступить в силу
+// не раньше завтрашнего дня, чтобы не менять уже начисленные проценты.
+func AddRate(c *gin.Context) {
+	var req struct {
+		Product       string  `json:"product" form:"product"`
+		AnnualRate    float64 `json:"annual_rate" form:"annual_rate"`
+		EffectiveFrom string  `json:"effective_from" form:"effective_from"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	if req.Product == "" {
+		req.Product = types.DefaultSavingsProduct
+	}
+
+	effectiveFrom, err := time.ParseInLocation(dateLayout, req.EffectiveFrom, time.Local)
+	if err != nil || req.AnnualRate < 0 {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_RATE"))
+		return
+	}
+
+	if !effectiveFrom.After(dayStart(time.Now())) {
+		c.Error(apierr.New(http.StatusBadRequest, "RETROACTIVE_RATE"))
+		return
+	}
+
+	_, err = database.DB.Exec(
+		"INSERT INTO savings_rates (product, annual_rate, effective_from, created_by) VALUES (?, ?, ?, ?)",
+		req.Product, req.AnnualRate, req.EffectiveFrom, auth.CurrentUserID(c),
+	)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "RATE_CREATE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Ставка добавлена",
+		Data:    req,
+	})
+}
+
+// ownAccount загружает счёт из параметра :id и проверяет владельца
+func ownAccount(c *gin.Context) (account, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return account{}, false
+	}
+
+	a, err := scanAccount(database.DB.QueryRow(selectAccount+" WHERE a.id = ? AND a.user_id = ?", id, auth.CurrentUserID(c)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.Error(apierr.New(http.StatusNotFound, "SAVINGS_NOT_FOUND"))
+		} else {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		}
+		return account{}, false
+	}
+
+	return a, true
+}
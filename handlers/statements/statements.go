@@ -0,0 +1,132 @@
+// Package statements выдаёт выписки по счетам в JSON и банковских форматах.
+package statements
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/handlers/transfers"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/statements"
+	"backend_golang/types"
+)
+
+const dateLayout = "2006-01-02"
+
+// Форматы выписки
+const (
+	FormatJSON    = "json"
+	FormatCamt053 = "camt053"
+	FormatCamt054 = "camt054"
+	FormatMT940   = "mt940"
+)
+
+var errForeignAccount = apierr.New(http.StatusNotFound, "ACCOUNT_NOT_FOUND")
+
+const adminKey = "statements_admin"
+
+// Get выдаёт выписку по счёту account (по умолчанию — основной счёт) за период
+// from..to включительно, даты в формате YYYY-MM-DD. Параметр format выбирает
+// формат: json, camt053, camt054 или mt940; для camt054 indicator=CRDT|DBIT
+// оставляет только зачисления или списания.
+func Get(c *gin.Context) {
+	account := c.Query("account")
+	if account == "" {
+		account = ledger.UserAccount(auth.CurrentAccountID(c))
+	}
+
+	from, to, ok := period(c)
+	if !ok {
+		return
+	}
+
+	if !c.GetBool(adminKey) {
+		owner, err := ledger.OwnerOf(database.DB, account)
+		if err == nil && owner != auth.CurrentAccountID(c) {
+			err = errForeignAccount
+		}
+		if err != nil {
+			transfers.RespondLedgerError(c, err)
+			return
+		}
+	}
+
+	st, err := statements.Build(database.DB, account, from, to)
+	if err != nil {
+		transfers.RespondLedgerError(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", FormatJSON)
+	var body []byte
+	var contentType, extension string
+
+	switch format {
+	case FormatJSON:
+		c.JSON(http.StatusOK, types.Response{
+			Success: true,
+			Message: "Выписка по счёту",
+			Data:    st,
+		})
+		return
+	case FormatCamt053:
+		body, err = statements.Camt053(st)
+		contentType, extension = "application/xml; charset=utf-8", "xml"
+	case FormatCamt054:
+		body, err = statements.Camt054(st, strings.ToUpper(c.Query("indicator")))
+		contentType, extension = "application/xml; charset=utf-8", "xml"
+	case FormatMT940:
+		body, err = statements.MT940(st)
+		contentType, extension = "text/plain; charset=us-ascii", "sta"
+	default:
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_FORMAT", i18n.Args{"format": format}))
+		return
+	}
+	if err != nil {
+		transfers.RespondLedgerError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+format+"-"+st.ID+"."+extension)
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// AdminGet выдаёт выписку по любому счёту, включая счета банка
+func AdminGet(c *gin.Context) {
+	c.Set(adminKey, true)
+	Get(c)
+}
+
+// period разбирает from и to; по умолчанию — текущий месяц
+func period(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	var err error
+	if raw := c.Query("from"); raw != "" {
+		if from, err = time.ParseInLocation(dateLayout, raw, time.Local); err != nil {
+			from = time.Time{}
+		}
+	}
+	if raw := c.Query("to"); raw != "" {
+		if to, err = time.ParseInLocation(dateLayout, raw, time.Local); err != nil {
+			to = time.Time{}
+		}
+	}
+
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_PERIOD"))
+		return from, to, false
+	}
+
+	// to включительно: выписка строится до начала следующего дня
+	return from, to.AddDate(0, 0, 1), true
+}
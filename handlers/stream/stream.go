@@ -0,0 +1,233 @@
+// Package stream отдаёт клиенту поток сообщений об остатке, входящих
+// переводах и безопасности по WebSocket или, если он недоступен, по SSE.
+// После обрыва клиент переподключается с Last-Event-ID и получает
+// пропущенное.
+package stream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"backend_golang/apierr"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/stream"
+	"backend_golang/types"
+)
+
+// writeTimeout сколько ждать, пока клиент примет одно сообщение
+const writeTimeout = 10 * time.Second
+
+// writer отправляет сообщения по конкретному протоколу
+type writer interface {
+	Send(m stream.Message) error
+	Ping() error
+	Close(reason string)
+}
+
+// SSE поток в формате text/event-stream; номер сообщения — в поле id,
+// тип — в поле event
+func SSE(c *gin.Context) {
+	sub, backlog, ok := open(c, c.GetHeader("Last-Event-ID"))
+	if !ok {
+		return
+	}
+	defer stream.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx не должен копить поток в буфере
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	run(c.Request.Context(), sub, backlog, newSSEWriter(c), auth.CurrentSession(c))
+}
+
+// WebSocket поток JSON-сообщений по WebSocket; номер последнего
+// полученного сообщения передаётся в ?last_event_id=
+func WebSocket(c *gin.Context) {
+	sub, backlog, ok := open(c, c.Query("last_event_id"))
+	if !ok {
+		return
+	}
+	defer stream.Unsubscribe(sub)
+
+	session := auth.CurrentSession(c)
+	server := websocket.Server{
+		// Origin не проверяется: мобильные клиенты его не шлют, а сессия
+		// передаётся явно, не в cookie, и чужая страница её не подставит.
+		// Из подпротоколов выбирается только bearer: второй — сама сессия
+		Handshake: func(config *websocket.Config, _ *http.Request) error {
+			offered := config.Protocol
+			config.Protocol = nil
+			for _, protocol := range offered {
+				if protocol == auth.WebSocketProtocol {
+					config.Protocol = []string{protocol}
+				}
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			ws.MaxPayloadBytes = 4 << 10
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// входящие кадры не нужны, чтение только замечает закрытие соединения
+			go func() {
+				var discard []byte
+				for websocket.Message.Receive(ws, &discard) == nil {
+				}
+				cancel()
+			}()
+
+			run(ctx, sub, backlog, wsWriter{ws}, session)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// open подключает клиента к потоку и готовит пропущенные сообщения
+func open(c *gin.Context, lastEventID string) (*stream.Subscriber, []stream.Message, bool) {
+	var afterID int64 = -1
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			c.Error(apierr.New(http.StatusBadRequest, "INVALID_LAST_EVENT_ID"))
+			return nil, nil, false
+		}
+		afterID = id
+	}
+
+	sub, err := stream.Subscribe(auth.CurrentUserID(c))
+	if errors.Is(err, stream.ErrTooManyStreams) {
+		c.Error(apierr.New(http.StatusTooManyRequests, "TOO_MANY_STREAMS", i18n.Args{"max": types.StreamMaxConnections}))
+		return nil, nil, false
+	}
+
+	backlog := make([]stream.Message, 0)
+	if afterID >= 0 {
+		backlog, err = stream.Replay(sub, afterID)
+		if err != nil {
+			stream.Unsubscribe(sub)
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+			return nil, nil, false
+		}
+	}
+	return sub, backlog, true
+}
+
+// run отправляет сначала пропущенное, затем новые сообщения, пока клиент
+// не отключится, сервер не отключит его или не закончится сессия
+func run(ctx context.Context, sub *stream.Subscriber, backlog []stream.Message, w writer, session string) {
+	var last int64 = -1
+	for _, m := range backlog {
+		if err := w.Send(m); err != nil {
+			return
+		}
+		last = m.ID
+	}
+
+	ticker := time.NewTicker(types.StreamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Done():
+			w.Close(sub.Reason())
+			return
+		case m := <-sub.C():
+			if m.ID <= last {
+				continue
+			}
+			if err := w.Send(m); err != nil {
+				return
+			}
+			last = m.ID
+		case <-ticker.C:
+			if active, err := auth.SessionActive(sub.UserID, session); err == nil && !active {
+				w.Close(stream.ReasonSessionExpired)
+				return
+			}
+			if err := w.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseWriter пишет события с ограничением по времени, чтобы медленный
+// клиент не держал обработчик бесконечно
+type sseWriter struct {
+	c  *gin.Context
+	rc *http.ResponseController
+}
+
+func newSSEWriter(c *gin.Context) sseWriter {
+	return sseWriter{c: c, rc: http.NewResponseController(c.Writer)}
+}
+
+func (w sseWriter) write(event sse.Event) error {
+	w.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := sse.Encode(w.c.Writer, event); err != nil {
+		return err
+	}
+	return w.rc.Flush()
+}
+
+func (w sseWriter) Send(m stream.Message) error {
+	return w.write(sse.Event{
+		Id:    strconv.FormatInt(m.ID, 10),
+		Event: m.Type,
+		Data:  m,
+	})
+}
+
+func (w sseWriter) Ping() error {
+	w.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := w.c.Writer.WriteString(": ping\n\n"); err != nil {
+		return err
+	}
+	return w.rc.Flush()
+}
+
+func (w sseWriter) Close(reason string) {
+	w.write(sse.Event{
+		Event: "close",
+		Data:  map[string]string{"reason": reason},
+	})
+}
+
+type wsWriter struct {
+	ws *websocket.Conn
+}
+
+func (w wsWriter) Send(m stream.Message) error {
+	w.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return websocket.JSON.Send(w.ws, m)
+}
+
+func (w wsWriter) Ping() error {
+	w.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return websocket.JSON.Send(w.ws, map[string]string{"type": "ping"})
+}
+
+func (w wsWriter) Close(reason string) {
+	w.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	websocket.JSON.Send(w.ws, map[string]interface{}{
+		"type": "close",
+		"data": map[string]string{"reason": reason},
+	})
+}
@@ -0,0 +1,151 @@
+package transfers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/fraud"
+	"backend_golang/handlers/auth"
+	"backend_golang/ledger"
+	"backend_golang/sanctions"
+	"backend_golang/types"
+)
+
+// Transfer перевод между пользователями до проведения
+type Transfer struct {
+	// UserID кто переводит, AccountID с чьего счёта
+	UserID      int64
+	AccountID   int64
+	RecipientID int64
+	Amount      float64
+	Memo        string
+	// PaymentRequestID запрос денег, который оплачивается переводом
+	PaymentRequestID int64
+	// Password подтверждает перевод, если антифрод запросил step-up
+	Password string
+}
+
+// Check итог проверок перевода перед проведением
+type Check struct {
+	Screening sanctions.Screening
+	Event     fraud.Event
+	Decision  fraud.Decision
+}
+
+// Checks сверяет получателя с санкционными списками при каждом переводе
+// (списки обновляются, а проверка при регистрации могла быть давно)
+// и прогоняет перевод через антифрод. Ошибка уже готова для c.Error:
+// получатель заблокирован, перевод заблокирован или нужен пароль.
+// Совпадение по спискам и решение review перевод не останавливают:
+// его ставит в очередь Check.Queue.
+func Checks(c *gin.Context, t Transfer) (Check, error) {
+	var check Check
+
+	screening, err := sanctions.ScreenUser(database.DB, t.RecipientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return check, apierr.New(http.StatusNotFound, "RECIPIENT_NOT_FOUND")
+	}
+	if err != nil {
+		return check, apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR")
+	}
+	if screening.Status == sanctions.StatusBlocked {
+		return check, apierr.New(http.StatusForbidden, "RECIPIENT_BLOCKED")
+	}
+	check.Screening = screening
+	if screening.Hit() {
+		return check, nil
+	}
+
+	check.Event = fraud.Event{
+		Type:      fraud.EventTransfer,
+		UserID:    t.AccountID,
+		ActorID:   t.UserID,
+		Amount:    t.Amount,
+		Recipient: ledger.UserAccount(t.RecipientID),
+		DeviceID:  c.GetHeader(fraud.DeviceHeader),
+		IP:        c.ClientIP(),
+	}
+	check.Decision, err = fraud.Evaluate(database.DB, check.Event)
+	if err != nil {
+		return check, apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR")
+	}
+
+	switch check.Decision.Action {
+	case fraud.ActionBlock:
+		return check, apierr.New(http.StatusForbidden, "FRAUD_BLOCKED")
+	case fraud.ActionStepUp:
+		if t.Password == "" {
+			return check, apierr.New(http.StatusPreconditionRequired, "STEP_UP_REQUIRED")
+		}
+		if err := auth.CheckPassword(t.UserID, t.Password); err != nil {
+			if errors.Is(err, auth.ErrWrongPassword) {
+				return check, apierr.New(http.StatusUnauthorized, "INVALID_PASSWORD")
+			}
+			return check, err
+		}
+	}
+	return check, nil
+}
+
+// Queue ставит перевод в очередь комплаенса или аналитика, если этого
+// потребовали проверки, и возвращает номер проверки; 0 — перевод можно
+// проводить. Вызывается в транзакции списания после auth.CheckSpending.
+func (check Check) Queue(tx *sql.Tx, t Transfer) (int64, error) {
+	switch {
+	case check.Screening.Hit():
+		return sanctions.QueueTransfer(tx, t.UserID, check.Screening, sanctions.PendingTransfer{
+			OwnerID:          t.AccountID,
+			FromAccount:      ledger.UserAccount(t.AccountID),
+			ToAccount:        ledger.UserAccount(t.RecipientID),
+			Amount:           t.Amount,
+			Memo:             t.Memo,
+			PaymentRequestID: t.PaymentRequestID,
+		})
+	case check.Decision.Action == fraud.ActionReview:
+		return fraud.QueueTransfer(tx, check.Event, check.Decision, fraud.PendingTransfer{
+			FromAccount:      ledger.UserAccount(t.AccountID),
+			ToAccount:        ledger.UserAccount(t.RecipientID),
+			Amount:           t.Amount,
+			Memo:             t.Memo,
+			PaymentRequestID: t.PaymentRequestID,
+		})
+	}
+	return 0, nil
+}
+
+// RespondQueued отвечает 202 на перевод, поставленный в очередь Check.Queue
+func (check Check) RespondQueued(c *gin.Context, t Transfer, reviewID int64) {
+	if !check.Screening.Hit() {
+		c.JSON(http.StatusAccepted, types.Response{
+			Success: true,
+			Message: "Перевод отправлен на проверку, сумма заблокирована до решения",
+			Data: map[string]interface{}{
+				"review_id": reviewID,
+				"status":    fraud.ReviewPending,
+			},
+		})
+		return
+	}
+
+	audit.LogRequest(c, t.UserID, audit.EventSanctionsHit, audit.UserSubject(t.RecipientID), audit.OutcomeFailure, map[string]interface{}{
+		"subject_type": sanctions.SubjectTransfer,
+		"review_id":    reviewID,
+		"amount":       t.Amount,
+		"matches":      check.Screening.Matches,
+	})
+
+	c.JSON(http.StatusAccepted, types.Response{
+		Success: true,
+		Message: "Перевод отправлен на проверку комплаенса, сумма заблокирована до решения",
+		Data: map[string]interface{}{
+			"review_id": reviewID,
+			"status":    sanctions.ReviewPending,
+		},
+	})
+}
@@ -0,0 +1,353 @@
+package transfers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы запросов на возврат
+const (
+	ReversalPending   = "pending"
+	ReversalAccepted  = "accepted"
+	ReversalDeclined  = "declined"
+	ReversalCancelled = "cancelled"
+)
+
+// ReasonCodes коды причин принудительного возврата администратором
+var ReasonCodes = map[string]string{
+	"FRAUD":            "Мошенническая операция",
+	"DUPLICATE":        "Повторное списание",
+	"TECHNICAL_ERROR":  "Техническая ошибка",
+	"CUSTOMER_DISPUTE": "Спор клиента",
+	"COURT_ORDER":      "Решение суда",
+}
+
+var errRequestNotPending = errors.New("reversal request is not pending")
+
+type reversalRequest struct {
+	ID            int64   `json:"id"`
+	TransactionID int64   `json:"transaction_id"`
+	RequestedBy   int64   `json:"requested_by"`
+	RecipientID   int64   `json:"recipient_id"`
+	Amount        float64 `json:"amount"`
+	Reason        string  `json:"reason,omitempty"`
+	Status        string  `json:"status"`
+	ReversalTxID  int64   `json:"reversal_tx_id,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// RequestReversal создаёт запрос на возврат перевода.
+// Деньги вернутся только после согласия получателя.
+func RequestReversal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	var req struct {
+		Amount float64 `json:"amount" form:"amount"`
+		Reason string  `json:"reason" form:"reason"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	var requestID int64
+
+	// Исходная транзакция блокируется до вставки запроса: два одновременных
+	// запроса на один перевод не пройдут проверку на открытый запрос оба
+	err = database.WithTx(func(tx *sql.Tx) error {
+		t, err := ledger.GetForUpdate(tx, id)
+		if err != nil {
+			return err
+		}
+		if t.FromAccount != ledger.UserAccount(userID) {
+			return sql.ErrNoRows
+		}
+
+		kind, recipientID, err := ledger.ParseAccount(t.ToAccount)
+		if err != nil || kind != "user" || t.Kind != ledger.KindTransfer {
+			return ledger.ErrNotReversible
+		}
+
+		remaining := ledger.Remaining(t)
+		if req.Amount == 0 {
+			req.Amount = remaining
+		}
+		if req.Amount < 0 {
+			return ledger.ErrInvalidAmount
+		}
+		if ledger.Cents(req.Amount) > ledger.Cents(remaining) {
+			return ledger.ErrReversalExceeds
+		}
+
+		var pending bool
+		err = tx.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM reversal_requests WHERE transaction_id = ? AND status = ?)",
+			t.ID, ReversalPending,
+		).Scan(&pending)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return apierr.New(http.StatusConflict, "REVERSAL_PENDING")
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO reversal_requests (transaction_id, requested_by, recipient_id, amount, reason)
+			VALUES (?, ?, ?, ?, ?)`,
+			t.ID, userID, recipientID, ledger.Round(req.Amount), req.Reason,
+		)
+		if err != nil {
+			return apierr.Wrap(err, http.StatusInternalServerError, "REVERSAL_CREATE_ERROR")
+		}
+
+		requestID, err = result.LastInsertId()
+		return err
+	})
+
+	if err != nil {
+		RespondLedgerError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Запрос на возврат отправлен получателю",
+		Data: map[string]interface{}{
+			"request_id": requestID,
+			"amount":     ledger.Round(req.Amount),
+			"status":     ReversalPending,
+		},
+	})
+}
+
+// ListReversals возвращает входящие и исходящие запросы на возврат.
+// Параметр direction=incoming|outgoing ограничивает выборку.
+func ListReversals(c *gin.Context) {
+	userID := auth.CurrentUserID(c)
+
+	query := `SELECT id, transaction_id, requested_by, recipient_id, amount, reason, status,
+		reversal_tx_id, created_at FROM reversal_requests`
+	var args []interface{}
+
+	switch c.Query("direction") {
+	case "incoming":
+		query += " WHERE recipient_id = ?"
+		args = append(args, userID)
+	case "outgoing":
+		query += " WHERE requested_by = ?"
+		args = append(args, userID)
+	default:
+		query += " WHERE recipient_id = ? OR requested_by = ?"
+		args = append(args, userID, userID)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+	defer rows.Close()
+
+	requests := make([]reversalRequest, 0)
+	for rows.Next() {
+		var r reversalRequest
+		var reversalTxID sql.NullInt64
+		var createdAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.TransactionID, &r.RequestedBy, &r.RecipientID, &r.Amount,
+			&r.Reason, &r.Status, &reversalTxID, &createdAt); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
+			return
+		}
+		r.ReversalTxID = reversalTxID.Int64
+		r.CreatedAt = createdAt.Time.Format(types.TimeLayout)
+		requests = append(requests, r)
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запросы на возврат",
+		Data:    requests,
+	})
+}
+
+// AcceptReversal подтверждает возврат получателем и проводит его
+func AcceptReversal(c *gin.Context) {
+	resolveReversal(c, ReversalAccepted)
+}
+
+// DeclineReversal отклоняет возврат получателем
+func DeclineReversal(c *gin.Context) {
+	resolveReversal(c, ReversalDeclined)
+}
+
+// CancelReversal отзывает запрос на возврат его автором
+func CancelReversal(c *gin.Context) {
+	resolveReversal(c, ReversalCancelled)
+}
+
+func resolveReversal(c *gin.Context, status string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	userID := auth.CurrentUserID(c)
+	var reversalTxID int64
+
+	err = database.WithTx(func(tx *sql.Tx) error {
+		var r reversalRequest
+		err := tx.QueryRow(
+			`SELECT id, transaction_id, requested_by, recipient_id, amount, status
+			FROM reversal_requests WHERE id = ? FOR UPDATE`,
+			id,
+		).Scan(&r.ID, &r.TransactionID, &r.RequestedBy, &r.RecipientID, &r.Amount, &r.Status)
+		if err != nil {
+			return err
+		}
+
+		owner := r.RecipientID
+		if status == ReversalCancelled {
+			owner = r.RequestedBy
+		}
+		if owner != userID {
+			return sql.ErrNoRows
+		}
+		if r.Status != ReversalPending {
+			return errRequestNotPending
+		}
+
+		if status == ReversalAccepted {
+			reversalTxID, err = ledger.Reverse(tx, r.TransactionID, ledger.Transaction{
+				Amount:    r.Amount,
+				Memo:      "Возврат по запросу #" + strconv.FormatInt(r.ID, 10),
+				CreatedBy: userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var reversalTx interface{}
+		if reversalTxID != 0 {
+			reversalTx = reversalTxID
+		}
+
+		_, err = tx.Exec(
+			"UPDATE reversal_requests SET status = ?, reversal_tx_id = ?, resolved_at = NOW() WHERE id = ?",
+			status, reversalTx, r.ID,
+		)
+		return err
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.Error(apierr.New(http.StatusNotFound, "REVERSAL_NOT_FOUND"))
+		} else if err == errRequestNotPending {
+			c.Error(apierr.New(http.StatusConflict, "REVERSAL_NOT_PENDING"))
+		} else {
+			RespondLedgerError(c, err)
+		}
+		return
+	}
+
+	data := map[string]interface{}{
+		"request_id": id,
+		"status":     status,
+	}
+	if reversalTxID != 0 {
+		data["reversal_tx_id"] = reversalTxID
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Запрос на возврат обработан",
+		Data:    data,
+	})
+}
+
+// ForceReversal проводит возврат по решению администратора без согласия получателя
+func ForceReversal(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	var req struct {
+		Amount     float64 `json:"amount" form:"amount"`
+		ReasonCode string  `json:"reason_code" form:"reason_code"`
+		Comment    string  `json:"comment" form:"comment"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+
+	reason, ok := ReasonCodes[req.ReasonCode]
+	if !ok {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_REASON_CODE"))
+		return
+	}
+
+	memo := reason
+	if req.Comment != "" {
+		memo += ": " + req.Comment
+	}
+
+	var reversalTxID int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		amount := req.Amount
+		if amount == 0 {
+			original, err := ledger.GetForUpdate(tx, id)
+			if err != nil {
+				return err
+			}
+			amount = ledger.Remaining(original)
+		}
+
+		var err error
+		reversalTxID, err = ledger.Reverse(tx, id, ledger.Transaction{
+			Amount:     amount,
+			Memo:       memo,
+			ReasonCode: req.ReasonCode,
+			CreatedBy:  auth.CurrentUserID(c),
+			Force:      true,
+		})
+		return err
+	})
+
+	if err != nil {
+		RespondLedgerError(c, err)
+		return
+	}
+
+	t, err := ledger.Get(database.DB, reversalTxID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Возврат проведён",
+		Data:    t,
+	})
+}
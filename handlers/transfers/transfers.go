@@ -0,0 +1,176 @@
+// Package transfers отвечает за переводы между пользователями
+// и их возвраты.
+package transfers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/fraud"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
+	"backend_golang/types"
+	"backend_golang/validation"
+)
+
+func Create(c *gin.Context) {
+	var req struct {
+		ToUserID      int64   `json:"to_user_id" form:"to_user_id" binding:"required_without=ToPhoneNumber"`
+		ToPhoneNumber string  `json:"to_phone_number" form:"to_phone_number" binding:"omitempty,phone"`
+		Amount        float64 `json:"amount" form:"amount" binding:"required,gt=0"`
+		Memo          string  `json:"memo" form:"memo"`
+		// Password подтверждает перевод, если антифрод запросил step-up
+		Password string `json:"password" form:"password"`
+	}
+
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	recipientID := req.ToUserID
+	if recipientID == 0 {
+		err := database.DB.QueryRow(
+			"SELECT id FROM users WHERE phone_number = ?",
+			req.ToPhoneNumber,
+		).Scan(&recipientID)
+
+		if err != nil {
+			if err == sql.ErrNoRows {
+				c.Error(apierr.New(http.StatusNotFound, "RECIPIENT_NOT_FOUND"))
+			} else {
+				c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+			}
+			return
+		}
+	}
+
+	userID := auth.CurrentUserID(c)
+	accountID := auth.CurrentAccountID(c)
+	if recipientID == accountID {
+		c.Error(apierr.New(http.StatusBadRequest, "SELF_TRANSFER"))
+		return
+	}
+
+	t := Transfer{
+		UserID:      userID,
+		AccountID:   accountID,
+		RecipientID: recipientID,
+		Amount:      req.Amount,
+		Memo:        req.Memo,
+		Password:    req.Password,
+	}
+	check, err := Checks(c, t)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var txID, reviewID int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		if err := auth.CheckSpending(tx, userID, accountID, req.Amount); err != nil {
+			return err
+		}
+
+		var err error
+		if reviewID, err = check.Queue(tx, t); err != nil || reviewID != 0 {
+			return err
+		}
+
+		txID, err = ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindTransfer,
+			FromAccount: ledger.UserAccount(accountID),
+			ToAccount:   ledger.UserAccount(recipientID),
+			Amount:      req.Amount,
+			Memo:        req.Memo,
+			CreatedBy:   userID,
+		})
+		return err
+	})
+
+	if err != nil {
+		RespondLedgerError(c, err)
+		return
+	}
+	if reviewID != 0 {
+		check.RespondQueued(c, t, reviewID)
+		return
+	}
+
+	if err := fraud.RememberDevice(database.DB, userID, check.Event.DeviceID); err != nil {
+		log.Printf("❌ Remember device for user %d failed: %v", userID, err)
+	}
+
+	done, err := ledger.Get(database.DB, txID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Перевод выполнен",
+		Data:    done,
+	})
+}
+
+func GetByID(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	t, err := ledger.Get(database.DB, id)
+	if err != nil {
+		RespondLedgerError(c, err)
+		return
+	}
+
+	account := ledger.UserAccount(auth.CurrentAccountID(c))
+	if t.FromAccount != account && t.ToAccount != account {
+		c.Error(apierr.New(http.StatusNotFound, "TRANSACTION_NOT_FOUND"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Транзакция найдена",
+		Data:    t,
+	})
+}
+
+// History возвращает проводки по выбранному счёту от новых к старым.
+// Параметр before_id задаёт страницу, limit — её размер (до 100).
+func History(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	beforeID, _ := strconv.ParseInt(c.Query("before_id"), 10, 64)
+
+	entries, err := ledger.History(database.DB, ledger.UserAccount(auth.CurrentAccountID(c)), beforeID, limit)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "История операций",
+		Data:    entries,
+	})
+}
+
+// RespondLedgerError передаёт ошибку проведения транзакции в apierr.Handler;
+// sql.ErrNoRows — транзакция не найдена
+func RespondLedgerError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "TRANSACTION_NOT_FOUND"))
+}
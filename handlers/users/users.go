@@ -10,18 +10,96 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"backend_golang/apierr"
+	"backend_golang/audit"
 	"backend_golang/database"
+	"backend_golang/events"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/ledger"
 	"backend_golang/types"
+	"backend_golang/validation"
 )
 
+// withBalances пересчитывает остатки пользователя с учётом холдов
+func withBalances(user types.UserResponse) types.UserResponse {
+	balance := ledger.NewBalance(user.LedgerBalance, user.Pending)
+	user.LedgerBalance = balance.LedgerBalance
+	user.AvailableBalance = balance.AvailableBalance
+	user.Pending = balance.Pending
+	return user
+}
+
+// allowed проверяет, что у текущего пользователя есть право permission
+// на счёт пользователя userID, и отвечает клиенту, если нет
+func allowed(c *gin.Context, userID int64, permission string) bool {
+	access, err := auth.AccessTo(database.DB, auth.CurrentUserID(c), userID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return false
+	}
+
+	if access.Permission == "" {
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
+		return false
+	}
+	if !access.Allows(permission) {
+		c.Error(apierr.New(http.StatusForbidden, "FORBIDDEN"))
+		return false
+	}
+	return true
+}
+
+// managesProfile проверяет, что текущий пользователь может менять или удалять
+// профиль userID: это он сам, опекун детского профиля или администратор.
+// Права совладельцев распространяются только на счёт, не на профиль владельца.
+func managesProfile(c *gin.Context, userID int64) bool {
+	actorID := auth.CurrentUserID(c)
+	if actorID == userID {
+		return true
+	}
+
+	var guardianID sql.NullInt64
+	err := database.DB.QueryRow("SELECT guardian_id FROM users WHERE id = ?", userID).Scan(&guardianID)
+	if err == sql.ErrNoRows {
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
+		return false
+	}
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return false
+	}
+	if guardianID.Valid && guardianID.Int64 == actorID {
+		return true
+	}
+
+	admin, err := auth.HasRole(actorID, auth.RoleAdmin)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return false
+	}
+	if admin {
+		return true
+	}
+
+	// Совладелец знает о профиле и получает 403, остальным он неотличим от несуществующего
+	access, err := auth.AccessTo(database.DB, actorID, userID)
+	if err != nil {
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+		return false
+	}
+	if access.Permission == "" {
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
+	} else {
+		c.Error(apierr.New(http.StatusForbidden, "FORBIDDEN"))
+	}
+	return false
+}
+
 func GetAll(c *gin.Context) {
-	rows, err := database.DB.Query("SELECT id, name, surname, phone_number, balance FROM users ORDER BY id")
+	rows, err := database.DB.Query("SELECT id, name, surname, phone_number, balance, " + ledger.PendingSQL + " FROM users ORDER BY id")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка базы данных: " + err.Error(),
-			Error:   "DATABASE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		return
 	}
 	defer rows.Close()
@@ -30,15 +108,11 @@ func GetAll(c *gin.Context) {
 
 	for rows.Next() {
 		var user types.UserResponse
-		if err := rows.Scan(&user.ID, &user.Name, &user.Surname, &user.PhoneNumber, &user.Balance); err != nil {
-			c.JSON(http.StatusInternalServerError, types.Response{
-				Success: false,
-				Message: "Ошибка сканирования пользователя: " + err.Error(),
-				Error:   "SCAN_ERROR",
-			})
+		if err := rows.Scan(&user.ID, &user.Name, &user.Surname, &user.PhoneNumber, &user.LedgerBalance, &user.Pending); err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "SCAN_ERROR"))
 			return
 		}
-		users = append(users, user)
+		users = append(users, withBalances(user))
 	}
 
 	if len(users) == 0 {
@@ -62,39 +136,32 @@ func GetByID(c *gin.Context) {
 
 	id, err := strconv.Atoi(idUser)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Неверный формат параметра 'id'",
-			Error:   "INVALID_ID",
-		})
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	if !allowed(c, int64(id), auth.PermissionView) {
 		return
 	}
 
 	var user types.UserResponse
 	err = database.DB.QueryRow(
-		"SELECT id, name, surname, phone_number, balance FROM users WHERE id = ?",
+		"SELECT id, name, surname, phone_number, balance, "+ledger.PendingSQL+" FROM users WHERE id = ?",
 		id,
 	).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Surname,
 		&user.PhoneNumber,
-		&user.Balance,
+		&user.LedgerBalance,
+		&user.Pending,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			c.JSON(http.StatusNotFound, types.Response{
-				Success: false,
-				Message: "Пользователь не найден",
-				Error:   "USER_NOT_FOUND",
-			})
+			c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
 		} else {
-			c.JSON(http.StatusInternalServerError, types.Response{
-				Success: false,
-				Message: "Ошибка базы данных: " + err.Error(),
-				Error:   "DATABASE_ERROR",
-			})
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		}
 		return
 	}
@@ -102,7 +169,7 @@ func GetByID(c *gin.Context) {
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Пользователь найден",
-		Data:    user,
+		Data:    withBalances(user),
 	})
 }
 
@@ -111,58 +178,58 @@ func UpdateProfile(c *gin.Context) {
 
 	userID, err := strconv.Atoi(userIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Неверный формат параметра 'id'",
-			Error:   "INVALID_ID",
-		})
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	if !managesProfile(c, int64(userID)) {
 		return
 	}
 
 	var updateData struct {
-		Name        *string `json:"name"`
-		Surname     *string `json:"surname"`
-		PhoneNumber *string `json:"phone_number"`
+		Name        *string `json:"name" form:"name" binding:"omitempty,person_name"`
+		Surname     *string `json:"surname" form:"surname" binding:"omitempty,person_name"`
+		PhoneNumber *string `json:"phone_number" form:"phone_number" binding:"omitempty,phone"`
 	}
 
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Неверный формат данных: " + err.Error(),
-			Error:   "INVALID_JSON",
-		})
+	if err := validation.Bind(c, &updateData); err != nil {
+		c.Error(err)
 		return
 	}
 
 	if updateData.Name == nil && updateData.Surname == nil && updateData.PhoneNumber == nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Необходимо указать хотя бы одно поле для обновления",
-			Error:   "NO_FIELDS_TO_UPDATE",
-		})
+		c.Error(apierr.New(http.StatusBadRequest, "NO_FIELDS_TO_UPDATE"))
 		return
 	}
 
 	var exists bool
 	err = database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка базы данных: " + err.Error(),
-			Error:   "DATABASE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		return
 	}
 
 	if !exists {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
 		return
 	}
 
+	if updateData.PhoneNumber != nil {
+		var taken bool
+		err = database.DB.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM users WHERE phone_number = ? AND id <> ?)",
+			*updateData.PhoneNumber, userID,
+		).Scan(&taken)
+		if err != nil {
+			c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
+			return
+		}
+		if taken {
+			c.Error(apierr.New(http.StatusConflict, "PHONE_TAKEN"))
+			return
+		}
+	}
+
 	query := "UPDATE users SET "
 	args := []interface{}{}
 	updates := []string{}
@@ -187,60 +254,65 @@ func UpdateProfile(c *gin.Context) {
 	query += " WHERE id = ?"
 	args = append(args, userID)
 
-	result, err := database.DB.Exec(query, args...)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Не удалось обновить профиль: " + err.Error(),
-			Error:   "UPDATE_ERROR",
-		})
-		return
+	changed := make(map[string]interface{})
+	if updateData.Name != nil {
+		changed["name"] = *updateData.Name
 	}
+	if updateData.Surname != nil {
+		changed["surname"] = *updateData.Surname
+	}
+	if updateData.PhoneNumber != nil {
+		changed["phone_number"] = *updateData.PhoneNumber
+	}
+
+	var rowsAffected int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(query, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		payload := map[string]interface{}{"changed": changed}
+		return events.Record(tx, events.ForUser(events.ProfileUpdated, int64(userID), payload))
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке результата",
-			Error:   "RESULT_CHECK_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "UPDATE_ERROR"))
 		return
 	}
 
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь не был обновлен",
-			Error:   "NOT_UPDATED",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "NOT_UPDATED"))
 		return
 	}
 
 	var user types.UserResponse
 	err = database.DB.QueryRow(
-		"SELECT id, name, surname, phone_number, balance FROM users WHERE id = ?",
+		"SELECT id, name, surname, phone_number, balance, "+ledger.PendingSQL+" FROM users WHERE id = ?",
 		userID,
 	).Scan(
 		&user.ID,
 		&user.Name,
 		&user.Surname,
 		&user.PhoneNumber,
-		&user.Balance,
+		&user.LedgerBalance,
+		&user.Pending,
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка получения обновленных данных: " + err.Error(),
-			Error:   "FETCH_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "FETCH_ERROR"))
 		return
 	}
 
+	audit.LogRequest(c, auth.CurrentUserID(c), audit.EventProfileUpdated, audit.UserSubject(int64(userID)), audit.OutcomeSuccess, changed)
+
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Профиль успешно обновлен",
-		Data:    user,
+		Data:    withBalances(user),
 	})
 }
 
@@ -249,63 +321,52 @@ func UserDelete(c *gin.Context) {
 
 	userID, err := strconv.Atoi(userIDParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, types.Response{
-			Success: false,
-			Message: "Неверный формат параметра 'id'",
-			Error:   "INVALID_ID",
-		})
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": "id"}))
+		return
+	}
+
+	if !managesProfile(c, int64(userID)) {
 		return
 	}
 
 	var exists bool
 	err = database.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = ?)", userID).Scan(&exists)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка базы данных: " + err.Error(),
-			Error:   "DATABASE_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "DATABASE_ERROR"))
 		return
 	}
 
 	if !exists {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь не найден",
-			Error:   "USER_NOT_FOUND",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_FOUND"))
 		return
 	}
 
-	result, err := database.DB.Exec("DELETE FROM users WHERE id = ?", userID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Не удалось удалить пользователя: " + err.Error(),
-			Error:   "USER_DELETE_ERROR",
-		})
-		return
-	}
-
-	rowsAffected, err := result.RowsAffected()
+	var rowsAffected int64
+	err = database.WithTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("DELETE FROM users WHERE id = ?", userID)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		if err != nil || rowsAffected == 0 {
+			return err
+		}
+		return events.Record(tx, events.ForUser(events.UserDeleted, int64(userID), map[string]interface{}{
+			"deleted_by": auth.CurrentUserID(c),
+		}))
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.Response{
-			Success: false,
-			Message: "Ошибка при проверке результата",
-			Error:   "RESULT_CHECK_ERROR",
-		})
+		c.Error(apierr.Wrap(err, http.StatusInternalServerError, "USER_DELETE_ERROR"))
 		return
 	}
 
 	if rowsAffected == 0 {
-		c.JSON(http.StatusNotFound, types.Response{
-			Success: false,
-			Message: "Пользователь не был удален",
-			Error:   "USER_NOT_DELETED",
-		})
+		c.Error(apierr.New(http.StatusNotFound, "USER_NOT_DELETED"))
 		return
 	}
 
+	audit.LogRequest(c, auth.CurrentUserID(c), audit.EventUserDeleted, audit.UserSubject(int64(userID)), audit.OutcomeSuccess, nil)
+
 	c.JSON(http.StatusOK, types.Response{
 		Success: true,
 		Message: "Пользователь успешно удален",
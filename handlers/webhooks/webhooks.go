@@ -0,0 +1,258 @@
+// Package webhooks даёт клиентам регистрировать адреса для вебхуков,
+// смотреть журнал доставок и отправлять доставки заново.
+package webhooks
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/apierr"
+	"backend_golang/database"
+	"backend_golang/events"
+	"backend_golang/handlers/auth"
+	"backend_golang/i18n"
+	"backend_golang/types"
+	"backend_golang/validation"
+	"backend_golang/webhooks"
+)
+
+// Create регистрирует адрес (url, event_types, description).
+// Секрет подписи возвращается только в этом ответе.
+func Create(c *gin.Context) {
+	var req struct {
+		URL         string   `json:"url" form:"url"`
+		EventTypes  []string `json:"event_types" form:"event_types"`
+		Description string   `json:"description" form:"description"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()}))
+		return
+	}
+	if req.URL == "" {
+		c.Error(apierr.Missing("url"))
+		return
+	}
+	if len(req.EventTypes) == 0 {
+		req.EventTypes = []string{webhooks.AllEvents}
+	}
+
+	endpoint, err := webhooks.CreateEndpoint(database.DB, auth.CurrentUserID(c), req.URL, req.EventTypes, req.Description)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.Response{
+		Success: true,
+		Message: "Адрес для вебхуков зарегистрирован; сохраните секрет, он больше не будет показан",
+		Data:    endpoint,
+	})
+}
+
+// List возвращает адреса клиента и типы событий, на которые можно подписаться
+func List(c *gin.Context) {
+	endpoints, err := webhooks.ListEndpoints(database.DB, auth.CurrentUserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Адреса для вебхуков",
+		Data: map[string]interface{}{
+			"endpoints":   endpoints,
+			"event_types": events.Types,
+		},
+	})
+}
+
+func GetByID(c *gin.Context) {
+	endpoint, ok := ownEndpoint(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Адрес для вебхуков",
+		Data:    endpoint,
+	})
+}
+
+// Update меняет url, event_types, description или active
+func Update(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL         *string  `json:"url" form:"url"`
+		EventTypes  []string `json:"event_types" form:"event_types"`
+		Description *string  `json:"description" form:"description"`
+		Active      *bool    `json:"active" form:"active"`
+	}
+	if err := validation.Bind(c, &req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	endpoint, err := webhooks.UpdateEndpoint(database.DB, auth.CurrentUserID(c), id, req.URL, req.EventTypes, req.Description, req.Active)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Адрес для вебхуков обновлён",
+		Data:    endpoint,
+	})
+}
+
+// Delete удаляет адрес вместе с журналом доставок
+func Delete(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	err := database.WithTx(func(tx *sql.Tx) error {
+		return webhooks.DeleteEndpoint(tx, auth.CurrentUserID(c), id)
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Адрес для вебхуков удалён",
+	})
+}
+
+// RotateSecret выдаёт новый секрет подписи
+func RotateSecret(c *gin.Context) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	endpoint, err := webhooks.RotateSecret(database.DB, auth.CurrentUserID(c), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Секрет обновлён; прежний больше не действует",
+		Data:    endpoint,
+	})
+}
+
+// Deliveries возвращает журнал доставок адреса (?status=pending|delivered|dead)
+func Deliveries(c *gin.Context) {
+	endpoint, ok := ownEndpoint(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !webhooks.IsDeliveryStatus(status) {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_STATUS"))
+		return
+	}
+
+	deliveries, err := webhooks.ListDeliveries(database.DB, endpoint.ID, status)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Журнал доставок",
+		Data:    deliveries,
+	})
+}
+
+// Delivery возвращает доставку с телом события и всеми попытками
+func Delivery(c *gin.Context) {
+	endpoint, ok := ownEndpoint(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := webhooks.GetDelivery(database.DB, endpoint.ID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.Response{
+		Success: true,
+		Message: "Доставка",
+		Data:    delivery,
+	})
+}
+
+// Redeliver ставит доставку в очередь заново
+func Redeliver(c *gin.Context) {
+	endpoint, ok := ownEndpoint(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := webhooks.Redeliver(database.DB, endpoint.ID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, types.Response{
+		Success: true,
+		Message: "Доставка поставлена в очередь",
+		Data:    delivery,
+	})
+}
+
+func ownEndpoint(c *gin.Context) (webhooks.Endpoint, bool) {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return webhooks.Endpoint{}, false
+	}
+
+	endpoint, err := webhooks.GetEndpoint(database.DB, auth.CurrentUserID(c), id)
+	if err != nil {
+		respondError(c, err)
+		return endpoint, false
+	}
+	return endpoint, true
+}
+
+func paramID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.Error(apierr.New(http.StatusBadRequest, "INVALID_ID", i18n.Args{"param": name}))
+		return 0, false
+	}
+	return id, true
+}
+
+// respondError передаёт ошибку в apierr.Handler; sql.ErrNoRows — подписка или доставка не найдены
+func respondError(c *gin.Context, err error) {
+	c.Error(apierr.NoRows(err, "NOT_FOUND.webhook"))
+}
@@ -0,0 +1,174 @@
+package i18n
+
+// en сообщения на английском; ключи те же, что в ru
+var en = map[string]string{
+	"ACCOUNT_NOT_FOUND":                    "Account not found",
+	"ALREADY_HOLDER":                       "The user already has access or a pending invitation",
+	"AUTHORIZATION_NOT_FOUND":              "Authorization not found",
+	"CARD_EXPIRED":                         "The card has expired",
+	"CARD_FROZEN":                          "The card is frozen",
+	"CARD_ISSUE_ERROR":                     "Failed to issue the card",
+	"CARD_LIMIT_EXCEEDED":                  "Card limit exceeded",
+	"CARD_NOT_FOUND":                       "Card not found",
+	"CARD_UPDATE_ERROR":                    "Failed to update the card",
+	"COMPLIANCE_BLOCKED":                   "The account has been blocked by compliance",
+	"COMPLIANCE_REVIEW":                    "The account is under compliance review",
+	"DATABASE_ERROR":                       "Database error",
+	"DELIVERY_PENDING":                     "The delivery is still queued",
+	"DEPENDENT_PROFILE":                    "This operation is not available for a dependent profile",
+	"DEPENDENT_PROFILE.holders":            "A dependent profile cannot take part in joint accounts",
+	"DEPENDENT_PROFILE.loans":              "Loans are not available for a dependent profile",
+	"DOCUMENTS_LOCKED":                     "Documents cannot be changed while the application is under review or after a decision",
+	"DOCUMENT_FILE_MISSING":                "The document file is missing from storage",
+	"DUPLICATE_MESSAGE":                    "A message with this identifier has already been uploaded",
+	"EMPTY_FILE":                           "The file has no rows",
+	"FETCH_ERROR":                          "Failed to load data",
+	"FIELD.GT":                             "Field {{.field}} must be greater than {{.param}}",
+	"FIELD.GTE":                            "Field {{.field}} must be at least {{.param}}",
+	"FIELD.INVALID":                        "Field {{.field}} is invalid",
+	"FIELD.LEN":                            "Field {{.field}} must have exactly {{.param}} {{plural .param \"item\" \"items\"}}",
+	"FIELD.LENGTH":                         "Field {{.field}} must be exactly {{.param}} {{plural .param \"character\" \"characters\"}} long",
+	"FIELD.LT":                             "Field {{.field}} must be less than {{.param}}",
+	"FIELD.LTE":                            "Field {{.field}} must be at most {{.param}}",
+	"FIELD.MAX":                            "Field {{.field}} must be at most {{.param}}",
+	"FIELD.MAX_LENGTH":                     "Field {{.field}} must be at most {{.param}} {{plural .param \"character\" \"characters\"}} long",
+	"FIELD.MIN":                            "Field {{.field}} must be at least {{.param}}",
+	"FIELD.MIN_LENGTH":                     "Field {{.field}} must be at least {{.param}} {{plural .param \"character\" \"characters\"}} long",
+	"FIELD.ONEOF":                          "Field {{.field}} must be one of: {{.param}}",
+	"FIELD.PASSWORD_COMMON":                "This password is too common, please choose another one",
+	"FIELD.PASSWORD_TOO_LONG":              "Password must be at most {{.max}} {{plural .max \"byte\" \"bytes\"}} long",
+	"FIELD.PASSWORD_TOO_SHORT":             "Password must be at least {{.min}} {{plural .min \"character\" \"characters\"}} long",
+	"FIELD.PERSON_NAME":                    "Field {{.field}} may only contain letters, spaces, hyphens and apostrophes, up to {{.max}} {{plural .max \"character\" \"characters\"}}",
+	"FIELD.PHONE":                          "Field {{.field}} must be a phone number, e.g. +79123456789",
+	"FIELD.REQUIRED":                       "Field {{.field}} is required",
+	"FILE_TOO_LARGE":                       "The file is larger than {{.max_mb}} MB",
+	"FORBIDDEN":                            "Insufficient permissions",
+	"FORBIDDEN.account":                    "Insufficient permissions for this account",
+	"FORBIDDEN.owner_only":                 "Only the account owner can use this section",
+	"FRAUD_BLOCKED":                        "The transfer has been blocked by the security service",
+	"GOAL_CLOSED":                          "The goal is closed",
+	"GOAL_CREATE_ERROR":                    "Failed to create the goal",
+	"GOAL_NOT_FOUND":                       "Goal not found",
+	"HASH_ERROR":                           "Hashing failed",
+	"HOLD_NOT_ACTIVE":                      "This hold cannot be released manually",
+	"HOLD_NOT_FOUND":                       "Hold not found",
+	"ID_ERROR":                             "Failed to get the record ID",
+	"INSUFFICIENT_FUNDS":                   "Insufficient funds",
+	"INTERNAL_ERROR":                       "Internal server error. Reference: {{.correlation_id}}",
+	"INVALID_ACCOUNT":                      "Invalid account code, expected user:ID",
+	"INVALID_ACCOUNT_ID":                   "Invalid {{.header}} header",
+	"INVALID_AMOUNT":                       "Invalid amount",
+	"INVALID_AMOUNT.below_interest":        "The amount does not cover the interest accrued since the last payment",
+	"INVALID_AMOUNT.exceeds_authorization": "The amount exceeds the authorized amount",
+	"INVALID_AMOUNT.positive":              "The amount must be positive",
+	"INVALID_AUTHORIZATION_STATE":          "Not allowed in the current authorization status",
+	"INVALID_BATCH_STATE":                  "Not allowed in the current batch status",
+	"INVALID_CARD":                         "Invalid card number",
+	"INVALID_CHANNEL":                      "Channel must be inbox, push, email or sms",
+	"INVALID_CVV":                          "Invalid CVV",
+	"INVALID_DATE":                         "Date {{.param}} must be in YYYY-MM-DD format",
+	"INVALID_DOCUMENT_TYPE":                "document_type must be passport, id_card, driver_license, proof_of_address or selfie",
+	"INVALID_EMAIL":                        "Invalid email address",
+	"INVALID_EVENT":                        "Unknown event; see GET /notifications/settings for the list",
+	"INVALID_EVENT_TYPES":                  "Unknown event type; see GET /webhooks for the list",
+	"INVALID_FILE":                         "Failed to read the file: {{.error}}",
+	"INVALID_FORMAT":                       "Unknown statement format: {{.format}}",
+	"INVALID_ID":                           "Invalid '{{.param}}' parameter",
+	"INVALID_JSON":                         "Invalid request data: {{.error}}",
+	"INVALID_KYC_STATUS":                   "Not allowed in the current identification status",
+	"INVALID_LAST_EVENT_ID":                "Invalid Last-Event-ID",
+	"INVALID_LEVEL":                        "Unknown identification level",
+	"INVALID_LIMIT":                        "Limits cannot be negative",
+	"INVALID_LIMIT.transfer":               "The transfer permission requires a positive limit",
+	"INVALID_LOAN_STATE":                   "Not allowed in the current loan status",
+	"INVALID_LOAN_TERMS":                   "Amount, term or schedule type is out of range",
+	"INVALID_LOCALE":                       "Language must be ru or en",
+	"INVALID_NETWORK_KEY":                  "Invalid card network key",
+	"INVALID_OVERDRAFT":                    "The limit must be between 0 and {{.max}} and the rate non-negative",
+	"INVALID_PAIN001":                      "The message does not match the pain.001 structure",
+	"INVALID_PASSWORD":                     "Wrong password",
+	"INVALID_PERIOD":                       "The period is set by from and to dates in YYYY-MM-DD format, from not after to",
+	"INVALID_PERMISSION":                   "Permission must be view, transfer or full",
+	"INVALID_PLATFORM":                     "Platform must be ios, android or web",
+	"INVALID_QUIET_HOURS":                  "Quiet hours are set as HH:MM and start must differ from end",
+	"INVALID_RATE":                         "A non-negative rate and a YYYY-MM-DD date are required",
+	"INVALID_REASON_CODE":                  "Unknown reversal reason code",
+	"INVALID_RULE":                         "Invalid rule: round_up with a step above 0 or percent_incoming from 0 to 100",
+	"INVALID_SCHEDULE":                     "Amount, term in months and schedule type (annuity or differentiated) are required",
+	"INVALID_SESSION":                      "Invalid session",
+	"INVALID_STATUS":                       "status must be pending, delivered or dead",
+	"INVALID_TARGET_DATE":                  "The target date must be in the future, format YYYY-MM-DD",
+	"INVALID_TIMEZONE":                     "Unknown time zone; use a name like Europe/Moscow",
+	"INVALID_URL":                          "The URL must be https",
+	"INVALID_URL.private_address":          "The URL must point to a public address",
+	"INVALID_XML":                          "Invalid XML: {{.error}}",
+	"INVITATION_NOT_PENDING":               "The invitation has already been processed",
+	"KYC_LIMIT_EXCEEDED":                   "The limit for your identification level is exceeded{{if .error}} ({{.error}}){{end}}, please complete identification",
+	"LOAN_CREATE_ERROR":                    "Failed to create the application",
+	"LOAN_NOT_FOUND":                       "Loan not found",
+	"LOAN_OVERDUE":                         "Overdue payments must be repaid first",
+	"LOGIN_BLOCKED":                        "Sign-in has been blocked by the security service",
+	"LOGIN_UNDER_REVIEW":                   "Sign-in from this device is under review, please try again later",
+	"MISSING_DOCUMENTS":                    "An identity document (passport, id_card or driver_license) and a selfie are required",
+	"MISSING_FIELDS":                       "Required fields are missing: {{.fields}}",
+	"MISSING_SESSION":                      "A session is required",
+	"NOT_FOUND":                            "Not found",
+	"NOT_FOUND.kyc":                        "Customer or document not found",
+	"NOT_FOUND.notification":               "Notification or device not found",
+	"NOT_FOUND.webhook":                    "Endpoint or delivery not found",
+	"NOT_REVERSIBLE":                       "The transaction cannot be reversed",
+	"NOT_UPDATED":                          "The user was not updated",
+	"NO_FIELDS_TO_UPDATE":                  "At least one field to update is required",
+	"OVERDRAFT_NOT_FOUND":                  "Overdraft is not enabled",
+	"OVERDRAFT_UPDATE_ERROR":               "Failed to save the overdraft",
+	"PASSWORD_CHECK_ERROR":                 "Failed to check the password",
+	"PASSWORD_UPDATE_ERROR":                "Failed to change the password",
+	"PAYMENT_REQUEST_CREATE_ERROR":         "Failed to create the request",
+	"PAYMENT_REQUEST_EXPIRED":              "The request has expired",
+	"PAYMENT_REQUEST_NOT_FOUND":            "Payment request not found",
+	"PAYMENT_REQUEST_NOT_PENDING":          "The payment request has already been processed",
+	"PAYOUT_BATCH_NOT_FOUND":               "Payout batch not found",
+	"PHONE_TAKEN":                          "This phone number already belongs to another user",
+	"RATE_CREATE_ERROR":                    "Failed to add the rate",
+	"RECIPIENT_BLOCKED":                    "Transfers to this recipient are blocked by compliance",
+	"RECIPIENT_NOT_FOUND":                  "Recipient not found",
+	"REGISTRATION_ERROR":                   "Failed to register the user",
+	"RESULT_CHECK_ERROR":                   "Failed to check the result",
+	"RETROACTIVE_RATE":                     "A rate cannot be applied retroactively",
+	"REVERSAL_CREATE_ERROR":                "Failed to create the reversal request",
+	"REVERSAL_EXCEEDS_AMOUNT":              "The reversal amount exceeds the transaction amount",
+	"REVERSAL_NOT_FOUND":                   "Reversal request not found",
+	"REVERSAL_NOT_PENDING":                 "The reversal request has already been processed",
+	"REVERSAL_PENDING":                     "There is already a reversal request for this transaction",
+	"REVIEW_NOT_FOUND":                     "Review not found",
+	"REVIEW_NOT_PENDING":                   "A decision has already been made",
+	"ROUTE_NOT_FOUND":                      "No such endpoint",
+	"RULE_CREATE_ERROR":                    "Failed to create the rule",
+	"RULE_NOT_FOUND":                       "Rule not found",
+	"SAVINGS_CREATE_ERROR":                 "Failed to open the account",
+	"SAVINGS_NOT_FOUND":                    "Savings account not found",
+	"SCAN_ERROR":                           "Failed to read data",
+	"SCHEDULE_ERROR":                       "Failed to calculate the schedule",
+	"SELF_INVITE":                          "You cannot invite yourself",
+	"SELF_TRANSFER":                        "You cannot transfer money to yourself",
+	"SELF_TRANSFER.payment_request":        "You cannot pay your own request",
+	"SESSION_CREATE_ERROR":                 "Failed to create a session",
+	"SESSION_DELETE_ERROR":                 "Failed to delete the session",
+	"SESSION_UPDATE_ERROR":                 "Failed to update the session",
+	"SPENDING_LIMIT_EXCEEDED":              "Account spending limit exceeded",
+	"STEP_UP_REQUIRED":                     "Confirm the transfer with your password: repeat the request with the password field",
+	"TOO_MANY_ROWS":                        "The file has more than {{.max}} {{plural .max \"row\" \"rows\"}}",
+	"TOO_MANY_STREAMS":                     "{{.max}} {{plural .max \"stream is\" \"streams are\"}} already open, which is the maximum",
+	"TRANSACTION_NOT_FOUND":                "Transaction not found",
+	"UNSUPPORTED_CURRENCY":                 "Currency is not supported",
+	"UNSUPPORTED_FILE_TYPE":                "Only JPEG, PNG and PDF are accepted (images only for selfie)",
+	"UNSUPPORTED_MEDIA_TYPE":               "Content type {{.type}} is not supported: send JSON, form-urlencoded or multipart/form-data",
+	"UPDATE_ERROR":                         "Failed to update the profile",
+	"USER_DATA_ERROR":                      "Failed to load user data",
+	"USER_DELETE_ERROR":                    "Failed to delete the user",
+	"USER_EXISTS":                          "A user with this phone number already exists",
+	"USER_NOT_DELETED":                     "The user was not deleted",
+	"USER_NOT_FOUND":                       "User not found",
+	"USER_NOT_FOUND.session":               "No user with this session",
+	"VALIDATION_FAILED":                    "Invalid fields: {{.fields}}",
+}
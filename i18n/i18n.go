@@ -0,0 +1,254 @@
+// Package i18n переводит сообщения ответов API. Текст берётся из каталога
+// по коду ошибки (USER_NOT_FOUND, MISSING_FIELDS, ...); если у кода есть
+// несколько формулировок, они различаются суффиксом: SELF_TRANSFER.payment_request.
+// Язык — тот, что клиент выбрал в настройках, иначе по Accept-Language,
+// иначе types.DefaultLanguage.
+//
+// Сообщения — шаблоны text/template: параметры передаются в Args
+// ({{.error}}, {{.fields}}), а {{plural .n "форма" "формы" "форм"}} выбирает
+// форму слова по правилам языка.
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+
+	"backend_golang/types"
+)
+
+// Языки
+const (
+	RU = "ru"
+	EN = "en"
+)
+
+// Languages поддерживаемые языки
+var Languages = []string{RU, EN}
+
+// Args параметры сообщения
+type Args map[string]interface{}
+
+var catalogs = map[string]map[string]string{
+	RU: ru,
+	EN: en,
+}
+
+var (
+	compiled    = make(map[string]map[string]*template.Template)
+	parseErrors []error
+)
+
+func init() {
+	for lang, messages := range catalogs {
+		compiled[lang] = make(map[string]*template.Template, len(messages))
+		funcs := template.FuncMap{"plural": pluralFunc(lang)}
+		for key, text := range messages {
+			tmpl, err := template.New(key).Funcs(funcs).Option("missingkey=zero").Parse(text)
+			if err != nil {
+				parseErrors = append(parseErrors, fmt.Errorf("%s %s: %w", lang, key, err))
+				continue
+			}
+			compiled[lang][key] = tmpl
+		}
+	}
+}
+
+const languageKey = "language"
+
+// SetLanguage закрепляет язык ответа на запрос; неподдерживаемый язык
+// игнорируется
+func SetLanguage(c *gin.Context, lang string) {
+	if Supported(lang) {
+		c.Set(languageKey, lang)
+	}
+}
+
+// Language язык ответа на запрос
+func Language(c *gin.Context) string {
+	if lang := c.GetString(languageKey); lang != "" {
+		return lang
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// Supported проверяет, что язык есть в каталоге
+func Supported(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate выбирает язык по заголовку Accept-Language (RFC 9110):
+// поддерживаемый язык с наибольшим весом q, при равных весах — первый
+func Negotiate(header string) string {
+	best, bestQ := types.DefaultLanguage, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		// en-US, en_GB и EN считаются английским
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		primary, _, _ = strings.Cut(primary, "_")
+		if primary == "*" {
+			primary = types.DefaultLanguage
+		}
+		if q > bestQ && Supported(primary) {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// T сообщение key на языке запроса. Заодно выставляет Content-Language,
+// а Vary говорит кешам, что ответ зависит от Accept-Language.
+func T(c *gin.Context, key string, args ...Args) string {
+	lang := Language(c)
+	c.Header("Content-Language", lang)
+	if !strings.Contains(c.Writer.Header().Get("Vary"), "Accept-Language") {
+		c.Writer.Header().Add("Vary", "Accept-Language")
+	}
+	return Translate(lang, key, args...)
+}
+
+// Translate сообщение key на языке lang. Если перевода нет, берётся язык по
+// умолчанию, а если нет и его — возвращается сам key.
+func Translate(lang, key string, args ...Args) string {
+	tmpl := compiled[lang][key]
+	if tmpl == nil {
+		tmpl = compiled[types.DefaultLanguage][key]
+	}
+	if tmpl == nil {
+		log.Printf("⚠️ No message for %s", key)
+		return key
+	}
+
+	data := make(map[string]interface{})
+	for _, a := range args {
+		for k, v := range a {
+			data[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("⚠️ Message %s/%s failed: %v", lang, key, err)
+		return key
+	}
+	return buf.String()
+}
+
+// Keys все ключи каталога
+func Keys() []string {
+	seen := make(map[string]bool)
+	for _, messages := range catalogs {
+		for key := range messages {
+			seen[key] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has проверяет, что у кода или его варианта есть сообщение
+func Has(key string) bool {
+	_, ok := catalogs[types.DefaultLanguage][key]
+	return ok
+}
+
+// Check проверяет, что у каждого ключа есть перевод на все языки
+// и что все шаблоны разбираются
+func Check() error {
+	var problems []string
+	for _, err := range parseErrors {
+		problems = append(problems, err.Error())
+	}
+	for _, key := range Keys() {
+		for _, lang := range Languages {
+			if _, ok := catalogs[lang][key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: no translation for %s", lang, key))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("i18n: %d problem(s):\n  %s", len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// pluralFunc выбирает форму слова по числу: для русского — одна, две и пять
+// (1 строка, 2 строки, 5 строк), для английского — одна и много
+func pluralFunc(lang string) func(n interface{}, forms ...string) string {
+	return func(n interface{}, forms ...string) string {
+		if len(forms) == 0 {
+			return ""
+		}
+		form := 0
+		switch lang {
+		case RU:
+			form = pluralRU(n)
+		default:
+			if value, ok := integer(n); !ok || value != 1 {
+				form = 1
+			}
+		}
+		if form >= len(forms) {
+			form = len(forms) - 1
+		}
+		return forms[form]
+	}
+}
+
+func pluralRU(n interface{}) int {
+	value, ok := integer(n)
+	if !ok {
+		// дробные: «2,5 строки»
+		return 1
+	}
+	if value < 0 {
+		value = -value
+	}
+	switch {
+	case value%10 == 1 && value%100 != 11:
+		return 0
+	case value%10 >= 2 && value%10 <= 4 && (value%100 < 12 || value%100 > 14):
+		return 1
+	}
+	return 2
+}
+
+// integer целое значение числа; ok=false для дробных и не чисел
+func integer(n interface{}) (int64, bool) {
+	switch v := n.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,78 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", RU},
+		{"en", EN},
+		{"en-US,en;q=0.9", EN},
+		{"EN_gb", EN},
+		{"de-DE,de;q=0.9", RU},
+		{"de,en;q=0.5", EN},
+		{"ru;q=0.3,en;q=0.7", EN},
+		{"en;q=0.5,ru;q=0.5", EN},
+		{"en;q=0,ru;q=0.1", RU},
+		{"en;q=0", RU},
+		{"*", RU},
+		{"fr, *;q=0.1, en;q=0.2", EN},
+		{"en;q=abc, ru;q=0.1", RU},
+		{" en ; q = 0.8 , ru ; q = 0.9 ", RU},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	ru := pluralFunc(RU)
+	en := pluralFunc(EN)
+	tests := []struct {
+		n      interface{}
+		ru, en string
+	}{
+		{0, "строк", "lines"},
+		{1, "строка", "line"},
+		{2, "строки", "lines"},
+		{4, "строки", "lines"},
+		{5, "строк", "lines"},
+		{11, "строк", "lines"},
+		{12, "строк", "lines"},
+		{14, "строк", "lines"},
+		{21, "строка", "lines"},
+		{22, "строки", "lines"},
+		{101, "строка", "lines"},
+		{111, "строк", "lines"},
+		{-1, "строка", "lines"},
+		{int64(1), "строка", "line"},
+		{float64(1), "строка", "line"},
+		{2.5, "строки", "lines"},
+		{"1", "строки", "lines"},
+	}
+	for _, tt := range tests {
+		if got := ru(tt.n, "строка", "строки", "строк"); got != tt.ru {
+			t.Errorf("plural ru %v = %q, want %q", tt.n, got, tt.ru)
+		}
+		if got := en(tt.n, "line", "lines"); got != tt.en {
+			t.Errorf("plural en %v = %q, want %q", tt.n, got, tt.en)
+		}
+	}
+
+	if got := ru(5, "строка", "строки"); got != "строки" {
+		t.Errorf("plural ru with two forms = %q, want the last one", got)
+	}
+	if got := en(5); got != "" {
+		t.Errorf("plural without forms = %q, want empty", got)
+	}
+}
+
+func TestCatalogue(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,174 @@
+package i18n
+
+// ru сообщения на русском: ключ — код ошибки или код.вариант
+var ru = map[string]string{
+	"ACCOUNT_NOT_FOUND":                    "Счёт не найден",
+	"ALREADY_HOLDER":                       "У пользователя уже есть доступ или приглашение",
+	"AUTHORIZATION_NOT_FOUND":              "Авторизация не найдена",
+	"CARD_EXPIRED":                         "Срок действия карты истёк",
+	"CARD_FROZEN":                          "Карта заморожена",
+	"CARD_ISSUE_ERROR":                     "Не удалось выпустить карту",
+	"CARD_LIMIT_EXCEEDED":                  "Превышен лимит карты",
+	"CARD_NOT_FOUND":                       "Карта не найдена",
+	"CARD_UPDATE_ERROR":                    "Не удалось изменить карту",
+	"COMPLIANCE_BLOCKED":                   "Учётная запись заблокирована службой комплаенса",
+	"COMPLIANCE_REVIEW":                    "Учётная запись на проверке службы комплаенса",
+	"DATABASE_ERROR":                       "Ошибка базы данных",
+	"DELIVERY_PENDING":                     "Доставка ещё в очереди",
+	"DEPENDENT_PROFILE":                    "Операция недоступна для детского профиля",
+	"DEPENDENT_PROFILE.holders":            "Детский профиль не может участвовать в совместных счетах",
+	"DEPENDENT_PROFILE.loans":              "Кредиты недоступны для детского профиля",
+	"DOCUMENTS_LOCKED":                     "Документы нельзя менять, пока заявка на проверке или решение принято",
+	"DOCUMENT_FILE_MISSING":                "Файл документа не найден в хранилище",
+	"DUPLICATE_MESSAGE":                    "Сообщение с таким идентификатором уже загружено",
+	"EMPTY_FILE":                           "В файле нет строк",
+	"FETCH_ERROR":                          "Ошибка получения данных",
+	"FIELD.GT":                             "Поле {{.field}} должно быть больше {{.param}}",
+	"FIELD.GTE":                            "Поле {{.field}} должно быть не меньше {{.param}}",
+	"FIELD.INVALID":                        "Поле {{.field}} заполнено неверно",
+	"FIELD.LEN":                            "Поле {{.field}} должно содержать ровно {{.param}} {{plural .param \"элемент\" \"элемента\" \"элементов\"}}",
+	"FIELD.LENGTH":                         "Поле {{.field}} должно содержать ровно {{.param}} {{plural .param \"символ\" \"символа\" \"символов\"}}",
+	"FIELD.LT":                             "Поле {{.field}} должно быть меньше {{.param}}",
+	"FIELD.LTE":                            "Поле {{.field}} должно быть не больше {{.param}}",
+	"FIELD.MAX":                            "Поле {{.field}} должно быть не больше {{.param}}",
+	"FIELD.MAX_LENGTH":                     "Поле {{.field}} должно быть не длиннее {{.param}} {{plural .param \"символа\" \"символов\" \"символов\"}}",
+	"FIELD.MIN":                            "Поле {{.field}} должно быть не меньше {{.param}}",
+	"FIELD.MIN_LENGTH":                     "Поле {{.field}} должно быть не короче {{.param}} {{plural .param \"символа\" \"символов\" \"символов\"}}",
+	"FIELD.ONEOF":                          "Поле {{.field}} должно быть одним из: {{.param}}",
+	"FIELD.PASSWORD_COMMON":                "Этот пароль слишком распространён, выберите другой",
+	"FIELD.PASSWORD_TOO_LONG":              "Пароль должен быть не длиннее {{.max}} {{plural .max \"байта\" \"байт\" \"байт\"}}",
+	"FIELD.PASSWORD_TOO_SHORT":             "Пароль должен быть не короче {{.min}} {{plural .min \"символа\" \"символов\" \"символов\"}}",
+	"FIELD.PERSON_NAME":                    "Поле {{.field}} может содержать только буквы, пробел, дефис и апостроф, не длиннее {{.max}} {{plural .max \"символа\" \"символов\" \"символов\"}}",
+	"FIELD.PHONE":                          "Поле {{.field}} должно содержать номер телефона, например +79123456789",
+	"FIELD.REQUIRED":                       "Поле {{.field}} обязательно",
+	"FILE_TOO_LARGE":                       "Файл больше {{.max_mb}} МБ",
+	"FORBIDDEN":                            "Недостаточно прав",
+	"FORBIDDEN.account":                    "Недостаточно прав на счёт",
+	"FORBIDDEN.owner_only":                 "Раздел доступен только владельцу счёта",
+	"FRAUD_BLOCKED":                        "Перевод заблокирован службой безопасности",
+	"GOAL_CLOSED":                          "Копилка закрыта",
+	"GOAL_CREATE_ERROR":                    "Не удалось создать копилку",
+	"GOAL_NOT_FOUND":                       "Копилка не найдена",
+	"HASH_ERROR":                           "Ошибка при хешировании",
+	"HOLD_NOT_ACTIVE":                      "Холд нельзя снять вручную",
+	"HOLD_NOT_FOUND":                       "Холд не найден",
+	"ID_ERROR":                             "Ошибка при получении ID записи",
+	"INSUFFICIENT_FUNDS":                   "Недостаточно средств",
+	"INTERNAL_ERROR":                       "Внутренняя ошибка сервера. Код обращения: {{.correlation_id}}",
+	"INVALID_ACCOUNT":                      "Неверный код счёта, ожидается вид user:ID",
+	"INVALID_ACCOUNT_ID":                   "Неверный формат заголовка {{.header}}",
+	"INVALID_AMOUNT":                       "Неверная сумма",
+	"INVALID_AMOUNT.below_interest":        "Сумма не покрывает проценты, набежавшие с последнего платежа",
+	"INVALID_AMOUNT.exceeds_authorization": "Сумма превышает сумму авторизации",
+	"INVALID_AMOUNT.positive":              "Сумма должна быть положительной",
+	"INVALID_AUTHORIZATION_STATE":          "Операция недоступна в текущем статусе авторизации",
+	"INVALID_BATCH_STATE":                  "Операция недоступна в текущем статусе пакета",
+	"INVALID_CARD":                         "Неверный номер карты",
+	"INVALID_CHANNEL":                      "Канал: inbox, push, email или sms",
+	"INVALID_CVV":                          "Неверный CVV",
+	"INVALID_DATE":                         "Дата {{.param}} ожидается в формате YYYY-MM-DD",
+	"INVALID_DOCUMENT_TYPE":                "document_type: passport, id_card, driver_license, proof_of_address или selfie",
+	"INVALID_EMAIL":                        "Неверный адрес e-mail",
+	"INVALID_EVENT":                        "Неизвестное событие; список событий — в GET /notifications/settings",
+	"INVALID_EVENT_TYPES":                  "Неизвестный тип события; список типов — в GET /webhooks",
+	"INVALID_FILE":                         "Не удалось прочитать файл: {{.error}}",
+	"INVALID_FORMAT":                       "Неизвестный формат выписки: {{.format}}",
+	"INVALID_ID":                           "Неверный формат параметра '{{.param}}'",
+	"INVALID_JSON":                         "Неверный формат данных: {{.error}}",
+	"INVALID_KYC_STATUS":                   "Действие недоступно в текущем статусе идентификации",
+	"INVALID_LAST_EVENT_ID":                "Неверный формат Last-Event-ID",
+	"INVALID_LEVEL":                        "Неизвестный уровень идентификации",
+	"INVALID_LIMIT":                        "Лимиты не могут быть отрицательными",
+	"INVALID_LIMIT.transfer":               "Для права transfer нужен положительный лимит",
+	"INVALID_LOAN_STATE":                   "Операция недоступна в текущем статусе кредита",
+	"INVALID_LOAN_TERMS":                   "Сумма, срок или тип графика вне допустимых значений",
+	"INVALID_LOCALE":                       "Язык: ru или en",
+	"INVALID_NETWORK_KEY":                  "Неверный ключ карточной сети",
+	"INVALID_OVERDRAFT":                    "Лимит должен быть от 0 до {{.max}}, ставка неотрицательной",
+	"INVALID_PAIN001":                      "Сообщение не соответствует структуре pain.001",
+	"INVALID_PASSWORD":                     "Неправильный пароль",
+	"INVALID_PERIOD":                       "Период задаётся датами from и to в формате YYYY-MM-DD, from не позже to",
+	"INVALID_PERMISSION":                   "Право должно быть view, transfer или full",
+	"INVALID_PLATFORM":                     "Платформа: ios, android или web",
+	"INVALID_QUIET_HOURS":                  "Тихие часы задаются как HH:MM, начало и конец должны различаться",
+	"INVALID_RATE":                         "Нужны неотрицательная ставка и дата в формате YYYY-MM-DD",
+	"INVALID_REASON_CODE":                  "Неизвестный код причины возврата",
+	"INVALID_RULE":                         "Неверное правило: round_up с шагом больше 0 или percent_incoming от 0 до 100",
+	"INVALID_SCHEDULE":                     "Нужны сумма, срок в месяцах и тип графика (annuity или differentiated)",
+	"INVALID_SESSION":                      "Сессия недействительна",
+	"INVALID_STATUS":                       "status: pending, delivered или dead",
+	"INVALID_TARGET_DATE":                  "Дата цели должна быть в будущем, формат YYYY-MM-DD",
+	"INVALID_TIMEZONE":                     "Неизвестный часовой пояс; укажите его как Europe/Moscow",
+	"INVALID_URL":                          "Адрес должен быть https",
+	"INVALID_URL.private_address":          "Адрес должен вести в публичную сеть",
+	"INVALID_XML":                          "Неверный XML: {{.error}}",
+	"INVITATION_NOT_PENDING":               "Приглашение уже обработано",
+	"KYC_LIMIT_EXCEEDED":                   "Превышен лимит операций для вашего уровня идентификации{{if .error}} ({{.error}}){{end}}, пройдите идентификацию",
+	"LOAN_CREATE_ERROR":                    "Не удалось создать заявку",
+	"LOAN_NOT_FOUND":                       "Кредит не найден",
+	"LOAN_OVERDUE":                         "Сначала нужно погасить просроченные платежи",
+	"LOGIN_BLOCKED":                        "Вход заблокирован службой безопасности",
+	"LOGIN_UNDER_REVIEW":                   "Вход с этого устройства отправлен на проверку, попробуйте позже",
+	"MISSING_DOCUMENTS":                    "Нужны удостоверение личности (passport, id_card или driver_license) и selfie",
+	"MISSING_FIELDS":                       "Обязательные поля не заполнены: {{.fields}}",
+	"MISSING_SESSION":                      "Сессия обязательна",
+	"NOT_FOUND":                            "Не найдено",
+	"NOT_FOUND.kyc":                        "Клиент или документ не найден",
+	"NOT_FOUND.notification":               "Уведомление или устройство не найдено",
+	"NOT_FOUND.webhook":                    "Адрес или доставка не найдены",
+	"NOT_REVERSIBLE":                       "Транзакцию нельзя вернуть",
+	"NOT_UPDATED":                          "Пользователь не был обновлен",
+	"NO_FIELDS_TO_UPDATE":                  "Необходимо указать хотя бы одно поле для обновления",
+	"OVERDRAFT_NOT_FOUND":                  "Овердрафт не подключён",
+	"OVERDRAFT_UPDATE_ERROR":               "Не удалось сохранить овердрафт",
+	"PASSWORD_CHECK_ERROR":                 "Ошибка проверки пароля",
+	"PASSWORD_UPDATE_ERROR":                "Не удалось сменить пароль",
+	"PAYMENT_REQUEST_CREATE_ERROR":         "Не удалось создать запрос",
+	"PAYMENT_REQUEST_EXPIRED":              "Срок действия запроса истёк",
+	"PAYMENT_REQUEST_NOT_FOUND":            "Запрос денег не найден",
+	"PAYMENT_REQUEST_NOT_PENDING":          "Запрос денег уже обработан",
+	"PAYOUT_BATCH_NOT_FOUND":               "Пакет выплат не найден",
+	"PHONE_TAKEN":                          "Этот номер телефона уже привязан к другому пользователю",
+	"RATE_CREATE_ERROR":                    "Не удалось добавить ставку",
+	"RECIPIENT_BLOCKED":                    "Переводы этому получателю запрещены службой комплаенса",
+	"RECIPIENT_NOT_FOUND":                  "Получатель не найден",
+	"REGISTRATION_ERROR":                   "Ошибка при регистрации пользователя",
+	"RESULT_CHECK_ERROR":                   "Ошибка при проверке результата",
+	"RETROACTIVE_RATE":                     "Ставку нельзя применить задним числом",
+	"REVERSAL_CREATE_ERROR":                "Не удалось создать запрос на возврат",
+	"REVERSAL_EXCEEDS_AMOUNT":              "Сумма возврата превышает сумму транзакции",
+	"REVERSAL_NOT_FOUND":                   "Запрос на возврат не найден",
+	"REVERSAL_NOT_PENDING":                 "Запрос на возврат уже обработан",
+	"REVERSAL_PENDING":                     "По этой транзакции уже есть запрос на возврат",
+	"REVIEW_NOT_FOUND":                     "Событие не найдено",
+	"REVIEW_NOT_PENDING":                   "Решение по событию уже принято",
+	"ROUTE_NOT_FOUND":                      "Такого адреса нет",
+	"RULE_CREATE_ERROR":                    "Не удалось создать правило",
+	"RULE_NOT_FOUND":                       "Правило не найдено",
+	"SAVINGS_CREATE_ERROR":                 "Не удалось открыть счёт",
+	"SAVINGS_NOT_FOUND":                    "Сберегательный счёт не найден",
+	"SCAN_ERROR":                           "Ошибка чтения данных",
+	"SCHEDULE_ERROR":                       "Ошибка расчёта графика",
+	"SELF_INVITE":                          "Нельзя пригласить самого себя",
+	"SELF_TRANSFER":                        "Нельзя перевести деньги самому себе",
+	"SELF_TRANSFER.payment_request":        "Нельзя оплатить собственный запрос",
+	"SESSION_CREATE_ERROR":                 "Ошибка при создании сессии",
+	"SESSION_DELETE_ERROR":                 "Не удалось удалить сессию",
+	"SESSION_UPDATE_ERROR":                 "Не удалось изменить сессию",
+	"SPENDING_LIMIT_EXCEEDED":              "Превышен лимит трат по счёту",
+	"STEP_UP_REQUIRED":                     "Подтвердите перевод паролем: повторите запрос с полем password",
+	"TOO_MANY_ROWS":                        "В файле больше {{.max}} {{plural .max \"строки\" \"строк\" \"строк\"}}",
+	"TOO_MANY_STREAMS":                     "Уже открыто {{.max}} {{plural .max \"поток\" \"потока\" \"потоков\"}} — это максимум",
+	"TRANSACTION_NOT_FOUND":                "Транзакция не найдена",
+	"UNSUPPORTED_CURRENCY":                 "Валюта не поддерживается",
+	"UNSUPPORTED_FILE_TYPE":                "Допустимы JPEG, PNG и PDF (для selfie — только изображения)",
+	"UNSUPPORTED_MEDIA_TYPE":               "Тип данных {{.type}} не поддерживается: отправьте JSON, form-urlencoded или multipart/form-data",
+	"UPDATE_ERROR":                         "Не удалось обновить профиль",
+	"USER_DATA_ERROR":                      "Ошибка при получении данных пользователя",
+	"USER_DELETE_ERROR":                    "Не удалось удалить пользователя",
+	"USER_EXISTS":                          "Пользователь с таким телефоном уже существует",
+	"USER_NOT_DELETED":                     "Пользователь не был удален",
+	"USER_NOT_FOUND":                       "Пользователь не найден",
+	"USER_NOT_FOUND.session":               "Пользователь с такой сессией не найден",
+	"VALIDATION_FAILED":                    "Неверно заполнены поля: {{.fields}}",
+}
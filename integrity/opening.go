@@ -0,0 +1,27 @@
+package integrity
+
+import (
+	"database/sql"
+	"log"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+)
+
+// BackfillOpeningBalances проводит входящие остатки пользователей,
+// созданных до ведения книги. Проводки датируются задним числом,
+// поэтому снятые раньше снимки удаляются и SnapshotJob строит их заново.
+// Вызывается при старте до фоновых задач.
+func BackfillOpeningBalances() error {
+	return database.WithTx(func(tx *sql.Tx) error {
+		n, err := ledger.BackfillOpeningBalances(tx)
+		if err != nil || n == 0 {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM balance_snapshots"); err != nil {
+			return err
+		}
+		log.Printf("📒 Posted opening balances for %d pre-ledger users, snapshots will be rebuilt", n)
+		return nil
+	})
+}
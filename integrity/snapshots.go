@@ -0,0 +1,172 @@
+// Package integrity снимает остатки счетов на конец дня и проверяет,
+// что книги сходятся: проводки сбалансированы, остатки счетов равны
+// сумме проводок, а дневные снимки продолжают друг друга.
+package integrity
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+)
+
+const dateLayout = "2006-01-02"
+
+// snapshotDelay сколько ждать после полуночи, прежде чем закрывать день:
+// транзакции, начатые до полуночи, успевают зафиксироваться
+const snapshotDelay = 10 * time.Minute
+
+// Snapshot остаток счёта на конец дня
+type Snapshot struct {
+	Date       string  `json:"date"`
+	Account    string  `json:"account"`
+	Opening    float64 `json:"opening_balance"`
+	Movement   float64 `json:"movement"`
+	Closing    float64 `json:"closing_balance"`
+	EntryCount int     `json:"entry_count"`
+}
+
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// SnapshotJob снимает остатки за каждый закрытый день после последнего снимка.
+// Первый снимок делается за день первой проводки.
+func SnapshotJob() error {
+	today := dayStart(time.Now().Add(-snapshotDelay))
+
+	var last sql.NullTime
+	if err := database.DB.QueryRow("SELECT MAX(snapshot_date) FROM balance_snapshots").Scan(&last); err != nil {
+		return err
+	}
+
+	var day time.Time
+	if last.Valid {
+		day = dayStart(last.Time).AddDate(0, 0, 1)
+	} else {
+		var first sql.NullTime
+		if err := database.DB.QueryRow("SELECT MIN(created_at) FROM ledger_entries").Scan(&first); err != nil {
+			return err
+		}
+		if !first.Valid {
+			return nil
+		}
+		day = dayStart(first.Time)
+	}
+
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		if err := database.WithTx(func(tx *sql.Tx) error { return snapshotDay(tx, day) }); err != nil {
+			return fmt.Errorf("snapshot %s: %w", day.Format(dateLayout), err)
+		}
+	}
+	return nil
+}
+
+// snapshotDay записывает остатки на конец дня day: остаток предыдущего
+// снимка плюс проводки за день. Счета без движения переносятся с нулевым оборотом.
+func snapshotDay(tx *sql.Tx, day time.Time) error {
+	snapshots := make(map[string]*Snapshot)
+	var accounts []string
+	get := func(account string) *Snapshot {
+		s, ok := snapshots[account]
+		if !ok {
+			s = &Snapshot{Account: account}
+			snapshots[account] = s
+			accounts = append(accounts, account)
+		}
+		return s
+	}
+
+	rows, err := tx.Query(
+		"SELECT account, closing_balance FROM balance_snapshots WHERE snapshot_date = ? ORDER BY account",
+		day.AddDate(0, 0, -1).Format(dateLayout),
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var account string
+		var closing float64
+		if err := rows.Scan(&account, &closing); err != nil {
+			rows.Close()
+			return err
+		}
+		get(account).Opening = closing
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = tx.Query(
+		`SELECT account, SUM(amount), COUNT(*) FROM ledger_entries
+		WHERE created_at >= ? AND created_at < ? GROUP BY account ORDER BY account`,
+		day, day.AddDate(0, 0, 1),
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var account string
+		var movement float64
+		var count int
+		if err := rows.Scan(&account, &movement, &count); err != nil {
+			rows.Close()
+			return err
+		}
+		s := get(account)
+		s.Movement, s.EntryCount = ledger.Round(movement), count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		s := snapshots[account]
+		s.Closing = float64(ledger.Cents(s.Opening)+ledger.Cents(s.Movement)) / 100
+
+		_, err := tx.Exec(
+			`INSERT IGNORE INTO balance_snapshots
+			(snapshot_date, account, opening_balance, movement, closing_balance, entry_count)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			day.Format(dateLayout), account, s.Opening, s.Movement, s.Closing, s.EntryCount,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshots возвращает снимки за день date; account сужает выборку до одного счёта
+func Snapshots(q ledger.Querier, date time.Time, account string) ([]Snapshot, error) {
+	query := `SELECT snapshot_date, account, opening_balance, movement, closing_balance, entry_count
+		FROM balance_snapshots WHERE snapshot_date = ?`
+	args := []interface{}{date.Format(dateLayout)}
+	if account != "" {
+		query += " AND account = ?"
+		args = append(args, account)
+	}
+	query += " ORDER BY account"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]Snapshot, 0)
+	for rows.Next() {
+		var s Snapshot
+		var date time.Time
+		if err := rows.Scan(&date, &s.Account, &s.Opening, &s.Movement, &s.Closing, &s.EntryCount); err != nil {
+			return nil, err
+		}
+		s.Date = date.Format(dateLayout)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,350 @@
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Виды расхождений
+const (
+	CheckUnbalanced       = "unbalanced_transaction"
+	CheckMissingEntries   = "missing_entries"
+	CheckOrphanEntries    = "orphan_entries"
+	CheckAmountMismatch   = "amount_mismatch"
+	CheckBalanceMismatch  = "balance_mismatch"
+	CheckSnapshotChain    = "snapshot_chain"
+	CheckSnapshotMovement = "snapshot_movement"
+)
+
+// maxTransactionIDs сколько подозрительных транзакций показывать в одном расхождении
+const maxTransactionIDs = 50
+
+// Discrepancy одно найденное расхождение
+type Discrepancy struct {
+	Check          string  `json:"check"`
+	Account        string  `json:"account,omitempty"`
+	Date           string  `json:"date,omitempty"`
+	Expected       float64 `json:"expected"`
+	Actual         float64 `json:"actual"`
+	TransactionIDs []int64 `json:"transaction_ids"`
+}
+
+// Report результат проверки книг
+type Report struct {
+	CheckedAt     string        `json:"checked_at"`
+	SnapshotsFrom string        `json:"snapshots_from,omitempty"`
+	Transactions  int           `json:"transactions"`
+	Entries       int           `json:"entries"`
+	Snapshots     int           `json:"snapshots"`
+	OK            bool          `json:"ok"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Verify пересчитывает книги и возвращает все найденные расхождения.
+// Снимки проверяются начиная с даты from (нулевая дата — все снимки).
+// Проверка идёт в одной транзакции только для чтения, поэтому видит
+// согласованное состояние, даже если параллельно проводятся платежи.
+func Verify(from time.Time) (Report, error) {
+	report := Report{
+		CheckedAt:     time.Now().Format(types.TimeLayout),
+		Discrepancies: make([]Discrepancy, 0),
+	}
+	if !from.IsZero() {
+		report.SnapshotsFrom = from.Format(dateLayout)
+	}
+
+	tx, err := database.DB.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
+		"SELECT (SELECT COUNT(*) FROM transactions), (SELECT COUNT(*) FROM ledger_entries), (SELECT COUNT(*) FROM balance_snapshots WHERE snapshot_date >= ?)",
+		from.Format(dateLayout),
+	).Scan(&report.Transactions, &report.Entries, &report.Snapshots)
+	if err != nil {
+		return report, err
+	}
+
+	checks := []func(*sql.Tx, time.Time) ([]Discrepancy, error){
+		checkTransactions,
+		checkBalances,
+		checkSnapshots,
+	}
+	for _, check := range checks {
+		found, err := check(tx, from)
+		if err != nil {
+			return report, err
+		}
+		report.Discrepancies = append(report.Discrepancies, found...)
+	}
+
+	report.OK = len(report.Discrepancies) == 0
+	return report, nil
+}
+
+// checkTransactions проверяет, что у каждой транзакции есть проводки,
+// дебет равен кредиту, а сумма зачислений равна сумме транзакции
+func checkTransactions(tx *sql.Tx, _ time.Time) ([]Discrepancy, error) {
+	var found []Discrepancy
+
+	err := scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckUnbalanced}
+		var id int64
+		if err := rows.Scan(&id, &d.Actual); err != nil {
+			return err
+		}
+		d.TransactionIDs = []int64{id}
+		found = append(found, d)
+		return nil
+	}, `SELECT transaction_id, SUM(amount) FROM ledger_entries
+		GROUP BY transaction_id HAVING ROUND(SUM(amount), 2) <> 0 OR COUNT(*) < 2 ORDER BY transaction_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckMissingEntries}
+		var id int64
+		if err := rows.Scan(&id, &d.Expected); err != nil {
+			return err
+		}
+		d.TransactionIDs = []int64{id}
+		found = append(found, d)
+		return nil
+	}, `SELECT t.id, t.amount FROM transactions t
+		LEFT JOIN ledger_entries e ON e.transaction_id = t.id WHERE e.id IS NULL ORDER BY t.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckOrphanEntries}
+		var id int64
+		if err := rows.Scan(&id, &d.Actual); err != nil {
+			return err
+		}
+		d.TransactionIDs = []int64{id}
+		found = append(found, d)
+		return nil
+	}, `SELECT e.transaction_id, SUM(e.amount) FROM ledger_entries e
+		LEFT JOIN transactions t ON t.id = e.transaction_id WHERE t.id IS NULL
+		GROUP BY e.transaction_id ORDER BY e.transaction_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckAmountMismatch}
+		var id int64
+		if err := rows.Scan(&id, &d.Expected, &d.Actual); err != nil {
+			return err
+		}
+		d.TransactionIDs = []int64{id}
+		found = append(found, d)
+		return nil
+	}, `SELECT t.id, t.amount, SUM(CASE WHEN e.amount > 0 THEN e.amount ELSE 0 END)
+		FROM transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+		GROUP BY t.id, t.amount
+		HAVING ROUND(t.amount - SUM(CASE WHEN e.amount > 0 THEN e.amount ELSE 0 END), 2) <> 0
+		ORDER BY t.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// checkBalances сверяет остатки в таблицах счетов с суммой проводок.
+// Подозрительными считаются транзакции по счёту после его последнего снимка.
+func checkBalances(tx *sql.Tx, _ time.Time) ([]Discrepancy, error) {
+	var found []Discrepancy
+
+	tables := ledger.BalanceTables()
+	kinds := make([]string, 0, len(tables))
+	for kind := range tables {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		table := tables[kind]
+		err := scan(tx, func(rows *sql.Rows) error {
+			var id int64
+			d := Discrepancy{Check: CheckBalanceMismatch}
+			if err := rows.Scan(&id, &d.Expected, &d.Actual); err != nil {
+				return err
+			}
+			d.Account = kind + ":" + fmt.Sprint(id)
+			found = append(found, d)
+			return nil
+		}, `SELECT a.id, COALESCE(SUM(e.amount), 0), a.balance FROM `+table+` a
+			LEFT JOIN ledger_entries e ON e.account = CONCAT('`+kind+`:', a.id)
+			GROUP BY a.id, a.balance
+			HAVING ROUND(a.balance - COALESCE(SUM(e.amount), 0), 2) <> 0
+			ORDER BY a.id`)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range found {
+		var last sql.NullTime
+		err := tx.QueryRow(
+			"SELECT MAX(snapshot_date) FROM balance_snapshots WHERE account = ?", found[i].Account,
+		).Scan(&last)
+		if err != nil {
+			return nil, err
+		}
+
+		since := time.Time{}
+		if last.Valid {
+			since = dayStart(last.Time).AddDate(0, 0, 1)
+		}
+		found[i].TransactionIDs, err = transactionIDs(tx, found[i].Account, since, time.Now().AddDate(1, 0, 0))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// checkSnapshots проверяет, что входящий остаток снимка равен исходящему
+// остатку предыдущего дня, исходящий — входящему плюс оборот, а оборот
+// совпадает с проводками за день, пересчитанными заново
+func checkSnapshots(tx *sql.Tx, from time.Time) ([]Discrepancy, error) {
+	var found []Discrepancy
+	since := from.Format(dateLayout)
+
+	err := scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckSnapshotChain}
+		var date time.Time
+		if err := rows.Scan(&d.Account, &date, &d.Expected, &d.Actual); err != nil {
+			return err
+		}
+		d.Date = date.Format(dateLayout)
+		found = append(found, d)
+		return nil
+	}, `SELECT s.account, s.snapshot_date, COALESCE(p.closing_balance, 0), s.opening_balance
+		FROM balance_snapshots s
+		LEFT JOIN balance_snapshots p ON p.account = s.account AND p.snapshot_date = DATE_SUB(s.snapshot_date, INTERVAL 1 DAY)
+		WHERE s.snapshot_date >= ? AND ROUND(s.opening_balance - COALESCE(p.closing_balance, 0), 2) <> 0
+		ORDER BY s.snapshot_date, s.account`, since)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckSnapshotChain}
+		var date time.Time
+		if err := rows.Scan(&d.Account, &date, &d.Expected, &d.Actual); err != nil {
+			return err
+		}
+		d.Date = date.Format(dateLayout)
+		found = append(found, d)
+		return nil
+	}, `SELECT account, snapshot_date, opening_balance + movement, closing_balance FROM balance_snapshots
+		WHERE snapshot_date >= ? AND ROUND(closing_balance - opening_balance - movement, 2) <> 0
+		ORDER BY snapshot_date, account`, since)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(tx, func(rows *sql.Rows) error {
+		d := Discrepancy{Check: CheckSnapshotMovement}
+		var date time.Time
+		if err := rows.Scan(&d.Account, &date, &d.Actual, &d.Expected); err != nil {
+			return err
+		}
+		d.Date = date.Format(dateLayout)
+		found = append(found, d)
+		return nil
+	}, `SELECT s.account, s.snapshot_date, s.movement, COALESCE(SUM(e.amount), 0)
+		FROM balance_snapshots s
+		LEFT JOIN ledger_entries e ON e.account = s.account
+			AND e.created_at >= s.snapshot_date AND e.created_at < DATE_ADD(s.snapshot_date, INTERVAL 1 DAY)
+		WHERE s.snapshot_date >= ?
+		GROUP BY s.id, s.account, s.snapshot_date, s.movement
+		HAVING ROUND(s.movement - COALESCE(SUM(e.amount), 0), 2) <> 0
+		ORDER BY s.snapshot_date, s.account`, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// Подозрительные транзакции — проводки по счёту за день расхождения
+	for i := range found {
+		day, err := time.ParseInLocation(dateLayout, found[i].Date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		found[i].TransactionIDs, err = transactionIDs(tx, found[i].Account, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// transactionIDs возвращает транзакции с проводками по счёту за [from, to)
+func transactionIDs(tx *sql.Tx, account string, from, to time.Time) ([]int64, error) {
+	ids := make([]int64, 0)
+	err := scan(tx, func(rows *sql.Rows) error {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	}, `SELECT DISTINCT transaction_id FROM ledger_entries
+		WHERE account = ? AND created_at >= ? AND created_at < ? ORDER BY transaction_id LIMIT ?`,
+		account, from, to, maxTransactionIDs)
+	return ids, err
+}
+
+func scan(tx *sql.Tx, fn func(*sql.Rows) error, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Summary короткое текстовое описание отчёта для консоли
+func (r Report) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Checked %d transactions, %d entries, %d snapshots at %s\n",
+		r.Transactions, r.Entries, r.Snapshots, r.CheckedAt)
+	if r.OK {
+		b.WriteString("✅ Books balance\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "❌ %d discrepancies\n", len(r.Discrepancies))
+	for _, d := range r.Discrepancies {
+		fmt.Fprintf(&b, "  %-22s", d.Check)
+		if d.Account != "" {
+			fmt.Fprintf(&b, " account=%s", d.Account)
+		}
+		if d.Date != "" {
+			fmt.Fprintf(&b, " date=%s", d.Date)
+		}
+		fmt.Fprintf(&b, " expected=%.2f actual=%.2f transactions=%v\n", d.Expected, d.Actual, d.TransactionIDs)
+	}
+	return b.String()
+}
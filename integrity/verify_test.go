@@ -0,0 +1,124 @@
+package integrity
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+)
+
+// TestVerifyAfterBackfill проверяет на живой базе, что книги пользователя,
+// созданного до ведения книги, сходятся после проводки входящего остатка.
+// Нужна пустая база MySQL: TEST_MYSQL_DSN=user:pass@tcp(host:3306)/db?parseTime=True&loc=Local
+func TestVerifyAfterBackfill(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	database.DB = db
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		id BIGINT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(64) NOT NULL DEFAULT '',
+		surname VARCHAR(64) NOT NULL DEFAULT '',
+		phone_number VARCHAR(32) NOT NULL DEFAULT '',
+		balance DECIMAL(15,2) NOT NULL DEFAULT 0,
+		password_hash VARCHAR(255) NOT NULL DEFAULT '',
+		session VARCHAR(255) NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пользователь 1 получил 1000 до ведения книги, пользователь 2 создан с нуля
+	var legacy, fresh int64
+	for _, u := range []struct {
+		id      *int64
+		balance float64
+	}{{&legacy, 1000}, {&fresh, 0}} {
+		result, err := db.Exec("INSERT INTO users (balance) VALUES (?)", u.balance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *u.id, err = result.LastInsertId(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = database.WithTx(func(tx *sql.Tx) error {
+		_, err := ledger.Transfer(tx, ledger.Transaction{
+			Kind:        ledger.KindTransfer,
+			FromAccount: ledger.UserAccount(legacy),
+			ToAccount:   ledger.UserAccount(fresh),
+			Amount:      100,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Перевод сделан три дня назад, чтобы по нему успели сняться остатки
+	past := time.Now().AddDate(0, 0, -3)
+	for _, table := range []string{"transactions", "ledger_entries"} {
+		if _, err := db.Exec("UPDATE "+table+" SET created_at = ?", past); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SnapshotJob(); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Verify(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK || len(report.Discrepancies) != 1 || report.Discrepancies[0].Account != ledger.UserAccount(legacy) {
+		t.Fatalf("before backfill: want one balance mismatch for user %d, got %+v", legacy, report.Discrepancies)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := BackfillOpeningBalances(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var posted int
+	err = db.QueryRow("SELECT COUNT(*) FROM transactions WHERE kind = ?", ledger.KindOpeningBalance).Scan(&posted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posted != 1 {
+		t.Fatalf("backfill posted %d opening balances, want 1", posted)
+	}
+
+	if err := SnapshotJob(); err != nil {
+		t.Fatal(err)
+	}
+	report, err = Verify(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Fatalf("after backfill: %+v", report.Discrepancies)
+	}
+
+	balance, err := ledger.BalanceAt(db, ledger.UserAccount(legacy), past.Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance != 900 {
+		t.Errorf("balance after the transfer = %.2f, want 900", balance)
+	}
+}
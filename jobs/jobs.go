@@ -0,0 +1,23 @@
+// Package jobs запускает периодические фоновые задачи
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Every запускает fn сразу и затем каждые interval в отдельной горутине.
+// Ошибки задачи только логируются.
+func Every(name string, interval time.Duration, fn func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(); err != nil {
+				log.Printf("❌ Job %s failed: %v", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
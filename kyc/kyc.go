@@ -0,0 +1,374 @@
+// Package kyc отвечает за идентификацию клиентов: загрузку документов,
+// их проверку сотрудником и уровни идентификации, от которых зависят
+// лимиты расходных операций.
+package kyc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/notify"
+	"backend_golang/types"
+)
+
+// Статусы идентификации клиента
+const (
+	StatusNone          = "none"
+	StatusSubmitted     = "submitted"
+	StatusApproved      = "approved"
+	StatusRejected      = "rejected"
+	StatusNeedsMoreInfo = "needs_more_info"
+)
+
+// transitions допустимые смены статуса. Одобренного или отклонённого
+// клиента можно попросить дозагрузить документы, например при
+// истечении срока паспорта.
+var transitions = map[string][]string{
+	StatusNone:          {StatusSubmitted},
+	StatusSubmitted:     {StatusApproved, StatusRejected, StatusNeedsMoreInfo},
+	StatusApproved:      {StatusNeedsMoreInfo},
+	StatusRejected:      {StatusNeedsMoreInfo},
+	StatusNeedsMoreInfo: {StatusSubmitted},
+}
+
+// Виды документов
+const (
+	DocPassport       = "passport"
+	DocIDCard         = "id_card"
+	DocDriverLicense  = "driver_license"
+	DocProofOfAddress = "proof_of_address"
+	DocSelfie         = "selfie"
+)
+
+// documentTypes допустимые типы файлов для каждого вида документа.
+// Тип определяется по содержимому файла, а не по заголовку запроса.
+var documentTypes = map[string][]string{
+	DocPassport:       {"image/jpeg", "image/png", "application/pdf"},
+	DocIDCard:         {"image/jpeg", "image/png", "application/pdf"},
+	DocDriverLicense:  {"image/jpeg", "image/png", "application/pdf"},
+	DocProofOfAddress: {"image/jpeg", "image/png", "application/pdf"},
+	DocSelfie:         {"image/jpeg", "image/png"},
+}
+
+// identityDocuments удостоверения личности: для заявки нужно хотя бы одно
+var identityDocuments = []string{DocPassport, DocIDCard, DocDriverLicense}
+
+var (
+	ErrInvalidTransition   = errors.New("kyc: invalid status transition")
+	ErrDocumentsLocked     = errors.New("kyc: documents can not be changed in current status")
+	ErrMissingDocuments    = errors.New("kyc: identity document and selfie are required")
+	ErrUnknownDocumentType = errors.New("kyc: unknown document type")
+	ErrUnsupportedFile     = errors.New("kyc: unsupported file type")
+	ErrFileTooLarge        = errors.New("kyc: file is too large")
+	ErrInvalidLevel        = errors.New("kyc: invalid level")
+)
+
+// Blobs хранилище файлов документов; задаётся при запуске сервера
+var Blobs BlobStore
+
+// Profile состояние идентификации клиента
+type Profile struct {
+	UserID    int64         `json:"user_id"`
+	Name      string        `json:"name"`
+	Surname   string        `json:"surname"`
+	Status    string        `json:"status"`
+	Level     int           `json:"level"`
+	Tier      types.KYCTier `json:"tier"`
+	Note      string        `json:"note,omitempty"`
+	UpdatedAt string        `json:"updated_at,omitempty"`
+	Documents []Document    `json:"documents,omitempty"`
+}
+
+// Document загруженный документ; сам файл лежит в Blobs
+type Document struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Type        string `json:"document_type"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	CreatedAt   string `json:"created_at"`
+
+	BlobKey string `json:"-"`
+}
+
+// TierFor возвращает лимиты уровня идентификации
+func TierFor(level int) types.KYCTier {
+	for _, tier := range types.KYCTiers {
+		if tier.Level == level {
+			return tier
+		}
+	}
+	return types.KYCTiers[0]
+}
+
+const selectProfile = `SELECT id, name, surname, kyc_status, kyc_level, kyc_note, kyc_updated_at FROM users`
+
+func scanProfile(row interface{ Scan(...interface{}) error }) (Profile, error) {
+	var p Profile
+	var updatedAt sql.NullTime
+	err := row.Scan(&p.UserID, &p.Name, &p.Surname, &p.Status, &p.Level, &p.Note, &updatedAt)
+	if err != nil {
+		return p, err
+	}
+
+	p.Tier = TierFor(p.Level)
+	if updatedAt.Valid {
+		p.UpdatedAt = updatedAt.Time.Format(types.TimeLayout)
+	}
+	return p, nil
+}
+
+// Get возвращает состояние идентификации клиента вместе с документами;
+// forUpdate блокирует строку клиента до конца транзакции
+func Get(q ledger.Querier, userID int64, forUpdate bool) (Profile, error) {
+	query := selectProfile + " WHERE id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	p, err := scanProfile(q.QueryRow(query, userID))
+	if err != nil {
+		return p, err
+	}
+
+	p.Documents, err = Documents(q, userID)
+	return p, err
+}
+
+// List возвращает клиентов со статусом status от давно ждущих к новым
+func List(q ledger.Querier, status string) ([]Profile, error) {
+	rows, err := q.Query(selectProfile+" WHERE kyc_status = ? ORDER BY kyc_updated_at, id LIMIT 200", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]Profile, 0)
+	for rows.Next() {
+		p, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, rows.Err()
+}
+
+const selectDocument = `SELECT id, user_id, document_type, file_name, content_type, size, sha256, blob_key, created_at
+	FROM kyc_documents`
+
+func scanDocument(row interface{ Scan(...interface{}) error }) (Document, error) {
+	var d Document
+	var createdAt time.Time
+	err := row.Scan(&d.ID, &d.UserID, &d.Type, &d.FileName, &d.ContentType, &d.Size, &d.SHA256, &d.BlobKey, &createdAt)
+	d.CreatedAt = createdAt.Format(types.TimeLayout)
+	return d, err
+}
+
+// Documents возвращает документы клиента в порядке загрузки
+func Documents(q ledger.Querier, userID int64) ([]Document, error) {
+	rows, err := q.Query(selectDocument+" WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := make([]Document, 0)
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, d)
+	}
+	return documents, rows.Err()
+}
+
+// GetDocument возвращает документ клиента
+func GetDocument(q ledger.Querier, userID, id int64) (Document, error) {
+	return scanDocument(q.QueryRow(selectDocument+" WHERE id = ? AND user_id = ?", id, userID))
+}
+
+// editable документы можно менять, пока заявка не отправлена
+// или когда сотрудник попросил дозагрузить документы
+func editable(status string) bool {
+	return status == StatusNone || status == StatusNeedsMoreInfo
+}
+
+// AddDocument проверяет файл и сохраняет его в Blobs
+func AddDocument(userID int64, docType, fileName string, data []byte) (Document, error) {
+	contentTypes, ok := documentTypes[docType]
+	if !ok {
+		return Document{}, ErrUnknownDocumentType
+	}
+	if int64(len(data)) > types.KYCMaxFileSize {
+		return Document{}, ErrFileTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if len(data) == 0 || !contains(contentTypes, contentType) {
+		return Document{}, ErrUnsupportedFile
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return Document{}, err
+	}
+	sum := sha256.Sum256(data)
+	d := Document{
+		UserID:      userID,
+		Type:        docType,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		BlobKey:     fmt.Sprintf("%d/%s", userID, hex.EncodeToString(random)),
+	}
+
+	// Файл пишется до транзакции, чтобы не держать блокировку клиента
+	// во время записи; если строка не добавится, файл удаляется
+	if err := Blobs.Put(d.BlobKey, data); err != nil {
+		return Document{}, err
+	}
+
+	err := database.WithTx(func(tx *sql.Tx) error {
+		p, err := Get(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		if !editable(p.Status) {
+			return ErrDocumentsLocked
+		}
+
+		result, err := tx.Exec(
+			`INSERT INTO kyc_documents (user_id, document_type, file_name, content_type, size, sha256, blob_key)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			d.UserID, d.Type, d.FileName, d.ContentType, d.Size, d.SHA256, d.BlobKey,
+		)
+		if err != nil {
+			return err
+		}
+		d.ID, err = result.LastInsertId()
+		return err
+	})
+	if err != nil {
+		Blobs.Delete(d.BlobKey)
+		return Document{}, err
+	}
+
+	return GetDocument(database.DB, userID, d.ID)
+}
+
+// DeleteDocument удаляет документ, пока заявка не отправлена
+func DeleteDocument(userID, id int64) error {
+	var blobKey string
+	err := database.WithTx(func(tx *sql.Tx) error {
+		p, err := Get(tx, userID, true)
+		if err != nil {
+			return err
+		}
+		if !editable(p.Status) {
+			return ErrDocumentsLocked
+		}
+
+		d, err := GetDocument(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		blobKey = d.BlobKey
+
+		_, err = tx.Exec("DELETE FROM kyc_documents WHERE id = ?", id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return Blobs.Delete(blobKey)
+}
+
+// Submit отправляет документы на проверку; нужны удостоверение личности и селфи
+func Submit(tx *sql.Tx, userID int64) (Profile, error) {
+	p, err := Get(tx, userID, true)
+	if err != nil {
+		return p, err
+	}
+	if !allowed(p.Status, StatusSubmitted) {
+		return p, ErrInvalidTransition
+	}
+
+	var identity, selfie bool
+	for _, d := range p.Documents {
+		identity = identity || contains(identityDocuments, d.Type)
+		selfie = selfie || d.Type == DocSelfie
+	}
+	if !identity || !selfie {
+		return p, ErrMissingDocuments
+	}
+
+	return p, setStatus(tx, userID, StatusSubmitted, p.Level, "")
+}
+
+// Review переводит заявку в статус status по решению сотрудника. Уровень
+// level задаётся только при одобрении, отказ сбрасывает уровень до нулевого,
+// а запрос документов оставляет уровень прежним. Возвращается состояние
+// до решения.
+func Review(tx *sql.Tx, userID int64, status string, level int, note string) (Profile, error) {
+	p, err := Get(tx, userID, true)
+	if err != nil {
+		return p, err
+	}
+	if !allowed(p.Status, status) {
+		return p, ErrInvalidTransition
+	}
+
+	switch status {
+	case StatusApproved:
+		if level <= 0 || TierFor(level).Level != level {
+			return p, ErrInvalidLevel
+		}
+	case StatusRejected:
+		level = 0
+	default:
+		level = p.Level
+	}
+
+	if err := setStatus(tx, userID, status, level, note); err != nil {
+		return p, err
+	}
+
+	// уведомления kyc_approved, kyc_rejected, kyc_needs_more_info
+	return p, notify.Queue(tx, userID, "kyc_"+status, map[string]interface{}{
+		"level": level,
+		"note":  note,
+	})
+}
+
+func setStatus(tx *sql.Tx, userID int64, status string, level int, note string) error {
+	_, err := tx.Exec(
+		"UPDATE users SET kyc_status = ?, kyc_level = ?, kyc_note = ?, kyc_updated_at = NOW() WHERE id = ?",
+		status, level, note, userID,
+	)
+	return err
+}
+
+func allowed(from, to string) bool {
+	return contains(transitions[from], to)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package kyc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_golang/ledger"
+)
+
+// ErrLimitExceeded операция превышает лимит уровня идентификации клиента
+var ErrLimitExceeded = errors.New("kyc: limit exceeded")
+
+// CheckLimits проверяет, укладывается ли списание amount со счёта клиента
+// в лимиты его уровня идентификации. Расходом считаются переводы,
+// карточные списания и ещё не списанные карточные холды.
+func CheckLimits(tx *sql.Tx, userID int64, amount float64) error {
+	var level int
+	if err := tx.QueryRow("SELECT kyc_level FROM users WHERE id = ?", userID).Scan(&level); err != nil {
+		return err
+	}
+	tier := TierFor(level)
+
+	if ledger.Cents(amount) > ledger.Cents(tier.SingleOperation) {
+		return fmt.Errorf("%w: single operation limit %.2f", ErrLimitExceeded, tier.SingleOperation)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+
+	var spentToday, spentMonth float64
+	err := tx.QueryRow(
+		`SELECT COALESCE(SUM(CASE WHEN e.created_at >= ? THEN -e.amount ELSE 0 END), 0), COALESCE(SUM(-e.amount), 0)
+		FROM ledger_entries e
+		JOIN transactions t ON t.id = e.transaction_id
+		WHERE e.account = ? AND e.amount < 0 AND t.kind IN (?, ?) AND e.created_at >= ?`,
+		today, ledger.UserAccount(userID), ledger.KindTransfer, ledger.KindCardCapture, month,
+	).Scan(&spentToday, &spentMonth)
+	if err != nil {
+		return err
+	}
+
+	var heldToday, heldMonth float64
+	err = tx.QueryRow(
+		`SELECT COALESCE(SUM(CASE WHEN created_at >= ? THEN amount ELSE 0 END), 0), COALESCE(SUM(amount), 0)
+		FROM holds WHERE user_id = ? AND source = 'card' AND status = ? AND created_at >= ?`,
+		today, userID, ledger.HoldActive, month,
+	).Scan(&heldToday, &heldMonth)
+	if err != nil {
+		return err
+	}
+
+	if ledger.Cents(spentToday)+ledger.Cents(heldToday)+ledger.Cents(amount) > ledger.Cents(tier.DailyOutgoing) {
+		return fmt.Errorf("%w: daily limit %.2f", ErrLimitExceeded, tier.DailyOutgoing)
+	}
+	if ledger.Cents(spentMonth)+ledger.Cents(heldMonth)+ledger.Cents(amount) > ledger.Cents(tier.MonthlyOutgoing) {
+		return fmt.Errorf("%w: monthly limit %.2f", ErrLimitExceeded, tier.MonthlyOutgoing)
+	}
+	return nil
+}
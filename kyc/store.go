@@ -0,0 +1,151 @@
+package kyc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BlobStore хранилище файлов документов
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// ErrBlobNotFound файла с таким ключом нет в хранилище
+var ErrBlobNotFound = errors.New("kyc: blob not found")
+
+var blobKeyPattern = regexp.MustCompile(`^[a-z0-9_-]+(/[a-z0-9_-]+)*$`)
+
+// LocalStore хранит файлы в каталоге на диске, зашифрованными AES-256-GCM.
+// Ключ файла участвует в шифровании как дополнительные данные, поэтому
+// файл, подложенный под чужой ключ, не расшифруется.
+type LocalStore struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewLocalStore открывает хранилище в каталоге dir с ключом из keyFile
+// (32 байта в hex). Если файла с ключом нет, ключ создаётся.
+func NewLocalStore(dir, keyFile string) (*LocalStore, error) {
+	key, err := loadKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir, aead: aead}, nil
+}
+
+func loadKey(keyFile string) ([]byte, error) {
+	body, err := os.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(keyFile, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+		log.Printf("🔑 KYC encryption key created in %s", keyFile)
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(body)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("kyc key %s: expected 32 bytes in hex", keyFile)
+	}
+	return key, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !blobKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("kyc: invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put шифрует и записывает файл; файл сначала пишется во временный,
+// чтобы при сбое не остался обрезанный документ
+func (s *LocalStore) Put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := s.aead.Seal(nonce, nonce, data, []byte(key))
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get читает и расшифровывает файл
+func (s *LocalStore) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	size := s.aead.NonceSize()
+	if len(sealed) < size {
+		return nil, fmt.Errorf("kyc: blob %s is corrupted", key)
+	}
+	data, err := s.aead.Open(nil, sealed[:size], sealed[size:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("kyc: blob %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Delete удаляет файл; удаление несуществующего файла ошибкой не считается
+func (s *LocalStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Статусы холдов
+const (
+	HoldActive   = "active"
+	HoldReleased = "released"
+	HoldCaptured = "captured"
+	HoldExpired  = "expired"
+)
+
+// PendingSQL подзапрос суммы активных холдов для строки таблицы users
+const PendingSQL = `(SELECT COALESCE(SUM(holds.amount), 0) FROM holds
+	WHERE holds.user_id = users.id AND holds.status = 'active' AND holds.expires_at > NOW())`
+
+// Balance остатки по счёту: проведённый, доступный и заблокированный холдами
+type Balance struct {
+	LedgerBalance    float64 `json:"ledger_balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	Pending          float64 `json:"pending"`
+}
+
+// NewBalance считает доступный остаток из проведённого и суммы холдов
+func NewBalance(ledgerBalance, pending float64) Balance {
+	return Balance{
+		LedgerBalance:    Round(ledgerBalance),
+		AvailableBalance: float64(Cents(ledgerBalance)-Cents(pending)) / 100,
+		Pending:          Round(pending),
+	}
+}
+
+// Balances возвращает остатки пользователя
+func Balances(q Querier, userID int64) (Balance, error) {
+	var ledgerBalance, pending float64
+	err := q.QueryRow("SELECT balance, "+PendingSQL+" FROM users WHERE id = ?", userID).Scan(&ledgerBalance, &pending)
+	if err != nil {
+		return Balance{}, err
+	}
+	return NewBalance(ledgerBalance, pending), nil
+}
+
+var ErrHoldNotActive = errors.New("ledger: hold is not active")
+
+// Held возвращает сумму активных холдов по счёту
+func Held(q Querier, account string) (float64, error) {
+	kind, id, err := ParseAccount(account)
+	if err != nil || kind != "user" {
+		return 0, err
+	}
+
+	var held float64
+	err = q.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM holds WHERE user_id = ? AND status = ? AND expires_at > NOW()",
+		id, HoldActive,
+	).Scan(&held)
+	return held, err
+}
+
+// Hold блокировка суммы на счёте пользователя
+type Hold struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"user_id"`
+	Amount      float64   `json:"amount"`
+	Source      string    `json:"source"`
+	ReferenceID int64     `json:"reference_id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PlaceHold блокирует сумму на счёте пользователя, не проводя списание.
+// Холд уменьшает доступный остаток, но не остаток по счёту.
+func PlaceHold(tx *sql.Tx, h Hold) (int64, error) {
+	if Cents(h.Amount) <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	var balance float64
+	err := tx.QueryRow("SELECT balance FROM users WHERE id = ? FOR UPDATE", h.UserID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, ErrAccountNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	spendable, err := Spendable(tx, UserAccount(h.UserID), balance)
+	if err != nil {
+		return 0, err
+	}
+
+	if Cents(spendable)-Cents(h.Amount) < 0 {
+		return 0, ErrInsufficientFunds
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO holds (user_id, amount, source, reference_id, description, status, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		h.UserID, Round(h.Amount), h.Source, h.ReferenceID, h.Description, HoldActive, h.ExpiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// ListHolds возвращает холды пользователя; пустой status означает все статусы
+func ListHolds(q Querier, userID int64, status string) ([]Hold, error) {
+	query := `SELECT id, user_id, amount, source, reference_id, description, status, expires_at, created_at
+		FROM holds WHERE user_id = ?`
+	args := []interface{}{userID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]Hold, 0)
+	for rows.Next() {
+		var h Hold
+		if err := rows.Scan(&h.ID, &h.UserID, &h.Amount, &h.Source, &h.ReferenceID, &h.Description,
+			&h.Status, &h.ExpiresAt, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+// ReleaseHold снимает активный холд, переводя его в статус status
+func ReleaseHold(tx *sql.Tx, holdID int64, status string) error {
+	result, err := tx.Exec(
+		"UPDATE holds SET status = ? WHERE id = ? AND status = ?",
+		status, holdID, HoldActive,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrHoldNotActive
+	}
+	return nil
+}
+
+// ExpireHolds переводит просроченные активные холды в статус expired
+// и возвращает их количество
+func ExpireHolds(q Querier) (int64, error) {
+	result, err := q.Exec(
+		"UPDATE holds SET status = ? WHERE status = ? AND expires_at <= NOW()",
+		HoldExpired, HoldActive,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
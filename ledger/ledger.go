@@ -0,0 +1,666 @@
+// Package ledger проводит движения денег по счетам
+// методом двойной записи.
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_golang/types"
+)
+
+// Виды транзакций
+const (
+	KindTransfer          = "transfer"
+	KindReversal          = "reversal"
+	KindCardCapture       = "card_capture"
+	KindCardRefund        = "card_refund"
+	KindSavingsDeposit    = "savings_deposit"
+	KindSavingsWithdrawal = "savings_withdrawal"
+	KindInterest          = "interest"
+	KindLoanDisbursement  = "loan_disbursement"
+	KindLoanRepayment     = "loan_repayment"
+	KindLoanPrepayment    = "loan_prepayment"
+	KindOverdraftInterest = "overdraft_interest"
+	KindGoalContribution  = "goal_contribution"
+	KindGoalWithdrawal    = "goal_withdrawal"
+	KindOpeningBalance    = "opening_balance"
+)
+
+// Счета банка
+const (
+	BankCardSettlement  = "bank:card_settlement"
+	BankInterestExpense = "bank:interest_expense"
+	BankInterestIncome  = "bank:interest_income"
+	BankFeeIncome       = "bank:fee_income"
+	BankOpeningBalance  = "bank:opening_balance"
+)
+
+// Статусы транзакций
+const (
+	StatusPosted            = "posted"
+	StatusPartiallyReversed = "partially_reversed"
+	StatusReversed          = "reversed"
+)
+
+var (
+	ErrInvalidAmount     = errors.New("ledger: amount must be positive")
+	ErrUnbalanced        = errors.New("ledger: postings are not balanced")
+	ErrInsufficientFunds = errors.New("ledger: insufficient funds")
+	ErrAccountNotFound   = errors.New("ledger: account not found")
+	ErrInvalidAccount    = errors.New("ledger: invalid account")
+	ErrNotReversible     = errors.New("ledger: transaction cannot be reversed")
+	ErrReversalExceeds   = errors.New("ledger: reversal exceeds original amount")
+)
+
+// balanceTables связывает тип счёта с таблицей, где хранится его остаток.
+// Счета банка (bank:*) остатка в таблицах не имеют.
+var balanceTables = map[string]string{
+	"user":    "users",
+	"savings": "savings_accounts",
+	"loan":    "loans",
+	"goal":    "goals",
+}
+
+// BalanceTables возвращает копию balanceTables: тип счёта и таблицу с его остатком
+func BalanceTables() map[string]string {
+	tables := make(map[string]string, len(balanceTables))
+	for kind, table := range balanceTables {
+		tables[kind] = table
+	}
+	return tables
+}
+
+// debtKinds типы счетов, остаток которых может быть отрицательным:
+// остаток кредитного счёта равен долгу заёмщика со знаком минус
+var debtKinds = map[string]bool{
+	"loan": true,
+}
+
+// Posting одна проводка по счёту: отрицательная сумма списывает, положительная зачисляет
+type Posting struct {
+	Account string
+	Amount  float64
+}
+
+// Transaction описывает проводимую транзакцию
+type Transaction struct {
+	ID             int64   `json:"id"`
+	Kind           string  `json:"kind"`
+	Status         string  `json:"status"`
+	FromAccount    string  `json:"from_account"`
+	ToAccount      string  `json:"to_account"`
+	Amount         float64 `json:"amount"`
+	Currency       string  `json:"currency"`
+	Memo           string  `json:"memo,omitempty"`
+	ReferenceID    int64   `json:"reference_id,omitempty"`
+	ReversedAmount float64 `json:"reversed_amount"`
+	ReasonCode     string  `json:"reason_code,omitempty"`
+	CreatedBy      int64   `json:"created_by,omitempty"`
+	CreatedAt      string  `json:"created_at,omitempty"`
+
+	// Force разрешает уводить счета клиентов в минус (принудительные операции)
+	Force bool `json:"-"`
+}
+
+// Querier общий интерфейс для *sql.DB и *sql.Tx
+type Querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// UserAccount возвращает код счёта пользователя
+func UserAccount(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// SavingsAccount возвращает код сберегательного счёта
+func SavingsAccount(accountID int64) string {
+	return fmt.Sprintf("savings:%d", accountID)
+}
+
+// LoanAccount возвращает код кредитного счёта
+func LoanAccount(loanID int64) string {
+	return fmt.Sprintf("loan:%d", loanID)
+}
+
+// GoalAccount возвращает код копилки
+func GoalAccount(goalID int64) string {
+	return fmt.Sprintf("goal:%d", goalID)
+}
+
+// ParseAccount разбирает код счёта вида "user:12" на тип и идентификатор
+func ParseAccount(account string) (string, int64, error) {
+	kind, rawID, ok := strings.Cut(account, ":")
+	if !ok || kind == "" || rawID == "" {
+		return "", 0, ErrInvalidAccount
+	}
+	if kind == "bank" {
+		return kind, 0, nil
+	}
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidAccount
+	}
+	return kind, id, nil
+}
+
+// Cents переводит сумму в копейки
+func Cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Round округляет сумму до копеек
+func Round(amount float64) float64 {
+	return float64(Cents(amount)) / 100
+}
+
+// Transfer проводит перевод с FromAccount на ToAccount
+func Transfer(tx *sql.Tx, t Transaction) (int64, error) {
+	return Post(tx, t, []Posting{
+		{Account: t.FromAccount, Amount: -t.Amount},
+		{Account: t.ToAccount, Amount: t.Amount},
+	})
+}
+
+// Post записывает транзакцию и её проводки, обновляя остатки счетов.
+// Сумма проводок должна быть равна нулю.
+func Post(tx *sql.Tx, t Transaction, postings []Posting) (int64, error) {
+	if Cents(t.Amount) <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	var total int64
+	deltas := make(map[string]int64)
+	for _, p := range postings {
+		cents := Cents(p.Amount)
+		total += cents
+		deltas[p.Account] += cents
+	}
+	if total != 0 || len(postings) < 2 {
+		return 0, ErrUnbalanced
+	}
+
+	// Блокируем счета в одном порядке, чтобы не ловить дедлоки
+	accounts := make([]string, 0, len(deltas))
+	for account := range deltas {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+
+	for _, account := range accounts {
+		if err := applyDelta(tx, account, deltas[account], t.Force); err != nil {
+			return 0, err
+		}
+	}
+
+	if t.Currency == "" {
+		t.Currency = types.DefaultCurrency
+	}
+
+	var reference, createdBy interface{}
+	if t.ReferenceID != 0 {
+		reference = t.ReferenceID
+	}
+	if t.CreatedBy != 0 {
+		createdBy = t.CreatedBy
+	}
+
+	result, err := tx.Exec(
+		`INSERT INTO transactions
+		(kind, status, from_account, to_account, amount, currency, memo, reference_id, reason_code, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Kind, StatusPosted, t.FromAccount, t.ToAccount, Round(t.Amount), t.Currency, t.Memo,
+		reference, t.ReasonCode, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	txID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range postings {
+		_, err = tx.Exec(
+			"INSERT INTO ledger_entries (transaction_id, account, amount) VALUES (?, ?, ?)",
+			txID, p.Account, Round(p.Amount),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, hook := range postHooks {
+		if err := hook(tx, txID, t); err != nil {
+			return 0, err
+		}
+	}
+
+	return txID, nil
+}
+
+// PostHook вызывается внутри транзакции после проведения каждой транзакции
+type PostHook func(tx *sql.Tx, txID int64, t Transaction) error
+
+var postHooks []PostHook
+
+// OnPost регистрирует hook, вызываемый после каждой проведённой транзакции.
+// Ошибка hook откатывает всю транзакцию.
+func OnPost(hook PostHook) {
+	postHooks = append(postHooks, hook)
+}
+
+func applyDelta(tx *sql.Tx, account string, delta int64, force bool) error {
+	kind, id, err := ParseAccount(account)
+	if err != nil {
+		return err
+	}
+	if kind == "bank" {
+		return nil
+	}
+
+	table, ok := balanceTables[kind]
+	if !ok {
+		return ErrInvalidAccount
+	}
+
+	var balance float64
+	err = tx.QueryRow("SELECT balance FROM "+table+" WHERE id = ? FOR UPDATE", id).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if delta < 0 && !force && !debtKinds[kind] {
+		spendable, err := Spendable(tx, account, balance)
+		if err != nil {
+			return err
+		}
+		if Cents(spendable)+delta < 0 {
+			return ErrInsufficientFunds
+		}
+	}
+
+	_, err = tx.Exec("UPDATE "+table+" SET balance = balance + ? WHERE id = ?", float64(delta)/100, id)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range balanceHooks {
+		if err := hook(tx, account, balance, float64(Cents(balance)+delta)/100); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Spendable возвращает сумму, которую можно списать со счёта с остатком balance:
+// остаток за вычетом холдов плюс лимит овердрафта
+func Spendable(q Querier, account string, balance float64) (float64, error) {
+	held, err := Held(q, account)
+	if err != nil {
+		return 0, err
+	}
+
+	limit, err := OverdraftLimit(q, account)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(Cents(balance)-Cents(held)+Cents(limit)) / 100, nil
+}
+
+// OverdraftLimit возвращает лимит овердрафта пользователя; у остальных счетов его нет
+func OverdraftLimit(q Querier, account string) (float64, error) {
+	kind, id, err := ParseAccount(account)
+	if err != nil || kind != "user" {
+		return 0, err
+	}
+
+	var limit float64
+	err = q.QueryRow("SELECT limit_amount FROM overdrafts WHERE user_id = ?", id).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return limit, err
+}
+
+// BalanceHook вызывается внутри транзакции после изменения остатка счёта
+type BalanceHook func(tx *sql.Tx, account string, before, after float64) error
+
+var balanceHooks []BalanceHook
+
+// OnBalanceChange регистрирует hook, вызываемый при каждом изменении остатка.
+// Ошибка hook откатывает всю транзакцию.
+func OnBalanceChange(hook BalanceHook) {
+	balanceHooks = append(balanceHooks, hook)
+}
+
+// DaysInYear возвращает число дней в году для конвенции dayCount:
+// ACT/365, ACT/360 или ACT/ACT
+func DaysInYear(dayCount string, date time.Time) (float64, error) {
+	switch dayCount {
+	case "ACT/365":
+		return 365, nil
+	case "ACT/360":
+		return 360, nil
+	case "ACT/ACT":
+		year := date.Year()
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 366, nil
+		}
+		return 365, nil
+	}
+	return 0, fmt.Errorf("ledger: unknown day count convention %q", dayCount)
+}
+
+// Get возвращает транзакцию по ID
+func Get(q Querier, id int64) (Transaction, error) {
+	return scanTransaction(q.QueryRow(selectTransaction+" WHERE id = ?", id))
+}
+
+// GetForUpdate возвращает транзакцию по ID, блокируя строку до конца tx
+func GetForUpdate(tx *sql.Tx, id int64) (Transaction, error) {
+	return scanTransaction(tx.QueryRow(selectTransaction+" WHERE id = ? FOR UPDATE", id))
+}
+
+const selectTransaction = `SELECT id, kind, status, from_account, to_account, amount, currency, memo,
+	reference_id, reversed_amount, reason_code, created_by, created_at FROM transactions`
+
+func scanTransaction(row *sql.Row) (Transaction, error) {
+	var t Transaction
+	var reference, createdBy sql.NullInt64
+	var createdAt sql.NullTime
+
+	err := row.Scan(&t.ID, &t.Kind, &t.Status, &t.FromAccount, &t.ToAccount, &t.Amount, &t.Currency,
+		&t.Memo, &reference, &t.ReversedAmount, &t.ReasonCode, &createdBy, &createdAt)
+	if err != nil {
+		return t, err
+	}
+
+	t.ReferenceID = reference.Int64
+	t.CreatedBy = createdBy.Int64
+	if createdAt.Valid {
+		t.CreatedAt = createdAt.Time.Format(types.TimeLayout)
+	}
+	return t, nil
+}
+
+// Reverse проводит компенсирующую транзакцию по исходной transactionID.
+// Сумма, автор, комментарий и код причины берутся из r.
+// Суммарно нельзя вернуть больше, чем было переведено.
+func Reverse(tx *sql.Tx, transactionID int64, r Transaction) (int64, error) {
+	original, err := GetForUpdate(tx, transactionID)
+	if err != nil {
+		return 0, err
+	}
+
+	if original.Kind == KindReversal || original.Status == StatusReversed {
+		return 0, ErrNotReversible
+	}
+
+	if Cents(r.Amount) <= 0 {
+		return 0, ErrInvalidAmount
+	}
+	if Cents(r.Amount) > Cents(Remaining(original)) {
+		return 0, ErrReversalExceeds
+	}
+
+	r.Kind = KindReversal
+	r.FromAccount = original.ToAccount
+	r.ToAccount = original.FromAccount
+	r.Currency = original.Currency
+	r.ReferenceID = original.ID
+
+	reversalID, err := Transfer(tx, r)
+	if err != nil {
+		return 0, err
+	}
+
+	reversed := Round(original.ReversedAmount + r.Amount)
+	status := StatusPartiallyReversed
+	if Cents(reversed) == Cents(original.Amount) {
+		status = StatusReversed
+	}
+
+	_, err = tx.Exec(
+		"UPDATE transactions SET reversed_amount = ?, status = ? WHERE id = ?",
+		reversed, status, original.ID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return reversalID, nil
+}
+
+// Remaining возвращает сумму, которую ещё можно вернуть по транзакции
+func Remaining(t Transaction) float64 {
+	return float64(Cents(t.Amount)-Cents(t.ReversedAmount)) / 100
+}
+
+// BalanceAt возвращает остаток счёта по проводкам, сделанным до момента before
+func BalanceAt(q Querier, account string, before time.Time) (float64, error) {
+	var balance float64
+	err := q.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = ? AND created_at < ?",
+		account, before,
+	).Scan(&balance)
+	return balance, err
+}
+
+// BalanceBefore возвращает остаток счёта на момент before: текущий остаток
+// за вычетом проводок, сделанных с этого момента. В отличие от BalanceAt
+// учитывает деньги, появившиеся на счёте до ведения книги.
+func BalanceBefore(q Querier, account string, before time.Time) (float64, error) {
+	current, err := BalanceOf(q, account)
+	if err != nil {
+		return 0, err
+	}
+
+	var since float64
+	err = q.QueryRow(
+		"SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = ? AND created_at >= ?",
+		account, before,
+	).Scan(&since)
+	if err != nil {
+		return 0, err
+	}
+	return float64(Cents(current)-Cents(since)) / 100, nil
+}
+
+// BalanceOf возвращает текущий остаток счёта: из таблицы счёта,
+// а для счетов банка — сумму проводок
+func BalanceOf(q Querier, account string) (float64, error) {
+	kind, id, err := ParseAccount(account)
+	if err != nil {
+		return 0, err
+	}
+	if kind == "bank" {
+		return BalanceAt(q, account, time.Now().Add(time.Hour))
+	}
+
+	table, ok := balanceTables[kind]
+	if !ok {
+		return 0, ErrInvalidAccount
+	}
+
+	var balance float64
+	err = q.QueryRow("SELECT balance FROM "+table+" WHERE id = ?", id).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, ErrAccountNotFound
+	}
+	return balance, err
+}
+
+// OwnerOf возвращает ID пользователя, которому принадлежит счёт
+func OwnerOf(q Querier, account string) (int64, error) {
+	kind, id, err := ParseAccount(account)
+	if err != nil {
+		return 0, err
+	}
+	if kind == "user" {
+		return id, nil
+	}
+
+	table, ok := balanceTables[kind]
+	if !ok {
+		return 0, ErrInvalidAccount
+	}
+
+	var userID int64
+	err = q.QueryRow("SELECT user_id FROM "+table+" WHERE id = ?", id).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrAccountNotFound
+	}
+	return userID, err
+}
+
+// Entry проводка по счёту вместе с её транзакцией
+type Entry struct {
+	EntryID     int64     `json:"entry_id"`
+	EntryAmount float64   `json:"entry_amount"`
+	BookedAt    time.Time `json:"-"`
+	Transaction
+}
+
+// History возвращает проводки по счёту от новых к старым.
+// beforeID > 0 возвращает только проводки старше указанной — для постраничного вывода.
+func History(q Querier, account string, beforeID int64, limit int) ([]Entry, error) {
+	query := "SELECT id, transaction_id, amount, created_at FROM ledger_entries WHERE account = ?"
+	args := []interface{}{account}
+	if beforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	return loadEntries(q, query, args...)
+}
+
+// EntriesBetween возвращает проводки по счёту за период [from, to) от старых к новым
+func EntriesBetween(q Querier, account string, from, to time.Time) ([]Entry, error) {
+	return loadEntries(q,
+		`SELECT id, transaction_id, amount, created_at FROM ledger_entries
+		WHERE account = ? AND created_at >= ? AND created_at < ? ORDER BY id`,
+		account, from, to,
+	)
+}
+
+func loadEntries(q Querier, query string, args ...interface{}) ([]Entry, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.EntryID, &e.Transaction.ID, &e.EntryAmount, &e.BookedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.EntryAmount = Round(e.EntryAmount)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		t, err := Get(q, entries[i].Transaction.ID)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].Transaction = t
+	}
+
+	if entries == nil {
+		entries = make([]Entry, 0)
+	}
+	return entries, nil
+}
+
+// BackfillOpeningBalances проводит входящий остаток для пользователей,
+// чей users.balance не сходится с суммой проводок: их деньги появились
+// до ведения книги. Остаток в users не меняется, проводки датируются
+// секундой раньше первой проводки по счёту. Повторный вызов ничего не делает.
+// Возвращает число проведённых транзакций.
+func BackfillOpeningBalances(tx *sql.Tx) (int, error) {
+	type gap struct {
+		userID int64
+		amount float64
+		at     time.Time
+	}
+
+	rows, err := tx.Query(
+		`SELECT u.id, u.balance - COALESCE(SUM(e.amount), 0), MIN(e.created_at) FROM users u
+		LEFT JOIN ledger_entries e ON e.account = CONCAT('user:', u.id)
+		GROUP BY u.id, u.balance
+		HAVING ROUND(u.balance - COALESCE(SUM(e.amount), 0), 2) <> 0
+		ORDER BY u.id`,
+	)
+	if err != nil {
+		return 0, err
+	}
+	var gaps []gap
+	for rows.Next() {
+		var g gap
+		var first sql.NullTime
+		if err := rows.Scan(&g.userID, &g.amount, &first); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		g.at = time.Now()
+		if first.Valid {
+			g.at = first.Time.Add(-time.Second)
+		}
+		gaps = append(gaps, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, g := range gaps {
+		from, to := BankOpeningBalance, UserAccount(g.userID)
+		if g.amount < 0 {
+			from, to = to, from
+		}
+		amount := Round(math.Abs(g.amount))
+
+		result, err := tx.Exec(
+			`INSERT INTO transactions (kind, status, from_account, to_account, amount, currency, memo, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			KindOpeningBalance, StatusPosted, from, to, amount, types.DefaultCurrency, "Начальный остаток", g.at,
+		)
+		if err != nil {
+			return 0, err
+		}
+		txID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO ledger_entries (transaction_id, account, amount, created_at) VALUES (?, ?, ?, ?), (?, ?, ?, ?)",
+			txID, from, -amount, g.at, txID, to, amount, g.at,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(gaps), nil
+}
@@ -0,0 +1,36 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysInYear(t *testing.T) {
+	tests := []struct {
+		dayCount string
+		year     int
+		want     float64
+	}{
+		{"ACT/365", 2024, 365},
+		{"ACT/365", 2025, 365},
+		{"ACT/360", 2024, 360},
+		{"ACT/ACT", 2023, 365},
+		{"ACT/ACT", 2024, 366},
+		{"ACT/ACT", 1900, 365},
+		{"ACT/ACT", 2000, 366},
+		{"ACT/ACT", 2100, 365},
+	}
+	for _, tt := range tests {
+		got, err := DaysInYear(tt.dayCount, time.Date(tt.year, 6, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("DaysInYear(%s, %d) = %v, want %v", tt.dayCount, tt.year, got, tt.want)
+		}
+	}
+
+	if _, err := DaysInYear("30/360", time.Now()); err == nil {
+		t.Error("DaysInYear(30/360): want error")
+	}
+}
@@ -1,15 +1,99 @@
 package main
 
 import (
+	"backend_golang/apierr"
+	"backend_golang/audit"
+	"backend_golang/database"
+	"backend_golang/events"
+	"backend_golang/fraud"
+	"backend_golang/handlers/accounts"
+	auditapi "backend_golang/handlers/audit"
 	"backend_golang/handlers/auth"
+	"backend_golang/handlers/cards"
+	"backend_golang/handlers/compliance"
+	fraudapi "backend_golang/handlers/fraud"
+	"backend_golang/handlers/goals"
+	"backend_golang/handlers/holds"
+	integrityapi "backend_golang/handlers/integrity"
+	kycapi "backend_golang/handlers/kyc"
+	"backend_golang/handlers/loans"
+	"backend_golang/handlers/notifications"
+	"backend_golang/handlers/overdrafts"
+	"backend_golang/handlers/payments"
+	"backend_golang/handlers/payouts"
+	"backend_golang/handlers/savings"
+	"backend_golang/handlers/statements"
+	streamapi "backend_golang/handlers/stream"
+	"backend_golang/handlers/transfers"
 	"backend_golang/handlers/users"
+	webhooksapi "backend_golang/handlers/webhooks"
+	"backend_golang/i18n"
+	"backend_golang/integrity"
+	"backend_golang/jobs"
+	"backend_golang/kyc"
+	"backend_golang/ledger"
+	"backend_golang/notify"
+	"backend_golang/sanctions"
+	"backend_golang/stream"
+	"backend_golang/types"
+	"backend_golang/validation"
+	"backend_golang/webhooks"
 	"fmt"
+	"log"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
-	r := gin.Default()
+	if err := database.Connect(); err != nil {
+		log.Fatal("Error connecting to database:", err)
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	store, err := kyc.NewLocalStore(types.KYCStorageDir, types.KYCKeyFile)
+	if err != nil {
+		log.Fatal("Error opening KYC document storage:", err)
+	}
+	kyc.Blobs = store
+
+	publisher, err := events.NewPublisher(types.OutboxPublisher)
+	if err != nil {
+		log.Fatal("Error creating event publisher:", err)
+	}
+	events.DefaultPublisher = publisher
+
+	if err := i18n.Check(); err != nil {
+		log.Fatal("Error in message catalogue:", err)
+	}
+
+	if err := notify.Configure(); err != nil {
+		log.Fatal("Error configuring notification channels:", err)
+	}
+
+	if err := cards.Configure(); err != nil {
+		log.Fatal("Error configuring cards:", err)
+	}
+
+	if err := integrity.BackfillOpeningBalances(); err != nil {
+		log.Fatal("Error backfilling opening balances:", err)
+	}
+
+	// apierr.Handler стоит до Recovery, чтобы ответить и на панику
+	r := gin.New()
+	r.Use(gin.Logger(), apierr.Handler, gin.CustomRecovery(apierr.Recovered))
+	r.NoRoute(apierr.NoRoute)
+
+	ledger.OnBalanceChange(overdrafts.BalanceHook)
+	ledger.OnPost(goals.RuleHook)
+	ledger.OnPost(audit.PostHook)
+	ledger.OnPost(events.PostHook)
+	events.OnRecord(webhooks.EventHook)
+	events.OnRecord(notify.EventHook)
 
 	authGroup := r.Group("/auth")
 	{
@@ -17,18 +101,229 @@ func main() {
 		authGroup.POST("/login", auth.Login)
 		authGroup.DELETE("/logout", auth.Logout)
 		authGroup.PUT("/refresh", auth.RefreshSession)
-		authGroup.PUT("/password", auth.RefreshPassword)
+		authGroup.PUT("/password", auth.RequireSession, auth.RefreshPassword)
 		authGroup.GET("/session", auth.GetBySession)
 	}
 
-	usersGroup := r.Group("/users")
+	usersGroup := r.Group("/users", auth.RequireSession)
 	{
-		usersGroup.GET("", users.GetAll)
+		usersGroup.GET("", auth.RequireRole(auth.RoleAdmin), users.GetAll)
 		usersGroup.GET("/:id", users.GetByID)
 		usersGroup.DELETE("/:id", users.UserDelete)
 		usersGroup.PUT("/:id", users.UpdateProfile)
 	}
 
+	transfersGroup := r.Group("/transfers", auth.RequireSession)
+	{
+		transfersGroup.POST("", auth.RequireAccountAccess(auth.PermissionTransfer), transfers.Create)
+		transfersGroup.GET("/:id", auth.RequireAccountAccess(auth.PermissionView), transfers.GetByID)
+		transfersGroup.POST("/:id/reversals", transfers.RequestReversal)
+	}
+
+	reversalsGroup := r.Group("/reversals", auth.RequireSession)
+	{
+		reversalsGroup.GET("", transfers.ListReversals)
+		reversalsGroup.PUT("/:id/accept", transfers.AcceptReversal)
+		reversalsGroup.PUT("/:id/decline", transfers.DeclineReversal)
+		reversalsGroup.PUT("/:id/cancel", transfers.CancelReversal)
+	}
+
+	paymentsGroup := r.Group("/payment-requests", auth.RequireSession)
+	{
+		paymentsGroup.POST("", payments.Create)
+		paymentsGroup.GET("", payments.List)
+		paymentsGroup.POST("/:id/pay", payments.Pay)
+		paymentsGroup.PUT("/:id/decline", payments.Decline)
+		paymentsGroup.PUT("/:id/cancel", payments.Cancel)
+		paymentsGroup.GET("/link/:token", payments.GetByToken)
+		paymentsGroup.POST("/link/:token/pay", payments.PayByToken)
+	}
+
+	// Карты, вклады, кредиты и копилки принадлежат самому пользователю:
+	// совместный доступ к счёту на них не распространяется
+	cardsGroup := r.Group("/cards", auth.RequireSession, auth.OwnerOnly)
+	{
+		cardsGroup.POST("", cards.Issue)
+		cardsGroup.GET("", cards.List)
+		cardsGroup.PUT("/:id/freeze", cards.Freeze)
+		cardsGroup.PUT("/:id/unfreeze", cards.Unfreeze)
+		cardsGroup.PUT("/:id/limits", cards.UpdateLimits)
+	}
+
+	networkGroup := r.Group("/card-network", cards.RequireNetworkKey)
+	{
+		networkGroup.POST("/authorizations", cards.Authorize)
+		networkGroup.POST("/authorizations/:id/capture", cards.Capture)
+		networkGroup.POST("/authorizations/:id/reversal", cards.Reversal)
+		networkGroup.POST("/authorizations/:id/refund", cards.Refund)
+	}
+
+	accountGroup := r.Group("", auth.RequireSession, auth.RequireAccountAccess(auth.PermissionView))
+	{
+		accountGroup.GET("/balance", holds.GetBalance)
+		accountGroup.GET("/holds", holds.List)
+		accountGroup.GET("/transactions", transfers.History)
+		accountGroup.GET("/overdraft", overdrafts.Get)
+		accountGroup.GET("/overdraft/interest", overdrafts.Interest)
+	}
+
+	holdersGroup := r.Group("/account-holders", auth.RequireSession)
+	{
+		holdersGroup.POST("", accounts.Invite)
+		holdersGroup.GET("", accounts.List)
+		holdersGroup.PUT("/:id", accounts.Update)
+		holdersGroup.PUT("/:id/accept", accounts.Accept)
+		holdersGroup.PUT("/:id/decline", accounts.Decline)
+		holdersGroup.DELETE("/:id", accounts.Revoke)
+	}
+
+	dependentsGroup := r.Group("/dependents", auth.RequireSession)
+	{
+		dependentsGroup.POST("", accounts.CreateDependent)
+		dependentsGroup.GET("", accounts.ListDependents)
+		dependentsGroup.PUT("/:id/limit", accounts.UpdateDependentLimit)
+	}
+
+	savingsGroup := r.Group("/savings", auth.RequireSession, auth.OwnerOnly)
+	{
+		savingsGroup.POST("", savings.Open)
+		savingsGroup.GET("", savings.List)
+		savingsGroup.GET("/rates", savings.ListRates)
+		savingsGroup.POST("/:id/deposit", savings.Deposit)
+		savingsGroup.POST("/:id/withdraw", savings.Withdraw)
+		savingsGroup.GET("/:id/accruals", savings.Accruals)
+	}
+
+	loansGroup := r.Group("/loans", auth.RequireSession, auth.OwnerOnly)
+	{
+		loansGroup.GET("/calculator", loans.Calculator)
+		loansGroup.POST("", loans.Apply)
+		loansGroup.GET("", loans.List)
+		loansGroup.GET("/:id", loans.GetByID)
+		loansGroup.GET("/:id/schedule", loans.Schedule)
+		loansGroup.POST("/:id/prepay", loans.Prepay)
+	}
+
+	payoutsGroup := r.Group("/payouts", auth.RequireSession)
+	{
+		payoutsGroup.POST("", auth.RequireAccountAccess(auth.PermissionTransfer), payouts.Upload)
+		payoutsGroup.POST("/pain001", auth.RequireAccountAccess(auth.PermissionTransfer), payouts.ImportPain001)
+		payoutsGroup.GET("", auth.RequireAccountAccess(auth.PermissionView), payouts.List)
+		payoutsGroup.GET("/:id", auth.RequireAccountAccess(auth.PermissionView), payouts.GetByID)
+		payoutsGroup.GET("/:id/report", auth.RequireAccountAccess(auth.PermissionView), payouts.Report)
+		payoutsGroup.PUT("/:id/approve", auth.RequireAccountAccess(auth.PermissionFull), payouts.Approve)
+		payoutsGroup.PUT("/:id/cancel", auth.RequireAccountAccess(auth.PermissionTransfer), payouts.Cancel)
+	}
+
+	statementsGroup := r.Group("/statements", auth.RequireSession, auth.RequireAccountAccess(auth.PermissionView))
+	{
+		statementsGroup.GET("", statements.Get)
+	}
+
+	goalsGroup := r.Group("/goals", auth.RequireSession, auth.OwnerOnly)
+	{
+		goalsGroup.POST("", goals.Create)
+		goalsGroup.GET("", goals.List)
+		goalsGroup.GET("/:id", goals.GetByID)
+		goalsGroup.POST("/:id/contribute", goals.Contribute)
+		goalsGroup.POST("/:id/withdraw", goals.Withdraw)
+		goalsGroup.DELETE("/:id", goals.Close)
+		goalsGroup.POST("/:id/rules", goals.AddRule)
+		goalsGroup.GET("/:id/rules", goals.ListRules)
+		goalsGroup.DELETE("/:id/rules/:rule_id", goals.DeleteRule)
+	}
+
+	fraudGroup := r.Group("/fraud", auth.RequireSession, auth.RequireRole(auth.RoleAnalyst))
+	{
+		fraudGroup.GET("/rules", fraudapi.Rules)
+		fraudGroup.GET("/reviews", fraudapi.ListReviews)
+		fraudGroup.GET("/reviews/:id", fraudapi.GetReview)
+		fraudGroup.PUT("/reviews/:id/approve", fraudapi.Approve)
+		fraudGroup.PUT("/reviews/:id/reject", fraudapi.Reject)
+	}
+
+	complianceGroup := r.Group("/compliance", auth.RequireSession, auth.RequireRole(auth.RoleCompliance))
+	{
+		complianceGroup.GET("/lists", compliance.Lists)
+		complianceGroup.GET("/screen", compliance.Screen)
+		complianceGroup.GET("/reviews", compliance.ListReviews)
+		complianceGroup.GET("/reviews/:id", compliance.GetReview)
+		complianceGroup.PUT("/reviews/:id/clear", compliance.Clear)
+		complianceGroup.PUT("/reviews/:id/confirm", compliance.Confirm)
+	}
+
+	kycGroup := r.Group("/kyc", auth.RequireSession)
+	{
+		kycGroup.GET("", kycapi.Get)
+		kycGroup.POST("/documents", kycapi.UploadDocument)
+		kycGroup.DELETE("/documents/:id", kycapi.DeleteDocument)
+		kycGroup.POST("/submit", kycapi.Submit)
+	}
+
+	kycReviewGroup := r.Group("/kyc/reviews", auth.RequireSession, auth.RequireRole(auth.RoleKYCReviewer))
+	{
+		kycReviewGroup.GET("", kycapi.ListSubmissions)
+		kycReviewGroup.GET("/:user_id", kycapi.GetSubmission)
+		kycReviewGroup.GET("/:user_id/documents/:id", kycapi.DownloadDocument)
+		kycReviewGroup.PUT("/:user_id/approve", kycapi.Approve)
+		kycReviewGroup.PUT("/:user_id/reject", kycapi.Reject)
+		kycReviewGroup.PUT("/:user_id/request-info", kycapi.RequestInfo)
+	}
+
+	streamGroup := r.Group("/stream", auth.RequireSession)
+	{
+		streamGroup.GET("/ws", streamapi.WebSocket)
+		streamGroup.GET("/sse", streamapi.SSE)
+	}
+
+	webhooksGroup := r.Group("/webhooks", auth.RequireSession)
+	{
+		webhooksGroup.POST("", webhooksapi.Create)
+		webhooksGroup.GET("", webhooksapi.List)
+		webhooksGroup.GET("/:id", webhooksapi.GetByID)
+		webhooksGroup.PUT("/:id", webhooksapi.Update)
+		webhooksGroup.DELETE("/:id", webhooksapi.Delete)
+		webhooksGroup.POST("/:id/rotate-secret", webhooksapi.RotateSecret)
+		webhooksGroup.GET("/:id/deliveries", webhooksapi.Deliveries)
+		webhooksGroup.GET("/:id/deliveries/:delivery_id", webhooksapi.Delivery)
+		webhooksGroup.POST("/:id/deliveries/:delivery_id/redeliver", webhooksapi.Redeliver)
+	}
+
+	notificationsGroup := r.Group("/notifications", auth.RequireSession)
+	{
+		notificationsGroup.GET("/inbox", notifications.Inbox)
+		notificationsGroup.PUT("/inbox/read-all", notifications.MarkAllRead)
+		notificationsGroup.PUT("/inbox/:id/read", notifications.MarkRead)
+		notificationsGroup.PUT("/inbox/:id/unread", notifications.MarkUnread)
+		notificationsGroup.GET("/settings", notifications.Settings)
+		notificationsGroup.PUT("/settings", notifications.UpdateSettings)
+		notificationsGroup.PUT("/preferences", notifications.UpdatePreferences)
+		notificationsGroup.POST("/push-tokens", notifications.RegisterPushToken)
+		notificationsGroup.DELETE("/push-tokens", notifications.DeletePushToken)
+	}
+
+	auditGroup := r.Group("/audit", auth.RequireSession, auth.RequireRole(auth.RoleAuditor))
+	{
+		auditGroup.GET("", auditapi.List)
+		auditGroup.GET("/verify", auditapi.Verify)
+	}
+
+	adminGroup := r.Group("/admin", auth.RequireSession, auth.RequireRole(auth.RoleAdmin),
+		audit.Middleware(audit.EventAdminAction, auth.CurrentUserID))
+	{
+		adminGroup.POST("/transfers/:id/reverse", transfers.ForceReversal)
+		adminGroup.POST("/holds", holds.Place)
+		adminGroup.DELETE("/holds/:id", holds.Release)
+		adminGroup.POST("/savings/rates", savings.AddRate)
+		adminGroup.GET("/loans", loans.AdminList)
+		adminGroup.PUT("/loans/:id/approve", loans.Approve)
+		adminGroup.PUT("/loans/:id/reject", loans.Reject)
+		adminGroup.PUT("/overdrafts/:user_id", overdrafts.Set)
+		adminGroup.GET("/statements", statements.AdminGet)
+		adminGroup.GET("/ledger/verify", integrityapi.Verify)
+		adminGroup.GET("/ledger/snapshots", integrityapi.Snapshots)
+	}
+
 	fmt.Println("✅ Server started: http://localhost:8080")
 	fmt.Println("📌 Endpoints for Postman:")
 
@@ -46,5 +341,177 @@ func main() {
 	fmt.Println("  PUT    http://localhost:8080/auth/password")
 	fmt.Println("  GET    http://localhost:8080/auth/session")
 
+	fmt.Println("\n  TRANSFERS  ")
+	fmt.Println("  POST   http://localhost:8080/transfers")
+	fmt.Println("  GET    http://localhost:8080/transfers/:id")
+	fmt.Println("  POST   http://localhost:8080/transfers/:id/reversals")
+	fmt.Println("  GET    http://localhost:8080/reversals")
+	fmt.Println("  PUT    http://localhost:8080/reversals/:id/accept")
+	fmt.Println("  PUT    http://localhost:8080/reversals/:id/decline")
+	fmt.Println("  PUT    http://localhost:8080/reversals/:id/cancel")
+
+	fmt.Println("\n  PAYMENT REQUESTS  ")
+	fmt.Println("  POST   http://localhost:8080/payment-requests")
+	fmt.Println("  GET    http://localhost:8080/payment-requests")
+	fmt.Println("  POST   http://localhost:8080/payment-requests/:id/pay")
+	fmt.Println("  PUT    http://localhost:8080/payment-requests/:id/decline")
+	fmt.Println("  PUT    http://localhost:8080/payment-requests/:id/cancel")
+	fmt.Println("  GET    http://localhost:8080/payment-requests/link/:token")
+	fmt.Println("  POST   http://localhost:8080/payment-requests/link/:token/pay")
+
+	fmt.Println("\n  BALANCE  ")
+	fmt.Println("  GET    http://localhost:8080/balance")
+	fmt.Println("  GET    http://localhost:8080/holds")
+	fmt.Println("  GET    http://localhost:8080/transactions")
+	fmt.Println("  GET    http://localhost:8080/overdraft")
+	fmt.Println("  GET    http://localhost:8080/overdraft/interest")
+
+	fmt.Println("\n  SHARED ACCOUNTS  ")
+	fmt.Println("  POST   http://localhost:8080/account-holders")
+	fmt.Println("  GET    http://localhost:8080/account-holders")
+	fmt.Println("  PUT    http://localhost:8080/account-holders/:id")
+	fmt.Println("  PUT    http://localhost:8080/account-holders/:id/accept")
+	fmt.Println("  PUT    http://localhost:8080/account-holders/:id/decline")
+	fmt.Println("  DELETE http://localhost:8080/account-holders/:id")
+	fmt.Println("  POST   http://localhost:8080/dependents")
+	fmt.Println("  GET    http://localhost:8080/dependents")
+	fmt.Println("  PUT    http://localhost:8080/dependents/:id/limit")
+
+	fmt.Println("\n  SAVINGS  ")
+	fmt.Println("  POST   http://localhost:8080/savings")
+	fmt.Println("  GET    http://localhost:8080/savings")
+	fmt.Println("  GET    http://localhost:8080/savings/rates")
+	fmt.Println("  POST   http://localhost:8080/savings/:id/deposit")
+	fmt.Println("  POST   http://localhost:8080/savings/:id/withdraw")
+	fmt.Println("  GET    http://localhost:8080/savings/:id/accruals")
+
+	fmt.Println("\n  PAYOUTS  ")
+	fmt.Println("  POST   http://localhost:8080/payouts")
+	fmt.Println("  POST   http://localhost:8080/payouts/pain001")
+	fmt.Println("  GET    http://localhost:8080/payouts")
+	fmt.Println("  GET    http://localhost:8080/payouts/:id")
+	fmt.Println("  GET    http://localhost:8080/payouts/:id/report")
+	fmt.Println("  PUT    http://localhost:8080/payouts/:id/approve")
+	fmt.Println("  PUT    http://localhost:8080/payouts/:id/cancel")
+
+	fmt.Println("\n  STATEMENTS  ")
+	fmt.Println("  GET    http://localhost:8080/statements")
+
+	fmt.Println("\n  GOALS  ")
+	fmt.Println("  POST   http://localhost:8080/goals")
+	fmt.Println("  GET    http://localhost:8080/goals")
+	fmt.Println("  GET    http://localhost:8080/goals/:id")
+	fmt.Println("  POST   http://localhost:8080/goals/:id/contribute")
+	fmt.Println("  POST   http://localhost:8080/goals/:id/withdraw")
+	fmt.Println("  DELETE http://localhost:8080/goals/:id")
+	fmt.Println("  POST   http://localhost:8080/goals/:id/rules")
+	fmt.Println("  GET    http://localhost:8080/goals/:id/rules")
+	fmt.Println("  DELETE http://localhost:8080/goals/:id/rules/:rule_id")
+
+	fmt.Println("\n  LOANS  ")
+	fmt.Println("  GET    http://localhost:8080/loans/calculator")
+	fmt.Println("  POST   http://localhost:8080/loans")
+	fmt.Println("  GET    http://localhost:8080/loans")
+	fmt.Println("  GET    http://localhost:8080/loans/:id")
+	fmt.Println("  GET    http://localhost:8080/loans/:id/schedule")
+	fmt.Println("  POST   http://localhost:8080/loans/:id/prepay")
+
+	fmt.Println("\n  CARDS  ")
+	fmt.Println("  POST   http://localhost:8080/cards")
+	fmt.Println("  GET    http://localhost:8080/cards")
+	fmt.Println("  PUT    http://localhost:8080/cards/:id/freeze")
+	fmt.Println("  PUT    http://localhost:8080/cards/:id/unfreeze")
+	fmt.Println("  PUT    http://localhost:8080/cards/:id/limits")
+
+	fmt.Println("\n  CARD NETWORK SIMULATOR  ")
+	fmt.Println("  POST   http://localhost:8080/card-network/authorizations")
+	fmt.Println("  POST   http://localhost:8080/card-network/authorizations/:id/capture")
+	fmt.Println("  POST   http://localhost:8080/card-network/authorizations/:id/reversal")
+	fmt.Println("  POST   http://localhost:8080/card-network/authorizations/:id/refund")
+
+	fmt.Println("\n  FRAUD REVIEW  ")
+	fmt.Println("  GET    http://localhost:8080/fraud/rules")
+	fmt.Println("  GET    http://localhost:8080/fraud/reviews")
+	fmt.Println("  GET    http://localhost:8080/fraud/reviews/:id")
+	fmt.Println("  PUT    http://localhost:8080/fraud/reviews/:id/approve")
+	fmt.Println("  PUT    http://localhost:8080/fraud/reviews/:id/reject")
+
+	fmt.Println("\n  COMPLIANCE  ")
+	fmt.Println("  GET    http://localhost:8080/compliance/lists")
+	fmt.Println("  GET    http://localhost:8080/compliance/screen?name=")
+	fmt.Println("  GET    http://localhost:8080/compliance/reviews")
+	fmt.Println("  GET    http://localhost:8080/compliance/reviews/:id")
+	fmt.Println("  PUT    http://localhost:8080/compliance/reviews/:id/clear")
+	fmt.Println("  PUT    http://localhost:8080/compliance/reviews/:id/confirm")
+
+	fmt.Println("\n  KYC  ")
+	fmt.Println("  GET    http://localhost:8080/kyc")
+	fmt.Println("  POST   http://localhost:8080/kyc/documents")
+	fmt.Println("  DELETE http://localhost:8080/kyc/documents/:id")
+	fmt.Println("  POST   http://localhost:8080/kyc/submit")
+	fmt.Println("  GET    http://localhost:8080/kyc/reviews")
+	fmt.Println("  GET    http://localhost:8080/kyc/reviews/:user_id")
+	fmt.Println("  GET    http://localhost:8080/kyc/reviews/:user_id/documents/:id")
+	fmt.Println("  PUT    http://localhost:8080/kyc/reviews/:user_id/approve")
+	fmt.Println("  PUT    http://localhost:8080/kyc/reviews/:user_id/reject")
+	fmt.Println("  PUT    http://localhost:8080/kyc/reviews/:user_id/request-info")
+
+	fmt.Println("\n  STREAM  ")
+	fmt.Println("  GET    ws://localhost:8080/stream/ws?last_event_id=")
+	fmt.Println("  GET    http://localhost:8080/stream/sse")
+
+	fmt.Println("\n  WEBHOOKS  ")
+	fmt.Println("  POST   http://localhost:8080/webhooks")
+	fmt.Println("  GET    http://localhost:8080/webhooks")
+	fmt.Println("  GET    http://localhost:8080/webhooks/:id")
+	fmt.Println("  PUT    http://localhost:8080/webhooks/:id")
+	fmt.Println("  DELETE http://localhost:8080/webhooks/:id")
+	fmt.Println("  POST   http://localhost:8080/webhooks/:id/rotate-secret")
+	fmt.Println("  GET    http://localhost:8080/webhooks/:id/deliveries")
+	fmt.Println("  GET    http://localhost:8080/webhooks/:id/deliveries/:delivery_id")
+	fmt.Println("  POST   http://localhost:8080/webhooks/:id/deliveries/:delivery_id/redeliver")
+
+	fmt.Println("\n  NOTIFICATIONS  ")
+	fmt.Println("  GET    http://localhost:8080/notifications/inbox?unread=true")
+	fmt.Println("  PUT    http://localhost:8080/notifications/inbox/read-all")
+	fmt.Println("  PUT    http://localhost:8080/notifications/inbox/:id/read")
+	fmt.Println("  PUT    http://localhost:8080/notifications/inbox/:id/unread")
+	fmt.Println("  GET    http://localhost:8080/notifications/settings")
+	fmt.Println("  PUT    http://localhost:8080/notifications/settings")
+	fmt.Println("  PUT    http://localhost:8080/notifications/preferences")
+	fmt.Println("  POST   http://localhost:8080/notifications/push-tokens")
+	fmt.Println("  DELETE http://localhost:8080/notifications/push-tokens")
+
+	fmt.Println("\n  AUDIT  ")
+	fmt.Println("  GET    http://localhost:8080/audit")
+	fmt.Println("  GET    http://localhost:8080/audit/verify")
+
+	fmt.Println("\n  ADMIN  ")
+	fmt.Println("  POST   http://localhost:8080/admin/transfers/:id/reverse")
+	fmt.Println("  POST   http://localhost:8080/admin/holds")
+	fmt.Println("  DELETE http://localhost:8080/admin/holds/:id")
+	fmt.Println("  POST   http://localhost:8080/admin/savings/rates")
+	fmt.Println("  GET    http://localhost:8080/admin/loans")
+	fmt.Println("  PUT    http://localhost:8080/admin/loans/:id/approve")
+	fmt.Println("  PUT    http://localhost:8080/admin/loans/:id/reject")
+	fmt.Println("  PUT    http://localhost:8080/admin/overdrafts/:user_id")
+	fmt.Println("  GET    http://localhost:8080/admin/statements")
+	fmt.Println("  GET    http://localhost:8080/admin/ledger/verify")
+	fmt.Println("  GET    http://localhost:8080/admin/ledger/snapshots")
+
+	jobs.Every("expire-holds", time.Minute, holds.ExpireJob)
+	jobs.Every("savings-interest", time.Hour, savings.InterestJob)
+	jobs.Every("loan-repayments", time.Hour, loans.RepaymentJob)
+	jobs.Every("overdraft-interest", time.Hour, overdrafts.InterestJob)
+	jobs.Every("notifications", 10*time.Second, notify.DispatchJob)
+	jobs.Every("payouts", 10*time.Second, payouts.Job)
+	jobs.Every("balance-snapshots", time.Hour, integrity.SnapshotJob)
+	jobs.Every("fraud-rules", 10*time.Second, fraud.ReloadJob)
+	jobs.Every("sanctions-lists", time.Minute, sanctions.ReloadJob)
+	jobs.Every("common-passwords", time.Minute, validation.ReloadJob)
+	jobs.Every("outbox-relay", time.Second, events.RelayJob)
+	jobs.Every("webhooks", 5*time.Second, webhooks.DispatchJob)
+	jobs.Every("stream", 500*time.Millisecond, stream.PollJob)
+
 	r.Run(":8080")
 }
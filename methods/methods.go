@@ -22,3 +22,64 @@ func GenerateSecureSession(length int) string {
 		seconds,
 		micros)
 }
+
+// GenerateToken генерирует случайный hex-токен из length байт
+func GenerateToken(length int) string {
+	randomPart := make([]byte, length)
+	rand.Read(randomPart)
+
+	return fmt.Sprintf("%x", randomPart)
+}
+
+// LuhnCheckDigit считает контрольную цифру по алгоритму Луна для строки цифр
+func LuhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// LuhnValid проверяет номер карты по алгоритму Луна
+func LuhnValid(pan string) bool {
+	if len(pan) < 2 {
+		return false
+	}
+	for _, r := range pan {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return LuhnCheckDigit(pan[:len(pan)-1]) == int(pan[len(pan)-1]-'0')
+}
+
+// GeneratePAN генерирует номер карты длины length с префиксом bin и контрольной цифрой
+func GeneratePAN(bin string, length int) string {
+	digits := bin + GenerateDigits(length-len(bin)-1)
+	return digits + fmt.Sprint(LuhnCheckDigit(digits))
+}
+
+// GenerateDigits генерирует случайную строку из n цифр.
+// Байты от 250 отбрасываются, иначе цифры 0–5 выпадали бы чаще.
+func GenerateDigits(n int) string {
+	digits := make([]byte, 0, n)
+	random := make([]byte, n)
+	for len(digits) < n {
+		rand.Read(random)
+		for _, b := range random {
+			if b < 250 && len(digits) < n {
+				digits = append(digits, '0'+b%10)
+			}
+		}
+	}
+	return string(digits)
+}
@@ -0,0 +1,86 @@
+package methods
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLuhnCheckDigit(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+	}{
+		{"7992739871", 3},
+		{"411111111111111", 1},
+		{"220070000000000", 9},
+		{"0", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := LuhnCheckDigit(tt.digits); got != tt.want {
+			t.Errorf("LuhnCheckDigit(%q) = %d, want %d", tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		pan  string
+		want bool
+	}{
+		{"79927398713", true},
+		{"4111111111111111", true},
+		{"4111111111111112", false},
+		{"4111 1111 1111 1111", false},
+		{"411111111111111a", false},
+		{"0", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := LuhnValid(tt.pan); got != tt.want {
+			t.Errorf("LuhnValid(%q) = %v, want %v", tt.pan, got, tt.want)
+		}
+	}
+}
+
+func TestGeneratePAN(t *testing.T) {
+	tests := []struct {
+		bin    string
+		length int
+	}{
+		{"220070", 16},
+		{"4", 16},
+		{"2200700", 19},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			pan := GeneratePAN(tt.bin, tt.length)
+			if len(pan) != tt.length || !strings.HasPrefix(pan, tt.bin) || !LuhnValid(pan) {
+				t.Fatalf("GeneratePAN(%q, %d) = %q", tt.bin, tt.length, pan)
+			}
+		}
+	}
+}
+
+func TestGenerateDigits(t *testing.T) {
+	const n = 4000000
+	digits := GenerateDigits(n)
+	if len(digits) != n {
+		t.Fatalf("len = %d, want %d", len(digits), n)
+	}
+
+	var counts [10]int
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			t.Fatalf("unexpected character %q", d)
+		}
+		counts[d-'0']++
+	}
+
+	// При смещении b%10 цифры 0–5 выпадали бы на 1,6% чаще ожидаемого
+	for d, count := range counts {
+		if diff := count - n/10; diff > n/10*8/1000 || -diff > n/10*8/1000 {
+			t.Errorf("digit %d: %d times, want %d ± 0.8%%", d, count, n/10)
+		}
+	}
+}
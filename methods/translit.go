@@ -0,0 +1,32 @@
+package methods
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Translit транслитерирует русскую букву латиницей с сохранением регистра;
+// ok=false, если r не кириллица
+func Translit(r rune) (string, bool) {
+	lower := r
+	if r >= 'А' && r <= 'Я' {
+		lower = r + ('а' - 'А')
+	} else if r == 'Ё' {
+		lower = 'ё'
+	}
+
+	latin, ok := cyrillic[lower]
+	if !ok || lower == r || latin == "" {
+		return latin, ok
+	}
+	first, size := utf8.DecodeRuneInString(latin)
+	return strings.ToUpper(string(first)) + latin[size:], true
+}
@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"backend_golang/types"
+)
+
+// Каналы доставки
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+	// ChannelInbox уведомления внутри приложения; пишутся сразу при
+	// постановке в очередь и не зависят от тихих часов
+	ChannelInbox = "inbox"
+)
+
+// AllChannels каналы в порядке показа в настройках
+var AllChannels = []string{ChannelInbox, ChannelPush, ChannelEmail, ChannelSMS}
+
+// Message уведомление, готовое к отправке
+type Message struct {
+	ID      int64  `json:"id"`
+	UserID  int64  `json:"user_id"`
+	Event   string `json:"event"`
+	Channel string `json:"channel"`
+	Locale  string `json:"locale"`
+	// To адреса получателя: e-mail, номер телефона или push-токены
+	To     []string               `json:"to"`
+	Title  string                 `json:"title"`
+	Body   string                 `json:"body"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// Channel доставляет уведомление по одному каналу. Ошибка означает, что
+// уведомление не доставлено и будет отправлено повторно.
+type Channel interface {
+	Send(m Message) error
+}
+
+// Channels каналы, через которые DispatchJob отправляет уведомления;
+// настраиваются Configure
+var Channels = map[string]Channel{
+	ChannelEmail: LogChannel{},
+	ChannelSMS:   LogChannel{},
+	ChannelPush:  LogChannel{},
+}
+
+// Configure настраивает каналы по types.Notify*Transport
+func Configure() error {
+	transports := map[string]string{
+		ChannelEmail: types.NotifyEmailTransport,
+		ChannelSMS:   types.NotifySMSTransport,
+		ChannelPush:  types.NotifyPushTransport,
+	}
+	for channel, transport := range transports {
+		c, err := NewChannel(channel, transport)
+		if err != nil {
+			return err
+		}
+		Channels[channel] = c
+	}
+	return nil
+}
+
+// NewChannel создаёт канал по имени транспорта: log, file или smtp (только
+// для email). SMS- и push-провайдеры подключаются через SMSChannel и PushChannel.
+func NewChannel(channel, transport string) (Channel, error) {
+	switch transport {
+	case "log", "":
+		return LogChannel{}, nil
+	case "file":
+		return NewFileChannel(filepath.Join(types.NotifyFileDir, channel+".jsonl")), nil
+	case "smtp":
+		if channel == ChannelEmail {
+			return SMTPChannel{
+				Addr:     types.SMTPAddr,
+				From:     types.SMTPFrom,
+				Username: types.SMTPUsername,
+				Password: types.SMTPPassword,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("notify: unknown transport %q for channel %s", transport, channel)
+}
+
+// LogChannel пишет уведомления в лог; используется, пока канал не настроен
+type LogChannel struct{}
+
+func (LogChannel) Send(m Message) error {
+	log.Printf("📨 Notification %s to user %d %v: %s — %s", m.Channel, m.UserID, m.To, m.Title, m.Body)
+	return nil
+}
+
+// FileChannel дописывает уведомления в файл по одному JSON на строку;
+// подходит для разработки и проверок без внешних сервисов
+type FileChannel struct {
+	Path string
+
+	mu *sync.Mutex
+}
+
+func NewFileChannel(path string) FileChannel {
+	return FileChannel{Path: path, mu: &sync.Mutex{}}
+}
+
+func (c FileChannel) Send(m Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt string `json:"sent_at"`
+	}{m, time.Now().Format(types.TimeLayout)})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// SMTPChannel отправляет письма через SMTP-сервер; STARTTLS включается,
+// если сервер его поддерживает
+type SMTPChannel struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (c SMTPChannel) Send(m Message) error {
+	var auth smtp.Auth
+	if c.Username != "" {
+		host, _, err := net.SplitHostPort(c.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", c.Username, c.Password, host)
+	}
+
+	var body bytes.Buffer
+	qp := quotedprintable.NewWriter(&body)
+	if _, err := qp.Write([]byte(m.Body)); err != nil {
+		return err
+	}
+	if err := qp.Close(); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", c.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("utf-8", m.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	msg.Write(body.Bytes())
+
+	return smtp.SendMail(c.Addr, auth, c.From, m.To, msg.Bytes())
+}
+
+// SMSProvider шлюз отправки SMS
+type SMSProvider interface {
+	SendSMS(phone, text string) error
+}
+
+// SMSChannel отправляет текст уведомления через SMS-провайдера
+type SMSChannel struct {
+	Provider SMSProvider
+}
+
+func (c SMSChannel) Send(m Message) error {
+	for _, phone := range m.To {
+		if err := c.Provider.SendSMS(phone, m.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PushProvider сервис push-уведомлений (FCM, APNs и т.п.)
+type PushProvider interface {
+	Push(token, title, body string, data map[string]interface{}) error
+}
+
+// PushChannel отправляет уведомления через push-провайдера
+type PushChannel struct {
+	Provider PushProvider
+}
+
+// Send отправляет уведомление на все устройства клиента. Уведомление
+// считается доставленным, если его приняло хотя бы одно устройство:
+// иначе повтор пришёл бы второй раз на уже получившие.
+func (c PushChannel) Send(m Message) error {
+	var errs []error
+	for _, token := range m.To {
+		if err := c.Provider.Push(token, m.Title, m.Body, m.Params); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(m.To) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
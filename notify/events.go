@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"backend_golang/events"
+	"backend_golang/ledger"
+)
+
+// Уведомления о доменных событиях
+const (
+	EventTransferIncoming = "transfer_incoming"
+	EventNewLogin         = "new_login"
+	EventPasswordChanged  = "password_changed"
+)
+
+// EventHook ставит уведомления о входе, смене пароля и входящих переводах.
+// Регистрируется через events.OnRecord и вызывается в транзакции события.
+func EventHook(q ledger.Querier, e events.Event) error {
+	switch e.Type {
+	case events.LoggedIn:
+		for _, userID := range e.Users() {
+			err := Queue(q, userID, EventNewLogin, map[string]interface{}{
+				"ip":        e.Payload["ip"],
+				"device_id": e.Payload["device_id"],
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+	case events.PasswordChanged:
+		for _, userID := range e.Users() {
+			if err := Queue(q, userID, EventPasswordChanged, map[string]interface{}{}); err != nil {
+				return err
+			}
+		}
+
+	case events.TransferPosted:
+		from, _ := e.Payload["from_account"].(string)
+		to, _ := e.Payload["to_account"].(string)
+		kind, userID, err := ledger.ParseAccount(to)
+		if err != nil || kind != "user" || from == to {
+			return nil
+		}
+		return Queue(q, userID, EventTransferIncoming, map[string]interface{}{
+			"amount":       e.Payload["amount"],
+			"currency":     e.Payload["currency"],
+			"from_account": from,
+		})
+	}
+	return nil
+}
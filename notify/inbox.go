@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// InboxItem уведомление во входящих приложения
+type InboxItem struct {
+	ID        int64                  `json:"id"`
+	Event     string                 `json:"event"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Params    map[string]interface{} `json:"params"`
+	Read      bool                   `json:"read"`
+	ReadAt    string                 `json:"read_at,omitempty"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+// addToInbox пишет уведомление во входящие на языке клиента
+func addToInbox(q ledger.Querier, userID int64, t *Template, params map[string]interface{}, body string) error {
+	settings, err := GetSettings(q, userID)
+	if err != nil {
+		return err
+	}
+	title, text, err := t.Render(settings.Locale, params)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(
+		"INSERT INTO notification_inbox (user_id, event, title, body, params) VALUES (?, ?, LEFT(?, 255), ?, ?)",
+		userID, t.Event, title, text, body,
+	)
+	return err
+}
+
+// Inbox возвращает входящие клиента, новые первыми. beforeID листает
+// дальше от последнего полученного уведомления, unreadOnly оставляет
+// только непрочитанные.
+func Inbox(q ledger.Querier, userID int64, unreadOnly bool, beforeID int64, limit int) ([]InboxItem, error) {
+	query := "SELECT id, event, title, body, params, read_at, created_at FROM notification_inbox WHERE user_id = ?"
+	args := []interface{}{userID}
+	if unreadOnly {
+		query += " AND read_at IS NULL"
+	}
+	if beforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, beforeID)
+	}
+	args = append(args, limit)
+
+	rows, err := q.Query(query+" ORDER BY id DESC LIMIT ?", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]InboxItem, 0)
+	for rows.Next() {
+		var item InboxItem
+		var params string
+		var readAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&item.ID, &item.Event, &item.Title, &item.Body, &params, &readAt, &createdAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(params), &item.Params); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			item.Read = true
+			item.ReadAt = readAt.Time.Format(types.TimeLayout)
+		}
+		item.CreatedAt = createdAt.Format(types.TimeLayout)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// UnreadCount число непрочитанных уведомлений клиента
+func UnreadCount(q ledger.Querier, userID int64) (int, error) {
+	var count int
+	err := q.QueryRow(
+		"SELECT COUNT(*) FROM notification_inbox WHERE user_id = ? AND read_at IS NULL", userID,
+	).Scan(&count)
+	return count, err
+}
+
+// MarkRead отмечает уведомление клиента прочитанным или снова непрочитанным;
+// sql.ErrNoRows, если такого уведомления у клиента нет
+func MarkRead(q ledger.Querier, userID, id int64, read bool) error {
+	var exists bool
+	err := q.QueryRow("SELECT 1 FROM notification_inbox WHERE id = ? AND user_id = ?", id, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if read {
+		_, err = q.Exec("UPDATE notification_inbox SET read_at = COALESCE(read_at, NOW()) WHERE id = ?", id)
+	} else {
+		_, err = q.Exec("UPDATE notification_inbox SET read_at = NULL WHERE id = ?", id)
+	}
+	return err
+}
+
+// MarkAllRead отмечает прочитанными все уведомления клиента и возвращает их число
+func MarkAllRead(q ledger.Querier, userID int64) (int64, error) {
+	result, err := q.Exec("UPDATE notification_inbox SET read_at = NOW() WHERE user_id = ? AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
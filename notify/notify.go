@@ -0,0 +1,219 @@
+// Package notify ставит уведомления клиентам в очередь и отправляет их.
+// Уведомление пишется в очередь в той же транзакции, что и событие,
+// поэтому при откате клиент не получит сообщение о несостоявшейся операции.
+//
+// Текст берётся из шаблона события на языке клиента, каналы — из настроек
+// клиента, а пока он их не менял — из шаблона. Во входящие приложения
+// уведомление попадает сразу; e-mail, SMS и push отправляет DispatchJob,
+// в тихие часы клиента откладывая всё, кроме уведомлений безопасности.
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+)
+
+// Статусы уведомления в очереди
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+	// StatusSkipped у клиента нет адреса для канала: не указан e-mail
+	// или не зарегистрировано ни одно устройство
+	StatusSkipped = "skipped"
+)
+
+// MaxAttempts число попыток отправки, после которого уведомление считается неотправленным
+const MaxAttempts = 5
+
+// Queue ставит уведомление в очередь по всем каналам, включённым у клиента
+// для этого события
+func Queue(q ledger.Querier, userID int64, event string, params map[string]interface{}) error {
+	t, ok := Templates[event]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+	}
+	channels, err := enabledChannels(q, userID, event)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		if channel == ChannelInbox {
+			if err := addToInbox(q, userID, t, params, string(body)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = q.Exec(
+			"INSERT INTO notifications (user_id, event, params, status, channel) VALUES (?, ?, ?, ?, ?)",
+			userID, event, string(body), StatusPending, channel,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pending struct {
+	id       int64
+	userID   int64
+	event    string
+	params   string
+	attempts int
+	channel  string
+}
+
+// DispatchJob отправляет уведомления из очереди
+func DispatchJob() error {
+	rows, err := database.DB.Query(
+		`SELECT id, user_id, event, params, attempts, channel FROM notifications
+		WHERE status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?) ORDER BY id LIMIT 100`,
+		StatusPending, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	var queue []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.userID, &p.event, &p.params, &p.attempts, &p.channel); err != nil {
+			rows.Close()
+			return err
+		}
+		queue = append(queue, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range queue {
+		if err := dispatch(p); err != nil {
+			return fmt.Errorf("notification %d: %w", p.id, err)
+		}
+	}
+	return nil
+}
+
+func dispatch(p pending) error {
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(p.params), &params); err != nil {
+		return err
+	}
+
+	m := Message{ID: p.id, UserID: p.userID, Event: p.event, Channel: p.channel, Params: params}
+	channel, ok := Channels[p.channel]
+	t, known := Templates[p.event]
+	if !ok || !known {
+		// уведомления, поставленные в очередь до появления каналов, уходят в лог, как раньше
+		m.Title = p.event
+		return finish(p, LogChannel{}.Send(m))
+	}
+
+	settings, err := GetSettings(database.DB, p.userID)
+	if err != nil {
+		return err
+	}
+	if !t.Critical {
+		if until, quiet := settings.QuietUntil(time.Now()); quiet {
+			_, err := database.DB.Exec("UPDATE notifications SET next_attempt_at = ? WHERE id = ?", until, p.id)
+			return err
+		}
+	}
+
+	m.To, err = addresses(p.userID, p.channel, settings)
+	if err != nil {
+		return err
+	}
+	if len(m.To) == 0 {
+		_, err := database.DB.Exec(
+			"UPDATE notifications SET status = ?, last_error = ? WHERE id = ?",
+			StatusSkipped, "no "+p.channel+" address", p.id,
+		)
+		return err
+	}
+
+	m.Locale = settings.Locale
+	m.Title, m.Body, err = t.Render(settings.Locale, params)
+	if err != nil {
+		return err
+	}
+	return finish(p, channel.Send(m))
+}
+
+// finish записывает результат попытки отправки
+func finish(p pending, sendErr error) error {
+	if sendErr != nil {
+		status := StatusPending
+		if p.attempts+1 >= MaxAttempts {
+			status = StatusFailed
+		}
+		_, err := database.DB.Exec(
+			"UPDATE notifications SET attempts = attempts + 1, status = ?, last_error = LEFT(?, 255) WHERE id = ?",
+			status, sendErr.Error(), p.id,
+		)
+		return err
+	}
+
+	_, err := database.DB.Exec(
+		"UPDATE notifications SET attempts = attempts + 1, status = ?, sent_at = NOW() WHERE id = ?",
+		StatusSent, p.id,
+	)
+	return err
+}
+
+// addresses адреса клиента для канала: e-mail из настроек, телефон из
+// профиля, push-токены его устройств
+func addresses(userID int64, channel string, settings Settings) ([]string, error) {
+	switch channel {
+	case ChannelEmail:
+		if settings.Email == "" {
+			return nil, nil
+		}
+		return []string{settings.Email}, nil
+
+	case ChannelSMS:
+		var phone string
+		err := database.DB.QueryRow("SELECT phone_number FROM users WHERE id = ?", userID).Scan(&phone)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil || phone == "" {
+			return nil, err
+		}
+		return []string{phone}, nil
+
+	case ChannelPush:
+		rows, err := database.DB.Query("SELECT token FROM push_tokens WHERE user_id = ? ORDER BY id", userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var tokens []string
+		for rows.Next() {
+			var token string
+			if err := rows.Scan(&token); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+		}
+		return tokens, rows.Err()
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+}
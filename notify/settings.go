@@ -0,0 +1,292 @@
+package notify
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+	_ "time/tzdata" // часовые пояса тихих часов не зависят от tzdata на сервере
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+var (
+	ErrUnknownEvent      = errors.New("notify: unknown event")
+	ErrUnknownChannel    = errors.New("notify: unknown channel")
+	ErrInvalidLocale     = errors.New("notify: unsupported locale")
+	ErrInvalidEmail      = errors.New("notify: invalid email")
+	ErrInvalidTimezone   = errors.New("notify: unknown timezone")
+	ErrInvalidQuietHours = errors.New("notify: quiet hours must be HH:MM and differ")
+	ErrInvalidPlatform   = errors.New("notify: unknown push platform")
+)
+
+// Платформы push-токенов
+var Platforms = []string{"ios", "android", "web"}
+
+// Settings настройки уведомлений клиента. Тихие часы задаются временем
+// начала и конца (HH:MM) в часовом поясе клиента и могут переходить через
+// полночь; пустые значения выключают их.
+type Settings struct {
+	Locale    string `json:"locale"`
+	Email     string `json:"email"`
+	Timezone  string `json:"timezone"`
+	QuietFrom string `json:"quiet_from"`
+	QuietTo   string `json:"quiet_to"`
+}
+
+// Preference каналы, по которым клиент получает уведомление о событии
+type Preference struct {
+	Event    string          `json:"event"`
+	Critical bool            `json:"critical"`
+	Channels map[string]bool `json:"channels"`
+}
+
+// GetSettings возвращает настройки клиента; пока клиент их не менял —
+// значения по умолчанию
+func GetSettings(q ledger.Querier, userID int64) (Settings, error) {
+	var s Settings
+	err := q.QueryRow(
+		"SELECT locale, email, timezone, quiet_from, quiet_to FROM notification_settings WHERE user_id = ?", userID,
+	).Scan(&s.Locale, &s.Email, &s.Timezone, &s.QuietFrom, &s.QuietTo)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return s, err
+	}
+	if s.Locale == "" {
+		s.Locale = types.DefaultLanguage
+	}
+	if s.Timezone == "" {
+		s.Timezone = types.NotifyDefaultTimezone
+	}
+	return s, nil
+}
+
+// UpdateSettings меняет настройки клиента; nil-поля не меняются, пустые
+// quiet_from и quiet_to выключают тихие часы
+func UpdateSettings(q ledger.Querier, userID int64, locale, email, timezone, quietFrom, quietTo *string) (Settings, error) {
+	s, err := GetSettings(q, userID)
+	if err != nil {
+		return s, err
+	}
+
+	if locale != nil {
+		if !contains(Locales, *locale) {
+			return s, ErrInvalidLocale
+		}
+		s.Locale = *locale
+	}
+	if email != nil {
+		if *email != "" {
+			addr, err := mail.ParseAddress(*email)
+			if err != nil || addr.Address != *email {
+				return s, ErrInvalidEmail
+			}
+		}
+		s.Email = *email
+	}
+	if timezone != nil {
+		if _, err := time.LoadLocation(*timezone); err != nil || *timezone == "" {
+			return s, ErrInvalidTimezone
+		}
+		s.Timezone = *timezone
+	}
+	if quietFrom != nil {
+		s.QuietFrom = *quietFrom
+	}
+	if quietTo != nil {
+		s.QuietTo = *quietTo
+	}
+	if err := validateQuietHours(s.QuietFrom, s.QuietTo); err != nil {
+		return s, err
+	}
+
+	_, err = q.Exec(
+		`INSERT INTO notification_settings (user_id, locale, email, timezone, quiet_from, quiet_to)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE locale = VALUES(locale), email = VALUES(email), timezone = VALUES(timezone),
+		quiet_from = VALUES(quiet_from), quiet_to = VALUES(quiet_to)`,
+		userID, s.Locale, s.Email, s.Timezone, s.QuietFrom, s.QuietTo,
+	)
+	return s, err
+}
+
+func validateQuietHours(from, to string) error {
+	if from == "" && to == "" {
+		return nil
+	}
+	start, err := clock(from)
+	if err != nil {
+		return err
+	}
+	end, err := clock(to)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return ErrInvalidQuietHours
+	}
+	return nil
+}
+
+// clock минуты от полуночи для времени HH:MM
+func clock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, ErrInvalidQuietHours
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// QuietUntil конец тихих часов, если now попадает в них
+func (s Settings) QuietUntil(now time.Time) (time.Time, bool) {
+	if s.QuietFrom == "" || s.QuietTo == "" {
+		return time.Time{}, false
+	}
+	start, err := clock(s.QuietFrom)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := clock(s.QuietTo)
+	if err != nil {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.Local
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	quiet := minute >= start && minute < end
+	if start > end {
+		// через полночь, например 23:00–08:00
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// Preferences каналы по всем событиям: значения по умолчанию из шаблонов
+// с учётом изменений клиента
+func Preferences(q ledger.Querier, userID int64) ([]Preference, error) {
+	rows, err := q.Query("SELECT event, channel, enabled FROM notification_preferences WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	custom := make(map[string]map[string]bool)
+	for rows.Next() {
+		var event, channel string
+		var enabled bool
+		if err := rows.Scan(&event, &channel, &enabled); err != nil {
+			return nil, err
+		}
+		if custom[event] == nil {
+			custom[event] = make(map[string]bool)
+		}
+		custom[event][channel] = enabled
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := make([]Preference, 0, len(Events))
+	for _, event := range Events {
+		t := Templates[event]
+		p := Preference{Event: event, Critical: t.Critical, Channels: make(map[string]bool)}
+		for _, channel := range AllChannels {
+			p.Channels[channel] = contains(t.Channels, channel)
+			if enabled, ok := custom[event][channel]; ok {
+				p.Channels[channel] = enabled
+			}
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+// SetPreference включает или выключает канал для события
+func SetPreference(q ledger.Querier, userID int64, event, channel string, enabled bool) error {
+	if _, ok := Templates[event]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+	}
+	if !contains(AllChannels, channel) {
+		return fmt.Errorf("%w: %s", ErrUnknownChannel, channel)
+	}
+
+	_, err := q.Exec(
+		`INSERT INTO notification_preferences (user_id, event, channel, enabled) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`,
+		userID, event, channel, enabled,
+	)
+	return err
+}
+
+// enabledChannels каналы, по которым клиент получает уведомление о событии
+func enabledChannels(q ledger.Querier, userID int64, event string) ([]string, error) {
+	prefs, err := Preferences(q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels []string
+	for _, p := range prefs {
+		if p.Event != event {
+			continue
+		}
+		for _, channel := range AllChannels {
+			if p.Channels[channel] {
+				channels = append(channels, channel)
+			}
+		}
+	}
+	return channels, nil
+}
+
+// RegisterPushToken привязывает токен устройства к клиенту; токен, ранее
+// привязанный к другому клиенту, переходит к новому
+func RegisterPushToken(q ledger.Querier, userID int64, token, platform string) error {
+	if !contains(Platforms, platform) {
+		return ErrInvalidPlatform
+	}
+	_, err := q.Exec(
+		`INSERT INTO push_tokens (user_id, token, platform) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE user_id = VALUES(user_id), platform = VALUES(platform)`,
+		userID, token, platform,
+	)
+	return err
+}
+
+// DeletePushToken отвязывает токен устройства клиента
+func DeletePushToken(q ledger.Querier, userID int64, token string) error {
+	result, err := q.Exec("DELETE FROM push_tokens WHERE user_id = ? AND token = ?", userID, token)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
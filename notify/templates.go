@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"backend_golang/i18n"
+	"backend_golang/types"
+)
+
+// Языки уведомлений — те же, что у сообщений API
+const (
+	LocaleRU = i18n.RU
+	LocaleEN = i18n.EN
+)
+
+// Locales поддерживаемые языки
+var Locales = i18n.Languages
+
+// Template шаблон уведомления об одном событии
+type Template struct {
+	Event string `json:"event"`
+	// Critical уведомления безопасности: приходят и в тихие часы
+	Critical bool `json:"critical"`
+	// Channels каналы, пока клиент не изменил настройки
+	Channels []string `json:"default_channels"`
+
+	text map[string]*template.Template
+}
+
+// Text заголовок и текст уведомления на одном языке; в тексте доступны
+// параметры уведомления: {{.amount}}, {{.note}} и т.п.
+type Text struct {
+	Title string
+	Body  string
+}
+
+// Templates шаблоны по событиям
+var Templates = make(map[string]*Template)
+
+// Events события в порядке объявления, для настроек клиента
+var Events []string
+
+// Register добавляет шаблон; ошибка в тексте шаблона — ошибка программы
+func Register(event string, critical bool, channels []string, texts map[string]Text) {
+	t := &Template{Event: event, Critical: critical, Channels: channels, text: make(map[string]*template.Template)}
+	for _, locale := range Locales {
+		text, ok := texts[locale]
+		if !ok {
+			panic(fmt.Sprintf("notify: template %s has no %s text", event, locale))
+		}
+		t.text[locale] = template.Must(template.New(event + "." + locale).Option("missingkey=zero").
+			Parse(`{{define "title"}}` + text.Title + `{{end}}{{define "body"}}` + text.Body + `{{end}}`))
+	}
+	if _, ok := Templates[event]; !ok {
+		Events = append(Events, event)
+	}
+	Templates[event] = t
+}
+
+// Render заголовок и текст уведомления на языке locale; неизвестный язык
+// заменяется языком по умолчанию
+func (t *Template) Render(locale string, params map[string]interface{}) (string, string, error) {
+	tmpl, ok := t.text[locale]
+	if !ok {
+		tmpl = t.text[types.DefaultLanguage]
+	}
+
+	// числа из JSON приходят как float64 и без этого печатались бы как 1e+06
+	values := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		if f, ok := v.(float64); ok {
+			v = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		values[k] = v
+	}
+
+	var title, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&title, "title", values); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", values); err != nil {
+		return "", "", err
+	}
+	return title.String(), body.String(), nil
+}
+
+func init() {
+	Register("overdraft_entered", false, []string{ChannelInbox, ChannelPush}, map[string]Text{
+		LocaleRU: {"Вы в овердрафте", "Остаток на счёте {{.balance}}, лимит овердрафта {{.limit}}."},
+		LocaleEN: {"You are in overdraft", "Your balance is {{.balance}}, overdraft limit {{.limit}}."},
+	})
+	Register("overdraft_left", false, []string{ChannelInbox, ChannelPush}, map[string]Text{
+		LocaleRU: {"Овердрафт погашен", "Остаток на счёте {{.balance}}."},
+		LocaleEN: {"Overdraft repaid", "Your balance is {{.balance}}."},
+	})
+	Register("overdraft_breached", false, []string{ChannelInbox, ChannelPush, ChannelSMS}, map[string]Text{
+		LocaleRU: {"Превышен лимит овердрафта", "Остаток на счёте {{.balance}} превышает лимит овердрафта {{.limit}}. Пополните счёт."},
+		LocaleEN: {"Overdraft limit exceeded", "Your balance of {{.balance}} exceeds the overdraft limit of {{.limit}}. Please top up your account."},
+	})
+	Register("kyc_approved", false, []string{ChannelInbox, ChannelEmail, ChannelPush}, map[string]Text{
+		LocaleRU: {"Личность подтверждена", "Документы проверены, уровень идентификации: {{.level}}. Лимиты операций увеличены."},
+		LocaleEN: {"Identity verified", "Your documents have been verified, identification level: {{.level}}. Your limits have been raised."},
+	})
+	Register("kyc_rejected", false, []string{ChannelInbox, ChannelEmail, ChannelPush}, map[string]Text{
+		LocaleRU: {"Документы не приняты", "Мы не смогли подтвердить личность.{{if .note}} Причина: {{.note}}{{end}}"},
+		LocaleEN: {"Documents rejected", "We could not verify your identity.{{if .note}} Reason: {{.note}}{{end}}"},
+	})
+	Register("kyc_needs_more_info", false, []string{ChannelInbox, ChannelEmail, ChannelPush}, map[string]Text{
+		LocaleRU: {"Нужны дополнительные документы", "Для проверки личности нужны дополнительные документы.{{if .note}} {{.note}}{{end}}"},
+		LocaleEN: {"More documents needed", "We need more documents to verify your identity.{{if .note}} {{.note}}{{end}}"},
+	})
+	Register(EventTransferIncoming, false, []string{ChannelInbox, ChannelPush}, map[string]Text{
+		LocaleRU: {"Входящий перевод", "Зачислено {{.amount}} {{.currency}}."},
+		LocaleEN: {"Incoming transfer", "You received {{.amount}} {{.currency}}."},
+	})
+	Register(EventNewLogin, true, []string{ChannelInbox, ChannelPush, ChannelEmail}, map[string]Text{
+		LocaleRU: {"Вход в аккаунт", "Выполнен вход в аккаунт{{if .device_id}} с устройства {{.device_id}}{{end}}{{if .ip}}, IP {{.ip}}{{end}}. Если это были не вы, смените пароль."},
+		LocaleEN: {"New sign-in", "Someone signed in to your account{{if .device_id}} from device {{.device_id}}{{end}}{{if .ip}}, IP {{.ip}}{{end}}. If this wasn't you, change your password."},
+	})
+	Register(EventPasswordChanged, true, []string{ChannelInbox, ChannelEmail, ChannelSMS}, map[string]Text{
+		LocaleRU: {"Пароль изменён", "Пароль от аккаунта изменён. Если это были не вы, срочно свяжитесь с банком."},
+		LocaleEN: {"Password changed", "Your account password has been changed. If this wasn't you, contact the bank immediately."},
+	})
+}
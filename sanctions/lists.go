@@ -0,0 +1,454 @@
+// Package sanctions сверяет имена клиентов и получателей с санкционными
+// списками (OFAC SDN, сводный список ЕС). Списки читаются из файлов в
+// types.SanctionsListDir и перечитываются без перезапуска, как только файлы
+// меняются. Совпадения уходят в очередь комплаенса и блокируют операцию
+// до решения офицера.
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"backend_golang/types"
+)
+
+// Названия списков
+const (
+	ListOFAC = "OFAC SDN"
+	ListEU   = "EU"
+)
+
+// Entry запись санкционного списка
+type Entry struct {
+	List     string   `json:"list"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type,omitempty"`
+	Programs []string `json:"programs,omitempty"`
+	Aliases  []string `json:"aliases,omitempty"`
+
+	// names слова имени и псевдонимов после нормализации:
+	// names[0] — имя, names[i] — Aliases[i-1]
+	names [][]string
+}
+
+// Lists загруженные списки
+type Lists struct {
+	Entries  []*Entry       `json:"-"`
+	Files    []string       `json:"files"`
+	Counts   map[string]int `json:"counts"`
+	LoadedAt string         `json:"loaded_at,omitempty"`
+}
+
+var (
+	mu        sync.RWMutex
+	current   = &Lists{Files: []string{}, Counts: map[string]int{}}
+	signature string
+)
+
+// Current возвращает действующие списки
+func Current() *Lists {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Load читает все файлы списков из каталога dir и делает их действующими.
+// Поддерживаются sdn.xml, sdn.csv и alt.csv OFAC, XML и CSV сводного
+// списка ЕС; прочие файлы пропускаются. Если хоть один файл с ошибкой,
+// остаются прежние списки.
+func Load(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	b := &builder{entries: make(map[string]*Entry), aliases: make(map[string][]string)}
+	lists := &Lists{Files: []string{}, Counts: map[string]int{}}
+
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+
+		loaded, err := b.load(path)
+		if err != nil {
+			return fmt.Errorf("sanctions list %s: %w", path, err)
+		}
+		if loaded {
+			lists.Files = append(lists.Files, file.Name())
+		}
+	}
+
+	for _, key := range b.order {
+		e := b.entries[key]
+		for _, alias := range b.aliases[e.ID] {
+			if e.List == ListOFAC {
+				b.addName(e, alias)
+			}
+		}
+		if e.Name == "" {
+			continue
+		}
+
+		e.names = make([][]string, 0, len(e.Aliases)+1)
+		for _, name := range append([]string{e.Name}, e.Aliases...) {
+			e.names = append(e.names, tokens(name))
+		}
+		lists.Entries = append(lists.Entries, e)
+		lists.Counts[e.List]++
+	}
+	lists.LoadedAt = time.Now().Format(types.TimeLayout)
+
+	mu.Lock()
+	current = lists
+	mu.Unlock()
+	return nil
+}
+
+// ReloadJob перечитывает списки, если в каталоге изменился хоть один файл.
+// Пока каталога нет, списки пусты и проверка ничего не находит.
+func ReloadJob() error {
+	sig, err := dirSignature(types.SanctionsListDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sig == signature {
+		return nil
+	}
+
+	// Подпись запоминается и при ошибке, как и у правил антифрода:
+	// повторять ту же ошибку незачем, следующая правка файлов перечитается
+	signature = sig
+	if err := Load(types.SanctionsListDir); err != nil {
+		return err
+	}
+
+	lists := Current()
+	log.Printf("🚫 Sanctions lists loaded from %s: %d entries in %d files",
+		types.SanctionsListDir, len(lists.Entries), len(lists.Files))
+	return nil
+}
+
+func dirSignature(dir string) (string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s|%d|%d;", file.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
+
+// builder собирает записи из нескольких файлов: псевдонимы OFAC
+// лежат в alt.csv отдельно от основных записей sdn.csv
+type builder struct {
+	entries map[string]*Entry
+	order   []string
+	aliases map[string][]string
+}
+
+func (b *builder) entry(list, id string) *Entry {
+	key := list + "|" + id
+	e, ok := b.entries[key]
+	if !ok {
+		e = &Entry{List: list, ID: id}
+		b.entries[key] = e
+		b.order = append(b.order, key)
+	}
+	return e
+}
+
+func (b *builder) addName(e *Entry, name string) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || strings.EqualFold(name, e.Name) {
+		return
+	}
+	if e.Name == "" {
+		e.Name = name
+		return
+	}
+	for _, alias := range e.Aliases {
+		if strings.EqualFold(alias, name) {
+			return
+		}
+	}
+	e.Aliases = append(e.Aliases, name)
+}
+
+func (b *builder) addProgram(e *Entry, program string) {
+	program = strings.TrimSpace(program)
+	if program == "" {
+		return
+	}
+	for _, p := range e.Programs {
+		if p == program {
+			return
+		}
+	}
+	e.Programs = append(e.Programs, program)
+}
+
+// load разбирает файл по его имени и корневому элементу;
+// loaded=false для файлов, которые списком не являются
+func (b *builder) load(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case base == "sdn.csv":
+		return true, b.ofacCSV(f)
+	case base == "alt.csv":
+		return true, b.ofacAliases(f)
+	case strings.HasSuffix(base, ".csv"):
+		return true, b.euCSV(f)
+	case strings.HasSuffix(base, ".xml"):
+		return true, b.xml(f)
+	}
+	return false, nil
+}
+
+// ofacNull пустое значение в CSV-файлах OFAC
+const ofacNull = "-0-"
+
+func ofacField(record []string, i int) string {
+	if i >= len(record) {
+		return ""
+	}
+	value := strings.TrimSpace(record[i])
+	if value == ofacNull {
+		return ""
+	}
+	return value
+}
+
+func newCSVReader(r io.Reader, comma rune) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader
+}
+
+// ofacCSV читает sdn.csv: ent_num, SDN_Name, SDN_Type, Program, ...
+func (b *builder) ofacCSV(r io.Reader) error {
+	reader := newCSVReader(r, ',')
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		id := ofacField(record, 0)
+		if id == "" || len(record) < 2 {
+			continue
+		}
+
+		e := b.entry(ListOFAC, id)
+		b.addName(e, ofacField(record, 1))
+		e.Type = ofacField(record, 2)
+		// программы в одной ячейке: "SDGT] [IRGC"
+		for _, program := range strings.Split(ofacField(record, 3), "] [") {
+			b.addProgram(e, strings.Trim(program, "[]"))
+		}
+	}
+}
+
+// ofacAliases читает alt.csv: ent_num, alt_num, alt_type, alt_name, alt_remarks
+func (b *builder) ofacAliases(r io.Reader) error {
+	reader := newCSVReader(r, ',')
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		id, name := ofacField(record, 0), ofacField(record, 3)
+		if id != "" && name != "" {
+			b.aliases[id] = append(b.aliases[id], name)
+		}
+	}
+}
+
+// euCSV читает CSV сводного списка ЕС: одна строка на псевдоним,
+// разделитель — точка с запятой. Поддерживаются заголовки форматов 1.0 и 1.1.
+func (b *builder) euCSV(r io.Reader) error {
+	reader := newCSVReader(r, ';')
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+
+	column := func(names ...string) int {
+		for i, h := range header {
+			h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+			for _, name := range names {
+				if h == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+
+	idColumn := column("entity_logicalid")
+	nameColumn := column("namealias_wholename", "naal_wholename")
+	if idColumn < 0 || nameColumn < 0 {
+		return fmt.Errorf("unknown CSV format: no Entity_LogicalId or NameAlias_WholeName column")
+	}
+	typeColumn := column("entity_subjecttype", "subject_type")
+	programColumn := column("entity_regulation_programme", "leba_programme")
+
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		id := field(record, idColumn)
+		if id == "" {
+			continue
+		}
+
+		e := b.entry(ListEU, id)
+		b.addName(e, field(record, nameColumn))
+		if t := field(record, typeColumn); t != "" {
+			e.Type = t
+		}
+		b.addProgram(e, field(record, programColumn))
+	}
+}
+
+type ofacXMLEntry struct {
+	UID       string   `xml:"uid"`
+	FirstName string   `xml:"firstName"`
+	LastName  string   `xml:"lastName"`
+	Type      string   `xml:"sdnType"`
+	Programs  []string `xml:"programList>program"`
+	Akas      []struct {
+		FirstName string `xml:"firstName"`
+		LastName  string `xml:"lastName"`
+	} `xml:"akaList>aka"`
+}
+
+type euXMLEntity struct {
+	LogicalID   string `xml:"logicalId,attr"`
+	SubjectType struct {
+		Code string `xml:"code,attr"`
+	} `xml:"subjectType"`
+	Regulations []struct {
+		Programme string `xml:"programme,attr"`
+	} `xml:"regulation"`
+	Aliases []struct {
+		WholeName string `xml:"wholeName,attr"`
+	} `xml:"nameAlias"`
+}
+
+// xml читает sdn.xml OFAC (корень sdnList) или XML сводного списка ЕС
+// (корень export). Файлы большие, поэтому записи разбираются по одной.
+func (b *builder) xml(r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	root := ""
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "sdnList" && root != "export" {
+				return fmt.Errorf("unknown XML root element %q", root)
+			}
+			continue
+		}
+
+		switch {
+		case root == "sdnList" && start.Name.Local == "sdnEntry":
+			var v ofacXMLEntry
+			if err := decoder.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			if v.UID == "" {
+				continue
+			}
+			e := b.entry(ListOFAC, v.UID)
+			b.addName(e, v.FirstName+" "+v.LastName)
+			for _, aka := range v.Akas {
+				b.addName(e, aka.FirstName+" "+aka.LastName)
+			}
+			e.Type = v.Type
+			for _, program := range v.Programs {
+				b.addProgram(e, program)
+			}
+		case root == "export" && start.Name.Local == "sanctionEntity":
+			var v euXMLEntity
+			if err := decoder.DecodeElement(&v, &start); err != nil {
+				return err
+			}
+			if v.LogicalID == "" {
+				continue
+			}
+			e := b.entry(ListEU, v.LogicalID)
+			for _, alias := range v.Aliases {
+				b.addName(e, alias.WholeName)
+			}
+			e.Type = v.SubjectType.Code
+			for _, regulation := range v.Regulations {
+				b.addProgram(e, regulation.Programme)
+			}
+		}
+	}
+
+	if root == "" {
+		return fmt.Errorf("empty XML document")
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package sanctions
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"backend_golang/methods"
+	"backend_golang/types"
+)
+
+// maxMatches сколько самых похожих записей возвращает Screen
+const maxMatches = 10
+
+// Match совпадение имени с записью списка
+type Match struct {
+	List     string   `json:"list"`
+	EntryID  string   `json:"entry_id"`
+	Name     string   `json:"name"`
+	Matched  string   `json:"matched"`
+	Score    float64  `json:"score"`
+	Programs []string `json:"programs,omitempty"`
+}
+
+// Screen сверяет имя со всеми записями действующих списков и возвращает
+// совпадения не ниже types.SanctionsMatchThreshold, от самых похожих.
+// Последнее слово name считается фамилией: «Иван Петров».
+func Screen(name string) []Match {
+	matches := make([]Match, 0)
+	query := tokens(name)
+	if len(query) == 0 {
+		return matches
+	}
+
+	for _, e := range Current().Entries {
+		best, bestName := 0.0, 0
+		for i, candidate := range e.names {
+			if score := similarity(query, candidate); score > best {
+				best, bestName = score, i
+			}
+		}
+		if best < types.SanctionsMatchThreshold {
+			continue
+		}
+
+		matched := e.Name
+		if bestName > 0 {
+			matched = e.Aliases[bestName-1]
+		}
+		matches = append(matches, Match{
+			List:     e.List,
+			EntryID:  e.ID,
+			Name:     e.Name,
+			Matched:  matched,
+			Score:    math.Round(best*1000) / 1000,
+			Programs: e.Programs,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > maxMatches {
+		matches = matches[:maxMatches]
+	}
+	return matches
+}
+
+// similarity сравнивает имя клиента с именем из списка по словам. Последнее
+// слово клиента — фамилия — должно само совпасть с первым или последним словом
+// записи: в списках фамилия стоит в начале или в конце, а отчество в середине.
+// Каждое из остальных слов клиента должно найти свою пару среди оставшихся слов
+// записи; лишние слова записи (отчество, второе имя) не мешают. Схожесть имени —
+// худшая из схожестей пар: одной похожей фамилии мало, нужны и похожие имена.
+func similarity(query, candidate []string) float64 {
+	if len(query) == 0 || len(candidate) == 0 {
+		return 0
+	}
+	// Одно слово совпадает только с записью из одного слова: иначе «Ivan»
+	// совпал бы с любым Ivan из списков
+	if len(query) == 1 && len(candidate) > 1 {
+		return 0
+	}
+
+	surname, given := query[len(query)-1], query[:len(query)-1]
+	best := 0.0
+	for _, i := range []int{0, len(candidate) - 1} {
+		score := jaroWinkler(surname, candidate[i])
+		if score <= best {
+			continue
+		}
+		if len(given) > 0 {
+			rest := append(append([]string(nil), candidate[:i]...), candidate[i+1:]...)
+			score = math.Min(score, coverage(given, rest))
+		}
+		best = math.Max(best, score)
+	}
+	return best
+}
+
+// coverage худшая схожесть слов a с парными им словами b. Пары подбираются
+// от самых похожих, и каждое слово b входит не больше чем в одну пару:
+// иначе «Petr Petrov» совпал бы с любым Petrov. Слово без пары даёт 0.
+func coverage(a, b []string) float64 {
+	if len(b) < len(a) {
+		return 0
+	}
+
+	type pair struct {
+		i, j  int
+		score float64
+	}
+
+	pairs := make([]pair, 0, len(a)*len(b))
+	for i, word := range a {
+		for j, other := range b {
+			pairs = append(pairs, pair{i, j, jaroWinkler(word, other)})
+		}
+	}
+	sort.Slice(pairs, func(x, y int) bool { return pairs[x].score > pairs[y].score })
+
+	usedA := make([]bool, len(a))
+	usedB := make([]bool, len(b))
+	worst := 1.0
+	for _, p := range pairs {
+		if usedA[p.i] || usedB[p.j] {
+			continue
+		}
+		usedA[p.i], usedB[p.j] = true, true
+		worst = math.Min(worst, p.score)
+	}
+	return worst
+}
+
+// jaroWinkler схожесть строк от 0 до 1 с бонусом за общее начало
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	s1, s2 := []rune(a), []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := len(s1)
+	if len(s2) > window {
+		window = len(s2)
+	}
+	window = window/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+	matches := 0
+	for i := range s1 {
+		from, to := i-window, i+window+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(s2) {
+			to = len(s2)
+		}
+		for j := from; j < to; j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, k := 0, 0
+	for i := range s1 {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[k] {
+			k++
+		}
+		if s1[i] != s2[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < 4 && prefix < len(s1) && prefix < len(s2) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// latin буквы с диакритикой и украинские буквы, которых нет в methods.Translit
+var latin = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'č': "ch", 'ď': "d", 'đ': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ě': "e", 'ę': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'ř': "r", 'ś': "s", 'š': "sh", 'ş': "s", 'ť': "t", 'ţ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "zh",
+	'ß': "ss", 'æ': "ae", 'œ': "oe",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// folds сводит к одному виду разные латинские записи одних и тех же
+// звуков: Khodorkovsky/Hodorkovskij, Aleksandr/Alexander, Iurii/Yuri, Maria/Mariya
+var folds = strings.NewReplacer(
+	"shch", "sch",
+	"iya", "ya",
+	"iu", "yu",
+	"ia", "ya",
+	"kh", "h",
+	"ph", "f",
+	"ck", "k",
+	"x", "ks",
+	"j", "y",
+	"w", "v",
+)
+
+// tokens приводит имя к словам для сравнения: кириллица транслитерируется,
+// диакритика снимается, регистр и знаки препинания не учитываются,
+// инициалы отбрасываются
+func tokens(name string) []string {
+	var b strings.Builder
+	for _, r := range name {
+		if translit, ok := methods.Translit(r); ok {
+			b.WriteString(translit)
+			continue
+		}
+
+		r = unicode.ToLower(r)
+		switch {
+		case latin[r] != "":
+			b.WriteString(latin[r])
+		case r == '\'', r == '’', r == '`':
+			// O'Brien и OBrien — одно имя
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte(' ')
+		}
+	}
+
+	words := strings.Fields(strings.ToLower(b.String()))
+	result := make([]string, 0, len(words))
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < 2 {
+			continue
+		}
+		result = append(result, fold(word))
+	}
+	return result
+}
+
+func fold(word string) string {
+	word = folds.Replace(word)
+
+	// Yuriy, Yurii, Yuryy -> Yury
+	for _, suffix := range []string{"iy", "ii", "yy"} {
+		if strings.HasSuffix(word, suffix) {
+			word = strings.TrimSuffix(word, suffix) + "y"
+			break
+		}
+	}
+
+	// двойные буквы: Kassym/Kasym, Gennady/Genady
+	var b strings.Builder
+	var prev rune
+	for _, r := range word {
+		if r != prev {
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
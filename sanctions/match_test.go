@@ -0,0 +1,80 @@
+package sanctions
+
+import (
+	"math"
+	"testing"
+
+	"backend_golang/types"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "martha", 1},
+		{"martha", "marhta", 0.961},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.813},
+		{"abc", "", 0},
+		{"abc", "xyz", 0},
+	}
+	for _, tt := range tests {
+		got := jaroWinkler(tt.a, tt.b)
+		if math.Abs(got-tt.want) > 0.001 {
+			t.Errorf("jaroWinkler(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Юрий Ходорковский", []string{"yury", "hodorkovsky"}},
+		{"KHODORKOVSKIY, Iurii B.", []string{"hodorkovsky", "yury"}},
+		{"Seán O'Brien", []string{"sean", "obrien"}},
+		{"Gennady  Kassym", []string{"genady", "kasym"}},
+	}
+	for _, tt := range tests {
+		got := tokens(tt.name)
+		if len(got) != len(tt.want) {
+			t.Errorf("tokens(%q) = %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("tokens(%q) = %q, want %q", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		client, entry string
+		hit           bool
+	}{
+		// фамилия в списке первой, отчества у клиента нет
+		{"Иван Петров", "PETROV, Ivan Ivanovich", true},
+		{"Иван Петров", "Ivan Ivanovich Petrov", true},
+		{"Юрий Ходорковский", "Khodorkovskiy Iurii", true},
+		{"Мария Петрова", "Maria Ivanovna Petrova", true},
+		{"Aleksandr Smirnov", "Alexander SMIRNOV", true},
+
+		// похожие целиком, но разные люди
+		{"Иван Петров", "Ivan Popov", false},
+		{"Мария Иванова", "Maria Ivanovna Petrova", false},
+		{"Петр Петров", "Petrov", false},
+		{"Иван", "Ivan Petrov", false},
+		{"Анна Петрова", "Ivan Petrov", false},
+	}
+	for _, tt := range tests {
+		score := similarity(tokens(tt.client), tokens(tt.entry))
+		if hit := score >= types.SanctionsMatchThreshold; hit != tt.hit {
+			t.Errorf("similarity(%q, %q) = %.3f, hit %v, want %v", tt.client, tt.entry, score, hit, tt.hit)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package sanctions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Что проверялось
+const (
+	SubjectRegistration = "registration"
+	SubjectTransfer     = "transfer"
+	SubjectPayout       = "payout"
+)
+
+// Статусы проверки
+const (
+	ReviewPending = "pending"
+	// ReviewCleared совпадение ложное: операция продолжается
+	ReviewCleared = "cleared"
+	// ReviewConfirmed совпадение подтверждено: операция отменяется, клиент блокируется
+	ReviewConfirmed = "confirmed"
+)
+
+// Статусы клиента (users.compliance_status)
+const (
+	StatusClear   = "clear"
+	StatusPending = "pending"
+	StatusBlocked = "blocked"
+)
+
+// HoldSource источник холда на сумму перевода, ждущего комплаенса
+const HoldSource = "compliance_review"
+
+var ErrReviewNotPending = errors.New("sanctions: review is not pending")
+
+// Screening результат проверки клиента
+type Screening struct {
+	UserID  int64
+	Name    string
+	Status  string
+	Matches []Match
+}
+
+// Hit показывает, что операцию с клиентом нельзя проводить без решения комплаенса
+func (s Screening) Hit() bool {
+	return len(s.Matches) > 0
+}
+
+// Review событие в очереди комплаенса
+type Review struct {
+	ID            int64                  `json:"id"`
+	SubjectType   string                 `json:"subject_type"`
+	UserID        int64                  `json:"user_id"`
+	ActorID       int64                  `json:"actor_id"`
+	ScreenedName  string                 `json:"screened_name"`
+	Matches       []Match                `json:"matches"`
+	Payload       map[string]interface{} `json:"payload,omitempty"`
+	HoldID        int64                  `json:"hold_id,omitempty"`
+	Status        string                 `json:"status"`
+	DecidedBy     int64                  `json:"decided_by,omitempty"`
+	Note          string                 `json:"note,omitempty"`
+	TransactionID int64                  `json:"transaction_id,omitempty"`
+	CreatedAt     string                 `json:"created_at"`
+	DecidedAt     string                 `json:"decided_at,omitempty"`
+}
+
+// PendingTransfer перевод, который будет проведён, когда совпадение снимут
+type PendingTransfer struct {
+	OwnerID     int64   `json:"owner_id"`
+	FromAccount string  `json:"from_account"`
+	ToAccount   string  `json:"to_account"`
+	Amount      float64 `json:"amount"`
+	Memo        string  `json:"memo,omitempty"`
+	// PaymentRequestID запрос денег, который оплачивается переводом
+	PaymentRequestID int64 `json:"payment_request_id,omitempty"`
+}
+
+// ScreenUser сверяет имя и фамилию клиента со списками. Совпадения,
+// которые комплаенс уже снял для этого клиента, не возвращаются.
+func ScreenUser(q ledger.Querier, userID int64) (Screening, error) {
+	s := Screening{UserID: userID}
+
+	var name, surname string
+	err := q.QueryRow(
+		"SELECT name, surname, compliance_status FROM users WHERE id = ?", userID,
+	).Scan(&name, &surname, &s.Status)
+	if err != nil {
+		return s, err
+	}
+	s.Name = name + " " + surname
+
+	matches := Screen(s.Name)
+	if len(matches) == 0 {
+		s.Matches = matches
+		return s, nil
+	}
+
+	rows, err := q.Query("SELECT list, entry_id FROM sanctions_clearances WHERE user_id = ?", userID)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	cleared := make(map[string]bool)
+	for rows.Next() {
+		var list, entryID string
+		if err := rows.Scan(&list, &entryID); err != nil {
+			return s, err
+		}
+		cleared[list+"|"+entryID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	s.Matches = make([]Match, 0, len(matches))
+	for _, m := range matches {
+		if !cleared[m.List+"|"+m.EntryID] {
+			s.Matches = append(s.Matches, m)
+		}
+	}
+	return s, nil
+}
+
+// QueueRegistration ставит нового клиента в очередь комплаенса;
+// до решения его сессии не действуют
+func QueueRegistration(tx *sql.Tx, s Screening) (int64, error) {
+	_, err := tx.Exec("UPDATE users SET compliance_status = ? WHERE id = ?", StatusPending, s.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return Queue(tx, SubjectRegistration, s.UserID, s, nil, 0)
+}
+
+// QueueTransfer блокирует сумму перевода холдом и ставит перевод в очередь
+// комплаенса; s — проверка получателя
+func QueueTransfer(tx *sql.Tx, actorID int64, s Screening, t PendingTransfer) (int64, error) {
+	holdID, err := ledger.PlaceHold(tx, ledger.Hold{
+		UserID:      t.OwnerID,
+		Amount:      t.Amount,
+		Source:      HoldSource,
+		Description: "Перевод на проверке комплаенса",
+		ExpiresAt:   time.Now().Add(types.SanctionsReviewHold),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reviewID, err := Queue(tx, SubjectTransfer, actorID, s, t, holdID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec("UPDATE holds SET reference_id = ? WHERE id = ?", reviewID, holdID)
+	return reviewID, err
+}
+
+// Queue добавляет совпадения проверки s в очередь комплаенса
+func Queue(q ledger.Querier, subjectType string, actorID int64, s Screening, payload interface{}, holdID int64) (int64, error) {
+	matches, err := json.Marshal(s.Matches)
+	if err != nil {
+		return 0, err
+	}
+
+	body := []byte("{}")
+	if payload != nil {
+		if body, err = json.Marshal(payload); err != nil {
+			return 0, err
+		}
+	}
+
+	var hold interface{}
+	if holdID != 0 {
+		hold = holdID
+	}
+
+	result, err := q.Exec(
+		`INSERT INTO compliance_reviews
+		(subject_type, user_id, actor_id, screened_name, matches, payload, hold_id, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		subjectType, s.UserID, actorID, s.Name, string(matches), string(body), hold, ReviewPending,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+const selectReview = `SELECT id, subject_type, user_id, actor_id, screened_name, matches, payload,
+	hold_id, status, decided_by, note, transaction_id, created_at, decided_at FROM compliance_reviews`
+
+// GetReview возвращает событие из очереди; forUpdate блокирует строку до конца транзакции
+func GetReview(q ledger.Querier, id int64, forUpdate bool) (Review, error) {
+	query := selectReview + " WHERE id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := q.Query(query, id)
+	if err != nil {
+		return Review{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Review{}, err
+		}
+		return Review{}, sql.ErrNoRows
+	}
+	return scanReview(rows)
+}
+
+// ListReviews возвращает очередь от старых к новым; пустой status означает все статусы
+func ListReviews(q ledger.Querier, status string) ([]Review, error) {
+	query := selectReview
+	var args []interface{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id LIMIT 200"
+
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]Review, 0)
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+func scanReview(rows *sql.Rows) (Review, error) {
+	var r Review
+	var matches, payload string
+	var holdID, decidedBy, transactionID sql.NullInt64
+	var createdAt time.Time
+	var decidedAt sql.NullTime
+
+	err := rows.Scan(&r.ID, &r.SubjectType, &r.UserID, &r.ActorID, &r.ScreenedName, &matches, &payload,
+		&holdID, &r.Status, &decidedBy, &r.Note, &transactionID, &createdAt, &decidedAt)
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal([]byte(matches), &r.Matches); err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal([]byte(payload), &r.Payload); err != nil {
+		return r, err
+	}
+
+	r.HoldID = holdID.Int64
+	r.DecidedBy = decidedBy.Int64
+	r.TransactionID = transactionID.Int64
+	r.CreatedAt = createdAt.Format(types.TimeLayout)
+	if decidedAt.Valid {
+		r.DecidedAt = decidedAt.Time.Format(types.TimeLayout)
+	}
+	return r, nil
+}
+
+// PendingTransferOf разбирает перевод из события очереди
+func PendingTransferOf(r Review) (PendingTransfer, error) {
+	var t PendingTransfer
+	body, err := json.Marshal(r.Payload)
+	if err != nil {
+		return t, err
+	}
+	err = json.Unmarshal(body, &t)
+	return t, err
+}
+
+// Decide закрывает событие очереди решением офицера комплаенса.
+// Снятые совпадения запоминаются за клиентом и больше не останавливают
+// его операции; подтверждённое совпадение блокирует клиента.
+func Decide(tx *sql.Tx, r Review, status string, officerID int64, note string, transactionID int64) error {
+	var transaction interface{}
+	if transactionID != 0 {
+		transaction = transactionID
+	}
+
+	result, err := tx.Exec(
+		`UPDATE compliance_reviews SET status = ?, decided_by = ?, note = ?, transaction_id = ?, decided_at = NOW()
+		WHERE id = ? AND status = ?`,
+		status, officerID, note, transaction, r.ID, ReviewPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrReviewNotPending
+	}
+
+	if status == ReviewConfirmed {
+		_, err = tx.Exec("UPDATE users SET compliance_status = ? WHERE id = ?", StatusBlocked, r.UserID)
+		return err
+	}
+
+	for _, m := range r.Matches {
+		_, err := tx.Exec(
+			"INSERT IGNORE INTO sanctions_clearances (user_id, list, entry_id, review_id) VALUES (?, ?, ?, ?)",
+			r.UserID, m.List, m.EntryID, r.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.SubjectType == SubjectRegistration {
+		_, err = tx.Exec(
+			"UPDATE users SET compliance_status = ? WHERE id = ? AND compliance_status = ?",
+			StatusClear, r.UserID, StatusPending,
+		)
+	}
+	return err
+}
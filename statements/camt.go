@@ -0,0 +1,260 @@
+package statements
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+// Пространства имён выгружаемых сообщений ISO 20022
+const (
+	Camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+	Camt054Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.054.001.02"
+)
+
+const (
+	isoDateTime = "2006-01-02T15:04:05"
+	isoDate     = "2006-01-02"
+)
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtAccountID struct {
+	Other struct {
+		ID string `xml:"Id"`
+	} `xml:"Id>Othr"`
+}
+
+type camtAccount struct {
+	camtAccountID
+	Currency  string `xml:"Ccy,omitempty"`
+	OwnerName string `xml:"Ownr>Nm,omitempty"`
+}
+
+type camtGroupHeader struct {
+	MessageID string `xml:"MsgId"`
+	Created   string `xml:"CreDtTm"`
+}
+
+type camtPeriod struct {
+	From string `xml:"FrDtTm"`
+	To   string `xml:"ToDtTm"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      string     `xml:"Dt>Dt"`
+}
+
+type camtTotals struct {
+	Count string `xml:"NbOfNtries"`
+	Sum   string `xml:"Sum"`
+}
+
+type camtSummary struct {
+	Total struct {
+		camtTotals
+		Net struct {
+			Amount    string `xml:"Amt"`
+			Indicator string `xml:"CdtDbtInd"`
+		} `xml:"TtlNetNtry"`
+	} `xml:"TtlNtries"`
+	Credits camtTotals `xml:"TtlCdtNtries"`
+	Debits  camtTotals `xml:"TtlDbtNtries"`
+}
+
+type camtParty struct {
+	Name string `xml:"Nm,omitempty"`
+}
+
+type camtRelatedParties struct {
+	Debtor          *camtParty     `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camtAccountID `xml:"DbtrAcct,omitempty"`
+	Creditor        *camtParty     `xml:"Cdtr,omitempty"`
+	CreditorAccount *camtAccountID `xml:"CdtrAcct,omitempty"`
+}
+
+type camtEntry struct {
+	Reference   string     `xml:"NtryRef"`
+	Amount      camtAmount `xml:"Amt"`
+	Indicator   string     `xml:"CdtDbtInd"`
+	Status      string     `xml:"Sts"`
+	BookingDate string     `xml:"BookgDt>DtTm"`
+	ValueDate   string     `xml:"ValDt>Dt"`
+	ServicerRef string     `xml:"AcctSvcrRef"`
+	BankCode    string     `xml:"BkTxCd>Prtry>Cd"`
+	Details     struct {
+		Refs struct {
+			EndToEndID string `xml:"EndToEndId,omitempty"`
+			TxID       string `xml:"TxId"`
+		} `xml:"Refs"`
+		Parties    camtRelatedParties `xml:"RltdPties"`
+		Remittance string             `xml:"RmtInf>Ustrd,omitempty"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+type camtStatement struct {
+	ID       string        `xml:"Id"`
+	Created  string        `xml:"CreDtTm"`
+	Period   camtPeriod    `xml:"FrToDt"`
+	Account  camtAccount   `xml:"Acct"`
+	Balances []camtBalance `xml:"Bal,omitempty"`
+	Summary  *camtSummary  `xml:"TxsSummry,omitempty"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camt053Document struct {
+	XMLName   xml.Name        `xml:"Document"`
+	Namespace string          `xml:"xmlns,attr"`
+	Header    camtGroupHeader `xml:"BkToCstmrStmt>GrpHdr"`
+	Statement camtStatement   `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camt054Document struct {
+	XMLName      xml.Name        `xml:"Document"`
+	Namespace    string          `xml:"xmlns,attr"`
+	Header       camtGroupHeader `xml:"BkToCstmrDbtCdtNtfctn>GrpHdr"`
+	Notification camtStatement   `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"`
+}
+
+// Camt053 выгружает выписку в формате camt.053 (Bank To Customer Statement)
+func Camt053(st Statement) ([]byte, error) {
+	doc := camt053Document{
+		Namespace: Camt053Namespace,
+		Header:    header(st),
+		Statement: camtBody(st, st.Entries),
+	}
+
+	doc.Statement.Balances = []camtBalance{
+		balance("OPBD", st.Opening, st.Currency, st.From),
+		balance("CLBD", st.Closing, st.Currency, st.To.Add(-time.Second)),
+	}
+
+	summary := &camtSummary{}
+	var credits, debits int
+	for _, e := range st.Entries {
+		if e.Credit() {
+			credits++
+		} else {
+			debits++
+		}
+	}
+	summary.Total.Count = strconv.Itoa(len(st.Entries))
+	summary.Total.Sum = formatAmount(st.Credits + st.Debits)
+	summary.Total.Net.Amount = formatAmount(st.Credits - st.Debits)
+	summary.Total.Net.Indicator = indicator(st.Credits - st.Debits)
+	summary.Credits = camtTotals{strconv.Itoa(credits), formatAmount(st.Credits)}
+	summary.Debits = camtTotals{strconv.Itoa(debits), formatAmount(st.Debits)}
+	doc.Statement.Summary = summary
+
+	return marshal(doc)
+}
+
+// Camt054 выгружает уведомления о зачислениях и списаниях (camt.054).
+// onlyIndicator CRDT или DBIT оставляет только зачисления или списания.
+func Camt054(st Statement, onlyIndicator string) ([]byte, error) {
+	entries := make([]Entry, 0, len(st.Entries))
+	for _, e := range st.Entries {
+		if onlyIndicator == "" || indicator(e.Amount) == onlyIndicator {
+			entries = append(entries, e)
+		}
+	}
+
+	doc := camt054Document{
+		Namespace:    Camt054Namespace,
+		Header:       header(st),
+		Notification: camtBody(st, entries),
+	}
+	return marshal(doc)
+}
+
+func header(st Statement) camtGroupHeader {
+	return camtGroupHeader{
+		MessageID: "MSG-" + st.ID + "-" + st.GeneratedAt.Format("150405"),
+		Created:   st.GeneratedAt.Format(isoDateTime),
+	}
+}
+
+func camtBody(st Statement, entries []Entry) camtStatement {
+	body := camtStatement{
+		ID:      st.ID,
+		Created: st.GeneratedAt.Format(isoDateTime),
+		Period: camtPeriod{
+			From: st.From.Format(isoDateTime),
+			To:   st.To.Add(-time.Second).Format(isoDateTime),
+		},
+	}
+	body.Account.Other.ID = st.Account
+	body.Account.Currency = st.Currency
+	body.Account.OwnerName = st.OwnerName
+
+	body.Entries = make([]camtEntry, 0, len(entries))
+	for _, e := range entries {
+		ce := camtEntry{
+			Reference:   strconv.FormatInt(e.EntryID, 10),
+			Amount:      camtAmount{st.Currency, formatAmount(e.Amount)},
+			Indicator:   indicator(e.Amount),
+			Status:      "BOOK",
+			BookingDate: e.BookedAt.Format(isoDateTime),
+			ValueDate:   e.BookedAt.Format(isoDate),
+			ServicerRef: strconv.FormatInt(e.TransactionID, 10),
+			BankCode:    e.Kind,
+		}
+		ce.Details.Refs.EndToEndID = e.EndToEndID
+		ce.Details.Refs.TxID = strconv.FormatInt(e.TransactionID, 10)
+		ce.Details.Remittance = e.Memo
+
+		own := &camtAccountID{}
+		own.Other.ID = st.Account
+		other := &camtAccountID{}
+		other.Other.ID = e.Counterparty
+
+		if e.Credit() {
+			ce.Details.Parties = camtRelatedParties{
+				Debtor:          &camtParty{Name: e.CounterpartyName},
+				DebtorAccount:   other,
+				Creditor:        &camtParty{Name: st.OwnerName},
+				CreditorAccount: own,
+			}
+		} else {
+			ce.Details.Parties = camtRelatedParties{
+				Debtor:          &camtParty{Name: st.OwnerName},
+				DebtorAccount:   own,
+				Creditor:        &camtParty{Name: e.CounterpartyName},
+				CreditorAccount: other,
+			}
+		}
+		body.Entries = append(body.Entries, ce)
+	}
+	return body
+}
+
+func balance(code string, amount float64, currency string, date time.Time) camtBalance {
+	return camtBalance{
+		Code:      code,
+		Amount:    camtAmount{currency, formatAmount(amount)},
+		Indicator: indicator(amount),
+		Date:      date.Format(isoDate),
+	}
+}
+
+// indicator возвращает CRDT для неотрицательных сумм и DBIT для отрицательных
+func indicator(amount float64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func marshal(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,202 @@
+package statements
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/methods"
+	"backend_golang/types"
+)
+
+const (
+	mt940LineLength = 65
+	mt940InfoLines  = 6
+	mt940Date       = "060102"
+	mt940EntryDate  = "0102"
+	mt940NoRef      = "NONREF"
+
+	// mt940HeaderLines поля :20:, :25:, :28C: и входящий остаток
+	mt940HeaderLines = 4
+)
+
+// mt940Codes коды типа операции для поля 61
+var mt940Codes = map[string]string{
+	ledger.KindTransfer:          "TRF",
+	ledger.KindReversal:          "TRF",
+	ledger.KindSavingsDeposit:    "TRF",
+	ledger.KindSavingsWithdrawal: "TRF",
+	ledger.KindGoalContribution:  "TRF",
+	ledger.KindGoalWithdrawal:    "TRF",
+	ledger.KindInterest:          "INT",
+	ledger.KindOverdraftInterest: "INT",
+	ledger.KindLoanDisbursement:  "LDP",
+	ledger.KindLoanRepayment:     "LDP",
+	ledger.KindLoanPrepayment:    "LDP",
+}
+
+// MT940 выгружает выписку в формате SWIFT MT940 (блок 4, строки через CRLF).
+// Если выписка не помещается в types.MT940MaxMessageLength символов, она
+// делится на несколько сообщений с общим номером выписки в :28C: —
+// у промежуточных сообщений остатки помечаются как :60M: и :62M:.
+func MT940(st Statement) ([]byte, error) {
+	var out strings.Builder
+	seq := 1
+	opening, openingDate, openingTag := st.Opening, st.From, "60F"
+
+	message := mt940Header(st, seq, openingTag, opening, openingDate)
+	size := linesLength(message)
+	closingSize := linesLength([]string{mt940Balance("62F", st.Closing, st.Currency, st.To), "-"})
+
+	for _, e := range st.Entries {
+		lines := mt940Entry(e)
+		// сообщение без проводок не делится, даже если проводка в него не влезает
+		if size+linesLength(lines)+closingSize > types.MT940MaxMessageLength && len(message) > mt940HeaderLines {
+			writeMessage(&out, message, mt940Balance("62M", opening, st.Currency, openingDate))
+
+			seq++
+			openingTag = "60M"
+			message = mt940Header(st, seq, openingTag, opening, openingDate)
+			size = linesLength(message)
+		}
+		message = append(message, lines...)
+		size += linesLength(lines)
+		opening, openingDate = e.Balance, e.BookedAt
+	}
+
+	writeMessage(&out, message, mt940Balance("62F", st.Closing, st.Currency, st.To.Add(-time.Second)))
+	return []byte(out.String()), nil
+}
+
+func mt940Header(st Statement, seq int, openingTag string, opening float64, date time.Time) []string {
+	return []string{
+		":20:" + clip(swiftText("ST"+st.From.Format(mt940Date)+st.To.Add(-time.Second).Format(mt940Date)), 16),
+		":25:" + clip(swiftText(st.Account), 35),
+		fmt.Sprintf(":28C:%d/%d", st.From.YearDay(), seq),
+		mt940Balance(openingTag, opening, st.Currency, date),
+	}
+}
+
+func writeMessage(out *strings.Builder, lines []string, closing string) {
+	for _, line := range append(lines, closing, "-") {
+		out.WriteString(line)
+		out.WriteString("\r\n")
+	}
+}
+
+func linesLength(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		n += len(line) + len("\r\n")
+	}
+	return n
+}
+
+// mt940Balance поле остатка: признак C/D, дата YYMMDD, валюта и сумма
+func mt940Balance(tag string, amount float64, currency string, date time.Time) string {
+	mark := "C"
+	if amount < 0 {
+		mark = "D"
+	}
+	return ":" + tag + ":" + mark + date.Format(mt940Date) + currency + mt940Amount(amount)
+}
+
+// mt940Entry поля 61 и 86 одной проводки
+func mt940Entry(e Entry) []string {
+	mark := "C"
+	if !e.Credit() {
+		mark = "D"
+	}
+	if e.Kind == ledger.KindReversal {
+		// сторно зачисления списывает деньги, сторно списания — зачисляет
+		mark = "RC"
+		if e.Credit() {
+			mark = "RD"
+		}
+	}
+
+	code, ok := mt940Codes[e.Kind]
+	if !ok {
+		code = "MSC"
+	}
+
+	reference := clip(swiftText(e.EndToEndID), 16)
+	if reference == "" {
+		reference = mt940NoRef
+	}
+
+	lines := []string{
+		":61:" + e.BookedAt.Format(mt940Date) + e.BookedAt.Format(mt940EntryDate) + mark + mt940Amount(e.Amount) +
+			"N" + code + reference + "//" + clip(fmt.Sprint(e.TransactionID), 16),
+	}
+	if e.Counterparty != "" {
+		lines = append(lines, clip(swiftText(e.Counterparty), 34))
+	}
+
+	info := wrap(swiftText(strings.TrimSpace(e.Memo+" "+e.CounterpartyName)), mt940LineLength, mt940InfoLines)
+	if len(info) > 0 {
+		info[0] = ":86:" + info[0]
+		lines = append(lines, info...)
+	}
+	return lines
+}
+
+// mt940Amount сумма по модулю с запятой в качестве разделителя
+func mt940Amount(amount float64) string {
+	return strings.Replace(formatAmount(amount), ".", ",", 1)
+}
+
+// wrap режет текст на строки не длиннее width, не больше maxLines строк
+func wrap(text string, width, maxLines int) []string {
+	var lines []string
+	for text != "" && len(lines) < maxLines {
+		if len(text) <= width {
+			lines = append(lines, text)
+			break
+		}
+		cut := strings.LastIndex(text[:width+1], " ")
+		if cut <= 0 {
+			cut = width
+		}
+		lines = append(lines, strings.TrimRight(text[:cut], " "))
+		text = strings.TrimLeft(text[cut:], " ")
+	}
+
+	// строка, начинающаяся с ":" или "-", читается как новое поле или конец сообщения
+	for i, line := range lines {
+		if strings.HasPrefix(line, ":") || strings.HasPrefix(line, "-") {
+			lines[i] = "." + line[1:]
+		}
+	}
+	return lines
+}
+
+func clip(text string, max int) string {
+	if len(text) > max {
+		return text[:max]
+	}
+	return text
+}
+
+// swiftText приводит текст к набору символов SWIFT X: кириллица
+// транслитерируется, прочие недопустимые символы заменяются точкой
+func swiftText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			strings.ContainsRune("/-?:().,'+ ", r):
+			b.WriteRune(r)
+		case r == '\n', r == '\r', r == '\t':
+			b.WriteByte(' ')
+		default:
+			if latin, ok := methods.Translit(r); ok {
+				b.WriteString(latin)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,143 @@
+package statements
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		maxLines int
+		want     []string
+	}{
+		{"", 10, 6, nil},
+		{"short", 10, 6, []string{"short"}},
+		{"exactly 10", 10, 6, []string{"exactly 10"}},
+		{"one two three four", 9, 6, []string{"one two", "three", "four"}},
+		{"abcdefghijklmno", 5, 6, []string{"abcde", "fghij", "klmno"}},
+		{"one two three four", 5, 2, []string{"one", "two"}},
+		{"pay :59: now", 4, 6, []string{"pay", ".59:", "now"}},
+		{"a -b", 2, 6, []string{"a", ".b"}},
+	}
+	for _, tt := range tests {
+		got := wrap(tt.text, tt.width, tt.maxLines)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("wrap(%q, %d, %d) = %q, want %q", tt.text, tt.width, tt.maxLines, got, tt.want)
+		}
+	}
+}
+
+func TestSwiftText(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Payment 42/1", "Payment 42/1"},
+		{"Оплата", "Oplata"},
+		{"line\nbreak", "line break"},
+		{"50% & more", "50. . more"},
+	}
+	for _, tt := range tests {
+		if got := swiftText(tt.text); got != tt.want {
+			t.Errorf("swiftText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMT940Split(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	st := Statement{
+		Account:  "user:1",
+		Currency: "RUB",
+		From:     from,
+		To:       from.AddDate(0, 1, 0),
+		Opening:  1000,
+	}
+	balance := st.Opening
+	for i := 1; i <= 40; i++ {
+		balance += 10
+		st.Entries = append(st.Entries, Entry{
+			TransactionID: int64(i),
+			BookedAt:      from.Add(time.Duration(i) * time.Hour),
+			Amount:        10,
+			Kind:          ledger.KindTransfer,
+			Memo:          strings.Repeat("memo ", 20),
+			Counterparty:  "user:2",
+			Balance:       balance,
+		})
+	}
+	st.Closing = balance
+
+	tests := []struct {
+		maxLength int
+		messages  int
+	}{
+		{100000, 1},
+		{2000, 0},
+		{600, 0},
+	}
+	defer func(n int) { types.MT940MaxMessageLength = n }(types.MT940MaxMessageLength)
+
+	for _, tt := range tests {
+		types.MT940MaxMessageLength = tt.maxLength
+		body, err := MT940(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messages := strings.SplitAfter(strings.TrimSuffix(string(body), "-\r\n"), "-\r\n")
+		if tt.messages != 0 && len(messages) != tt.messages {
+			t.Errorf("max %d: %d messages, want %d", tt.maxLength, len(messages), tt.messages)
+		}
+		if tt.messages == 0 && len(messages) < 2 {
+			t.Errorf("max %d: statement was not split", tt.maxLength)
+		}
+
+		entries := 0
+		for i, m := range messages {
+			if !strings.HasSuffix(m, "-\r\n") {
+				m += "-\r\n"
+			}
+			if len(m) > tt.maxLength {
+				t.Errorf("max %d: message %d is %d characters", tt.maxLength, i+1, len(m))
+			}
+
+			lines := strings.Split(strings.TrimSuffix(m, "\r\n"), "\r\n")
+			if want := fmt.Sprintf(":28C:%d/%d", from.YearDay(), i+1); lines[2] != want {
+				t.Errorf("max %d: message %d has %s, want %s", tt.maxLength, i+1, lines[2], want)
+			}
+
+			opening, closing := "60M", "62M"
+			if i == 0 {
+				opening = "60F"
+			}
+			if i == len(messages)-1 {
+				closing = "62F"
+			}
+			if !strings.HasPrefix(lines[3], ":"+opening+":") || !strings.HasPrefix(lines[len(lines)-2], ":"+closing+":") {
+				t.Errorf("max %d: message %d balances %s … %s, want %s … %s",
+					tt.maxLength, i+1, lines[3], lines[len(lines)-2], opening, closing)
+			}
+
+			for _, line := range lines {
+				if strings.HasPrefix(line, ":61:") {
+					entries++
+					continue
+				}
+				// в 65 символов строки поля 86 тег не входит
+				if text := strings.TrimPrefix(line, ":86:"); len(text) > mt940LineLength {
+					t.Errorf("max %d: line longer than %d: %q", tt.maxLength, mt940LineLength, line)
+				}
+			}
+		}
+		if entries != len(st.Entries) {
+			t.Errorf("max %d: %d entries written, want %d", tt.maxLength, entries, len(st.Entries))
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package statements собирает выписки по счетам из проводок ledger
+// и выгружает их в банковских форматах.
+package statements
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Statement выписка по счёту за период [From, To)
+type Statement struct {
+	ID          string    `json:"id"`
+	Account     string    `json:"account"`
+	OwnerName   string    `json:"owner_name"`
+	Currency    string    `json:"currency"`
+	From        time.Time `json:"-"`
+	To          time.Time `json:"-"`
+	Opening     float64   `json:"opening_balance"`
+	Closing     float64   `json:"closing_balance"`
+	Credits     float64   `json:"total_credits"`
+	Debits      float64   `json:"total_debits"`
+	Entries     []Entry   `json:"entries"`
+	GeneratedAt time.Time `json:"-"`
+
+	Period struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	} `json:"period"`
+}
+
+// Entry строка выписки
+type Entry struct {
+	EntryID          int64     `json:"entry_id"`
+	TransactionID    int64     `json:"transaction_id"`
+	BookedAt         time.Time `json:"-"`
+	Booked           string    `json:"booked_at"`
+	Amount           float64   `json:"amount"`
+	Kind             string    `json:"kind"`
+	Memo             string    `json:"memo,omitempty"`
+	Counterparty     string    `json:"counterparty"`
+	CounterpartyName string    `json:"counterparty_name,omitempty"`
+	EndToEndID       string    `json:"end_to_end_id,omitempty"`
+	Balance          float64   `json:"balance_after"`
+}
+
+// Credit показывает, зачисление ли это
+func (e Entry) Credit() bool {
+	return e.Amount > 0
+}
+
+// Build собирает выписку по счёту account за период [from, to).
+// Входящий остаток считается от текущего остатка счёта назад,
+// поэтому выписка сходится с остатком, который видит клиент.
+func Build(q ledger.Querier, account string, from, to time.Time) (Statement, error) {
+	st := Statement{
+		ID:          fmt.Sprintf("%s-%s-%s", strings.ReplaceAll(account, ":", ""), from.Format("20060102"), to.Format("20060102")),
+		Account:     account,
+		Currency:    types.DefaultCurrency,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+	}
+	st.Period.From = from.Format(types.TimeLayout)
+	st.Period.To = to.Format(types.TimeLayout)
+
+	current, err := ledger.BalanceOf(q, account)
+	if err != nil {
+		return st, err
+	}
+
+	var sinceFrom, sinceTo float64
+	err = q.QueryRow(
+		`SELECT COALESCE(SUM(amount), 0), COALESCE(SUM(CASE WHEN created_at >= ? THEN amount ELSE 0 END), 0)
+		FROM ledger_entries WHERE account = ? AND created_at >= ?`,
+		to, account, from,
+	).Scan(&sinceFrom, &sinceTo)
+	if err != nil {
+		return st, err
+	}
+	st.Opening = float64(ledger.Cents(current)-ledger.Cents(sinceFrom)) / 100
+	st.Closing = float64(ledger.Cents(current)-ledger.Cents(sinceTo)) / 100
+
+	st.OwnerName, err = accountName(q, account)
+	if err != nil {
+		return st, err
+	}
+
+	entries, err := ledger.EntriesBetween(q, account, from, to)
+	if err != nil {
+		return st, err
+	}
+
+	running := ledger.Cents(st.Opening)
+	var credits, debits int64
+	st.Entries = make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		amount := ledger.Cents(e.EntryAmount)
+		running += amount
+		if amount > 0 {
+			credits += amount
+		} else {
+			debits -= amount
+		}
+
+		counterparty := e.FromAccount
+		if e.FromAccount == account {
+			counterparty = e.ToAccount
+		}
+
+		name, err := accountName(q, counterparty)
+		if err != nil {
+			return st, err
+		}
+
+		endToEnd, err := endToEndID(q, e.Transaction.ID)
+		if err != nil {
+			return st, err
+		}
+
+		st.Entries = append(st.Entries, Entry{
+			EntryID:          e.EntryID,
+			TransactionID:    e.Transaction.ID,
+			BookedAt:         e.BookedAt,
+			Booked:           e.BookedAt.Format(types.TimeLayout),
+			Amount:           e.EntryAmount,
+			Kind:             e.Kind,
+			Memo:             e.Memo,
+			Counterparty:     counterparty,
+			CounterpartyName: name,
+			EndToEndID:       endToEnd,
+			Balance:          float64(running) / 100,
+		})
+	}
+	st.Credits = float64(credits) / 100
+	st.Debits = float64(debits) / 100
+
+	return st, nil
+}
+
+// accountName возвращает имя владельца пользовательского счёта
+func accountName(q ledger.Querier, account string) (string, error) {
+	kind, _, err := ledger.ParseAccount(account)
+	if err != nil || kind == "bank" {
+		return "", nil
+	}
+
+	userID, err := ledger.OwnerOf(q, account)
+	if err == ledger.ErrAccountNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var name string
+	err = q.QueryRow("SELECT CONCAT(name, ' ', surname) FROM users WHERE id = ?", userID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return name, err
+}
+
+// endToEndID возвращает EndToEndId платёжного поручения, по которому
+// прошла транзакция, если она пришла из pain.001
+func endToEndID(q ledger.Querier, transactionID int64) (string, error) {
+	var id string
+	err := q.QueryRow(
+		"SELECT end_to_end_id FROM payout_rows WHERE transaction_id = ?", transactionID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// formatAmount форматирует модуль суммы с точкой и двумя знаками
+func formatAmount(amount float64) string {
+	cents := ledger.Cents(amount)
+	if cents < 0 {
+		cents = -cents
+	}
+	return strconv.FormatInt(cents/100, 10) + "." + fmt.Sprintf("%02d", cents%100)
+}
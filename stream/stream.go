@@ -0,0 +1,323 @@
+// Package stream доставляет клиентам сообщения в реальном времени: изменения
+// остатка, входящие переводы и предупреждения безопасности. Источник —
+// таблица outbox_events: PollJob читает новые события по порядку номеров
+// и раздаёт их подключённым клиентам, поэтому поток работает на любом
+// из серверов, а номер события служит Last-Event-ID для переподключения.
+package stream
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/events"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Типы сообщений
+const (
+	TypeBalance          = "balance"
+	TypeTransferIncoming = "transfer_incoming"
+	TypeSecurityAlert    = "security_alert"
+	// TypeResync пропущено больше, чем можно дослать: клиенту нужно
+	// заново запросить остаток и историю
+	TypeResync = "resync"
+)
+
+// Причины отключения потока сервером
+const (
+	ReasonSlowConsumer   = "slow_consumer"
+	ReasonSessionExpired = "session_expired"
+)
+
+var ErrTooManyStreams = errors.New("stream: too many open streams")
+
+// Message сообщение потока
+type Message struct {
+	ID        int64                  `json:"id"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+// Subscriber подключение клиента к потоку
+type Subscriber struct {
+	UserID int64
+	// From номер последнего события на момент подключения: всё, что новее,
+	// придёт в C, а более старое досылает Replay
+	From int64
+
+	c      chan Message
+	done   chan struct{}
+	reason string
+}
+
+// C сообщения для клиента по порядку номеров
+func (s *Subscriber) C() <-chan Message { return s.c }
+
+// Done закрывается, когда сервер отключает клиента; причина — в Reason
+func (s *Subscriber) Done() <-chan struct{} { return s.done }
+
+func (s *Subscriber) Reason() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return s.reason
+}
+
+var (
+	mu          sync.Mutex
+	subscribers = make(map[int64]map[*Subscriber]bool)
+	cursor      = int64(-1)
+	gapSince    time.Time
+)
+
+// Subscribe подключает клиента к потоку
+func Subscribe(userID int64) (*Subscriber, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(subscribers[userID]) >= types.StreamMaxConnections {
+		return nil, ErrTooManyStreams
+	}
+
+	s := &Subscriber{
+		UserID: userID,
+		From:   cursor,
+		c:      make(chan Message, types.StreamBufferSize),
+		done:   make(chan struct{}),
+	}
+	if subscribers[userID] == nil {
+		subscribers[userID] = make(map[*Subscriber]bool)
+	}
+	subscribers[userID][s] = true
+	return s, nil
+}
+
+// Unsubscribe отключает клиента; повторный вызов ничего не делает
+func Unsubscribe(s *Subscriber) {
+	mu.Lock()
+	defer mu.Unlock()
+	drop(s, "")
+}
+
+// drop убирает подписчика; вызывается под mu
+func drop(s *Subscriber, reason string) {
+	if !subscribers[s.UserID][s] {
+		return
+	}
+	delete(subscribers[s.UserID], s)
+	if len(subscribers[s.UserID]) == 0 {
+		delete(subscribers, s.UserID)
+	}
+	s.reason = reason
+	close(s.done)
+}
+
+// PollJob читает новые события из outbox и раздаёт их подписчикам.
+// Номера событий выдаются при вставке, а видны после фиксации, поэтому на
+// пропущенном номере чтение останавливается до types.StreamGapWait: иначе
+// событие медленной транзакции прошло бы мимо потока.
+func PollJob() error {
+	if cursor < 0 {
+		var last int64
+		if err := database.DB.QueryRow("SELECT COALESCE(MAX(id), 0) FROM outbox_events").Scan(&last); err != nil {
+			return err
+		}
+		mu.Lock()
+		cursor = last
+		mu.Unlock()
+		return nil
+	}
+
+	list, err := events.Since(database.DB, cursor, 500)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range list {
+		if e.Sequence != cursor+1 {
+			if gapSince.IsZero() {
+				gapSince = time.Now()
+			}
+			if time.Since(gapSince) < types.StreamGapWait {
+				return nil
+			}
+		}
+		gapSince = time.Time{}
+
+		if err := broadcast(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func broadcast(e events.Event) error {
+	mu.Lock()
+	var users []int64
+	for _, userID := range e.Users() {
+		if len(subscribers[userID]) > 0 {
+			users = append(users, userID)
+		}
+	}
+	mu.Unlock()
+
+	messages := make(map[int64]Message)
+	for _, userID := range users {
+		msg, ok, err := messageFor(database.DB, e, userID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			messages[userID] = msg
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	cursor = e.Sequence
+	for userID, msg := range messages {
+		for s := range subscribers[userID] {
+			if msg.ID <= s.From {
+				continue
+			}
+			select {
+			case s.c <- msg:
+			default:
+				// клиент не успевает читать: он переподключится с Last-Event-ID
+				// и получит пропущенное из outbox
+				drop(s, ReasonSlowConsumer)
+			}
+		}
+	}
+	return nil
+}
+
+// Replay возвращает сообщения клиента с номерами от afterID (не включая)
+// до s.From. Если часть событий уже удалена из outbox или пропущено
+// больше types.StreamReplayLimit, возвращается одно сообщение resync.
+func Replay(s *Subscriber, afterID int64) ([]Message, error) {
+	messages := make([]Message, 0)
+	if afterID >= s.From {
+		return messages, nil
+	}
+
+	var oldest int64
+	err := database.DB.QueryRow("SELECT COALESCE(MIN(id), 0) FROM outbox_events").Scan(&oldest)
+	if err != nil {
+		return nil, err
+	}
+	if oldest == 0 || afterID < oldest-1 {
+		return []Message{resync(s.From)}, nil
+	}
+
+	for afterID < s.From {
+		list, err := events.Since(database.DB, afterID, 500)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			break
+		}
+		for _, e := range list {
+			if e.Sequence > s.From {
+				return messages, nil
+			}
+			afterID = e.Sequence
+			if !contains(e.Users(), s.UserID) {
+				continue
+			}
+
+			msg, ok, err := messageFor(database.DB, e, s.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if len(messages) >= types.StreamReplayLimit {
+				return []Message{resync(s.From)}, nil
+			}
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func resync(id int64) Message {
+	return Message{
+		ID:        id,
+		Type:      TypeResync,
+		Data:      map[string]interface{}{},
+		CreatedAt: time.Now().Format(types.TimeLayout),
+	}
+}
+
+// messageFor превращает доменное событие в сообщение для клиента userID;
+// ok=false, если клиенту об этом событии сообщать нечего
+func messageFor(q ledger.Querier, e events.Event, userID int64) (Message, bool, error) {
+	msg := Message{
+		ID:        e.Sequence,
+		CreatedAt: e.OccurredAt.Format(types.TimeLayout),
+	}
+
+	switch e.Type {
+	case events.TransferPosted, events.TransactionPosted:
+		// В сообщении текущий остаток: при досылке пропущенного клиенту
+		// нужен актуальный, а не исторический
+		balance, err := ledger.Balances(q, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// клиент удалён, сообщать некому
+			return msg, false, nil
+		}
+		if err != nil {
+			return msg, false, err
+		}
+
+		msg.Type = TypeBalance
+		incoming := e.Payload["to_account"] == ledger.UserAccount(userID) &&
+			e.Payload["from_account"] != ledger.UserAccount(userID)
+		if e.Type == events.TransferPosted && incoming {
+			msg.Type = TypeTransferIncoming
+		}
+		msg.Data = map[string]interface{}{
+			"transaction_id": e.Payload["transaction_id"],
+			"kind":           e.Payload["kind"],
+			"amount":         e.Payload["amount"],
+			"currency":       e.Payload["currency"],
+			"incoming":       incoming,
+			"balance":        balance,
+		}
+		if incoming {
+			msg.Data["from_account"] = e.Payload["from_account"]
+		}
+		return msg, true, nil
+
+	case events.LoggedIn:
+		msg.Type = TypeSecurityAlert
+		msg.Data = map[string]interface{}{
+			"alert":     "new_login",
+			"ip":        e.Payload["ip"],
+			"device_id": e.Payload["device_id"],
+		}
+		return msg, true, nil
+
+	case events.PasswordChanged:
+		msg.Type = TypeSecurityAlert
+		msg.Data = map[string]interface{}{"alert": "password_changed"}
+		return msg, true, nil
+	}
+	return msg, false, nil
+}
+
+func contains(list []int64, value int64) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 // Package types its for all types in project
 package types
 
+import "time"
+
 type Response struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
@@ -9,11 +11,13 @@ type Response struct {
 }
 
 type UserResponse struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Surname     string  `json:"surname"`
-	PhoneNumber string  `json:"phone_number"`
-	Balance     float64 `json:"balance"`
+	ID               int     `json:"id"`
+	Name             string  `json:"name"`
+	Surname          string  `json:"surname"`
+	PhoneNumber      string  `json:"phone_number"`
+	LedgerBalance    float64 `json:"ledger_balance"`
+	AvailableBalance float64 `json:"available_balance"`
+	Pending          float64 `json:"pending"`
 }
 
 type ResponseForAuth struct {
@@ -27,4 +31,210 @@ type ResponseForAuth struct {
 
 var BcryptSalt = 12
 
-var DefaultSession = 32
\ No newline at end of file
+var DefaultSession = 32
+
+var DefaultCurrency = "RUB"
+
+var TimeLayout = "2006-01-02 15:04:05"
+
+var PaymentRequestTTL = 72 * time.Hour
+
+var PublicBaseURL = "http://localhost:8080"
+
+var CardBIN = "220070"
+
+var CardPANLength = 16
+
+var CardValidityYears = 3
+
+var CardHoldTTL = 7 * 24 * time.Hour
+
+// CardPANKeyFile файл с ключом HMAC номеров карт; создаётся при первом запуске
+var CardPANKeyFile = "card_pan.key"
+
+// CardNetworkKey ключ симулятора карточной сети (заголовок X-Network-Key).
+// Берётся из переменной окружения CARD_NETWORK_KEY; без него сервер не запускается.
+var CardNetworkKey = ""
+
+// DayCountConvention база начисления процентов: ACT/365, ACT/360 или ACT/ACT
+var DayCountConvention = "ACT/365"
+
+var DefaultSavingsProduct = "standard"
+
+var LoanAnnualRate = 19.9
+
+var LoanMinAmount = 1000.0
+
+var LoanMaxAmount = 500000.0
+
+var LoanMaxTermMonths = 60
+
+var LoanGraceDays = 3
+
+var LoanLateFee = 500.0
+
+// OverdraftAnnualRate ставка по овердрафту по умолчанию, % годовых
+var OverdraftAnnualRate = 24.9
+
+var OverdraftMaxLimit = 100000.0
+
+// GoalRoundUpStep шаг округления карточных платежей для копилок по умолчанию
+var GoalRoundUpStep = 10.0
+
+// PayoutMaxRows максимальное число строк в файле массовой выплаты
+var PayoutMaxRows = 5000
+
+var PayoutMaxFileSize int64 = 5 << 20
+
+// MT940MaxMessageLength максимальный размер одного сообщения MT940 в символах;
+// более длинная выписка делится на несколько сообщений
+var MT940MaxMessageLength = 2000
+
+// FraudRulesFile файл с правилами антифрода; перечитывается при изменении
+var FraudRulesFile = "fraud_rules.json"
+
+// FraudReviewHold на сколько блокируются деньги перевода, отправленного на проверку
+var FraudReviewHold = 72 * time.Hour
+
+// SanctionsListDir каталог с файлами санкционных списков (OFAC SDN, сводный список ЕС);
+// перечитывается при изменении файлов
+var SanctionsListDir = "sanctions_lists"
+
+// SanctionsMatchThreshold минимальная схожесть имени с записью списка (0..1),
+// при которой операция уходит на проверку комплаенса
+var SanctionsMatchThreshold = 0.9
+
+// SanctionsReviewHold на сколько блокируются деньги перевода, ждущего решения комплаенса
+var SanctionsReviewHold = 7 * 24 * time.Hour
+
+// KYCTier лимиты расходных операций для уровня идентификации клиента
+type KYCTier struct {
+	Level           int     `json:"level"`
+	Name            string  `json:"name"`
+	SingleOperation float64 `json:"single_operation"`
+	DailyOutgoing   float64 `json:"daily_outgoing"`
+	MonthlyOutgoing float64 `json:"monthly_outgoing"`
+}
+
+// KYCTiers уровни идентификации от меньшего к большему
+var KYCTiers = []KYCTier{
+	{Level: 0, Name: "anonymous", SingleOperation: 15000, DailyOutgoing: 15000, MonthlyOutgoing: 40000},
+	{Level: 1, Name: "basic", SingleOperation: 100000, DailyOutgoing: 300000, MonthlyOutgoing: 600000},
+	{Level: 2, Name: "full", SingleOperation: 1000000, DailyOutgoing: 3000000, MonthlyOutgoing: 10000000},
+}
+
+// KYCStorageDir каталог с зашифрованными документами клиентов
+var KYCStorageDir = "kyc_documents"
+
+// KYCKeyFile файл с ключом шифрования документов; создаётся при первом запуске
+var KYCKeyFile = "kyc.key"
+
+var KYCMaxFileSize int64 = 10 << 20
+
+// OutboxPublisher куда ретранслятор отправляет доменные события: log, nats или kafka
+var OutboxPublisher = "log"
+
+// OutboxNATSAddr адрес сервера NATS (host:port)
+var OutboxNATSAddr = "localhost:4222"
+
+// OutboxKafkaRESTURL адрес Kafka REST Proxy
+var OutboxKafkaRESTURL = "http://localhost:8082"
+
+// OutboxTopic тема Kafka или префикс темы NATS для доменных событий
+var OutboxTopic = "simple_bank.events"
+
+// OutboxRetention сколько хранятся уже опубликованные события
+var OutboxRetention = 7 * 24 * time.Hour
+
+// WebhookMaxAttempts число попыток доставки вебхука, после которого он уходит в dead
+var WebhookMaxAttempts = 8
+
+// WebhookRetryBase пауза перед второй попыткой; каждая следующая вдвое дольше
+var WebhookRetryBase = 30 * time.Second
+
+var WebhookTimeout = 10 * time.Second
+
+// WebhookAllowPrivate разрешает вебхуки на http и на локальные и частные адреса (для разработки)
+var WebhookAllowPrivate = false
+
+// WebhookSignatureTolerance на сколько подпись вебхука может отставать от часов получателя
+var WebhookSignatureTolerance = 5 * time.Minute
+
+// StreamBufferSize сколько сообщений ждёт отправки одному подключению;
+// медленного клиента отключают, и он продолжает с Last-Event-ID
+var StreamBufferSize = 64
+
+// StreamMaxConnections сколько потоков событий может держать один клиент
+var StreamMaxConnections = 5
+
+// StreamHeartbeat как часто поток шлёт пинг и перепроверяет сессию
+var StreamHeartbeat = 25 * time.Second
+
+// StreamReplayLimit сколько пропущенных сообщений досылается при
+// переподключении; если пропущено больше, клиенту приходит resync
+var StreamReplayLimit = 500
+
+// StreamGapWait сколько ждать событие с пропущенным номером: его транзакция
+// может ещё не зафиксироваться
+var StreamGapWait = 3 * time.Second
+
+// DefaultLanguage язык ответов API и уведомлений, если клиент не выбрал
+// язык и Accept-Language не подошёл: ru или en
+var DefaultLanguage = "ru"
+
+// NotifyDefaultTimezone часовой пояс тихих часов, пока клиент его не указал
+var NotifyDefaultTimezone = "Europe/Moscow"
+
+// NotifyEmailTransport как отправляются письма: log, file или smtp
+var NotifyEmailTransport = "log"
+
+// NotifySMSTransport как отправляются SMS: log или file; провайдер
+// подключается через notify.SMSChannel
+var NotifySMSTransport = "log"
+
+// NotifyPushTransport как отправляются push-уведомления: log или file;
+// провайдер подключается через notify.PushChannel
+var NotifyPushTransport = "log"
+
+// NotifyFileDir каталог, куда транспорт file пишет уведомления (<канал>.jsonl)
+var NotifyFileDir = "notifications_out"
+
+// SMTPAddr адрес SMTP-сервера (host:port)
+var SMTPAddr = "localhost:587"
+
+var SMTPFrom = "noreply@simple-bank.local"
+
+var SMTPUsername = ""
+
+var SMTPPassword = ""
+
+// ProblemResponses отвечать об ошибках в формате application/problem+json
+// (RFC 9457) всем клиентам, а не только тем, кто просит его в Accept
+var ProblemResponses = false
+
+// ProblemTypeBase начало URI в поле type ответа об ошибке; к нему
+// добавляется код ошибки: urn:simple-bank:problem:insufficient-funds
+var ProblemTypeBase = "urn:simple-bank:problem:"
+
+// PhoneDefaultCountryCode код страны для номеров без него: 8 912 345-67-89
+// и 912 345-67-89 становятся +79123456789
+var PhoneDefaultCountryCode = "7"
+
+// PhoneTrunkPrefix префикс междугородней связи, который заменяется кодом страны
+var PhoneTrunkPrefix = "8"
+
+// PhoneNationalLength длина номера без кода страны и префикса
+var PhoneNationalLength = 10
+
+// NameMaxLength наибольшая длина имени и фамилии
+var NameMaxLength = 50
+
+// PasswordMinLength и PasswordMaxLength допустимая длина пароля; bcrypt
+// учитывает только первые 72 байта, поэтому длиннее не имеет смысла
+var PasswordMinLength = 8
+
+var PasswordMaxLength = 72
+
+// CommonPasswordsFile список распространённых и утёкших паролей, по одному
+// в строке; такие пароли не принимаются. Пока файла нет, проверка выключена.
+var CommonPasswordsFile = "common_passwords.txt"
@@ -0,0 +1,31 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"backend_golang/types"
+)
+
+var personName = regexp.MustCompile(`^\p{L}[\p{L}\p{M}]*(?:[ '’-]\p{L}[\p{L}\p{M}]*)*$`)
+
+// validatePersonName пропускает буквы любых алфавитов, разделённые одним
+// пробелом, дефисом или апострофом: Анна-Мария, O'Neil. Лишние пробелы убираются.
+func validatePersonName(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	name := strings.Join(strings.Fields(field.String()), " ")
+	if len([]rune(name)) > types.NameMaxLength || !personName.MatchString(name) {
+		return false
+	}
+	if field.CanSet() {
+		field.SetString(name)
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+
+	"backend_golang/types"
+)
+
+var (
+	mu       sync.RWMutex
+	common   = map[string]bool{}
+	modified time.Time
+)
+
+// passwordProblem возвращает код нарушения политики паролей или "", если пароль подходит.
+// Длина сверху считается в байтах: bcrypt отбрасывает всё после 72-го.
+func passwordProblem(password string) string {
+	switch {
+	case utf8.RuneCountInString(password) < types.PasswordMinLength:
+		return "PASSWORD_TOO_SHORT"
+	case len(password) > types.PasswordMaxLength:
+		return "PASSWORD_TOO_LONG"
+	case isCommon(password):
+		return "PASSWORD_COMMON"
+	}
+	return ""
+}
+
+func validatePassword(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	return field.Kind() == reflect.String && passwordProblem(field.String()) == ""
+}
+
+// isCommon сравнивает без учёта регистра: Qwerty123 не лучше qwerty123
+func isCommon(password string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return common[strings.ToLower(password)]
+}
+
+// LoadCommonPasswords читает список распространённых паролей: по одному в строке,
+// пустые строки и строки с # пропускаются
+func LoadCommonPasswords(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	common = list
+	mu.Unlock()
+	return nil
+}
+
+// ReloadJob перечитывает список распространённых паролей, если файл изменился.
+// Пока файла нет, проверяется только длина пароля.
+func ReloadJob() error {
+	info, err := os.Stat(types.CommonPasswordsFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.ModTime().After(modified) {
+		return nil
+	}
+
+	// Как и у правил антифрода, время запоминается и при ошибке
+	modified = info.ModTime()
+	if err := LoadCommonPasswords(types.CommonPasswordsFile); err != nil {
+		return err
+	}
+
+	mu.RLock()
+	n := len(common)
+	mu.RUnlock()
+	log.Printf("🔑 Common passwords loaded from %s: %d entries", types.CommonPasswordsFile, n)
+	return nil
+}
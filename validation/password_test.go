@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordProblem(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(list, []byte("# leaked\nqwerty123\n\n  password1  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadCommonPasswords(list); err != nil {
+		t.Fatal(err)
+	}
+	defer LoadCommonPasswords(os.DevNull)
+
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"correct horse", ""},
+		{"1234567", "PASSWORD_TOO_SHORT"},
+		{"пароль1", "PASSWORD_TOO_SHORT"},
+		{"пароль12", ""},
+		{strings.Repeat("a", 72), ""},
+		{strings.Repeat("a", 73), "PASSWORD_TOO_LONG"},
+		{strings.Repeat("я", 37), "PASSWORD_TOO_LONG"},
+		{"qwerty123", "PASSWORD_COMMON"},
+		{"QWERTY123", "PASSWORD_COMMON"},
+		{"Password1", "PASSWORD_COMMON"},
+		{"# leaked", ""},
+	}
+	for _, tt := range tests {
+		if got := passwordProblem(tt.password); got != tt.want {
+			t.Errorf("passwordProblem(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
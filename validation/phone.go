@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"backend_golang/types"
+)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// NormalizePhone приводит телефон к E.164. Пробелы, дефисы, точки и скобки
+// отбрасываются, 00 в начале читается как +, а номер без кода страны получает
+// types.PhoneDefaultCountryCode. ok = false, если это не телефон.
+func NormalizePhone(raw string) (phone string, ok bool) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '.', r == '(', r == ')':
+		default:
+			return "", false
+		}
+	}
+
+	d := digits.String()
+	national := types.PhoneNationalLength
+	trunk := types.PhoneTrunkPrefix
+	switch {
+	case international:
+	case strings.HasPrefix(d, "00"):
+		d = d[2:]
+	case len(d) == national:
+		d = types.PhoneDefaultCountryCode + d
+	case trunk != "" && len(d) == len(trunk)+national && strings.HasPrefix(d, trunk):
+		d = types.PhoneDefaultCountryCode + d[len(trunk):]
+	}
+
+	phone = "+" + d
+	if !e164.MatchString(phone) {
+		return "", false
+	}
+	return phone, true
+}
+
+// validatePhone проверяет телефон и заменяет значение поля на E.164,
+// чтобы в базу и в поиск попадал один и тот же вид номера
+func validatePhone(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	phone, ok := NormalizePhone(field.String())
+	if !ok {
+		return false
+	}
+	if field.CanSet() {
+		field.SetString(phone)
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package validation
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw   string
+		phone string
+		ok    bool
+	}{
+		{"+79123456789", "+79123456789", true},
+		{"8 (912) 345-67-89", "+79123456789", true},
+		{"89123456789", "+79123456789", true},
+		{"9123456789", "+79123456789", true},
+		{" +7 912 345.67.89 ", "+79123456789", true},
+		{"0049 30 1234567", "+49301234567", true},
+		{"+44 20 7946 0958", "+442079460958", true},
+		{"79123456789", "+79123456789", true},
+		{"+0123456789", "", false},
+		{"+7912", "", false},
+		{"+7912345678901234", "", false},
+		{"8-912-ABC-67-89", "", false},
+		{"79+123456789", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		phone, ok := NormalizePhone(tt.raw)
+		if phone != tt.phone || ok != tt.ok {
+			t.Errorf("NormalizePhone(%q) = %q, %v, want %q, %v", tt.raw, phone, ok, tt.phone, tt.ok)
+		}
+	}
+}
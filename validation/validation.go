@@ -0,0 +1,137 @@
+// Package validation проверяет тела запросов по правилам в тегах binding
+// и отвечает сразу обо всех неверных полях.
+//
+// Кроме правил validator (required, min, max, gte, oneof...) доступны:
+//
+//	phone        телефон; значение приводится к E.164: 8 (912) 345-67-89 → +79123456789
+//	person_name  имя или фамилия: буквы, пробелы, дефис и апостроф
+//	password     пароль по политике types.Password* и без распространённых паролей
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"backend_golang/apierr"
+	"backend_golang/i18n"
+	"backend_golang/types"
+)
+
+// CodeValidationFailed код ответа, в errors которого перечислены неверные поля
+const CodeValidationFailed = "VALIDATION_FAILED"
+
+func init() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic("validation: gin validator is not go-playground/validator")
+	}
+
+	v.RegisterTagNameFunc(fieldName)
+	v.RegisterValidation("phone", validatePhone)
+	v.RegisterValidation("person_name", validatePersonName)
+	v.RegisterValidation("password", validatePassword)
+}
+
+// bodyBindings разбор тела по Content-Type. Поля читаются только из тела:
+// binding.Form взял бы их и из адреса, а адрес с паролем попадает в логи.
+var bodyBindings = map[string]binding.Binding{
+	binding.MIMEJSON:              binding.JSON,
+	binding.MIMEPOSTForm:          binding.FormPost,
+	binding.MIMEMultipartPOSTForm: binding.FormMultipart,
+}
+
+// Bind разбирает тело запроса в JSON, form-urlencoded или multipart и проверяет
+// его. Ошибка уже готова для c.Error: UNSUPPORTED_MEDIA_TYPE для другого
+// Content-Type, INVALID_JSON, если тело не разбирается, или VALIDATION_FAILED
+// со списком полей.
+func Bind(c *gin.Context, obj interface{}) error {
+	contentType := c.ContentType()
+	b, ok := bodyBindings[contentType]
+	if !ok && contentType == "" && c.Request.ContentLength == 0 {
+		// Пустое тело без типа: обязательные поля всё равно будут названы
+		b, ok = binding.FormPost, true
+	}
+	if !ok {
+		return apierr.New(http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", i18n.Args{"type": contentType})
+	}
+
+	err := c.ShouldBindWith(obj, b)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		return failed(errs)
+	}
+	return apierr.New(http.StatusBadRequest, "INVALID_JSON", i18n.Args{"error": err.Error()})
+}
+
+func failed(errs validator.ValidationErrors) *apierr.Error {
+	fields := make([]apierr.FieldError, 0, len(errs))
+	names := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		code, args := rule(fe)
+		fields = append(fields, apierr.FieldError{Field: fe.Field(), Code: code, Args: args})
+		names = append(names, fe.Field())
+	}
+
+	e := apierr.New(http.StatusBadRequest, CodeValidationFailed, i18n.Args{"fields": strings.Join(names, ", ")})
+	e.Fields = fields
+	return e
+}
+
+// stringRules коды для правил длины строки: у чисел min и max — значение
+var stringRules = map[string]string{"min": "MIN_LENGTH", "max": "MAX_LENGTH", "len": "LENGTH"}
+
+// rule переводит нарушенное правило в код FIELD.* каталога сообщений
+func rule(fe validator.FieldError) (string, i18n.Args) {
+	// Числа остаются числами, чтобы сообщение могло склонять слово: 8 символов
+	param := i18n.Args{"param": fe.Param()}
+	if n, err := strconv.Atoi(fe.Param()); err == nil {
+		param["param"] = n
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "REQUIRED", nil
+	case "phone":
+		return "PHONE", nil
+	case "person_name":
+		return "PERSON_NAME", i18n.Args{"max": types.NameMaxLength}
+	case "password":
+		value, _ := fe.Value().(string)
+		return passwordProblem(value), i18n.Args{"min": types.PasswordMinLength, "max": types.PasswordMaxLength}
+	case "min", "max", "len":
+		if fe.Kind() == reflect.String {
+			return stringRules[fe.Tag()], param
+		}
+		return strings.ToUpper(fe.Tag()), param
+	case "gt", "gte", "lt", "lte":
+		return strings.ToUpper(fe.Tag()), param
+	case "oneof":
+		return "ONEOF", i18n.Args{"param": strings.ReplaceAll(fe.Param(), " ", ", ")}
+	}
+	return "INVALID", nil
+}
+
+// fieldName называет поле так же, как клиент: по тегу json, а без него по form
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
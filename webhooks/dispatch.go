@@ -0,0 +1,320 @@
+package webhooks
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"backend_golang/database"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// dispatchBatch сколько доставок отправляется за один запуск
+const dispatchBatch = 100
+
+// dialer соединяется только с публичными адресами: DNS точки мог
+// измениться после проверки в ValidateURL
+var dialer = &net.Dialer{
+	Timeout: types.WebhookTimeout,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		if types.WebhookAllowPrivate {
+			return nil
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	},
+}
+
+var client = &http.Client{
+	Timeout: types.WebhookTimeout,
+	// без прокси из окружения: иначе dialer проверял бы адрес прокси
+	Transport: &http.Transport{
+		DialContext:         dialer.DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: types.WebhookTimeout,
+	},
+	// перенаправления не выполняются: подписанное тело ушло бы на другой адрес
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// DispatchJob отправляет доставки, время которых подошло
+func DispatchJob() error {
+	rows, err := database.DB.Query(
+		`SELECT d.id FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.status = ? AND d.next_attempt_at <= ? AND e.active
+		ORDER BY d.next_attempt_at, d.id LIMIT ?`,
+		StatusPending, time.Now(), dispatchBatch,
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Ошибка одной доставки не должна останавливать отправку остальных
+	for _, id := range ids {
+		if err := deliver(id); err != nil {
+			log.Printf("❌ Webhook delivery %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+func deliver(id int64) error {
+	var endpointID int64
+	var eventID, eventType, payload, endpointURL, secret string
+	var attempts int
+	err := database.DB.QueryRow(
+		`SELECT d.endpoint_id, d.event_id, d.event_type, d.payload, d.attempts, e.url, e.secret
+		FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.id = ?`, id,
+	).Scan(&endpointID, &eventID, &eventType, &payload, &attempts, &endpointURL, &secret)
+	if err != nil {
+		return err
+	}
+
+	// Доставку забирает тот, кто первым сдвинул время следующей попытки:
+	// запрос и его таймаут укладываются в этот запас
+	now := time.Now()
+	lease := now.Add(types.WebhookTimeout + time.Minute)
+	result, err := database.DB.Exec(
+		"UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?",
+		lease, id, StatusPending, now,
+	)
+	if err != nil {
+		return err
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+
+	attempt := attempts + 1
+	statusCode, responseBody, sendErr := send(endpointURL, secret, eventType, eventID, []byte(payload))
+	duration := time.Since(now)
+
+	var code interface{}
+	if statusCode != 0 {
+		code = statusCode
+	}
+	errText := ""
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+
+	return database.WithTx(func(tx *sql.Tx) error {
+		// в журнале попытки нумеруются сквозь повторные отправки
+		_, err := tx.Exec(
+			`INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, response_body, duration_ms)
+			SELECT ?, COUNT(*) + 1, ?, LEFT(?, 255), LEFT(?, 1024), ? FROM webhook_attempts WHERE delivery_id = ?`,
+			id, code, errText, responseBody, duration.Milliseconds(), id,
+		)
+		if err != nil {
+			return err
+		}
+
+		if sendErr == nil {
+			_, err = tx.Exec(
+				`UPDATE webhook_deliveries SET status = ?, attempts = ?, last_status_code = ?, last_error = '',
+				delivered_at = ? WHERE id = ?`,
+				StatusDelivered, attempt, code, time.Now(), id,
+			)
+			return err
+		}
+
+		status, next := StatusPending, time.Now().Add(retryDelay(attempt))
+		if attempt >= types.WebhookMaxAttempts {
+			status = StatusDead
+			log.Printf("💀 Webhook delivery %d to endpoint %d is dead after %d attempts: %s", id, endpointID, attempt, errText)
+		}
+		_, err = tx.Exec(
+			`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?,
+			last_error = LEFT(?, 255) WHERE id = ?`,
+			status, attempt, next, code, errText, id,
+		)
+		return err
+	})
+}
+
+// send отправляет подписанный запрос; доставленным считается ответ 2xx
+func send(endpointURL, secret, eventType, eventID string, body []byte) (int, string, error) {
+	req, err := http.NewRequest(http.MethodPost, endpointURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simple_bank-webhooks/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, eventID)
+	req.Header.Set(HeaderSignature, Sign(secret, time.Now(), body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, string(responseBody), fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, string(responseBody), nil
+}
+
+// retryDelay пауза после неудачной попытки attempt: WebhookRetryBase,
+// затем вдвое больше каждый раз, но не больше суток
+func retryDelay(attempt int) time.Duration {
+	delay := types.WebhookRetryBase
+	for i := 1; i < attempt && delay < 24*time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > 24*time.Hour {
+		delay = 24 * time.Hour
+	}
+	return delay
+}
+
+const selectDelivery = `SELECT id, endpoint_id, event_id, event_type, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at, payload FROM webhook_deliveries`
+
+func scanDelivery(row interface{ Scan(...interface{}) error }) (Delivery, error) {
+	var d Delivery
+	var statusCode sql.NullInt64
+	var nextAttempt, createdAt time.Time
+	var deliveredAt sql.NullTime
+	var payload string
+	err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &nextAttempt,
+		&statusCode, &d.LastError, &createdAt, &deliveredAt, &payload)
+	if err != nil {
+		return d, err
+	}
+
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		d.LastStatusCode = &code
+	}
+	if d.Status == StatusPending {
+		d.NextAttemptAt = nextAttempt.Format(types.TimeLayout)
+	}
+	d.CreatedAt = createdAt.Format(types.TimeLayout)
+	if deliveredAt.Valid {
+		d.DeliveredAt = deliveredAt.Time.Format(types.TimeLayout)
+	}
+	d.Payload = []byte(payload)
+	return d, nil
+}
+
+// ListDeliveries возвращает журнал доставок точки, новые первыми;
+// status отбирает доставки в одном статусе
+func ListDeliveries(q ledger.Querier, endpointID int64, status string) ([]Delivery, error) {
+	query := selectDelivery + " WHERE endpoint_id = ?"
+	args := []interface{}{endpointID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+
+	rows, err := q.Query(query+" ORDER BY id DESC LIMIT 200", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		d.Payload = nil
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery возвращает доставку точки вместе с телом и всеми попытками
+func GetDelivery(q ledger.Querier, endpointID, id int64) (Delivery, error) {
+	d, err := scanDelivery(q.QueryRow(selectDelivery+" WHERE id = ? AND endpoint_id = ?", id, endpointID))
+	if err != nil {
+		return d, err
+	}
+
+	rows, err := q.Query(
+		`SELECT attempt, status_code, error, response_body, duration_ms, created_at
+		FROM webhook_attempts WHERE delivery_id = ? ORDER BY id`, id,
+	)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+
+	d.Log = make([]Attempt, 0)
+	for rows.Next() {
+		var a Attempt
+		var statusCode sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&a.Attempt, &statusCode, &a.Error, &a.ResponseBody, &a.DurationMS, &createdAt); err != nil {
+			return d, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			a.StatusCode = &code
+		}
+		a.CreatedAt = createdAt.Format(types.TimeLayout)
+		d.Log = append(d.Log, a)
+	}
+	return d, rows.Err()
+}
+
+// Redeliver ставит доставленную или dead-доставку в очередь заново с полным
+// запасом попыток. Получатель увидит то же событие с тем же ID.
+func Redeliver(q ledger.Querier, endpointID, id int64) (Delivery, error) {
+	d, err := GetDelivery(q, endpointID, id)
+	if err != nil {
+		return d, err
+	}
+	if d.Status == StatusPending {
+		return d, ErrDeliveryPending
+	}
+
+	_, err = q.Exec(
+		"UPDATE webhook_deliveries SET status = ?, attempts = 0, next_attempt_at = ?, last_error = '' WHERE id = ?",
+		StatusPending, time.Now(), id,
+	)
+	if err != nil {
+		return d, err
+	}
+	log.Printf("🔁 Webhook delivery %d queued for redelivery", id)
+	return GetDelivery(q, endpointID, id)
+}
+
+// IsDeliveryStatus проверяет фильтр журнала доставок
+func IsDeliveryStatus(status string) bool {
+	return contains([]string{StatusPending, StatusDelivered, StatusDead}, status)
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса вебхука
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhooks: invalid signature")
+	ErrStaleSignature   = errors.New("webhooks: signature timestamp outside tolerance")
+)
+
+// Sign подписывает тело запроса: HMAC-SHA256 секретом точки от строки
+// "<timestamp>.<body>". Значение заголовка — "t=<unix>,v1=<hex>".
+// Метка времени входит в подпись, поэтому перехваченный запрос нельзя
+// повторить позже допустимого окна.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+// Verify проверяет заголовок подписи на стороне получателя
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleSignature
+	}
+
+	expected := signature(secret, t, body)
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	signedAt := time.Unix(1740830400, 0)
+	body := []byte(`{"event":"transfer.posted","id":1}`)
+	header := Sign("secret", signedAt, body)
+	tolerance := 5 * time.Minute
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		now    time.Time
+		want   error
+	}{
+		{"valid", "secret", header, body, signedAt, nil},
+		{"at tolerance", "secret", header, body, signedAt.Add(tolerance), nil},
+		{"clock behind", "secret", header, body, signedAt.Add(-tolerance), nil},
+		{"stale", "secret", header, body, signedAt.Add(tolerance + time.Second), ErrStaleSignature},
+		{"from the future", "secret", header, body, signedAt.Add(-tolerance - time.Second), ErrStaleSignature},
+		{"wrong secret", "other", header, body, signedAt, ErrInvalidSignature},
+		{"changed body", "secret", header, []byte(`{"event":"transfer.posted","id":2}`), signedAt, ErrInvalidSignature},
+		{"one of several signatures", "secret", Sign("old", signedAt, body) + ",v1=" + signature("secret", "1740830400", body), body, signedAt, nil},
+		{"replayed with new timestamp", "secret", "t=1740830460,v1=" + signature("secret", "1740830400", body), body, signedAt, ErrInvalidSignature},
+		{"no timestamp", "secret", "v1=" + signature("secret", "1740830400", body), body, signedAt, ErrInvalidSignature},
+		{"no signature", "secret", "t=1740830400", body, signedAt, ErrInvalidSignature},
+		{"garbage", "secret", "garbage", body, signedAt, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		err := Verify(tt.secret, tt.header, tt.body, tolerance, tt.now)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
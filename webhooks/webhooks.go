@@ -0,0 +1,357 @@
+// Package webhooks доставляет доменные события клиентам на их HTTP-адреса.
+// Доставка ставится в очередь в той же транзакции, что и событие, тело
+// подписывается секретом точки (см. Sign), неудачные попытки повторяются
+// с растущей паузой, а после types.WebhookMaxAttempts доставка уходит
+// в статус dead, откуда её можно отправить заново вручную.
+package webhooks
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"time"
+
+	"backend_golang/events"
+	"backend_golang/ledger"
+	"backend_golang/types"
+)
+
+// Статусы доставки
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// AllEvents подписка на все типы событий
+const AllEvents = "*"
+
+var (
+	ErrInvalidURL        = errors.New("webhooks: url must be https")
+	ErrPrivateAddress    = errors.New("webhooks: url resolves to a private address")
+	ErrInvalidEventTypes = errors.New("webhooks: unknown event type")
+	ErrDeliveryPending   = errors.New("webhooks: delivery is still pending")
+)
+
+// Endpoint адрес клиента для вебхуков
+type Endpoint struct {
+	ID          int64    `json:"id"`
+	UserID      int64    `json:"user_id"`
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Description string   `json:"description,omitempty"`
+	Active      bool     `json:"active"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+
+	// Secret показывается только при создании и смене секрета
+	Secret string `json:"secret,omitempty"`
+}
+
+// Delivery доставка одного события на одну точку
+type Delivery struct {
+	ID             int64           `json:"id"`
+	EndpointID     int64           `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  string          `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      string          `json:"created_at"`
+	DeliveredAt    string          `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+	Log            []Attempt       `json:"attempts_log,omitempty"`
+}
+
+// Attempt одна попытка доставки
+type Attempt struct {
+	Attempt      int    `json:"attempt"`
+	StatusCode   *int   `json:"status_code,omitempty"`
+	Error        string `json:"error,omitempty"`
+	ResponseBody string `json:"response_body,omitempty"`
+	DurationMS   int    `json:"duration_ms"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// ValidateURL проверяет адрес точки: только https и только публичные адреса,
+// иначе через вебхук можно достучаться до внутренней сети банка.
+// types.WebhookAllowPrivate снимает оба ограничения для разработки.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	if types.WebhookAllowPrivate && (u.Scheme == "https" || u.Scheme == "http") {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return ErrInvalidURL
+	}
+
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return ErrInvalidURL
+	}
+	for _, ip := range ips {
+		if !isPublic(ip) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// nonPublicNets сети, которые не покрывают методы net.IP
+var nonPublicNets = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),     // «эта сеть»
+	mustCIDR("100.64.0.0/10"), // CGNAT
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// isPublic проверяет, что ip не loopback, не частный, не link-local,
+// не multicast и не неуказанный адрес
+func isPublic(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateEventTypes проверяет подписку: известные типы событий или "*"
+func ValidateEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return ErrInvalidEventTypes
+	}
+	for _, t := range eventTypes {
+		if t != AllEvents && !contains(events.Types, t) {
+			return ErrInvalidEventTypes
+		}
+	}
+	return nil
+}
+
+// NewSecret случайный секрет подписи
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+const selectEndpoint = `SELECT id, user_id, url, event_types, description, active, created_at, updated_at
+	FROM webhook_endpoints`
+
+func scanEndpoint(row interface{ Scan(...interface{}) error }) (Endpoint, error) {
+	var e Endpoint
+	var eventTypes string
+	var createdAt, updatedAt time.Time
+	err := row.Scan(&e.ID, &e.UserID, &e.URL, &eventTypes, &e.Description, &e.Active, &createdAt, &updatedAt)
+	if err != nil {
+		return e, err
+	}
+	e.CreatedAt = createdAt.Format(types.TimeLayout)
+	e.UpdatedAt = updatedAt.Format(types.TimeLayout)
+	return e, json.Unmarshal([]byte(eventTypes), &e.EventTypes)
+}
+
+// GetEndpoint возвращает точку клиента
+func GetEndpoint(q ledger.Querier, userID, id int64) (Endpoint, error) {
+	return scanEndpoint(q.QueryRow(selectEndpoint+" WHERE id = ? AND user_id = ?", id, userID))
+}
+
+// ListEndpoints возвращает точки клиента
+func ListEndpoints(q ledger.Querier, userID int64) ([]Endpoint, error) {
+	rows, err := q.Query(selectEndpoint+" WHERE user_id = ? ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := make([]Endpoint, 0)
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// CreateEndpoint регистрирует точку; секрет возвращается в Endpoint.Secret
+func CreateEndpoint(q ledger.Querier, userID int64, rawURL string, eventTypes []string, description string) (Endpoint, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return Endpoint{}, err
+	}
+	if err := ValidateEventTypes(eventTypes); err != nil {
+		return Endpoint{}, err
+	}
+	secret, err := NewSecret()
+	if err != nil {
+		return Endpoint{}, err
+	}
+	list, err := json.Marshal(eventTypes)
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	result, err := q.Exec(
+		"INSERT INTO webhook_endpoints (user_id, url, secret, event_types, description) VALUES (?, ?, ?, ?, ?)",
+		userID, rawURL, secret, string(list), description,
+	)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Endpoint{}, err
+	}
+
+	e, err := GetEndpoint(q, userID, id)
+	e.Secret = secret
+	return e, err
+}
+
+// UpdateEndpoint меняет адрес, подписку, описание и активность точки;
+// nil-поля не меняются
+func UpdateEndpoint(q ledger.Querier, userID, id int64, rawURL *string, eventTypes []string, description *string, active *bool) (Endpoint, error) {
+	e, err := GetEndpoint(q, userID, id)
+	if err != nil {
+		return e, err
+	}
+
+	if rawURL != nil {
+		if err := ValidateURL(*rawURL); err != nil {
+			return e, err
+		}
+		e.URL = *rawURL
+	}
+	if eventTypes != nil {
+		if err := ValidateEventTypes(eventTypes); err != nil {
+			return e, err
+		}
+		e.EventTypes = eventTypes
+	}
+	if description != nil {
+		e.Description = *description
+	}
+	if active != nil {
+		e.Active = *active
+	}
+
+	list, err := json.Marshal(e.EventTypes)
+	if err != nil {
+		return e, err
+	}
+	_, err = q.Exec(
+		"UPDATE webhook_endpoints SET url = ?, event_types = ?, description = ?, active = ? WHERE id = ?",
+		e.URL, string(list), e.Description, e.Active, id,
+	)
+	if err != nil {
+		return e, err
+	}
+	return GetEndpoint(q, userID, id)
+}
+
+// RotateSecret выдаёт точке новый секрет; старый перестаёт действовать сразу
+func RotateSecret(q ledger.Querier, userID, id int64) (Endpoint, error) {
+	e, err := GetEndpoint(q, userID, id)
+	if err != nil {
+		return e, err
+	}
+	secret, err := NewSecret()
+	if err != nil {
+		return e, err
+	}
+	if _, err := q.Exec("UPDATE webhook_endpoints SET secret = ? WHERE id = ?", secret, id); err != nil {
+		return e, err
+	}
+	e.Secret = secret
+	return e, nil
+}
+
+// DeleteEndpoint удаляет точку вместе с журналом доставок
+func DeleteEndpoint(tx *sql.Tx, userID, id int64) error {
+	if _, err := GetEndpoint(tx, userID, id); err != nil {
+		return err
+	}
+
+	statements := []string{
+		"DELETE a FROM webhook_attempts a JOIN webhook_deliveries d ON d.id = a.delivery_id WHERE d.endpoint_id = ?",
+		"DELETE FROM webhook_deliveries WHERE endpoint_id = ?",
+		"DELETE FROM webhook_endpoints WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EventHook ставит событие в очередь доставки всем подписанным на него
+// активным точкам владельцев события. Вызывается в транзакции события.
+func EventHook(q ledger.Querier, e events.Event) error {
+	owners := e.Users()
+	if len(owners) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range owners {
+		endpoints, err := ListEndpoints(q, userID)
+		if err != nil {
+			return err
+		}
+		for _, endpoint := range endpoints {
+			if !endpoint.Active || !subscribed(endpoint, e.Type) {
+				continue
+			}
+			_, err := q.Exec(
+				`INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, status, next_attempt_at)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				endpoint.ID, e.ID, e.Type, string(body), StatusPending, time.Now(),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func subscribed(endpoint Endpoint, eventType string) bool {
+	return contains(endpoint.EventTypes, AllEvents) || contains(endpoint.EventTypes, eventType)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}